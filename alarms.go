@@ -0,0 +1,261 @@
+package tables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// DefaultAlarmPeriod is the period in seconds alarms evaluate unless set in the config.
+const DefaultAlarmPeriod = 300
+
+// Alarm kinds, used as suffix of the alarm names, e.g. prod-orders-ThrottleEvents.
+const (
+	AlarmThrottleEvents        = "ThrottleEvents"
+	AlarmSystemErrors          = "SystemErrors"
+	AlarmConsumedReadCapacity  = "ConsumedReadCapacity"
+	AlarmConsumedWriteCapacity = "ConsumedWriteCapacity"
+)
+
+var alarmKinds = []string{AlarmThrottleEvents, AlarmSystemErrors, AlarmConsumedReadCapacity, AlarmConsumedWriteCapacity}
+
+// systemErrorOperations are the operations whose SystemErrors are summed, the metric has no table-wide value.
+var systemErrorOperations = []string{"GetItem", "PutItem", "UpdateItem", "DeleteItem", "Query", "Scan", "BatchGetItem", "BatchWriteItem"}
+
+// AlarmsInfo configures the basic operational alarms of a table. Zero thresholds disable an alarm.
+type AlarmsInfo struct {
+	// Actions are the ARNs notified when an alarm fires, e.g. SNS topics.
+	Actions []string `yaml:"actions"`
+	// Period in seconds, DefaultAlarmPeriod if zero.
+	Period int64 `yaml:"period"`
+	// ThrottledRequests fires when more read and write requests are throttled within a period.
+	ThrottledRequests int64 `yaml:"throttled_requests"`
+	// SystemErrors fires when more requests fail with server errors within a period.
+	SystemErrors int64 `yaml:"system_errors"`
+	// ConsumedReadCapacity and ConsumedWriteCapacity fire when the average consumed
+	// capacity units per second exceed the threshold within a period.
+	ConsumedReadCapacity  int64 `yaml:"consumed_read_capacity"`
+	ConsumedWriteCapacity int64 `yaml:"consumed_write_capacity"`
+}
+
+// AlarmManager is a ResourceManager keeping the CloudWatch alarms configured in the alarms section
+// of each table in lockstep with the schema. Alarms are named after the table in DynamoDB and the
+// alarm kind, alarms removed from the config are deleted.
+type AlarmManager struct {
+	CloudWatch cloudwatchiface.CloudWatchAPI
+	env        string
+}
+
+// NewAlarmManager returns an AlarmManager for the tables of the environment.
+// Register it with WithResourceManagers.
+func NewAlarmManager(cw cloudwatchiface.CloudWatchAPI, env string) *AlarmManager {
+	return &AlarmManager{CloudWatch: cw, env: env}
+}
+
+// Name implements ResourceManager.
+func (am *AlarmManager) Name() string {
+	return "alarms"
+}
+
+// Validate implements ResourceManager.
+func (am *AlarmManager) Validate(tbl TableInfo) (string, error) {
+	missing, changed, extra, err := am.compare(tbl)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	for _, a := range missing {
+		parts = append(parts, "missing alarm: "+aws.StringValue(a.AlarmName))
+	}
+	for _, a := range changed {
+		parts = append(parts, "changed alarm: "+aws.StringValue(a.AlarmName))
+	}
+	for _, name := range extra {
+		parts = append(parts, "extra alarm: "+name)
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// Migrate implements ResourceManager.
+func (am *AlarmManager) Migrate(tbl TableInfo) error {
+	return am.migrate(tbl, noAudit)
+}
+
+func (am *AlarmManager) migrate(tbl TableInfo, audit auditFunc) error {
+	missing, changed, extra, err := am.compare(tbl)
+	if err != nil {
+		return err
+	}
+	for _, input := range append(missing, changed...) {
+		if err := audit("cloudwatch:PutMetricAlarm", input); err != nil {
+			return err
+		}
+		if _, err := am.CloudWatch.PutMetricAlarm(input); err != nil {
+			return err
+		}
+	}
+	if len(extra) > 0 {
+		input := &cloudwatch.DeleteAlarmsInput{AlarmNames: aws.StringSlice(extra)}
+		if err := audit("cloudwatch:DeleteAlarms", input); err != nil {
+			return err
+		}
+		_, err := am.CloudWatch.DeleteAlarms(input)
+		return err
+	}
+	return nil
+}
+
+// compare returns the inputs of missing and changed alarms and the names of alarms to delete.
+func (am *AlarmManager) compare(tbl TableInfo) (missing, changed []*cloudwatch.PutMetricAlarmInput, extra []string, err error) {
+	name := physicalName(am.env, tbl)
+	names := make([]*string, len(alarmKinds))
+	for i, kind := range alarmKinds {
+		names[i] = aws.String(alarmName(name, kind))
+	}
+	output, err := am.CloudWatch.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{AlarmNames: names})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	existing := make(map[string]*cloudwatch.MetricAlarm, len(output.MetricAlarms))
+	for _, a := range output.MetricAlarms {
+		existing[aws.StringValue(a.AlarmName)] = a
+	}
+
+	desired := AlarmInputs(tbl, am.env)
+	for _, input := range desired {
+		current, ok := existing[aws.StringValue(input.AlarmName)]
+		delete(existing, aws.StringValue(input.AlarmName))
+		switch {
+		case !ok:
+			missing = append(missing, input)
+		case alarmChanged(current, input):
+			changed = append(changed, input)
+		}
+	}
+	for name := range existing {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	return missing, changed, extra, nil
+}
+
+// AlarmInputs returns the inputs creating the alarms configured for the table in the given environment.
+func AlarmInputs(tbl TableInfo, env string) []*cloudwatch.PutMetricAlarmInput {
+	cfg := tbl.Alarms
+	if cfg == nil {
+		return nil
+	}
+	name := physicalName(env, tbl)
+	period := cfg.Period
+	if period <= 0 {
+		period = DefaultAlarmPeriod
+	}
+	tableDimension := []*cloudwatch.Dimension{{Name: aws.String("TableName"), Value: aws.String(name)}}
+	stat := func(id, metric string, dimensions []*cloudwatch.Dimension) *cloudwatch.MetricDataQuery {
+		return &cloudwatch.MetricDataQuery{
+			Id:         aws.String(id),
+			ReturnData: aws.Bool(false),
+			MetricStat: &cloudwatch.MetricStat{
+				Metric: &cloudwatch.Metric{
+					Namespace:  aws.String("AWS/DynamoDB"),
+					MetricName: aws.String(metric),
+					Dimensions: dimensions,
+				},
+				Period: aws.Int64(period),
+				Stat:   aws.String(cloudwatch.StatisticSum),
+			},
+		}
+	}
+	sum := func(label string, queries ...*cloudwatch.MetricDataQuery) []*cloudwatch.MetricDataQuery {
+		return append(queries, &cloudwatch.MetricDataQuery{
+			Id:         aws.String("total"),
+			Label:      aws.String(label),
+			Expression: aws.String("SUM(METRICS())"),
+			ReturnData: aws.Bool(true),
+		})
+	}
+	alarm := func(kind string, threshold float64) *cloudwatch.PutMetricAlarmInput {
+		return &cloudwatch.PutMetricAlarmInput{
+			AlarmName:          aws.String(alarmName(name, kind)),
+			AlarmDescription:   aws.String(fmt.Sprintf("%s of DynamoDB table %s, managed by tables", kind, name)),
+			AlarmActions:       aws.StringSlice(cfg.Actions),
+			ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorGreaterThanThreshold),
+			EvaluationPeriods:  aws.Int64(1),
+			Threshold:          aws.Float64(threshold),
+			TreatMissingData:   aws.String("notBreaching"),
+		}
+	}
+
+	inputs := []*cloudwatch.PutMetricAlarmInput{}
+	if cfg.ThrottledRequests > 0 {
+		input := alarm(AlarmThrottleEvents, float64(cfg.ThrottledRequests))
+		input.Metrics = sum(AlarmThrottleEvents,
+			stat("reads", "ReadThrottleEvents", tableDimension),
+			stat("writes", "WriteThrottleEvents", tableDimension),
+		)
+		inputs = append(inputs, input)
+	}
+	if cfg.SystemErrors > 0 {
+		queries := make([]*cloudwatch.MetricDataQuery, len(systemErrorOperations))
+		for i, op := range systemErrorOperations {
+			queries[i] = stat(strings.ToLower(op), "SystemErrors", append(tableDimension[:1:1], &cloudwatch.Dimension{
+				Name:  aws.String("Operation"),
+				Value: aws.String(op),
+			}))
+		}
+		input := alarm(AlarmSystemErrors, float64(cfg.SystemErrors))
+		input.Metrics = sum(AlarmSystemErrors, queries...)
+		inputs = append(inputs, input)
+	}
+	for _, c := range []struct {
+		kind, metric string
+		threshold    int64
+	}{
+		{AlarmConsumedReadCapacity, "ConsumedReadCapacityUnits", cfg.ConsumedReadCapacity},
+		{AlarmConsumedWriteCapacity, "ConsumedWriteCapacityUnits", cfg.ConsumedWriteCapacity},
+	} {
+		if c.threshold <= 0 {
+			continue
+		}
+		// Consumed capacity is summed per period, the threshold is per second.
+		input := alarm(c.kind, float64(c.threshold*period))
+		input.Namespace = aws.String("AWS/DynamoDB")
+		input.MetricName = aws.String(c.metric)
+		input.Dimensions = tableDimension
+		input.Period = aws.Int64(period)
+		input.Statistic = aws.String(cloudwatch.StatisticSum)
+		inputs = append(inputs, input)
+	}
+	return inputs
+}
+
+func alarmName(tableName, kind string) string {
+	return fmt.Sprintf("%s-%s", tableName, kind)
+}
+
+// alarmChanged reports whether the threshold, period or actions of the alarm differ from the input.
+func alarmChanged(current *cloudwatch.MetricAlarm, input *cloudwatch.PutMetricAlarmInput) bool {
+	if aws.Float64Value(current.Threshold) != aws.Float64Value(input.Threshold) ||
+		alarmPeriod(current.Period, current.Metrics) != alarmPeriod(input.Period, input.Metrics) {
+		return true
+	}
+	actions := aws.StringValueSlice(current.AlarmActions)
+	want := aws.StringValueSlice(input.AlarmActions)
+	sort.Strings(actions)
+	sort.Strings(want)
+	return strings.Join(actions, ",") != strings.Join(want, ",")
+}
+
+// alarmPeriod returns the period of a single metric alarm or of the first metric of a metric math alarm.
+func alarmPeriod(period *int64, metrics []*cloudwatch.MetricDataQuery) int64 {
+	for _, m := range metrics {
+		if m.MetricStat != nil {
+			return aws.Int64Value(m.MetricStat.Period)
+		}
+	}
+	return aws.Int64Value(period)
+}
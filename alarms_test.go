@@ -0,0 +1,133 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// alarmCloudWatch keeps alarms in memory.
+type alarmCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	alarms map[string]*cloudwatch.MetricAlarm
+}
+
+func (f *alarmCloudWatch) DescribeAlarms(input *cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error) {
+	output := &cloudwatch.DescribeAlarmsOutput{}
+	for _, name := range input.AlarmNames {
+		if a, ok := f.alarms[aws.StringValue(name)]; ok {
+			output.MetricAlarms = append(output.MetricAlarms, a)
+		}
+	}
+	return output, nil
+}
+
+func (f *alarmCloudWatch) PutMetricAlarm(input *cloudwatch.PutMetricAlarmInput) (*cloudwatch.PutMetricAlarmOutput, error) {
+	f.alarms[aws.StringValue(input.AlarmName)] = &cloudwatch.MetricAlarm{
+		AlarmName:    input.AlarmName,
+		AlarmActions: input.AlarmActions,
+		Threshold:    input.Threshold,
+		Period:       input.Period,
+		Metrics:      input.Metrics,
+	}
+	return &cloudwatch.PutMetricAlarmOutput{}, nil
+}
+
+func (f *alarmCloudWatch) DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudwatch.DeleteAlarmsOutput, error) {
+	for _, name := range input.AlarmNames {
+		delete(f.alarms, aws.StringValue(name))
+	}
+	return &cloudwatch.DeleteAlarmsOutput{}, nil
+}
+
+func TestAlarmInputs(t *testing.T) {
+	tbl := TableInfo{Title: "shop", TableName: "orders", Alarms: &AlarmsInfo{
+		Actions:              []string{"arn:aws:sns:us-east-1:123456789012:oncall"},
+		Period:               60,
+		ThrottledRequests:    10,
+		SystemErrors:         1,
+		ConsumedReadCapacity: 100,
+	}}
+	inputs := AlarmInputs(tbl, "prod")
+	if len(inputs) != 3 {
+		t.Fatalf("expected 3 alarms but got %d", len(inputs))
+	}
+	if name := aws.StringValue(inputs[0].AlarmName); name != "shop-prod-orders-ThrottleEvents" {
+		t.Fatalf("unexpected alarm name %s", name)
+	}
+	if n := len(inputs[1].Metrics); n != len(systemErrorOperations)+1 {
+		t.Fatalf("expected SystemErrors of every operation to be summed but got %d metrics", n)
+	}
+	if th := aws.Float64Value(inputs[2].Threshold); th != 6000 || aws.StringValue(inputs[2].MetricName) != "ConsumedReadCapacityUnits" {
+		t.Fatalf("expected consumed read capacity threshold of 6000 units per period but got %v", inputs[2])
+	}
+	if AlarmInputs(TableInfo{TableName: "users"}, "prod") != nil {
+		t.Fatal("expected no alarms for tables without alarms section")
+	}
+}
+
+func TestAlarmManager(t *testing.T) {
+	cw := &alarmCloudWatch{alarms: map[string]*cloudwatch.MetricAlarm{
+		"test-orders-SystemErrors": {AlarmName: aws.String("test-orders-SystemErrors")},
+	}}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName:   aws.String("test-orders"),
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+			},
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(5),
+			},
+		}}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled),
+		}}, nil
+	}
+	tbl := TableInfo{Title: "test", TableName: "orders", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5,
+		Alarms: &AlarmsInfo{ThrottledRequests: 10}}
+	c, err := NewController(db, "test", nil, []TableInfo{tbl}, WithClock(&fakeClock{}),
+		WithResourceManagers(NewAlarmManager(cw, "test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Validate()
+	if err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	want := "missing alarm: test-test-orders-ThrottleEvents"
+	if d := results[0].ResourceDiffs["alarms"]; d != want {
+		t.Fatalf("expected %q but got %q", want, d)
+	}
+	if ms := c.Migrate(results); ms[0].Status != StatusApplied {
+		t.Fatalf("expected alarms to be migrated but got %+v", ms[0])
+	}
+	if _, ok := cw.alarms["test-test-orders-ThrottleEvents"]; !ok || len(cw.alarms) != 2 {
+		t.Fatalf("expected throttle alarm to be created but got %v", cw.alarms)
+	}
+
+	// Alarms of other kinds are deleted once they are removed from the config.
+	cw.alarms["test-test-orders-SystemErrors"] = &cloudwatch.MetricAlarm{AlarmName: aws.String("test-test-orders-SystemErrors")}
+	am := NewAlarmManager(cw, "test")
+	if d, err := am.Validate(tbl); err != nil || d != "extra alarm: test-test-orders-SystemErrors" {
+		t.Fatalf("expected extra alarm but got %q, %v", d, err)
+	}
+	if err := am.Migrate(tbl); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := am.Validate(tbl); err != nil || d != "" {
+		t.Fatalf("expected alarms in sync but got %q, %v", d, err)
+	}
+}
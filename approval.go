@@ -0,0 +1,221 @@
+package tables
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// DefaultApprovalTimeout is the time SNSApprovalGate waits for an approval unless Timeout is set.
+const DefaultApprovalTimeout = time.Hour
+
+// RunApprover is consulted before a Migrate run applies any change. It receives the validation
+// results of the run and returns whether and by whom all of them were approved. Runs which are
+// rejected or fail to be approved are not migrated.
+type RunApprover func(ctx context.Context, runID string, results []*ValidationResult) (RunApproval, error)
+
+// RunApproval is the decision of a RunApprover.
+type RunApproval struct {
+	Approved bool
+	// Approver identifies who decided, e.g. an email address. It is logged and set on the
+	// Migration Results of approved runs.
+	Approver string
+}
+
+// ApprovalRequest is the plan published by SNSApprovalGate.
+type ApprovalRequest struct {
+	RunID   string `json:"run_id"`
+	Summary string `json:"summary"`
+	// Tables lists the tables with changes.
+	Tables []ValidationReport `json:"tables"`
+	// Respond is the URL of the SQS queue expecting the ApprovalResponse.
+	Respond string `json:"respond"`
+}
+
+// ApprovalResponse approves or rejects the run with the given ID.
+type ApprovalResponse struct {
+	RunID    string `json:"run_id"`
+	Approved bool   `json:"approved"`
+	// Approver identifies who responded, e.g. an email address.
+	Approver string `json:"approver"`
+	// Signature is required if the gate has a SigningKey, see SignApprovalResponse.
+	Signature string `json:"signature,omitempty"`
+}
+
+// SignApprovalResponse returns the signature of the response, the hex encoded HMAC-SHA256 of its
+// run ID, decision and approver with the given key.
+func SignApprovalResponse(key []byte, resp ApprovalResponse) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%s\n%t\n%s", resp.RunID, resp.Approved, resp.Approver)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SNSApprovalGate is a human-in-the-loop RunApprover. It publishes the plan of a run to an SNS topic
+// and blocks until an ApprovalResponse for the run arrives on an SQS queue, e.g. sent by a chat bot or
+// an approval page. Responses may be sent to the queue directly or through an SNS subscription.
+// Responses for other runs are left on the queue, responses for the run by approvers who are not
+// allowed or with an invalid signature are removed from it and ignored.
+type SNSApprovalGate struct {
+	SNS      snsiface.SNSAPI
+	SQS      sqsiface.SQSAPI
+	TopicARN string
+	QueueURL string
+	// Approvers lists who may approve or reject runs, matched against ApprovalResponse.Approver.
+	// Approve fails with ErrNoApprovers if it is empty.
+	Approvers []string
+	// SigningKey makes the gate accept signed responses only, so approvers cannot be impersonated
+	// by anyone able to send to the queue, see SignApprovalResponse.
+	SigningKey []byte
+	// Timeout is the time to wait for a response, DefaultApprovalTimeout if zero.
+	// ErrApprovalTimeout is returned once it expires.
+	Timeout time.Duration
+}
+
+// Approve publishes the plan and waits for the response. It is a RunApprover,
+// e.g. WithRunApprover(gate.Approve).
+func (g *SNSApprovalGate) Approve(ctx context.Context, runID string, results []*ValidationResult) (RunApproval, error) {
+	if len(g.Approvers) == 0 {
+		return RunApproval{}, ErrNoApprovers
+	}
+	req := ApprovalRequest{
+		RunID:   runID,
+		Summary: Summarize(results).String(),
+		Tables:  []ValidationReport{},
+		Respond: g.QueueURL,
+	}
+	for _, r := range results {
+		if r != nil && len(r.Diff) > 0 {
+			req.Tables = append(req.Tables, NewValidationReport(r))
+		}
+	}
+	// ApprovalRequest only holds plain values and never fails to marshal.
+	body, _ := json.Marshal(req)
+	if _, err := g.SNS.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn: aws.String(g.TopicARN),
+		Subject:  aws.String(fmt.Sprintf("Approve table migration %s", runID)),
+		Message:  aws.String(string(body)),
+	}); err != nil {
+		return RunApproval{}, err
+	}
+
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		resp, err := g.receive(ctx, runID)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return RunApproval{}, fmt.Errorf("%w: run %s", ErrApprovalTimeout, runID)
+			}
+			return RunApproval{}, err
+		}
+		if resp != nil {
+			return RunApproval{Approved: resp.Approved, Approver: resp.Approver}, nil
+		}
+	}
+}
+
+// receive long polls the queue once and returns the accepted response for the run, nil if none arrived.
+func (g *SNSApprovalGate) receive(ctx context.Context, runID string) (*ApprovalResponse, error) {
+	wait := int64(20)
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := int64(time.Until(deadline) / time.Second); left < wait {
+			wait = left
+		}
+	}
+	output, err := g.SQS.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(g.QueueURL),
+		MaxNumberOfMessages: aws.Int64(10),
+		WaitTimeSeconds:     aws.Int64(wait),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range output.Messages {
+		resp, ok := parseApprovalResponse(aws.StringValue(msg.Body))
+		if !ok || resp.RunID != runID {
+			continue
+		}
+		if _, err := g.SQS.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(g.QueueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		}); err != nil {
+			return nil, err
+		}
+		if !g.accepts(resp) {
+			continue
+		}
+		return resp, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// accepts reports whether the response is by an allowed approver and, with a signing key,
+// correctly signed.
+func (g *SNSApprovalGate) accepts(resp *ApprovalResponse) bool {
+	allowed := false
+	for _, approver := range g.Approvers {
+		if resp.Approver == approver {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	if len(g.SigningKey) == 0 {
+		return true
+	}
+	return hmac.Equal([]byte(resp.Signature), []byte(SignApprovalResponse(g.SigningKey, *resp)))
+}
+
+// parseApprovalResponse decodes a response sent to the queue directly or wrapped in an SNS notification.
+func parseApprovalResponse(body string) (*ApprovalResponse, bool) {
+	envelope := struct {
+		Type    string
+		Message string
+	}{}
+	if err := json.Unmarshal([]byte(body), &envelope); err == nil && envelope.Type == "Notification" {
+		body = envelope.Message
+	}
+	resp := &ApprovalResponse{}
+	if err := json.Unmarshal([]byte(body), resp); err != nil || resp.RunID == "" {
+		return nil, false
+	}
+	return resp, true
+}
+
+// approveRun consults the run approver and returns who approved the run, empty if the run has
+// no changes or no approver is set.
+func (c *Controller) approveRun(ctx context.Context, results []*ValidationResult) (string, error) {
+	if c.runApprover == nil || len(Changes(results)) == 0 {
+		return "", nil
+	}
+	runID := RunIDFromContext(ctx)
+	c.Log.Infof("Waiting for approval of run %s", runID)
+	approval, err := c.runApprover(ctx, runID, results)
+	if err != nil {
+		return "", err
+	}
+	if !approval.Approved {
+		return "", fmt.Errorf("%w: run %s rejected by %q", ErrRunNotApproved, runID, approval.Approver)
+	}
+	c.Log.Infof("Run %s approved by %q", runID, approval.Approver)
+	return approval.Approver, nil
+}
@@ -0,0 +1,141 @@
+package tables
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+type fakeSNS struct {
+	snsiface.SNSAPI
+	published []*sns.PublishInput
+}
+
+func (f *fakeSNS) PublishWithContext(ctx aws.Context, input *sns.PublishInput, opts ...request.Option) (*sns.PublishOutput, error) {
+	f.published = append(f.published, input)
+	return &sns.PublishOutput{}, nil
+}
+
+// fakeSQS returns the queued bodies on the first receive, later receives wait for ctx.
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	bodies  []string
+	deleted []string
+}
+
+func (f *fakeSQS) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	if len(f.bodies) == 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	output := &sqs.ReceiveMessageOutput{}
+	for _, body := range f.bodies {
+		output.Messages = append(output.Messages, &sqs.Message{Body: aws.String(body), ReceiptHandle: aws.String(body)})
+	}
+	f.bodies = nil
+	return output, nil
+}
+
+func (f *fakeSQS) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestSNSApprovalGate(t *testing.T) {
+	results := []*ValidationResult{
+		{TableInput: TableInfo{TableName: "orders"}, CreateTableInput: &dynamodb.CreateTableInput{}, Diff: "Table missing", CanMigrate: true},
+		{TableInput: TableInfo{TableName: "users"}, CanMigrate: true},
+	}
+	other := `{"run_id": "run-2", "approved": true, "approver": "ops"}`
+	// Responses of approvers who are not allowed are removed and ignored.
+	stranger := `{"run_id": "run-1", "approved": true, "approver": "mallory"}`
+	// Responses sent through an SNS subscription arrive wrapped in a notification.
+	approval := `{"Type": "Notification", "Message": "{\"run_id\": \"run-1\", \"approved\": true, \"approver\": \"ops\"}"}`
+	topic, queue := &fakeSNS{}, &fakeSQS{bodies: []string{"not json", other, stranger, approval}}
+	gate := &SNSApprovalGate{SNS: topic, SQS: queue, TopicARN: "arn:aws:sns:us-east-1:123456789012:approvals", QueueURL: "https://sqs/approvals",
+		Approvers: []string{"ops"}}
+
+	approved, err := gate.Approve(context.Background(), "run-1", results)
+	if err != nil || !approved.Approved || approved.Approver != "ops" {
+		t.Fatalf("expected run to be approved by ops but got %+v, %v", approved, err)
+	}
+	if len(queue.deleted) != 2 || queue.deleted[0] != stranger || queue.deleted[1] != approval {
+		t.Fatalf("expected only the responses for run-1 to be deleted but got %v", queue.deleted)
+	}
+	req := ApprovalRequest{}
+	if err := json.Unmarshal([]byte(aws.StringValue(topic.published[0].Message)), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.RunID != "run-1" || len(req.Tables) != 1 || req.Tables[0].Table != "orders" || req.Respond != "https://sqs/approvals" {
+		t.Fatalf("expected plan of orders to be published but got %+v", req)
+	}
+
+	gate.Timeout = 10 * time.Millisecond
+	if _, err := gate.Approve(context.Background(), "run-3", results); !errors.Is(err, ErrApprovalTimeout) {
+		t.Fatalf("expected ErrApprovalTimeout but got %v", err)
+	}
+
+	// With a signing key only signed responses are accepted.
+	gate.SigningKey = []byte("secret")
+	forged := ApprovalResponse{RunID: "run-4", Approved: true, Approver: "ops", Signature: SignApprovalResponse([]byte("guess"), ApprovalResponse{RunID: "run-4", Approved: true, Approver: "ops"})}
+	signed := ApprovalResponse{RunID: "run-4", Approved: false, Approver: "ops"}
+	signed.Signature = SignApprovalResponse(gate.SigningKey, signed)
+	for _, resp := range []ApprovalResponse{forged, signed} {
+		body, _ := json.Marshal(resp)
+		queue.bodies = append(queue.bodies, string(body))
+	}
+	gate.Timeout = time.Minute
+	if approved, err := gate.Approve(context.Background(), "run-4", results); err != nil || approved.Approved || approved.Approver != "ops" {
+		t.Fatalf("expected signed rejection only but got %+v, %v", approved, err)
+	}
+
+	gate.Approvers = nil
+	if _, err := gate.Approve(context.Background(), "run-5", results); !errors.Is(err, ErrNoApprovers) {
+		t.Fatalf("expected ErrNoApprovers but got %v", err)
+	}
+}
+
+func TestMigrateRunNotApproved(t *testing.T) {
+	db := &fakeDynamoDB{}
+	created := false
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		created = true
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	var approvedRun string
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}),
+		WithRunApprover(func(ctx context.Context, runID string, results []*ValidationResult) (RunApproval, error) {
+			approvedRun = runID
+			return RunApproval{Approved: runID == "run-2", Approver: "ops"}, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms := c.MigrateWithContext(ContextWithRunID(context.Background(), "run-1"), []*ValidationResult{
+		{TableInput: TableInfo{TableName: "orders"}, CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("orders")}, Diff: "Table missing", CanMigrate: true},
+	})
+	if created || approvedRun != "run-1" {
+		t.Fatalf("expected run-1 to be submitted without creating tables but got created=%v run=%q", created, approvedRun)
+	}
+	if ms[0].Status != StatusFailed || !errors.Is(ms[0].Errors[0], ErrRunNotApproved) || len(ms[0].Skipped) != 1 || ms[0].ApprovedBy != "" {
+		t.Fatalf("expected ErrRunNotApproved but got %+v", ms[0])
+	}
+
+	ms = c.MigrateWithContext(ContextWithRunID(context.Background(), "run-2"), []*ValidationResult{
+		{TableInput: TableInfo{TableName: "orders"}, CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("orders")}, Diff: "Table missing", CanMigrate: true},
+	})
+	if !created || ms[0].Status != StatusApplied || ms[0].ApprovedBy != "ops" {
+		t.Fatalf("expected run approved by ops to be applied but got %+v", ms[0])
+	}
+}
@@ -0,0 +1,161 @@
+package tables
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// AuditRecord is the verbatim request payload of a mutating AWS call.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	// RunID is the ID of the run making the call, see ContextWithRunID.
+	RunID string `json:"run_id,omitempty"`
+	// Operation is the API operation, e.g. dynamodb:UpdateTable.
+	Operation string `json:"operation"`
+	Region    string `json:"region,omitempty"`
+	// Request is the JSON encoded request input without unset fields.
+	Request json.RawMessage `json:"request"`
+}
+
+// AuditLog records the mutating AWS calls of a controller, see WithAuditLog.
+type AuditLog interface {
+	Record(r AuditRecord) error
+}
+
+// auditWriter writes records as JSON lines.
+type auditWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAuditWriter returns an AuditLog writing one JSON record per line to w, e.g. a file.
+// It is safe for concurrent use.
+func NewAuditWriter(w io.Writer) AuditLog {
+	return &auditWriter{w: w}
+}
+
+func (a *auditWriter) Record(r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.w.Write(append(line, '\n'))
+	return err
+}
+
+// S3AuditLog collects records in memory and stores them as JSON lines at an S3 URI with Flush,
+// e.g. once per run at s3://audit/tables/<run ID>.jsonl. Objects are encrypted at rest like
+// plans, see SavePlanS3.
+type S3AuditLog struct {
+	API s3iface.S3API
+	URI string
+	// KMSKeyID encrypts the object with a KMS key instead of S3 managed keys if set.
+	KMSKeyID string
+
+	mu      sync.Mutex
+	records bytes.Buffer
+}
+
+// Record implements AuditLog.
+func (l *S3AuditLog) Record(r AuditRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records.Write(line)
+	l.records.WriteByte('\n')
+	return nil
+}
+
+// Flush stores all records collected so far and returns the URI of the stored version.
+// Later flushes overwrite the object with all records, including the earlier ones.
+func (l *S3AuditLog) Flush() (string, error) {
+	l.mu.Lock()
+	data := append([]byte(nil), l.records.Bytes()...)
+	l.mu.Unlock()
+	return putS3(l.API, l.URI, data, l.KMSKeyID)
+}
+
+// audit records a mutating DynamoDB call on the table before it is sent. Calls are not sent if
+// they cannot be recorded, so the audit log misses no change.
+func (c *Controller) audit(ctx context.Context, tbl TableInfo, operation string, input interface{}) error {
+	return c.record(ctx, c.tableRegion(tbl), "dynamodb:"+operation, input)
+}
+
+// record adds a mutating AWS call of the given service operation, e.g. cloudwatch:PutMetricAlarm,
+// to the audit log.
+func (c *Controller) record(ctx context.Context, region, operation string, input interface{}) error {
+	if c.auditLog == nil {
+		return nil
+	}
+	request, err := requestJSON(input)
+	if err != nil {
+		return err
+	}
+	return c.auditLog.Record(AuditRecord{
+		Time:      c.clock.Now(),
+		RunID:     RunIDFromContext(ctx),
+		Operation: operation,
+		Region:    region,
+		Request:   request,
+	})
+}
+
+// auditFunc records a mutating AWS call before it is sent, see Controller.record.
+type auditFunc func(operation string, input interface{}) error
+
+// noAudit is the auditFunc of resource managers used on their own.
+func noAudit(string, interface{}) error {
+	return nil
+}
+
+// auditedResourceManager is implemented by the resource managers of this package, whose mutating
+// calls are recorded in the audit log of the controller they are registered with.
+type auditedResourceManager interface {
+	migrate(tbl TableInfo, audit auditFunc) error
+}
+
+// requestJSON encodes the input of an AWS call as JSON. SDK inputs mark unset fields with nil,
+// they are left out instead of being encoded as null.
+func requestJSON(input interface{}) (json.RawMessage, error) {
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(withoutNulls(v))
+}
+
+// withoutNulls removes null values from the objects of a decoded JSON value.
+func withoutNulls(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = withoutNulls(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = withoutNulls(e)
+		}
+	}
+	return v
+}
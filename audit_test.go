@@ -0,0 +1,159 @@
+package tables
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/dax"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type failingAuditLog struct{}
+
+func (failingAuditLog) Record(AuditRecord) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuditLog(t *testing.T) {
+	created := 0
+	db := &fakeDynamoDB{}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		created++
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	db.updateTTL = func(*dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+	buf := &bytes.Buffer{}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c, err := NewController(db, "", nil, nil, WithClock(&fakeClock{now: now}), WithRegion("eu-west-1"),
+		WithAuditLog(NewAuditWriter(buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*ValidationResult{{
+		TableInput:       TableInfo{TableName: "orders"},
+		CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("orders")},
+		UpdateTTLInput: &dynamodb.UpdateTimeToLiveInput{
+			TableName:               aws.String("orders"),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{AttributeName: aws.String("expiry"), Enabled: aws.Bool(true)},
+		},
+		Diff:       "Table missing",
+		CanMigrate: true,
+	}}
+	if ms := c.MigrateWithContext(ContextWithRunID(context.Background(), "run-1"), results); len(ms[0].Errors) > 0 {
+		t.Fatal(ms[0].Errors)
+	}
+
+	records := []AuditRecord{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		r := AuditRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records but got %+v", records)
+	}
+	create, ttl := records[0], records[1]
+	if create.Operation != "dynamodb:CreateTable" || create.RunID != "run-1" || create.Region != "eu-west-1" || !create.Time.Equal(now) {
+		t.Fatalf("unexpected record %+v", create)
+	}
+	if ttl.Operation != "dynamodb:UpdateTimeToLive" ||
+		string(ttl.Request) != `{"TableName":"orders","TimeToLiveSpecification":{"AttributeName":"expiry","Enabled":true}}` {
+		t.Fatalf("unexpected record %+v %s", ttl, ttl.Request)
+	}
+
+	c, err = NewController(db, "", nil, nil, WithClock(&fakeClock{}), WithAuditLog(failingAuditLog{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := c.Migrate(results); ms[0].Status != StatusFailed || created != 1 {
+		t.Fatalf("expected unrecorded call not to be sent but got %+v after %d creates", ms[0], created)
+	}
+}
+
+func TestS3AuditLog(t *testing.T) {
+	api := &fakeS3{versions: map[string][][]byte{}}
+	l := &S3AuditLog{API: api, URI: "s3://audit/tables/run-1.jsonl", KMSKeyID: "alias/audit"}
+	for _, op := range []string{"dynamodb:CreateTable", "dynamodb:UpdateTable"} {
+		if err := l.Record(AuditRecord{Operation: op, Request: json.RawMessage(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uri, err := l.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri != "s3://audit/tables/run-1.jsonl?versionId=1" {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+	if lines := bytes.Count(api.versions["audit/tables/run-1.jsonl"][0], []byte("\n")); lines != 2 {
+		t.Fatalf("expected 2 records but got %d", lines)
+	}
+}
+
+// memoryAuditLog keeps records in memory.
+type memoryAuditLog struct {
+	records []AuditRecord
+}
+
+func (l *memoryAuditLog) Record(r AuditRecord) error {
+	l.records = append(l.records, r)
+	return nil
+}
+
+func TestAuditResourceManagers(t *testing.T) {
+	cw := &alarmCloudWatch{alarms: map[string]*cloudwatch.MetricAlarm{
+		"orders-SystemErrors": {AlarmName: aws.String("orders-SystemErrors")},
+	}}
+	client := &fakeDAX{clusters: map[string]*dax.Cluster{}, subnetGroups: map[string]bool{}, parameterGroups: map[string]bool{}}
+	log := &memoryAuditLog{}
+	c, err := NewController(&fakeDynamoDB{}, "", nil, nil, WithClock(&fakeClock{}), WithAuditLog(log),
+		WithResourceManagers(NewAlarmManager(cw, ""), NewDAXManager(client)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*ValidationResult{{
+		TableInput: TableInfo{
+			TableName: "orders",
+			Alarms:    &AlarmsInfo{ThrottledRequests: 10},
+			DAX:       &DAXInfo{ClusterName: "orders-cache", NodeType: "dax.r5.large", ReplicationFactor: 1},
+		},
+		ResourceDiffs: map[string]string{"alarms": "missing alarm", "dax": "missing cluster"},
+		Diff:          "Resource alarms: missing alarm, Resource dax: missing cluster",
+		CanMigrate:    true,
+	}}
+	if ms := c.MigrateWithContext(ContextWithRunID(context.Background(), "run-1"), results); ms[0].Status != StatusApplied {
+		t.Fatalf("expected resources to be migrated but got %+v", ms[0])
+	}
+
+	ops := []string{}
+	for _, r := range log.records {
+		if r.RunID != "run-1" {
+			t.Fatalf("expected record of run-1 but got %+v", r)
+		}
+		ops = append(ops, r.Operation)
+	}
+	want := "cloudwatch:PutMetricAlarm cloudwatch:DeleteAlarms dax:CreateSubnetGroup dax:CreateParameterGroup dax:CreateCluster"
+	if got := strings.Join(ops, " "); got != want {
+		t.Fatalf("expected %s but got %s", want, got)
+	}
+
+	// Resource changes which cannot be recorded are not sent.
+	cw.alarms = map[string]*cloudwatch.MetricAlarm{}
+	c.auditLog = failingAuditLog{}
+	if ms := c.Migrate(results); ms[0].Status != StatusFailed || len(cw.alarms) != 0 {
+		t.Fatalf("expected unrecorded alarm not to be created but got %+v and %v", ms[0], cw.alarms)
+	}
+}
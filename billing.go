@@ -0,0 +1,84 @@
+package tables
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// BillingModeSwitchInterval is the minimum time DynamoDB requires between two switches
+// of a table to PAY_PER_REQUEST.
+const BillingModeSwitchInterval = 24 * time.Hour
+
+// billingMode returns the configured billing mode of the table, PROVISIONED if not set.
+func billingMode(tbl TableInfo) string {
+	if tbl.BillingMode == "" {
+		return dynamodb.BillingModeProvisioned
+	}
+	return tbl.BillingMode
+}
+
+// currentBillingMode returns the billing mode of the table in DynamoDB.
+// Tables created without a billing mode have no BillingModeSummary and are provisioned.
+func currentBillingMode(desc *dynamodb.TableDescription) string {
+	if desc == nil || desc.BillingModeSummary == nil || desc.BillingModeSummary.BillingMode == nil {
+		return dynamodb.BillingModeProvisioned
+	}
+	return aws.StringValue(desc.BillingModeSummary.BillingMode)
+}
+
+// billingModeSwitch returns an input switching the table to its configured billing mode,
+// nil if the billing mode already matches. Switching to PROVISIONED includes the configured
+// throughput of the table and of every GSI which exists in both the config and DynamoDB,
+// as required by the UpdateTable API.
+// ErrBillingModeSwitchLimit is returned if the table was switched to PAY_PER_REQUEST
+// within BillingModeSwitchInterval.
+func (c *Controller) billingModeSwitch(tbl TableInfo, desc *dynamodb.TableDescription, input *dynamodb.CreateTableInput) (*dynamodb.UpdateTableInput, error) {
+	mode := billingMode(tbl)
+	if mode == currentBillingMode(desc) {
+		return nil, nil
+	}
+
+	if mode == dynamodb.BillingModePayPerRequest && desc.BillingModeSummary != nil {
+		if last := desc.BillingModeSummary.LastUpdateToPayPerRequestDateTime; last != nil {
+			if next := last.Add(BillingModeSwitchInterval); c.clock.Now().Before(next) {
+				return nil, fmt.Errorf("%w: table %s was switched to %s at %s, next switch allowed after %s",
+					ErrBillingModeSwitchLimit, tbl.TableName, mode, last.UTC().Format(time.RFC3339), next.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+
+	update := UpdateTableInputBase(tbl, c.env)
+	update.BillingMode = aws.String(mode)
+	if mode != dynamodb.BillingModeProvisioned {
+		return update, nil
+	}
+
+	if pt := input.ProvisionedThroughput; pt != nil {
+		update.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  pt.ReadCapacityUnits,
+			WriteCapacityUnits: pt.WriteCapacityUnits,
+		}
+	}
+	existing := make(map[string]bool, len(desc.GlobalSecondaryIndexes))
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		existing[aws.StringValue(gsi.IndexName)] = true
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		if !existing[aws.StringValue(gsi.IndexName)] || gsi.ProvisionedThroughput == nil {
+			continue
+		}
+		update.GlobalSecondaryIndexUpdates = append(update.GlobalSecondaryIndexUpdates, &dynamodb.GlobalSecondaryIndexUpdate{
+			Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+				IndexName: gsi.IndexName,
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  gsi.ProvisionedThroughput.ReadCapacityUnits,
+					WriteCapacityUnits: gsi.ProvisionedThroughput.WriteCapacityUnits,
+				},
+			},
+		})
+	}
+	return update, nil
+}
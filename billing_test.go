@@ -0,0 +1,74 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestBillingModeSwitch(t *testing.T) {
+	now := time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)
+	c := newTestController(t, &fakeDynamoDB{}, &fakeClock{now: now})
+	tbl := TableInfo{
+		TableName:       "test",
+		PrimaryKey:      "id",
+		ReadThroughput:  5,
+		WriteThroughput: 10,
+		Indexes: []IndexInfo{
+			{IndexName: "gsi", PrimaryKey: "name", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 2},
+			{IndexName: "new", PrimaryKey: "kind", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 2},
+		},
+	}
+	onDemand := &dynamodb.TableDescription{
+		BillingModeSummary: &dynamodb.BillingModeSummary{
+			BillingMode:                       aws.String(dynamodb.BillingModePayPerRequest),
+			LastUpdateToPayPerRequestDateTime: aws.Time(now.Add(-48 * time.Hour)),
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{IndexName: aws.String("gsi")},
+		},
+	}
+
+	// Switching back to PROVISIONED includes the throughput of the table and existing GSIs.
+	update, err := c.billingModeSwitch(tbl, onDemand, CreateTableInput(tbl, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(update.BillingMode) != dynamodb.BillingModeProvisioned {
+		t.Fatalf("expected switch to PROVISIONED but got %v", update.BillingMode)
+	}
+	if aws.Int64Value(update.ProvisionedThroughput.WriteCapacityUnits) != 10 {
+		t.Fatalf("expected table throughput in switch but got %v", update.ProvisionedThroughput)
+	}
+	if len(update.GlobalSecondaryIndexUpdates) != 1 || aws.StringValue(update.GlobalSecondaryIndexUpdates[0].Update.IndexName) != "gsi" {
+		t.Fatalf("expected throughput of existing GSI only but got %v", update.GlobalSecondaryIndexUpdates)
+	}
+
+	// Matching billing mode needs no switch.
+	if update, err := c.billingModeSwitch(tbl, &dynamodb.TableDescription{}, CreateTableInput(tbl, "")); err != nil || update != nil {
+		t.Fatalf("expected no switch but got %v, %v", update, err)
+	}
+
+	// Switching to PAY_PER_REQUEST is limited to once per 24 hours.
+	tbl.BillingMode = dynamodb.BillingModePayPerRequest
+	provisioned := &dynamodb.TableDescription{
+		BillingModeSummary: &dynamodb.BillingModeSummary{
+			BillingMode:                       aws.String(dynamodb.BillingModeProvisioned),
+			LastUpdateToPayPerRequestDateTime: aws.Time(now.Add(-time.Hour)),
+		},
+	}
+	if _, err := c.billingModeSwitch(tbl, provisioned, CreateTableInput(tbl, "")); !errors.Is(err, ErrBillingModeSwitchLimit) {
+		t.Fatalf("expected ErrBillingModeSwitchLimit but got %v", err)
+	}
+	provisioned.BillingModeSummary.LastUpdateToPayPerRequestDateTime = aws.Time(now.Add(-25 * time.Hour))
+	update, err = c.billingModeSwitch(tbl, provisioned, CreateTableInput(tbl, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.StringValue(update.BillingMode) != dynamodb.BillingModePayPerRequest || update.ProvisionedThroughput != nil {
+		t.Fatalf("expected switch to PAY_PER_REQUEST without throughput but got %v", update)
+	}
+}
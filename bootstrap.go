@@ -0,0 +1,139 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DefaultCreateConcurrency is the default number of tables CreateAll creates at the same time.
+const DefaultCreateConcurrency = 10
+
+// errTableExists stops retrying CreateTable for tables which already exist.
+var errTableExists = errors.New("table already exists")
+
+// BootstrapResult is the outcome of creating a single table with CreateAll.
+type BootstrapResult struct {
+	TableInput TableInfo
+	// Created is true if the table was created and is ACTIVE.
+	Created bool
+	// Existed is true if the table already existed, it is left untouched.
+	Existed bool
+	// TTLApplied is true if the configured TTL was set on the created table.
+	TTLApplied bool
+	Error      error
+}
+
+// BootstrapReport lists the results of a CreateAll run in config order.
+type BootstrapReport struct {
+	Results  []BootstrapResult
+	Duration time.Duration
+	// RunID is the ID of the run, see ContextWithRunID.
+	RunID string
+}
+
+// HasErrors reports whether any table failed to be created.
+func (r *BootstrapReport) HasErrors() bool {
+	for _, res := range r.Results {
+		if res.Error != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAll creates all managed tables of an empty environment without describing them first.
+// Tables are created concurrently, at most WithCreateConcurrency at a time, and the configured
+// TTLs are applied once the tables are ACTIVE. Tables which already exist are reported and left
+// untouched, deprecated tables are never created. Use Validate and Migrate for environments
+// which are not empty.
+func (c *Controller) CreateAll(ctx context.Context) *BootstrapReport {
+	ctx, runID := c.startRun(ctx)
+	c.Log.Infof("CreateAll run %s started", runID)
+	start := c.clock.Now()
+	tables := []TableInfo{}
+	for _, tbl := range managedTables(filterTables(c.tenantTables(), c.filter)) {
+		if !tbl.Deprecated {
+			tables = append(tables, tbl)
+		}
+	}
+
+	report := &BootstrapReport{Results: make([]BootstrapResult, len(tables)), RunID: runID}
+	slots := make(chan struct{}, c.createConcurrency)
+	var wg sync.WaitGroup
+	for i, tbl := range tables {
+		wg.Add(1)
+		go func(i int, tbl TableInfo) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				report.Results[i] = BootstrapResult{TableInput: tbl, Error: ctx.Err()}
+				return
+			}
+			report.Results[i] = c.bootstrapTable(ctx, tbl)
+		}(i, tbl)
+	}
+	wg.Wait()
+
+	report.Duration = c.clock.Now().Sub(start)
+	return report
+}
+
+func (c *Controller) bootstrapTable(ctx context.Context, tbl TableInfo) BootstrapResult {
+	res := BootstrapResult{TableInput: tbl}
+	c.Log.Infof("Creating table %s", tbl.TableName)
+	input := CreateTableInput(tbl, c.env)
+	c.compliance.applyCreate(input)
+	c.addRunTags(ctx, input)
+	c.inputHooks.onCreateTable(input)
+	err := c.hooks.run(ctx, tbl, ChangeCreateTable, input, func() error {
+		err := c.withRetry(ctx, func() error {
+			if err := c.audit(ctx, tbl, "CreateTable", input); err != nil {
+				return err
+			}
+			_, err := c.client(tbl).CreateTable(input)
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceInUseException {
+				return errTableExists
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return c.waitUntilTableExists(ctx, tbl)
+	})
+	if err == errTableExists {
+		c.Log.Infof("Create table [%s] skipped: table exists", tbl.TableName)
+		res.Existed = true
+		return res
+	}
+	if err != nil {
+		c.Log.Errorf("Create table [%s] with error: %v", tbl.TableName, err)
+		res.Error = err
+		return res
+	}
+	res.Created = true
+
+	if input := NewUpdateTimeToLiveInput(tbl, c.env, tbl.TTL); input != nil {
+		if err := c.updateTTL(ctx, tbl, input); err != nil {
+			c.Log.Errorf("Update TTL of table [%s] with error: %v", tbl.TableName, err)
+			res.Error = err
+			return res
+		}
+		res.TTLApplied = true
+	}
+	if input := c.compliance.continuousBackupsInput(tbl, c.env); input != nil {
+		if err := c.updateContinuousBackups(ctx, tbl, input); err != nil {
+			c.Log.Errorf("Enable point in time recovery of table [%s] with error: %v", tbl.TableName, err)
+			res.Error = err
+			return res
+		}
+	}
+	return res
+}
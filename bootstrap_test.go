@@ -0,0 +1,156 @@
+package tables
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCreateAll(t *testing.T) {
+	var mu sync.Mutex
+	ttls := []string{}
+	db := &fakeDynamoDB{}
+	db.createTable = func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		if input.SSESpecification == nil {
+			t.Errorf("expected SSESpecification from hook on %s", aws.StringValue(input.TableName))
+		}
+		if aws.StringValue(input.TableName) == "existing" {
+			return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "exists", nil)
+		}
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	db.updateTTL = func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		ttls = append(ttls, aws.StringValue(input.TableName))
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+	c, err := NewController(db, "test", nil, []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", TTL: &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true}},
+		{TableName: "existing", PrimaryKey: "id"},
+		{TableName: "old", PrimaryKey: "id", Deprecated: true},
+	}, WithClock(&fakeClock{}), WithCreateConcurrency(1), WithCreateTableInputHook(func(input *dynamodb.CreateTableInput) {
+		input.SSESpecification = &dynamodb.SSESpecification{Enabled: aws.Bool(true)}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := c.CreateAll(context.Background())
+	if report.HasErrors() {
+		t.Fatalf("expected no errors but got %+v", report.Results)
+	}
+	if len(report.Results) != 2 {
+		t.Fatalf("expected deprecated table to be skipped but got %+v", report.Results)
+	}
+	if r := report.Results[0]; !r.Created || !r.TTLApplied {
+		t.Fatalf("expected orders to be created with TTL but got %+v", r)
+	}
+	if r := report.Results[1]; r.Created || !r.Existed {
+		t.Fatalf("expected existing table to be left untouched but got %+v", r)
+	}
+	if len(ttls) != 1 || ttls[0] != "orders" {
+		t.Fatalf("expected TTL of orders only but got %v", ttls)
+	}
+}
+
+func TestCreateAllHooks(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.createTable = func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		switch aws.StringValue(input.TableName) {
+		case "existing":
+			return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "exists", nil)
+		case "broken":
+			return nil, awserr.New("ValidationException", "invalid key schema", nil)
+		}
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	db.updateTTL = func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+
+	var mu sync.Mutex
+	events := []string{}
+	record := func(kind string) func(HookEvent) {
+		return func(ev HookEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, kind+" "+ev.TableName+" "+string(ev.Type))
+		}
+	}
+	c, err := NewController(db, "test", nil, []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", TTL: &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true}},
+		{TableName: "existing", PrimaryKey: "id"},
+		{TableName: "broken", PrimaryKey: "id"},
+	}, WithClock(&fakeClock{}), WithCreateConcurrency(1), WithHooks(Hooks{
+		BeforeCreate: record("before"),
+		AfterCreate:  record("after"),
+		BeforeUpdate: record("before"),
+		AfterUpdate:  record("after"),
+		OnError:      record("error"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.CreateAll(context.Background())
+	// Tables are created concurrently, only the order per table is fixed.
+	sort.SliceStable(events, func(i, j int) bool {
+		return strings.Fields(events[i])[1] < strings.Fields(events[j])[1]
+	})
+	expected := []string{
+		"before broken " + string(ChangeCreateTable),
+		"error broken " + string(ChangeCreateTable),
+		"before existing " + string(ChangeCreateTable),
+		"before orders " + string(ChangeCreateTable),
+		"after orders " + string(ChangeCreateTable),
+		"before orders " + string(ChangeUpdateTTL),
+		"after orders " + string(ChangeUpdateTTL),
+	}
+	if diff := cmp.Diff(expected, events); diff != "" {
+		t.Fatalf("unexpected hook events: %s", diff)
+	}
+}
+
+func TestCreateAllRunID(t *testing.T) {
+	db := &fakeDynamoDB{}
+	inputs := make(chan *dynamodb.CreateTableInput, 1)
+	db.createTable = func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		inputs <- input
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	runIDs := make(chan string, 1)
+	c, err := NewController(db, "test", nil, []TableInfo{{TableName: "orders", PrimaryKey: "id"}},
+		WithClock(&fakeClock{}), WithConfigRevision("abc123"), WithHooks(Hooks{
+			AfterCreate: func(ev HookEvent) { runIDs <- ev.RunID },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := c.CreateAll(ContextWithRunID(context.Background(), "build-42"))
+	if report.HasErrors() || report.RunID != "build-42" {
+		t.Fatalf("expected run build-42 without errors but got %+v", report)
+	}
+	if id := <-runIDs; id != "build-42" {
+		t.Fatalf("expected hook event of run build-42 but got %q", id)
+	}
+	tags := map[string]string{}
+	for _, tag := range (<-inputs).Tags {
+		tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if tags[RunIDTagKey] != "build-42" || tags[ConfigRevisionTagKey] != "abc123" {
+		t.Fatalf("expected run and revision tags but got %v", tags)
+	}
+
+	if report := c.CreateAll(context.Background()); report.RunID == "" || report.RunID == "build-42" {
+		t.Fatalf("expected a new run ID but got %q", report.RunID)
+	}
+}
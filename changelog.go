@@ -0,0 +1,72 @@
+package tables
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DiffConfigData is the same as DiffConfigs for two revisions of a config file, e.g. the
+// contents of tables.yaml at two git revisions. The settings of env are applied to both
+// revisions, an empty env uses the table values. Includes are not resolved since
+// revisions are not read from disk.
+func DiffConfigData(old, new []byte, env string) (ChangeSet, error) {
+	oldTables, err := decodeConfig(old, env)
+	if err != nil {
+		return nil, fmt.Errorf("old revision: %w", err)
+	}
+	newTables, err := decodeConfig(new, env)
+	if err != nil {
+		return nil, fmt.Errorf("new revision: %w", err)
+	}
+	return DiffConfigs(oldTables, newTables), nil
+}
+
+// Breaking reports whether the change removes data or access paths or cannot be applied
+// in place: removed or deprecated tables, removed GSIs and changed keys or projections.
+func (ch ConfigChange) Breaking() bool {
+	switch ch.Kind {
+	case ConfigTableRemoved, ConfigTableDeprecated, ConfigKeySchemaChanged, ConfigIndexRemoved, ConfigIndexChanged:
+		return true
+	}
+	return false
+}
+
+// WriteChangelog writes the changes as a Markdown changelog for release notes and data
+// governance records, with a section per table in order of the change set:
+//
+//	## Schema changes v1.2.0
+//
+//	### orders
+//	- adds GSI byEmail to orders
+//	- **breaking:** removes GSI byName from orders
+//
+// Revisions without changes are noted as such.
+func WriteChangelog(w io.Writer, title string, cs ChangeSet) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "## %s\n", title)
+	if len(cs) == 0 {
+		b.WriteString("\nNo schema changes.\n")
+	}
+
+	tables := []string{}
+	byTable := map[string][]ConfigChange{}
+	for _, ch := range cs {
+		if _, ok := byTable[ch.TableName]; !ok {
+			tables = append(tables, ch.TableName)
+		}
+		byTable[ch.TableName] = append(byTable[ch.TableName], ch)
+	}
+	for _, name := range tables {
+		fmt.Fprintf(b, "\n### %s\n", name)
+		for _, ch := range byTable[name] {
+			if ch.Breaking() {
+				fmt.Fprintf(b, "- **breaking:** %s\n", ch.Description)
+				continue
+			}
+			fmt.Fprintf(b, "- %s\n", ch.Description)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,63 @@
+package tables
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteChangelog(t *testing.T) {
+	old := []byte(`
+- table_name: orders
+  primary_key: id
+  indexes:
+    - index_name: byName
+      primary_key: name
+      primary_key_type: S
+- table_name: legacy
+  primary_key: id
+`)
+	new := []byte(`
+tables:
+  - table_name: orders
+    primary_key: id
+    indexes:
+      - index_name: byEmail
+        primary_key: email
+        primary_key_type: S
+  - table_name: users
+    primary_key: id
+`)
+
+	cs, err := DiffConfigData(old, new, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	if err := WriteChangelog(b, "Schema changes v1.2.0", cs); err != nil {
+		t.Fatal(err)
+	}
+	expected := `## Schema changes v1.2.0
+
+### orders
+- adds GSI byEmail to orders
+- **breaking:** removes GSI byName from orders
+
+### users
+- adds table users
+
+### legacy
+- **breaking:** removes table legacy
+`
+	if b.String() != expected {
+		t.Fatalf("expected changelog\n%s\nbut got\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	WriteChangelog(b, "v1.2.1", nil)
+	if b.String() != "## v1.2.1\n\nNo schema changes.\n" {
+		t.Fatalf("unexpected changelog without changes: %q", b.String())
+	}
+	if _, err := DiffConfigData([]byte("- table_name: [invalid"), new, ""); err == nil {
+		t.Fatal("expected error for invalid revision")
+	}
+}
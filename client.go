@@ -0,0 +1,79 @@
+package tables
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by the Controller.
+// Both *dynamodb.DynamoDB and mocks generated from dynamodbiface satisfy it.
+type DynamoDBAPI = dynamodbiface.DynamoDBAPI
+
+// ClientFactory builds a DynamoDB client for the given region and account.
+// It lets callers plug in their own session and credential handling
+// (SSO, web identity, custom proxies) instead of passing a single pre-built client.
+// Empty region or account values mean the caller's defaults should be used.
+type ClientFactory func(region, account string) DynamoDBAPI
+
+// SessionClientFactory returns a ClientFactory creating clients from the given session.
+// Throttling and transient 5xx errors are retried by the SDK's DefaultRetryer with up to
+// maxRetries attempts and exponential backoff, while the Controller only retries
+// application-level errors such as ResourceInUse and LimitExceeded.
+// The account is not used, pass a session with the desired credentials instead.
+func SessionClientFactory(sess *session.Session, maxRetries int) ClientFactory {
+	return func(region, account string) DynamoDBAPI {
+		cfg := request.WithRetryer(aws.NewConfig(), client.DefaultRetryer{
+			NumMaxRetries: maxRetries,
+		})
+		if region != "" {
+			cfg = cfg.WithRegion(region)
+		}
+		return dynamodb.New(sess, cfg)
+	}
+}
+
+// clientCache holds the clients built by a ClientFactory keyed by region and account.
+type clientCache struct {
+	mu      sync.Mutex
+	clients map[string]DynamoDBAPI
+}
+
+func (cc *clientCache) get(region, account string, factory ClientFactory) DynamoDBAPI {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	key := region + "/" + account
+	if cli, ok := cc.clients[key]; ok {
+		return cli
+	}
+	if cc.clients == nil {
+		cc.clients = make(map[string]DynamoDBAPI)
+	}
+	cli := factory(region, account)
+	cc.clients[key] = cli
+	return cli
+}
+
+// client returns the DynamoDB client used for operations on the given table.
+// The client passed to NewController is used unless a ClientFactory is configured.
+// Tables declaring their own region are routed to a client for that region.
+func (c *Controller) client(tbl TableInfo) DynamoDBAPI {
+	if c.clientFactory == nil {
+		return c.DynamoDB
+	}
+	return c.clients.get(c.tableRegion(tbl), c.account, c.clientFactory)
+}
+
+// tableRegion returns the region of the given table, falling back to the controller default.
+func (c *Controller) tableRegion(tbl TableInfo) string {
+	if tbl.Region != "" {
+		return tbl.Region
+	}
+	return c.region
+}
@@ -0,0 +1,107 @@
+package tables
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// staleDynamoDB returns a fake client describing the given tables with half their configured
+// read throughput, so Validate reports an update for each of them. Calls are passed to record.
+func staleDynamoDB(tables []TableInfo, record func(api, table string)) *fakeDynamoDB {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		record("DescribeTable", aws.StringValue(input.TableName))
+		for _, tbl := range tables {
+			if tbl.TableName == aws.StringValue(input.TableName) {
+				stale := tbl
+				stale.ReadThroughput /= 2
+				return &dynamodb.DescribeTableOutput{Table: describeInput(CreateTableInput(stale, ""))}, nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected table %s", aws.StringValue(input.TableName))
+	}
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		record("UpdateTable", aws.StringValue(input.TableName))
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	return db
+}
+
+func TestClientFactoryCache(t *testing.T) {
+	tables := []TableInfo{{TableName: "orders", PrimaryKey: "id", ReadThroughput: 10, WriteThroughput: 5}}
+	var mu sync.Mutex
+	calls := map[string]int{}
+	clients := map[string]*fakeDynamoDB{}
+	factory := func(region, account string) DynamoDBAPI {
+		mu.Lock()
+		defer mu.Unlock()
+		key := region + "/" + account
+		calls[key]++
+		clients[key] = staleDynamoDB(tables, func(string, string) {})
+		return clients[key]
+	}
+	c, err := NewController(nil, "", nil, tables, WithClock(&fakeClock{}),
+		WithRegion("us-east-1"), WithAccount("123456789012"), WithClientFactory(factory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		results, _ := c.Validate()
+		if ms := c.Migrate(results); ms[0].Status != StatusApplied {
+			t.Fatalf("expected run %d to update the table but got %+v", i, ms[0])
+		}
+	}
+	if len(calls) != 1 || calls["us-east-1/123456789012"] != 1 {
+		t.Fatalf("expected a single client for the default region and account but got %v", calls)
+	}
+	if n := clients["us-east-1/123456789012"].updateTableCall; n != 3 {
+		t.Fatalf("expected every run to use the cached client but it got %d updates", n)
+	}
+}
+
+func TestTableRegion(t *testing.T) {
+	tables := []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", ReadThroughput: 10, WriteThroughput: 5},
+		{TableName: "users", PrimaryKey: "id", ReadThroughput: 10, WriteThroughput: 5, Region: "eu-west-1"},
+	}
+	var mu sync.Mutex
+	calls := map[string][]string{}
+	factory := func(region, account string) DynamoDBAPI {
+		return staleDynamoDB(tables, func(api, table string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[region] = append(calls[region], api+" "+table)
+		})
+	}
+	c, err := NewController(nil, "", nil, tables, WithClock(&fakeClock{}), WithRegion("us-east-1"), WithClientFactory(factory))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := c.Validate()
+	for _, m := range c.Migrate(results) {
+		if m.Status != StatusApplied {
+			t.Fatalf("expected table %s to be updated but got %+v", m.TableInput.TableName, m)
+		}
+	}
+	for region, table := range map[string]string{"us-east-1": "orders", "eu-west-1": "users"} {
+		updated := false
+		for _, call := range calls[region] {
+			if call != "DescribeTable "+table && call != "UpdateTable "+table {
+				t.Fatalf("expected only calls for %s in %s but got %v", table, region, calls[region])
+			}
+			updated = updated || call == "UpdateTable "+table
+		}
+		if !updated {
+			t.Fatalf("expected %s to be updated through the %s client but got %v", table, region, calls[region])
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected clients for two regions but got %v", calls)
+	}
+}
@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Clock abstracts the passing of time for retry and wait logic,
+// so it can be unit-tested with a fake clock instead of real delays.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// realClock is the default Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// sleepWithContext sleeps on the Clock unless ctx is done.
+// It is used by retries and polling loops.
+func (c *Controller) sleepWithContext(ctx aws.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+	c.clock.Sleep(d)
+	return ctx.Err()
+}
@@ -0,0 +1,134 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Compliance holds organization-wide requirements which are added to the desired state of every
+// managed table regardless of its config, see WithCompliance. Tables violating a requirement are
+// reported as drift and brought in line by Migrate. Requirements are enforced one way only,
+// e.g. tables with deletion protection are never unprotected if it is not required.
+type Compliance struct {
+	// PointInTimeRecovery requires continuous backups with point in time recovery.
+	PointInTimeRecovery bool `json:"point_in_time_recovery,omitempty" yaml:"point_in_time_recovery"`
+	// KMSMasterKeyARN requires encryption with the customer managed KMS key of the given ARN.
+	KMSMasterKeyARN string `json:"kms_master_key_arn,omitempty" yaml:"kms_master_key_arn"`
+	// DeletionProtection requires deletion protection, e.g. only in prod.
+	DeletionProtection bool `json:"deletion_protection,omitempty" yaml:"deletion_protection"`
+}
+
+// compareCompliance compares an existing table to the compliance requirements and adds the inputs
+// fixing violations to the result. It returns the diff of the violations, empty if there are none.
+func (c *Controller) compareCompliance(tbl TableInfo, desc *dynamodb.TableDescription, result *ValidationResult) (string, error) {
+	req := c.compliance
+	if req == nil {
+		return "", nil
+	}
+	name := aws.String(physicalName(c.env, tbl))
+	parts := []string{}
+
+	if req.DeletionProtection && !aws.BoolValue(desc.DeletionProtectionEnabled) {
+		parts = append(parts, "deletion protection: disabled => enabled")
+		result.UpdateTableInput = append(result.UpdateTableInput, &dynamodb.UpdateTableInput{
+			TableName:                 name,
+			DeletionProtectionEnabled: aws.Bool(true),
+		})
+	}
+
+	if req.KMSMasterKeyARN != "" {
+		if current := currentEncryption(desc.SSEDescription); current != req.KMSMasterKeyARN {
+			parts = append(parts, fmt.Sprintf("encryption: %s => %s", current, req.KMSMasterKeyARN))
+			result.UpdateTableInput = append(result.UpdateTableInput, &dynamodb.UpdateTableInput{
+				TableName:        name,
+				SSESpecification: req.sseSpecification(),
+			})
+		}
+	}
+
+	if req.PointInTimeRecovery {
+		output, err := c.client(tbl).DescribeContinuousBackups(&dynamodb.DescribeContinuousBackupsInput{
+			TableName: name,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !isPITREnabled(output.ContinuousBackupsDescription) {
+			parts = append(parts, "point in time recovery: disabled => enabled")
+			result.UpdateContinuousBackupsInput = req.continuousBackupsInput(tbl, c.env)
+		}
+	}
+
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "Compliance: " + strings.Join(parts, ", "), nil
+}
+
+// applyCreate adds the encryption and deletion protection requirements to the input of a new table.
+// Point in time recovery can only be enabled once the table exists.
+func (req *Compliance) applyCreate(input *dynamodb.CreateTableInput) {
+	if req == nil {
+		return
+	}
+	if req.DeletionProtection {
+		input.DeletionProtectionEnabled = aws.Bool(true)
+	}
+	if req.KMSMasterKeyARN != "" {
+		input.SSESpecification = req.sseSpecification()
+	}
+}
+
+func (req *Compliance) sseSpecification() *dynamodb.SSESpecification {
+	return &dynamodb.SSESpecification{
+		Enabled:        aws.Bool(true),
+		SSEType:        aws.String(dynamodb.SSETypeKms),
+		KMSMasterKeyId: aws.String(req.KMSMasterKeyARN),
+	}
+}
+
+// continuousBackupsInput returns the input enabling point in time recovery on the table,
+// nil if it is not required.
+func (req *Compliance) continuousBackupsInput(tbl TableInfo, env string) *dynamodb.UpdateContinuousBackupsInput {
+	if req == nil || !req.PointInTimeRecovery {
+		return nil
+	}
+	return &dynamodb.UpdateContinuousBackupsInput{
+		TableName: aws.String(physicalName(env, tbl)),
+		PointInTimeRecoverySpecification: &dynamodb.PointInTimeRecoverySpecification{
+			PointInTimeRecoveryEnabled: aws.Bool(true),
+		},
+	}
+}
+
+// currentEncryption returns the KMS key ARN a table is encrypted with,
+// or "AWS owned key" for tables using the default encryption.
+func currentEncryption(sse *dynamodb.SSEDescription) string {
+	if sse == nil || aws.StringValue(sse.Status) == dynamodb.SSEStatusDisabled || aws.StringValue(sse.SSEType) != dynamodb.SSETypeKms {
+		return "AWS owned key"
+	}
+	return aws.StringValue(sse.KMSMasterKeyArn)
+}
+
+func isPITREnabled(d *dynamodb.ContinuousBackupsDescription) bool {
+	return d != nil && d.PointInTimeRecoveryDescription != nil &&
+		aws.StringValue(d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus) == dynamodb.PointInTimeRecoveryStatusEnabled
+}
+
+func (c *Controller) updateContinuousBackups(ctx context.Context, ti TableInfo, input *dynamodb.UpdateContinuousBackupsInput) error {
+	input = awsutil.CopyOf(input).(*dynamodb.UpdateContinuousBackupsInput)
+	return c.hooks.run(ctx, ti, ChangeUpdateContinuousBackups, input, func() error {
+		return c.withRetry(ctx, func() error {
+			if err := c.audit(ctx, ti, "UpdateContinuousBackups", input); err != nil {
+				return err
+			}
+			_, err := c.client(ti).UpdateContinuousBackups(input)
+			return err
+		})
+	})
+}
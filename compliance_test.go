@@ -0,0 +1,89 @@
+package tables
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// complianceDynamoDB records enabled continuous backups.
+type complianceDynamoDB struct {
+	fakeDynamoDB
+	backups []*dynamodb.UpdateContinuousBackupsInput
+}
+
+func (f *complianceDynamoDB) UpdateContinuousBackups(input *dynamodb.UpdateContinuousBackupsInput) (*dynamodb.UpdateContinuousBackupsOutput, error) {
+	f.backups = append(f.backups, input)
+	return &dynamodb.UpdateContinuousBackupsOutput{}, nil
+}
+
+func TestCompliance(t *testing.T) {
+	db := &complianceDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		if aws.StringValue(input.TableName) != "orders" {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName:             aws.String("orders"),
+			TableStatus:           aws.String(dynamodb.TableStatusActive),
+			AttributeDefinitions:  []*dynamodb.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: aws.String("S")}},
+			KeySchema:             []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(5), WriteCapacityUnits: aws.Int64(5)},
+		}}, nil
+	}
+	db.describeBackups = func(*dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+		return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: &dynamodb.ContinuousBackupsDescription{
+			PointInTimeRecoveryDescription: &dynamodb.PointInTimeRecoveryDescription{
+				PointInTimeRecoveryStatus: aws.String(dynamodb.PointInTimeRecoveryStatusDisabled),
+			},
+		}}, nil
+	}
+	var updates []*dynamodb.UpdateTableInput
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		updates = append(updates, input)
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	var creates []*dynamodb.CreateTableInput
+	db.createTable = func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		creates = append(creates, input)
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	req := Compliance{PointInTimeRecovery: true, KMSMasterKeyARN: "arn:cmk", DeletionProtection: true}
+	c, err := NewController(db, "", nil, []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5},
+		{TableName: "users", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5},
+	}, WithClock(&fakeClock{}), WithCompliance(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Validate()
+	if err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	orders, users := results[0], results[1]
+	if !strings.Contains(orders.Diff, "Compliance: deletion protection: disabled => enabled, encryption: AWS owned key => arn:cmk, point in time recovery: disabled => enabled") {
+		t.Fatalf("unexpected diff %q", orders.Diff)
+	}
+	if len(orders.UpdateTableInput) != 2 || orders.UpdateContinuousBackupsInput == nil {
+		t.Fatalf("expected two updates and continuous backups but got %+v", orders)
+	}
+	if in := users.CreateTableInput; !aws.BoolValue(in.DeletionProtectionEnabled) || aws.StringValue(in.SSESpecification.KMSMasterKeyId) != "arn:cmk" {
+		t.Fatalf("expected requirements in create input but got %+v", in)
+	}
+	if users.UpdateContinuousBackupsInput == nil {
+		t.Fatal("expected point in time recovery for created table")
+	}
+
+	for _, m := range c.Migrate(results) {
+		if len(m.Errors) > 0 {
+			t.Fatalf("unexpected errors %v", m.Errors)
+		}
+	}
+	if len(updates) != 2 || len(creates) != 1 || len(db.backups) != 2 {
+		t.Fatalf("expected 2 updates, 1 create and 2 backup changes but got %d, %d, %d", len(updates), len(creates), len(db.backups))
+	}
+}
@@ -0,0 +1,399 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"gopkg.in/yaml.v2"
+)
+
+// configFile is the mapping form of a config file. A config file is either a list of
+// tables or a mapping holding the tables and shared definitions:
+//
+//	defaults:
+//	  read_throughput: 5
+//	  write_throughput: 5
+//	attribute_types:
+//	  timestamp: "N"
+//	index_templates:
+//	  by_created:
+//	    primary_key: "created"
+//	    primary_key_type: "timestamp"
+//	tables:
+//	  - table_name: "orders"
+//	    indexes:
+//	      - template: "by_created"
+type configFile struct {
+	// AttributeTypes are named aliases of attribute types usable in key type fields.
+	AttributeTypes map[string]string `yaml:"attribute_types"`
+	// IndexTemplates are named index shapes tables can reference via IndexInfo.Template.
+	IndexTemplates map[string]IndexInfo `yaml:"index_templates"`
+	// Defaults are merged into every table, see configDefaults.
+	Defaults *configDefaults `yaml:"defaults"`
+	Tables   []TableInfo     `yaml:"tables"`
+	// Environments and EnvAliases are decoded separately, see environments.
+	Environments interface{}       `yaml:"environments"`
+	EnvAliases   map[string]string `yaml:"env_aliases"`
+}
+
+// decodeConfig decodes a config file in list or mapping form, applies the settings
+// of the given environment and resolves its templates.
+func decodeConfig(data []byte, env string) ([]TableInfo, error) {
+	return decodeConfigOverlay(data, nil, env, false)
+}
+
+// decodeConfigOverlay decodes a config like decodeConfig and applies the overlay file
+// of the environment on top of the environment settings, see applyOverlay.
+// Strict configs must not contain unknown fields, see Strict.
+func decodeConfigOverlay(data, overlay []byte, env string, strict bool) ([]TableInfo, error) {
+	var root interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if strict {
+		if err := checkKnownFields(data); err != nil {
+			return nil, err
+		}
+	}
+	cfg := configFile{}
+	if _, ok := root.(map[interface{}]interface{}); ok {
+		if err := yaml.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if err := applyEnv(data, cfg.Tables, env); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.Unmarshal(data, &cfg.Tables); err != nil {
+		return nil, err
+	}
+	if cfg.Tables == nil {
+		cfg.Tables = []TableInfo{}
+	}
+	if err := applyOverlay(overlay, cfg.Tables, strict); err != nil {
+		return nil, err
+	}
+
+	if err := prepareTables(cfg.Tables, cfg.Defaults, cfg.IndexTemplates, cfg.AttributeTypes); err != nil {
+		return nil, err
+	}
+	return cfg.Tables, nil
+}
+
+// configDefaults holds the defaults section of a config, merged into every table at load time
+// so nearly identical tables need not repeat the same values.
+type configDefaults struct {
+	// ReadThroughput and WriteThroughput are used by provisioned tables which omit them.
+	ReadThroughput  int64 `yaml:"read_throughput" hcl:"read_throughput,optional"`
+	WriteThroughput int64 `yaml:"write_throughput" hcl:"write_throughput,optional"`
+	// TTLAttribute enables TTL on this attribute for tables without a ttl section.
+	TTLAttribute string `yaml:"ttl_attribute" hcl:"ttl_attribute,optional"`
+	// ProjectionType is used by indexes which omit it, after applying their template.
+	ProjectionType string `yaml:"projection_type" hcl:"projection_type,optional"`
+}
+
+// prepareTables applies the defaults, index templates and attribute type aliases to decoded
+// tables, checks their attribute types, projections, lifecycles, ignore rules and billing modes and fills in index throughput.
+func prepareTables(tables []TableInfo, defaults *configDefaults, templates map[string]IndexInfo, aliases map[string]string) error {
+	if err := applyIndexTemplates(tables, templates); err != nil {
+		return err
+	}
+	if defaults != nil {
+		applyDefaults(tables, *defaults)
+	}
+	if err := checkProjectionTypes(tables); err != nil {
+		return err
+	}
+	if err := checkLifecycles(tables); err != nil {
+		return err
+	}
+	if err := checkIgnoreRules(tables); err != nil {
+		return err
+	}
+	if err := resolveAttributeTypes(tables, aliases); err != nil {
+		return err
+	}
+	if err := applyDeclaredAttributes(tables); err != nil {
+		return err
+	}
+	if err := checkAttributeTypes(tables); err != nil {
+		return err
+	}
+	if err := checkBillingModes(tables); err != nil {
+		return err
+	}
+	inheritIndexThroughput(tables)
+	return nil
+}
+
+// inheritIndexThroughput sets the read and write throughput of indexes which omit them
+// to the throughput of their table, since DynamoDB rejects GSIs with zero capacity.
+func inheritIndexThroughput(tables []TableInfo) {
+	for i := range tables {
+		tbl := &tables[i]
+		for j := range tbl.Indexes {
+			index := &tbl.Indexes[j]
+			if index.ReadThroughput == 0 {
+				index.ReadThroughput = tbl.ReadThroughput
+			}
+			if index.WriteThroughput == 0 {
+				index.WriteThroughput = tbl.WriteThroughput
+			}
+		}
+	}
+}
+
+// applyDefaults sets the zero values of tables and their indexes covered by the defaults.
+// PAY_PER_REQUEST tables take no throughput.
+func applyDefaults(tables []TableInfo, defaults configDefaults) {
+	for i := range tables {
+		tbl := &tables[i]
+		if tbl.BillingMode != dynamodb.BillingModePayPerRequest {
+			if tbl.ReadThroughput == 0 {
+				tbl.ReadThroughput = defaults.ReadThroughput
+			}
+			if tbl.WriteThroughput == 0 {
+				tbl.WriteThroughput = defaults.WriteThroughput
+			}
+		}
+		if tbl.TTL == nil && defaults.TTLAttribute != "" {
+			tbl.TTL = &TTLAttributeInfo{AttributeName: defaults.TTLAttribute, Enabled: true}
+		}
+		for j := range tbl.Indexes {
+			if tbl.Indexes[j].ProjectionType == "" {
+				tbl.Indexes[j].ProjectionType = defaults.ProjectionType
+			}
+		}
+	}
+}
+
+// checkProjectionTypes rejects index projection types other than ALL, KEYS_ONLY and INCLUDE.
+func checkProjectionTypes(tables []TableInfo) error {
+	for _, tbl := range tables {
+		for _, index := range tbl.Indexes {
+			switch index.ProjectionType {
+			case "", dynamodb.ProjectionTypeAll, dynamodb.ProjectionTypeKeysOnly, dynamodb.ProjectionTypeInclude:
+			default:
+				return fmt.Errorf("table %s: index %s: %w: %q", tbl.TableName, index.IndexName, ErrInvalidProjectionType, index.ProjectionType)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLifecycles rejects lifecycles other than managed and create_only, so a misspelled
+// create_only does not silently make the table managed.
+func checkLifecycles(tables []TableInfo) error {
+	for _, tbl := range tables {
+		if !isLifecycle(tbl.Lifecycle) {
+			return fmt.Errorf("table %s: %w: %q", tbl.TableName, ErrInvalidLifecycle, tbl.Lifecycle)
+		}
+	}
+	return nil
+}
+
+// isLifecycle returns true for the known lifecycles and the empty default.
+func isLifecycle(lifecycle string) bool {
+	switch lifecycle {
+	case "", LifecycleManaged, LifecycleCreateOnly:
+		return true
+	}
+	return false
+}
+
+// resolveAttributeTypes replaces attribute type aliases in key type fields with their
+// attribute type and rejects key types which are neither S, N, B nor an alias.
+func resolveAttributeTypes(tables []TableInfo, aliases map[string]string) error {
+	for alias, typ := range aliases {
+		if !isAttributeType(typ) {
+			return fmt.Errorf("attribute type alias %s: %w: %q", alias, ErrInvalidAttributeType, typ)
+		}
+	}
+	resolve := func(typ *string, table, field string) error {
+		if *typ == "" || isAttributeType(*typ) {
+			return nil
+		}
+		if resolved, ok := aliases[*typ]; ok {
+			*typ = resolved
+			return nil
+		}
+		return fmt.Errorf("table %s: %s: %w: %q", table, field, ErrInvalidAttributeType, *typ)
+	}
+
+	for i := range tables {
+		tbl := &tables[i]
+		if err := resolve(&tbl.SortKeyType, tbl.TableName, "sort_key_type"); err != nil {
+			return err
+		}
+		for k := range tbl.Attributes {
+			attr := &tbl.Attributes[k]
+			if attr.Type == "" {
+				return fmt.Errorf("table %s: attribute %s: %w: missing type", tbl.TableName, attr.Name, ErrInvalidAttributeType)
+			}
+			if err := resolve(&attr.Type, tbl.TableName, "attributes."+attr.Name); err != nil {
+				return err
+			}
+		}
+		for j := range tbl.Indexes {
+			index := &tbl.Indexes[j]
+			if err := resolve(&index.PrimaryKeyType, tbl.TableName, index.IndexName+".primary_key_type"); err != nil {
+				return err
+			}
+			if err := resolve(&index.SortKeyType, tbl.TableName, index.IndexName+".sort_key_type"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyDeclaredAttributes sets the key types omitted by the sort key and indexes of a table to
+// the types declared in its attributes, and rejects keys whose type is neither set nor declared.
+func applyDeclaredAttributes(tables []TableInfo) error {
+	for i := range tables {
+		tbl := &tables[i]
+		declared := make(map[string]string, len(tbl.Attributes))
+		for _, attr := range tbl.Attributes {
+			declared[attr.Name] = attr.Type
+		}
+		apply := func(key string, typ *string, field string) error {
+			if key == "" || *typ != "" {
+				return nil
+			}
+			if t, ok := declared[key]; ok {
+				*typ = t
+				return nil
+			}
+			return fmt.Errorf("table %s: %s: %w: %s", tbl.TableName, field, ErrUndeclaredAttributeType, key)
+		}
+
+		if err := apply(tbl.SortKey, &tbl.SortKeyType, "sort_key"); err != nil {
+			return err
+		}
+		for j := range tbl.Indexes {
+			index := &tbl.Indexes[j]
+			if err := apply(index.PrimaryKey, &index.PrimaryKeyType, index.IndexName+".primary_key"); err != nil {
+				return err
+			}
+			if err := apply(index.SortKey, &index.SortKeyType, index.IndexName+".sort_key"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkAttributeTypes rejects tables referencing the same attribute with different types in their
+// keys, indexes or declared attributes, since DynamoDB rejects conflicting attribute definitions.
+func checkAttributeTypes(tables []TableInfo) error {
+	for _, tbl := range tables {
+		seen := map[string]AttributeUse{}
+		for _, ref := range attributeRefs(tbl) {
+			if prev, ok := seen[ref.name]; ok && prev.Type != ref.use.Type {
+				return fmt.Errorf("table %s: %w: %s is %s in %s but %s in %s", tbl.TableName, ErrConflictingAttributeType,
+					ref.name, prev.Type, prev.Field, ref.use.Type, ref.use.Field)
+			}
+			seen[ref.name] = ref.use
+		}
+	}
+	return nil
+}
+
+// checkBillingModes returns an error listing every table whose throughput contradicts its billing
+// mode, which DynamoDB would reject with a ValidationException: on-demand tables or indexes with
+// throughput, or tables explicitly set to PROVISIONED without capacity. Tables omitting both are
+// left to the comparison. Indexes of provisioned tables omitting their throughput inherit it,
+// see inheritIndexThroughput.
+func checkBillingModes(tables []TableInfo) error {
+	conflicts := []string{}
+	for _, tbl := range tables {
+		conflict := func(format string, args ...interface{}) {
+			conflicts = append(conflicts, fmt.Sprintf("table %s: ", tbl.TableName)+fmt.Sprintf(format, args...))
+		}
+		switch tbl.BillingMode {
+		case "":
+		case dynamodb.BillingModePayPerRequest:
+			if tbl.ReadThroughput != 0 || tbl.WriteThroughput != 0 {
+				conflict("%s with throughput %d/%d", tbl.BillingMode, tbl.ReadThroughput, tbl.WriteThroughput)
+			}
+			for _, index := range tbl.Indexes {
+				if index.ReadThroughput != 0 || index.WriteThroughput != 0 {
+					conflict("%s with index %s throughput %d/%d", tbl.BillingMode, index.IndexName, index.ReadThroughput, index.WriteThroughput)
+				}
+			}
+		case dynamodb.BillingModeProvisioned:
+			if tbl.ReadThroughput <= 0 || tbl.WriteThroughput <= 0 {
+				conflict("%s without capacity, throughput %d/%d", tbl.BillingMode, tbl.ReadThroughput, tbl.WriteThroughput)
+			}
+		default:
+			conflict("unknown billing mode %s", tbl.BillingMode)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%w: %s", ErrBillingModeConflict, strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+func isAttributeType(typ string) bool {
+	switch typ {
+	case dynamodb.ScalarAttributeTypeS, dynamodb.ScalarAttributeTypeN, dynamodb.ScalarAttributeTypeB:
+		return true
+	}
+	return false
+}
+
+// applyIndexTemplates replaces indexes referencing a template with the template,
+// overridden by the values set on the index itself. Indexes without a name are
+// named after their template.
+func applyIndexTemplates(tables []TableInfo, templates map[string]IndexInfo) error {
+	for i := range tables {
+		for j, index := range tables[i].Indexes {
+			if index.Template == "" {
+				continue
+			}
+			tpl, ok := templates[index.Template]
+			if !ok {
+				return fmt.Errorf("table %s: %w: %q", tables[i].TableName, ErrUnknownIndexTemplate, index.Template)
+			}
+			if tpl.IndexName == "" {
+				tpl.IndexName = index.Template
+			}
+			tables[i].Indexes[j] = overrideIndex(tpl, index)
+		}
+	}
+	return nil
+}
+
+// overrideIndex returns base with all non-zero values of override applied.
+func overrideIndex(base, override IndexInfo) IndexInfo {
+	if override.IndexName != "" {
+		base.IndexName = override.IndexName
+	}
+	if override.PrimaryKey != "" {
+		base.PrimaryKey = override.PrimaryKey
+	}
+	if override.PrimaryKeyType != "" {
+		base.PrimaryKeyType = override.PrimaryKeyType
+	}
+	if override.SortKey != "" {
+		base.SortKey = override.SortKey
+	}
+	if override.SortKeyType != "" {
+		base.SortKeyType = override.SortKeyType
+	}
+	if override.ReadThroughput != 0 {
+		base.ReadThroughput = override.ReadThroughput
+	}
+	if override.WriteThroughput != 0 {
+		base.WriteThroughput = override.WriteThroughput
+	}
+	if len(override.ProjectedFields) > 0 {
+		base.ProjectedFields = override.ProjectedFields
+	}
+	if override.ProjectionType != "" {
+		base.ProjectionType = override.ProjectionType
+	}
+	base.Template = ""
+	return base
+}
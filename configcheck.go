@@ -0,0 +1,125 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// MaxGSIsPerTable is the default DynamoDB quota of global secondary indexes per table.
+const MaxGSIsPerTable = 20
+
+// ConfigError is a finding of ValidateConfig in the definition of a table or one of its indexes.
+type ConfigError struct {
+	TableName string
+	// IndexName is set for findings of a single index.
+	IndexName string
+	// Field is the config field at fault, e.g. sort_key_type.
+	Field   string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	if e.IndexName != "" {
+		return fmt.Sprintf("table %s: index %s: %s: %s", e.TableName, e.IndexName, e.Field, e.Message)
+	}
+	return fmt.Sprintf("table %s: %s: %s", e.TableName, e.Field, e.Message)
+}
+
+// ValidateConfig checks table definitions without calling AWS, so broken configs fail CI before
+// they reach DynamoDB: missing keys and names, missing or invalid key types, conflicting attribute
+// types, missing throughput of provisioned tables, invalid lifecycles, tag ignore rules, duplicate table and index
+// names and more GSIs than MaxGSIsPerTable. Unmanaged tables are only checked for duplicate names.
+// Findings are returned in config order, none if the config is valid.
+func ValidateConfig(tables []TableInfo) []ConfigError {
+	errs := []ConfigError{}
+	seen := map[string]bool{}
+	for _, tbl := range tables {
+		report := func(index, field, format string, args ...interface{}) {
+			errs = append(errs, ConfigError{TableName: tbl.TableName, IndexName: index, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+		if tbl.TableName == "" {
+			report("", "table_name", "missing table name")
+		} else if key := tbl.Tenant + "/" + tbl.TableName; seen[key] {
+			report("", "table_name", "duplicate table name")
+		} else {
+			seen[key] = true
+		}
+		if tbl.Unmanaged {
+			continue
+		}
+
+		if tbl.PrimaryKey == "" {
+			report("", "primary_key", "missing primary key")
+		}
+		if !isLifecycle(tbl.Lifecycle) {
+			report("", "lifecycle", "invalid lifecycle %q", tbl.Lifecycle)
+		}
+		for _, rule := range tbl.Ignore {
+			if strings.HasPrefix(rule, IgnoreTagPrefix) {
+				report("", "ignore", "%s: tags are not compared and cannot be ignored", rule)
+			}
+		}
+		checkKeyType(tbl.SortKey, tbl.SortKeyType, "sort_key_type", func(field, msg string) { report("", field, "%s", msg) })
+
+		provisioned := billingMode(tbl) == dynamodb.BillingModeProvisioned
+		if provisioned && tbl.ReadThroughput <= 0 {
+			report("", "read_throughput", "provisioned table without read throughput")
+		}
+		if provisioned && tbl.WriteThroughput <= 0 {
+			report("", "write_throughput", "provisioned table without write throughput")
+		}
+
+		if n := len(tbl.Indexes); n > MaxGSIsPerTable {
+			report("", "indexes", "%d GSIs exceed the limit of %d per table", n, MaxGSIsPerTable)
+		}
+		indexes := map[string]bool{}
+		for _, index := range tbl.Indexes {
+			name := index.IndexName
+			reportIndex := func(field, msg string) { report(name, field, "%s", msg) }
+			if name == "" {
+				reportIndex("index_name", "missing index name")
+			} else if indexes[name] {
+				reportIndex("index_name", "duplicate index name")
+			}
+			indexes[name] = true
+			if index.PrimaryKey == "" {
+				reportIndex("primary_key", "missing primary key")
+			} else if index.PrimaryKeyType == "" && index.PrimaryKey != tbl.PrimaryKey && index.PrimaryKey != tbl.SortKey {
+				reportIndex("primary_key_type", "missing primary key type")
+			} else if index.PrimaryKeyType != "" && !isAttributeType(index.PrimaryKeyType) {
+				reportIndex("primary_key_type", fmt.Sprintf("invalid attribute type %q", index.PrimaryKeyType))
+			}
+			checkKeyType(index.SortKey, index.SortKeyType, "sort_key_type", reportIndex)
+			if provisioned && index.ReadThroughput <= 0 {
+				reportIndex("read_throughput", "provisioned index without read throughput")
+			}
+			if provisioned && index.WriteThroughput <= 0 {
+				reportIndex("write_throughput", "provisioned index without write throughput")
+			}
+		}
+
+		types := map[string]AttributeUse{}
+		for _, ref := range attributeRefs(tbl) {
+			if prev, ok := types[ref.name]; ok && prev.Type != ref.use.Type {
+				report("", ref.use.Field, "%s is %s in %s but %s here", ref.name, prev.Type, prev.Field, ref.use.Type)
+				continue
+			}
+			types[ref.name] = ref.use
+		}
+	}
+	return errs
+}
+
+// checkKeyType reports a sort key without a valid type, and a type without a sort key.
+func checkKeyType(key, typ, field string, report func(field, msg string)) {
+	switch {
+	case key != "" && typ == "":
+		report(field, "sort key without type")
+	case key == "" && typ != "":
+		report(field, "type without sort key")
+	case typ != "" && !isAttributeType(typ):
+		report(field, fmt.Sprintf("invalid attribute type %q", typ))
+	}
+}
@@ -0,0 +1,77 @@
+package tables
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	valid := TableInfo{
+		TableName:       "orders",
+		PrimaryKey:      "id",
+		SortKey:         "created",
+		SortKeyType:     "N",
+		ReadThroughput:  5,
+		WriteThroughput: 5,
+		Indexes: []IndexInfo{
+			{IndexName: "by_customer", PrimaryKey: "customer_id", PrimaryKeyType: "S", SortKey: "created", SortKeyType: "N", ReadThroughput: 1, WriteThroughput: 1},
+			{IndexName: "by_created", PrimaryKey: "created", ReadThroughput: 1, WriteThroughput: 1},
+		},
+	}
+	onDemand := TableInfo{TableName: "events", PrimaryKey: "id", BillingMode: "PAY_PER_REQUEST",
+		Indexes: []IndexInfo{{IndexName: "by_type", PrimaryKey: "type", PrimaryKeyType: "S"}}}
+	if errs := ValidateConfig([]TableInfo{valid, onDemand}); len(errs) > 0 {
+		t.Fatalf("expected valid config but got %v", errs)
+	}
+
+	tooMany := TableInfo{TableName: "wide", PrimaryKey: "id", BillingMode: "PAY_PER_REQUEST"}
+	for i := 0; i <= MaxGSIsPerTable; i++ {
+		tooMany.Indexes = append(tooMany.Indexes, IndexInfo{IndexName: fmt.Sprintf("by_attr%d", i), PrimaryKey: fmt.Sprintf("attr%d", i), PrimaryKeyType: "S"})
+	}
+	tables := []TableInfo{
+		valid,
+		{
+			TableName:      "users",
+			SortKey:        "created",
+			ReadThroughput: 5,
+			Lifecycle:      "create-only",
+			Indexes: []IndexInfo{
+				{IndexName: "by_email", PrimaryKey: "email", ReadThroughput: 1, WriteThroughput: 1},
+				{IndexName: "by_email", PrimaryKey: "email", PrimaryKeyType: "X%d", SortKeyType: "N", ReadThroughput: 1, WriteThroughput: 1},
+				{IndexName: "by_id", PrimaryKey: "id", PrimaryKeyType: "N", ReadThroughput: 1},
+			},
+		},
+		valid,
+		tooMany,
+		{TableName: "legacy", Unmanaged: true},
+	}
+	expected := []string{
+		"table users: primary_key: missing primary key",
+		`table users: lifecycle: invalid lifecycle "create-only"`,
+		"table users: sort_key_type: sort key without type",
+		"table users: write_throughput: provisioned table without write throughput",
+		"table users: index by_email: primary_key_type: missing primary key type",
+		"table users: index by_email: index_name: duplicate index name",
+		`table users: index by_email: primary_key_type: invalid attribute type "X%d"`,
+		"table users: index by_email: sort_key_type: type without sort key",
+		"table users: index by_id: write_throughput: provisioned index without write throughput",
+		"table orders: table_name: duplicate table name",
+		"table wide: indexes: 21 GSIs exceed the limit of 20 per table",
+	}
+	errs := ValidateConfig(tables)
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors but got %d: %v", len(expected), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("expected %q but got %q", expected[i], err.Error())
+		}
+	}
+
+	conflict := valid
+	conflict.Indexes = []IndexInfo{{IndexName: "by_created", PrimaryKey: "created", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1}}
+	errs = ValidateConfig([]TableInfo{conflict})
+	if len(errs) != 1 || errs[0].Field != "by_created.primary_key" {
+		t.Fatalf("expected conflicting attribute type but got %v", errs)
+	}
+}
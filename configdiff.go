@@ -0,0 +1,159 @@
+package tables
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConfigChangeKind is the kind of a change between two config revisions.
+type ConfigChangeKind string
+
+const (
+	ConfigTableAdded             ConfigChangeKind = "TableAdded"
+	ConfigTableRemoved           ConfigChangeKind = "TableRemoved"
+	ConfigTableDeprecated        ConfigChangeKind = "TableDeprecated"
+	ConfigKeySchemaChanged       ConfigChangeKind = "KeySchemaChanged"
+	ConfigThroughputChanged      ConfigChangeKind = "ThroughputChanged"
+	ConfigBillingModeChanged     ConfigChangeKind = "BillingModeChanged"
+	ConfigTTLChanged             ConfigChangeKind = "TTLChanged"
+	ConfigIndexAdded             ConfigChangeKind = "IndexAdded"
+	ConfigIndexRemoved           ConfigChangeKind = "IndexRemoved"
+	ConfigIndexChanged           ConfigChangeKind = "IndexChanged"
+	ConfigIndexThroughputChanged ConfigChangeKind = "IndexThroughputChanged"
+)
+
+// ConfigChange is a single intended schema change between two config revisions.
+type ConfigChange struct {
+	TableName string
+	// IndexName is set for index changes.
+	IndexName   string
+	Kind        ConfigChangeKind
+	Description string
+}
+
+func (ch ConfigChange) String() string {
+	return ch.Description
+}
+
+// ChangeSet lists the changes between two config revisions in config order.
+type ChangeSet []ConfigChange
+
+func (cs ChangeSet) String() string {
+	lines := make([]string, len(cs))
+	for i, ch := range cs {
+		lines[i] = ch.Description
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DiffConfigs compares two config revisions without accessing AWS and describes the intended
+// schema changes, e.g. "adds GSI byEmail to orders". Tables and indexes are matched by name.
+// Changes of tables in new are listed first, followed by the tables removed from old.
+func DiffConfigs(old, new []TableInfo) ChangeSet {
+	cs := ChangeSet{}
+	previous := make(map[string]TableInfo, len(old))
+	for _, tbl := range old {
+		previous[tbl.TableName] = tbl
+	}
+	current := make(map[string]bool, len(new))
+	for _, tbl := range new {
+		current[tbl.TableName] = true
+		prev, ok := previous[tbl.TableName]
+		if !ok {
+			cs = append(cs, ConfigChange{
+				TableName:   tbl.TableName,
+				Kind:        ConfigTableAdded,
+				Description: fmt.Sprintf("adds table %s", tbl.TableName),
+			})
+			continue
+		}
+		cs = append(cs, diffTableConfig(prev, tbl)...)
+	}
+	for _, tbl := range old {
+		if !current[tbl.TableName] {
+			cs = append(cs, ConfigChange{
+				TableName:   tbl.TableName,
+				Kind:        ConfigTableRemoved,
+				Description: fmt.Sprintf("removes table %s", tbl.TableName),
+			})
+		}
+	}
+	return cs
+}
+
+func diffTableConfig(old, new TableInfo) ChangeSet {
+	cs := ChangeSet{}
+	name := new.TableName
+	add := func(kind ConfigChangeKind, index, format string, args ...interface{}) {
+		cs = append(cs, ConfigChange{
+			TableName:   name,
+			IndexName:   index,
+			Kind:        kind,
+			Description: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if new.Deprecated && !old.Deprecated {
+		add(ConfigTableDeprecated, "", "deprecates table %s", name)
+	}
+	if old.PrimaryKey != new.PrimaryKey || old.SortKey != new.SortKey || old.SortKeyType != new.SortKeyType {
+		add(ConfigKeySchemaChanged, "", "changes key schema of %s from %s to %s", name, keySchemaString(old), keySchemaString(new))
+	}
+	if billingMode(old) != billingMode(new) {
+		add(ConfigBillingModeChanged, "", "changes billing mode of %s from %s to %s", name, billingMode(old), billingMode(new))
+	}
+	if old.ReadThroughput != new.ReadThroughput || old.WriteThroughput != new.WriteThroughput {
+		add(ConfigThroughputChanged, "", "changes throughput of %s from %d/%d to %d/%d (read/write)",
+			name, old.ReadThroughput, old.WriteThroughput, new.ReadThroughput, new.WriteThroughput)
+	}
+	if !reflect.DeepEqual(old.TTL, new.TTL) {
+		add(ConfigTTLChanged, "", "changes TTL of %s from %s to %s", name, ttlString(old.TTL), ttlString(new.TTL))
+	}
+
+	previous := make(map[string]IndexInfo, len(old.Indexes))
+	for _, idx := range old.Indexes {
+		previous[idx.IndexName] = idx
+	}
+	current := make(map[string]bool, len(new.Indexes))
+	for _, idx := range new.Indexes {
+		current[idx.IndexName] = true
+		prev, ok := previous[idx.IndexName]
+		if !ok {
+			add(ConfigIndexAdded, idx.IndexName, "adds GSI %s to %s", idx.IndexName, name)
+			continue
+		}
+		if prev.PrimaryKey != idx.PrimaryKey || prev.PrimaryKeyType != idx.PrimaryKeyType ||
+			prev.SortKey != idx.SortKey || prev.SortKeyType != idx.SortKeyType ||
+			prev.ProjectionType != idx.ProjectionType || !reflect.DeepEqual(prev.ProjectedFields, idx.ProjectedFields) {
+			add(ConfigIndexChanged, idx.IndexName, "changes keys or projection of GSI %s on %s", idx.IndexName, name)
+		}
+		if prev.ReadThroughput != idx.ReadThroughput || prev.WriteThroughput != idx.WriteThroughput {
+			add(ConfigIndexThroughputChanged, idx.IndexName, "changes throughput of GSI %s on %s from %d/%d to %d/%d (read/write)",
+				idx.IndexName, name, prev.ReadThroughput, prev.WriteThroughput, idx.ReadThroughput, idx.WriteThroughput)
+		}
+	}
+	for _, idx := range old.Indexes {
+		if !current[idx.IndexName] {
+			add(ConfigIndexRemoved, idx.IndexName, "removes GSI %s from %s", idx.IndexName, name)
+		}
+	}
+	return cs
+}
+
+func keySchemaString(tbl TableInfo) string {
+	if tbl.SortKey == "" {
+		return tbl.PrimaryKey
+	}
+	return fmt.Sprintf("%s/%s", tbl.PrimaryKey, tbl.SortKey)
+}
+
+func ttlString(ttl *TTLAttributeInfo) string {
+	if ttl == nil {
+		return "unset"
+	}
+	if !ttl.Enabled {
+		return fmt.Sprintf("%s (disabled)", ttl.AttributeName)
+	}
+	return ttl.AttributeName
+}
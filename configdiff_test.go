@@ -0,0 +1,54 @@
+package tables
+
+import (
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	old := []TableInfo{
+		{
+			TableName:       "orders",
+			PrimaryKey:      "id",
+			ReadThroughput:  1,
+			WriteThroughput: 1,
+			Indexes: []IndexInfo{
+				{IndexName: "byStatus", PrimaryKey: "status", PrimaryKeyType: "S"},
+			},
+		},
+		{TableName: "legacy", PrimaryKey: "id"},
+	}
+	new := []TableInfo{
+		{
+			TableName:       "orders",
+			PrimaryKey:      "id",
+			ReadThroughput:  1,
+			WriteThroughput: 1,
+			Indexes: []IndexInfo{
+				{IndexName: "byStatus", PrimaryKey: "status", PrimaryKeyType: "S"},
+				{IndexName: "byEmail", PrimaryKey: "email", PrimaryKeyType: "S"},
+			},
+			TTL: &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true},
+		},
+		{TableName: "users", PrimaryKey: "id"},
+	}
+
+	expected := []string{
+		"changes TTL of orders from unset to expires_at",
+		"adds GSI byEmail to orders",
+		"adds table users",
+		"removes table legacy",
+	}
+	cs := DiffConfigs(old, new)
+	if len(cs) != len(expected) {
+		t.Fatalf("expected %d changes but got %d:\n%s", len(expected), len(cs), cs)
+	}
+	for i, ch := range cs {
+		if ch.Description != expected[i] {
+			t.Errorf("expected change %q but got %q", expected[i], ch.Description)
+		}
+	}
+
+	if cs := DiffConfigs(old, old); len(cs) != 0 {
+		t.Fatalf("expected no changes but got:\n%s", cs)
+	}
+}
@@ -0,0 +1,111 @@
+package tables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// AttributeUse is a single reference to an attribute in the config.
+type AttributeUse struct {
+	// Env is the environment the table was loaded for, empty if not loaded per environment.
+	Env       string
+	TableName string
+	// Field references the attribute, e.g. sort_key, byUser.primary_key or attributes.
+	Field string
+	Type  string
+}
+
+func (u AttributeUse) String() string {
+	location := u.TableName + "." + u.Field
+	if u.Env != "" {
+		location = u.Env + "/" + location
+	}
+	return fmt.Sprintf("%s in %s", u.Type, location)
+}
+
+// AttributeTypeConflict reports an attribute referenced with different types, which
+// frequently signals a config typo, e.g. created_at as S on one table and N on another.
+type AttributeTypeConflict struct {
+	Attribute string
+	Uses      []AttributeUse
+}
+
+func (c AttributeTypeConflict) String() string {
+	uses := make([]string, len(c.Uses))
+	for i, u := range c.Uses {
+		uses[i] = u.String()
+	}
+	return fmt.Sprintf("attribute %s has conflicting types: %s", c.Attribute, strings.Join(uses, ", "))
+}
+
+// FindAttributeTypeConflicts reports attributes which are referenced with different types
+// across the given tables, sorted by attribute name. Tables with conflicting types within
+// themselves fail to load, across tables the same name may legitimately have different types.
+func FindAttributeTypeConflicts(tables []TableInfo) []AttributeTypeConflict {
+	return FindEnvAttributeTypeConflicts(map[string][]TableInfo{"": tables})
+}
+
+// FindEnvAttributeTypeConflicts is the same as FindAttributeTypeConflicts for the tables of
+// several environments keyed by environment, e.g. loaded with LoadEnv.
+func FindEnvAttributeTypeConflicts(envTables map[string][]TableInfo) []AttributeTypeConflict {
+	envs := make([]string, 0, len(envTables))
+	for env := range envTables {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	uses := map[string][]AttributeUse{}
+	for _, env := range envs {
+		for _, tbl := range envTables[env] {
+			for _, ref := range attributeRefs(tbl) {
+				ref.use.Env = env
+				uses[ref.name] = append(uses[ref.name], ref.use)
+			}
+		}
+	}
+
+	conflicts := []AttributeTypeConflict{}
+	for name, us := range uses {
+		for _, u := range us[1:] {
+			if u.Type != us[0].Type {
+				conflicts = append(conflicts, AttributeTypeConflict{Attribute: name, Uses: us})
+				break
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Attribute < conflicts[j].Attribute
+	})
+	return conflicts
+}
+
+// attributeRef is a typed reference to the named attribute.
+type attributeRef struct {
+	name string
+	use  AttributeUse
+}
+
+// attributeRefs lists the typed attribute references of a table: its keys, declared
+// attributes and index keys.
+func attributeRefs(tbl TableInfo) []attributeRef {
+	refs := []attributeRef{}
+	add := func(name, typ, field string) {
+		if name != "" && typ != "" {
+			refs = append(refs, attributeRef{name: name, use: AttributeUse{TableName: tbl.TableName, Field: field, Type: typ}})
+		}
+	}
+	// The primary key of a table is always a string, see CreateTableInput.
+	add(tbl.PrimaryKey, dynamodb.ScalarAttributeTypeS, "primary_key")
+	add(tbl.SortKey, tbl.SortKeyType, "sort_key")
+	for _, attr := range tbl.Attributes {
+		add(attr.Name, attr.Type, "attributes")
+	}
+	for _, index := range tbl.Indexes {
+		add(index.PrimaryKey, index.PrimaryKeyType, index.IndexName+".primary_key")
+		add(index.SortKey, index.SortKeyType, index.IndexName+".sort_key")
+	}
+	return refs
+}
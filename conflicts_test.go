@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"testing"
+)
+
+func TestFindAttributeTypeConflicts(t *testing.T) {
+	tables := []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", SortKey: "created_at", SortKeyType: "N"},
+		{TableName: "audit", PrimaryKey: "id", Indexes: []IndexInfo{
+			{IndexName: "byCreated", PrimaryKey: "created_at", PrimaryKeyType: "S"},
+		}},
+		{TableName: "users", PrimaryKey: "email", Attributes: []AttributeInfo{{Name: "created_at", Type: "N"}}},
+	}
+
+	conflicts := FindAttributeTypeConflicts(tables)
+	if len(conflicts) != 1 {
+		t.Fatalf("expected a single conflict but got %v", conflicts)
+	}
+	expected := "attribute created_at has conflicting types: N in orders.sort_key, S in audit.byCreated.primary_key, N in users.attributes"
+	if s := conflicts[0].String(); s != expected {
+		t.Fatalf("expected %q but got %q", expected, s)
+	}
+
+	conflicts = FindEnvAttributeTypeConflicts(map[string][]TableInfo{
+		"dev":  {{TableName: "orders", PrimaryKey: "id", SortKey: "created_at", SortKeyType: "N"}},
+		"prod": {{TableName: "orders", PrimaryKey: "id", SortKey: "created_at", SortKeyType: "S"}},
+	})
+	if len(conflicts) != 1 || conflicts[0].Uses[0].Env != "dev" || conflicts[0].Uses[1].Env != "prod" {
+		t.Fatalf("expected conflict between environments but got %v", conflicts)
+	}
+}
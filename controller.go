@@ -1,13 +1,19 @@
 package tables
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/google/go-cmp/cmp"
 )
 
 const (
@@ -20,20 +26,125 @@ const (
 // Tables contains a list of table definitions defined in the config file and unmarshalled via Load.
 // env is a Environment variable that is used as part of the table name prefixes.
 // Log takes an implementation of the Logger instance. If nil is passed, it takes the defaultLogger.
+// A Controller is safe for concurrent use once created, results and their inputs are never
+// modified by it and share no data with the config. Use Refresh to replace the config of a
+// controller in use instead of setting Tables.
 type Controller struct {
-	DynamoDB *dynamodb.DynamoDB
+	DynamoDB DynamoDBAPI
 	// TableInfo gets loaded from config
 	Tables []TableInfo
+	// Guards Tables, see Refresh. Shared state is held by pointer so that copies of the
+	// controller, such as the offline copy of ValidateAgainstState, do not copy locks.
+	mu *sync.RWMutex
 	// Environment string used as table prefix
 	env string
 	// Default logger if no logging implementation is defined.
 	Log Logger
+
+	// Optional client factory used instead of DynamoDB when set.
+	clientFactory ClientFactory
+	// Default region and account passed to the client factory.
+	region  string
+	account string
+	clients *clientCache
+
+	// Change budget per Migrate run, negative values mean no limit.
+	maxChanges            int
+	maxDestructiveChanges int
+	// Policy for throughput reductions.
+	allowCapacityDecrease    bool
+	capacityDecreaseApprover CapacityDecreaseApprover
+	// Time to wait for newly created GSIs to become ACTIVE, zero disables waiting.
+	indexWaitTimeout time.Duration
+	// Time to wait for busy tables to become ACTIVE before migrating, zero skips busy tables.
+	busyWaitTimeout time.Duration
+	// Clock used for retry and wait delays.
+	clock Clock
+	// Retry policies keyed by AWS error code.
+	retryPolicies map[string]RetryPolicy
+	// Abort Migrate on the first failed table instead of continuing with the others.
+	stopOnError bool
+	// Maximum duration of a single table migration, zero means no limit.
+	tableTimeout time.Duration
+	// Scopes Validate to the selected tables, nil selects all tables.
+	filter Filter
+	// Schedule deletion of deprecated tables, deletions require approval.
+	prune         bool
+	pruneApprover DeletionApprover
+	// Additional options passed to every schema comparison.
+	cmpOptions []cmp.Option
+	// Check values with hand-written comparers before diffing them with cmp.
+	fastDiff bool
+	// Number of items sampled to verify the TTL attribute, zero disables sampling.
+	ttlSampleSize int64
+	// Time to wait for TTL updates to reach a terminal state, zero disables waiting.
+	ttlWaitTimeout time.Duration
+	// Approver consulted before disabling or switching TTL, nil rejects all such changes.
+	ttlChangeApprover TTLChangeApprover
+	// Store config fingerprints as table tags after migration.
+	fingerprints bool
+	// Tag tables found in sync by Validate, the only change Validate may make.
+	refreshFingerprints bool
+	// Skip the comparison of tables with matching fingerprints.
+	fastValidate bool
+	// Maximum age of a fingerprint skipping the comparison, zero means no limit.
+	fullValidationInterval time.Duration
+	// Tenants for which a copy of each table is managed.
+	tenants []string
+	// Maximum number of tables CreateAll creates at the same time.
+	createConcurrency int
+	// Table states cached by Describe.
+	describeCache    *describeCache
+	describeCacheTTL time.Duration
+	// Hooks adjusting inputs before they are sent.
+	inputHooks inputHooks
+	// Lifecycle hooks called around changes.
+	hooks Hooks
+	// Managers of resources associated with each table.
+	resourceManagers []ResourceManager
+	// Maximum number of tables ValidateFleet compares at the same time.
+	fleetConcurrency int
+	// Table recording applied migrations and the data steps migrations refer to.
+	migrationsTable string
+	dataSteps       map[string]DataStep
+	// Revision of the config, e.g. a commit hash, tagged on tables next to the run ID.
+	configRevision string
+	// Approver consulted before a Migrate run applies any change, nil approves all runs.
+	runApprover RunApprover
+	// Notifiers told about every finished Migrate run.
+	notifiers []Notifier
+	// KMS client telling customer managed keys apart in the security posture, may be nil.
+	kms kmsiface.KMSAPI
+	// Organization-wide requirements added to the desired state of every table, nil disables them.
+	compliance *Compliance
+	// CloudWatch client and window of the utilization advisories, nil disables them.
+	utilizationCloudWatch cloudwatchiface.CloudWatchAPI
+	utilizationWindow     time.Duration
+	// Naming convention checked when the config is set, violations are added to the validation warnings.
+	naming NamingConvention
+	// Log of the request payloads of mutating DynamoDB calls, nil disables auditing.
+	auditLog AuditLog
+	// First invalid option argument, returned by NewController.
+	optionErr error
 }
 
 // ValidationResult contains result information of a single table schema validation.
 type ValidationResult struct {
 	// TableInfo loaded from config file
 	TableInput TableInfo
+	// Current description of the table in DynamoDB, nil if the table is missing.
+	TableDescription *dynamodb.TableDescription
+	// Current status of the table in DynamoDB (e.g. ACTIVE, UPDATING), empty if the table is missing.
+	TableStatus string
+	// Current TTL of the table in DynamoDB, nil if the table is missing or TTL was not compared.
+	TimeToLiveDescription *dynamodb.TimeToLiveDescription
+	// FingerprintMatched is true if the comparison was skipped because the fingerprint tag
+	// of the table matches its config, see WithFastValidate.
+	FingerprintMatched bool
+	// Warnings about the table which do not block migration, e.g. the table is deprecated.
+	Warnings []string
+	// Advisories suggesting throughput changes based on consumed capacity, see WithUtilizationAdvisories.
+	Advisories []Advisory
 	// If any table is missing, CreateTableInput will contain an input for creating the table.
 	CreateTableInput *dynamodb.CreateTableInput
 	// If table schemas mismatch, such as updated table throughput or newly added GSI,
@@ -43,10 +154,21 @@ type ValidationResult struct {
 	// If TTL is missing or the status of TTL is changed, UpdateTTLInput wil contain an input for
 	// updating the TTL.
 	UpdateTTLInput *dynamodb.UpdateTimeToLiveInput
+	// If point in time recovery is required by WithCompliance but disabled,
+	// UpdateContinuousBackupsInput will contain an input for enabling it.
+	UpdateContinuousBackupsInput *dynamodb.UpdateContinuousBackupsInput
+	// If the table is deprecated and prune mode is enabled, DeleteTableInput will contain
+	// an input for deleting the table.
+	DeleteTableInput *dynamodb.DeleteTableInput
+	// Diffs reported by resource managers keyed by manager name, see ResourceManager.
+	ResourceDiffs map[string]string
 	// A diff string that shows all the mismatched table schemas
 	Diff string
 	// true if table schema can be migrated.
 	CanMigrate bool
+	// Indexes whose changes cannot be migrated, e.g. changed key schemas. The other changes of
+	// the table are still migrated if CanMigrate is true, the table is reported as incompatible.
+	BlockedIndexes []*GSIIndexResult
 	// Error contains error information when a table schema can not be migrated.
 	Error error
 }
@@ -55,10 +177,50 @@ type ValidationResult struct {
 type MigrationResult struct {
 	// TableInfo loaded from config file
 	TableInput TableInfo
+	// ID of the Migrate run, see ContextWithRunID
+	RunID string
+	// Approver of the run if approved via WithRunApprover
+	ApprovedBy string
+	// Stated intent of the run and the table, see ContextWithChangeDescription
+	Description string
+	// Status of the migration
+	Status MigrationStatus
+	// Last observed status of the table if the migration timed out
+	TableStatus string
+	// Final TTL status (ENABLED or DISABLED) if TTL was updated and WithWaitForTTL is set
+	TTLStatus string
+	// Changes applied to the table
+	Applied []Change
+	// Changes not applied, e.g. because the migration was cancelled
+	Skipped []Change
+	// State of the table captured before applying its changes, used by Rollback.
+	// nil if the table was missing.
+	PriorState *PriorState
 	// Errors occurred during migration
 	Errors []error
 }
 
+// MigrationStatus is the outcome of a single table schema migration.
+type MigrationStatus string
+
+const (
+	// StatusApplied means all changes were applied without errors.
+	StatusApplied MigrationStatus = "Applied"
+	// StatusSkipped means the table had no changes to apply or was not selected.
+	StatusSkipped MigrationStatus = "Skipped"
+	// StatusFailed means at least one change could not be applied.
+	StatusFailed MigrationStatus = "Failed"
+	// StatusBusy means the table was not ACTIVE (e.g. CREATING or UPDATING) and was skipped.
+	StatusBusy MigrationStatus = "Busy"
+	// StatusCancelled means the context was cancelled before all changes were applied.
+	StatusCancelled MigrationStatus = "Cancelled"
+	// StatusAborted means the table was not migrated because an earlier table failed
+	// and the controller stops on the first error.
+	StatusAborted MigrationStatus = "Aborted"
+	// StatusTimedOut means the table migration exceeded the per-table timeout.
+	StatusTimedOut MigrationStatus = "TimedOut"
+)
+
 type ResetResult struct {
 	TableName string
 	Error     error
@@ -69,69 +231,209 @@ type ResetResult struct {
 // env represents Environment which is used as table prefix
 // You can optionally pass a logger implementation.
 // If no logging implementation is passed the default logger is used.
-func NewController(db *dynamodb.DynamoDB, env string, logger Logger, data []TableInfo) (*Controller, error) {
+// db may be nil when a ClientFactory is passed via WithClientFactory.
+func NewController(db DynamoDBAPI, env string, logger Logger, data []TableInfo, opts ...Option) (*Controller, error) {
 	if logger == nil {
 		logger = &defaultLogger{}
 	}
 
-	return &Controller{
-		DynamoDB: db,
-		Tables:   data,
-		env:      env,
-		Log:      logger,
-	}, nil
+	c := &Controller{
+		DynamoDB:              db,
+		Tables:                data,
+		mu:                    &sync.RWMutex{},
+		env:                   env,
+		Log:                   logger,
+		maxChanges:            -1,
+		maxDestructiveChanges: -1,
+		clients:               &clientCache{},
+		clock:                 realClock{},
+		retryPolicies:         DefaultRetryPolicies(),
+		createConcurrency:     DefaultCreateConcurrency,
+		describeCache:         &describeCache{},
+		describeCacheTTL:      DefaultDescribeCacheTTL,
+		fleetConcurrency:      DefaultFleetConcurrency,
+		migrationsTable:       DefaultMigrationsTable,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.optionErr != nil {
+		return nil, c.optionErr
+	}
+	if c.DynamoDB == nil && c.clientFactory == nil {
+		return nil, ErrMissingClient
+	}
+	if err := c.checkConfig(c.Tables); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Refresh atomically replaces the config of the controller, e.g. with the tables reloaded by a
+// Watcher, so a long-lived service can keep using one controller. The tables are checked like
+// those passed to NewController and the config is kept if they are invalid. Calls in progress
+// finish with the config they started with, later calls use the new config.
+func (c *Controller) Refresh(tables []TableInfo) error {
+	if err := c.checkConfig(tables); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Tables = tables
+	return nil
+}
+
+// checkConfig checks the tables of a config against the controller settings and logs naming warnings.
+func (c *Controller) checkConfig(tables []TableInfo) error {
+	warnings, err := CheckNaming(tables, c.naming)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		c.Log.Infof("Naming of table [%s]: %s", w.TableName, w.Message)
+	}
+
+	if err := checkLifecycles(tables); err != nil {
+		return err
+	}
+	if err := checkIgnoreRules(tables); err != nil {
+		return err
+	}
+	if c.clientFactory == nil {
+		for _, tbl := range tables {
+			if tbl.Region != "" && tbl.Region != c.region {
+				return fmt.Errorf("table %s: %w", tbl.TableName, ErrRegionWithoutClientFactory)
+			}
+		}
+	}
+	return nil
 }
 
 // Validate compares the table schemas in the config file to
 // the table descriptions in the current database.
+// Only tables selected by WithFilter or WithSelector are compared.
+// Results are returned in config order, copies of a table per tenant in the order of WithTenants.
 // A common error ErrValidationFailed is also returned if
 // any comparison contains schema mismatches.
+// The results are reported to every registered ValidationNotifier.
 func (c *Controller) Validate() ([]*ValidationResult, error) {
-	resultChan := make(chan *ValidationResult, len(c.Tables))
+	res, err := c.validate(filterTables(c.tenantTables(), c.filter))
+	c.notifyValidation(&ValidationRunReport{Env: c.env, Results: res})
+	return res, err
+}
+
+// ValidateTable compares a single managed table, identified by its name in the config or
+// in DynamoDB, and returns its Validation Result with the same errors as Validate.
+// ErrTableNotFound is returned if no managed table has the name. Validation notifiers are told
+// about the result as a partial validation.
+func (c *Controller) ValidateTable(name string) (*ValidationResult, error) {
+	tbl, err := c.lookupTable(name)
+	if err != nil {
+		return nil, err
+	}
+	results, err := c.validate([]TableInfo{tbl})
+	c.notifyValidation(&ValidationRunReport{Env: c.env, Results: results, Partial: true})
+	if len(results) == 0 {
+		return nil, err
+	}
+	return results[0], err
+}
+
+// lookupTable returns the managed table with the given name in the config or in DynamoDB.
+func (c *Controller) lookupTable(name string) (TableInfo, error) {
+	matches := []TableInfo{}
+	for _, tbl := range managedTables(c.tenantTables()) {
+		if physicalName(c.env, tbl) == name {
+			return tbl, nil
+		}
+		if tbl.TableName == name {
+			matches = append(matches, tbl)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return TableInfo{}, fmt.Errorf("%w: %s", ErrTableNotFound, name)
+	case 1:
+		return matches[0], nil
+	}
+	return TableInfo{}, fmt.Errorf("%w: %s matches %d tables, use the table name in DynamoDB", ErrAmbiguousTableName, name, len(matches))
+}
+
+func (c *Controller) validate(tables []TableInfo) ([]*ValidationResult, error) {
+	tables = managedTables(tables)
+	res := make([]*ValidationResult, len(tables))
 
 	var wg sync.WaitGroup
-	for _, tbl := range c.Tables {
+	for i, tbl := range tables {
 		wg.Add(1)
-		go func(tbl TableInfo, resultChan chan *ValidationResult) {
+		go func(i int, tbl TableInfo) {
 			defer wg.Done()
-			result, err := c.compare(tbl)
-			if err != nil {
-				result = &ValidationResult{}
-				result.CanMigrate = false
-				result.Error = err
-				c.Log.Errorf("Validate table [%s] with error: %v", tbl.TableName, result.Error)
-			} else {
-				c.Log.Infof("Validate table [%s] with diff: %v", tbl.TableName, result.Diff)
-			}
-			resultChan <- result
-		}(tbl, resultChan)
+			res[i] = c.validateWith(context.Background(), tbl, c.compare)
+		}(i, tbl)
 	}
 	wg.Wait()
-	close(resultChan)
 
-	res := []*ValidationResult{}
-	isBackwardIncompatible := false
-	isDiff := false
+	outcome := validationOutcome{}
+	for _, r := range res {
+		outcome.add(r)
+	}
+	return res, outcome.err()
+}
 
-	for r := range resultChan {
-		res = append(res, r)
-		if !r.CanMigrate {
-			isBackwardIncompatible = true
-		}
-		if len(r.Diff) > 0 {
-			isDiff = true
+// validateWith compares a single table with the given compare function, runs the resource
+// managers and logs the outcome. Comparison errors are returned as part of the result.
+func (c *Controller) validateWith(ctx context.Context, tbl TableInfo, compare func(TableInfo) (*ValidationResult, error)) *ValidationResult {
+	result, err := compare(tbl)
+	if err != nil {
+		result = &ValidationResult{TableInput: tbl}
+		result.CanMigrate = false
+		result.Error = err
+		c.Log.Errorf("Validate table [%s] with error: %v", tbl.TableName, result.Error)
+		return result
+	}
+	// The naming convention was checked when the config was set and cannot fail.
+	warnings, _ := CheckNaming([]TableInfo{tbl}, c.naming)
+	for _, w := range warnings {
+		result.Warnings = append(result.Warnings, w.Message)
+	}
+	c.validateResources(tbl, result)
+	// Tables a full comparison found in sync are tagged with their fingerprint if enabled,
+	// so they are not only tagged once Migrate changed them.
+	if c.refreshFingerprints && result.inSync() {
+		if err := c.tagFingerprint(ctx, tbl); err != nil {
+			c.Log.Errorf("Tag fingerprint of table [%s] with error: %v", tbl.TableName, err)
 		}
 	}
+	c.Log.Infof("Validate table [%s] with diff: %v", tbl.TableName, result.Diff)
+	return result
+}
 
-	if isBackwardIncompatible {
-		return res, ErrBackwardIncompatible
-	}
+// validationOutcome accumulates the common error of validation results.
+type validationOutcome struct {
+	incompatible bool
+	diff         bool
+}
 
-	if isDiff {
-		return res, ErrBackwardCompatible
+func (o *validationOutcome) add(r *ValidationResult) {
+	if r.incompatible() {
+		o.incompatible = true
+	}
+	if len(r.Diff) > 0 {
+		o.diff = true
 	}
+}
 
-	return res, nil
+// err returns ErrBackwardIncompatible if any result cannot be migrated,
+// ErrBackwardCompatible if any result has a diff and nil otherwise.
+func (o validationOutcome) err() error {
+	if o.incompatible {
+		return ErrBackwardIncompatible
+	}
+	if o.diff {
+		return ErrBackwardCompatible
+	}
+	return nil
 }
 
 // Migrate attempts to update table schemas based on given validation result.
@@ -139,22 +441,105 @@ func (c *Controller) Validate() ([]*ValidationResult, error) {
 // Any Validation Result that contains schema mismatches which cannot be migrated
 // will be skipped.
 // Any errors occur during migration process are included in the Migration Result.
+// A Migration Result is returned for every Validation Result in the same order, results
+// without schema mismatches and results of unmanaged tables have StatusSkipped.
+// If the results exceed the change budget, nothing is migrated and every Migration Result
+// with changes contains the *ChangeBudgetError.
 func (c *Controller) Migrate(results []*ValidationResult) []*MigrationResult {
+	return c.MigrateWithContext(context.Background(), results)
+}
+
+// MigrateWithContext is the same as Migrate with an additional context.
+// When ctx is cancelled no new operations are issued, in-flight calls are waited for
+// and the changes which were not applied are listed as Skipped with StatusCancelled.
+func (c *Controller) MigrateWithContext(ctx context.Context, results []*ValidationResult) []*MigrationResult {
+	ctx, runID := c.startRun(ctx)
+	c.Log.Infof("Migrate run %s started", runID)
+	start := c.clock.Now()
 	ms := make([]*MigrationResult, len(results))
-	var wg sync.WaitGroup
+	approver := ""
+	defer func() {
+		for _, m := range ms {
+			m.RunID = runID
+			m.ApprovedBy = approver
+		}
+		c.notify(&RunReport{
+			RunID:       runID,
+			Env:         c.env,
+			Description: ChangeDescriptionFromContext(ctx),
+			Duration:    c.clock.Now().Sub(start),
+			Plan:        results,
+			Results:     ms,
+		})
+	}()
 	for i, res := range results {
-		if len(res.Diff) > 0 {
-			wg.Add(1)
-			go func(i int, res *ValidationResult) {
-				defer wg.Done()
+		if !res.pending() {
+			ms[i] = &MigrationResult{TableInput: res.TableInput, Status: StatusSkipped}
+		}
+	}
+
+	if err := c.CheckBudget(results); err != nil {
+		c.Log.Errorf("Migrate aborted: %v", err)
+		for i, res := range results {
+			if res.pending() {
 				ms[i] = &MigrationResult{
 					TableInput: res.TableInput,
+					Status:     StatusFailed,
+					Errors:     []error{err},
 				}
-				errs := c.migrate(res)
-				if len(errs) > 0 {
-					ms[i].Errors = errs
+			}
+		}
+		return ms
+	}
+	approver, err := c.approveRun(ctx, results)
+	if err != nil {
+		c.Log.Errorf("Migrate aborted: %v", err)
+		for i, res := range results {
+			if res.pending() {
+				ms[i] = &MigrationResult{
+					TableInput: res.TableInput,
+					Status:     StatusFailed,
+					Skipped:    tableChanges(res),
+					Errors:     []error{err},
 				}
-				c.Log.Infof("Migrate table [%s] with errors: %+v", res.TableInput.TableName, ms[i].Errors)
+			}
+		}
+		return ms
+	}
+	approved := c.approve(Changes(results))
+
+	if c.stopOnError {
+		// Tables are migrated one by one in order, the first failure aborts the remaining tables.
+		var failed string
+		for i, res := range results {
+			if !res.pending() {
+				continue
+			}
+			if failed != "" {
+				ms[i] = &MigrationResult{
+					TableInput: res.TableInput,
+					Status:     StatusAborted,
+					Skipped:    tableChanges(res),
+					Errors:     []error{ErrMigrationAborted},
+				}
+				continue
+			}
+			ms[i] = c.migrateTable(ctx, res, approved)
+			if ms[i].Status != StatusApplied {
+				failed = res.TableInput.TableName
+				c.Log.Errorf("Migrate aborted after table [%s] failed", failed)
+			}
+		}
+		return ms
+	}
+
+	var wg sync.WaitGroup
+	for i, res := range results {
+		if res.pending() {
+			wg.Add(1)
+			go func(i int, res *ValidationResult) {
+				defer wg.Done()
+				ms[i] = c.migrateTable(ctx, res, approved)
 			}(i, res)
 		}
 	}
@@ -163,15 +548,66 @@ func (c *Controller) Migrate(results []*ValidationResult) []*MigrationResult {
 	return ms
 }
 
+// migrateTable migrates a single table and returns its Migration Result.
+func (c *Controller) migrateTable(parent context.Context, res *ValidationResult, approved approvals) *MigrationResult {
+	m := &MigrationResult{
+		TableInput:  res.TableInput,
+		Status:      StatusApplied,
+		Description: changeDescription(parent, res.TableInput.Description),
+	}
+	ctx := parent
+	if c.tableTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, c.tableTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if m.Status != StatusApplied && parent.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+			m.Status = StatusTimedOut
+			if desc, err := c.describeTable(res.TableInput); err == nil {
+				m.TableStatus = aws.StringValue(desc.TableStatus)
+			}
+			c.Log.Errorf("Migrate table [%s] timed out with status: %s", res.TableInput.TableName, m.TableStatus)
+		}
+	}()
+
+	if err := c.waitUntilNotBusy(ctx, res); err != nil {
+		// A run cancelled while the table is busy is reported as cancelled, not busy.
+		if ctx.Err() != nil {
+			m.Status = StatusCancelled
+			m.Errors = []error{ctx.Err()}
+			m.Skipped = tableChanges(res)
+			c.Log.Infof("Migrate table [%s] cancelled with status: %s", res.TableInput.TableName, res.TableStatus)
+			return m
+		}
+		m.Status = StatusBusy
+		m.Errors = []error{err}
+		c.Log.Infof("Migrate table [%s] skipped with status: %s", res.TableInput.TableName, res.TableStatus)
+		return m
+	}
+	c.migrate(ctx, res, m, approved)
+	c.describeCache.invalidate(physicalName(c.env, res.TableInput))
+	if ctx.Err() != nil && len(m.Skipped) > 0 {
+		m.Status = StatusCancelled
+	} else if len(m.Errors) > 0 {
+		m.Status = StatusFailed
+	}
+	c.Log.Infof("Migrate table [%s] in run %s with errors: %+v", res.TableInput.TableName, RunIDFromContext(ctx), m.Errors)
+	return m
+}
+
+// Reset removes all configured tables from DynamoDB.
+// Unmanaged tables are never removed.
 func (c *Controller) Reset() []ResetResult {
-	rs := make([]ResetResult, len(c.Tables))
+	tables := managedTables(c.tenantTables())
+	rs := make([]ResetResult, len(tables))
 	var wg sync.WaitGroup
-	for i, tbl := range c.Tables {
+	for i, tbl := range tables {
 		c.Log.Infof("Processing table %s", tbl.TableName)
 		wg.Add(1)
 		go func(i int, tbl TableInfo) {
 			defer wg.Done()
-			err := c.deleteTable(withPrefix(c.env, tbl.Title, tbl.TableName))
+			err := c.deleteTable(context.Background(), tbl)
 			rs[i] = ResetResult{
 				TableName: tbl.TableName,
 				Error:     err,
@@ -186,37 +622,97 @@ func (c *Controller) Reset() []ResetResult {
 	return rs
 }
 
-func (c *Controller) migrate(r *ValidationResult) []error {
-	errs := []error{}
-
-	if r.Error != nil {
-		return []error{ErrInvalidMigrationInput}
+// migrate applies the changes of a single validation result in order and records
+// them in the given Migration Result. Once ctx is done no further changes are started,
+// the remaining changes are recorded as skipped.
+func (c *Controller) migrate(ctx context.Context, r *ValidationResult, m *MigrationResult, approved approvals) {
+	if r.Error != nil || !r.CanMigrate || r.TableInput.Unmanaged {
+		m.Errors = append(m.Errors, ErrInvalidMigrationInput)
+		return
 	}
-	if !r.CanMigrate {
-		return []error{ErrInvalidMigrationInput}
+
+	if err := c.capturePriorState(r, m); err != nil {
+		m.Errors = append(m.Errors, err)
+		m.Skipped = append(m.Skipped, tableChanges(r)...)
+		return
 	}
-	// migrate
-	if r.CreateTableInput != nil {
-		c.Log.Infof("Creating table %s", aws.StringValue(r.CreateTableInput.TableName))
-		if err := c.createTable(r.TableInput); err != nil {
-			errs = append(errs, err)
+
+	changes := tableChanges(r)
+	for i, ch := range changes {
+		if ctx.Err() != nil {
+			m.Skipped = append(m.Skipped, changes[i:]...)
+			m.Errors = append(m.Errors, ctx.Err())
+			return
 		}
+
+		var err error
+		switch input := ch.Input.(type) {
+		case *dynamodb.CreateTableInput:
+			c.Log.Infof("Creating table %s", aws.StringValue(input.TableName))
+			err = c.createTable(ctx, r.TableInput, input)
+		case *dynamodb.UpdateTimeToLiveInput:
+			if ch.TTLRetentionChange && !approved.ttlChange {
+				c.Log.Errorf("Skipping TTL change for table %s", aws.StringValue(input.TableName))
+				m.Skipped = append(m.Skipped, ch)
+				m.Errors = append(m.Errors, ErrTTLChangeNotApproved)
+				continue
+			}
+			c.Log.Infof("Updating TTL for table %s", aws.StringValue(input.TableName))
+			err = c.updateTTL(ctx, r.TableInput, input)
+			if err == nil && c.ttlWaitTimeout > 0 {
+				m.TTLStatus, err = c.waitForTTL(ctx, r.TableInput, c.ttlWaitTimeout)
+			}
+		case *dynamodb.UpdateContinuousBackupsInput:
+			c.Log.Infof("Enabling point in time recovery for table %s", aws.StringValue(input.TableName))
+			err = c.updateContinuousBackups(ctx, r.TableInput, input)
+		case *dynamodb.UpdateTableInput:
+			if ch.CapacityDecrease && !approved.capacityDecrease {
+				c.Log.Errorf("Skipping capacity decrease for table %s", aws.StringValue(input.TableName))
+				m.Skipped = append(m.Skipped, ch)
+				m.Errors = append(m.Errors, ErrCapacityDecreaseNotApproved)
+				continue
+			}
+			c.Log.Infof("Updating table %s", aws.StringValue(input.TableName))
+			err = c.updateTable(ctx, r.TableInput, input)
+		case *dynamodb.DeleteTableInput:
+			if !approved.deletion {
+				c.Log.Errorf("Skipping deletion of table %s", aws.StringValue(input.TableName))
+				m.Skipped = append(m.Skipped, ch)
+				m.Errors = append(m.Errors, ErrDeletionNotApproved)
+				continue
+			}
+			c.Log.Infof("Deleting deprecated table %s", aws.StringValue(input.TableName))
+			err = c.deleteTable(ctx, r.TableInput)
+		}
+		if err != nil {
+			m.Errors = append(m.Errors, err)
+			// All following changes depend on the table being created.
+			if ch.Type == ChangeCreateTable {
+				m.Skipped = append(m.Skipped, changes[i+1:]...)
+				return
+			}
+			continue
+		}
+		m.Applied = append(m.Applied, ch)
 	}
-	if r.UpdateTTLInput != nil {
-		c.Log.Infof("Updating TTL for table %s", aws.StringValue(r.UpdateTTLInput.TableName))
-		if err := c.updateTTL(r.UpdateTTLInput); err != nil {
-			errs = append(errs, err)
+
+	if c.indexWaitTimeout > 0 && len(m.Errors) == 0 {
+		c.Log.Infof("Waiting for indexes of table %s", r.TableInput.TableName)
+		if err := c.waitForIndexes(ctx, r.TableInput, createdIndexes(r), c.indexWaitTimeout); err != nil {
+			m.Errors = append(m.Errors, err)
 		}
 	}
-	if len(r.UpdateTableInput) > 0 {
-		for _, input := range r.UpdateTableInput {
-			c.Log.Infof("Updating table %s", aws.StringValue(input.TableName))
-			if err := c.updateTable(r.TableInput, input); err != nil {
-				errs = append(errs, err)
-			}
+
+	if len(r.ResourceDiffs) > 0 && len(m.Errors) == 0 && ctx.Err() == nil {
+		c.migrateResources(ctx, r, m)
+	}
+
+	// Tables in sync with their config are tagged with its fingerprint.
+	if c.fingerprints && len(m.Errors) == 0 && len(r.BlockedIndexes) == 0 && !r.FingerprintMatched && !r.TableInput.Deprecated {
+		if err := c.tagFingerprint(ctx, r.TableInput); err != nil {
+			c.Log.Errorf("Tag fingerprint of table [%s] with error: %v", r.TableInput.TableName, err)
 		}
 	}
-	return errs
 }
 
 // compare compares table schema
@@ -226,33 +722,84 @@ func (c *Controller) compare(tbl TableInfo) (*ValidationResult, error) {
 	diff := ""
 	canMigrate := true
 	result := &ValidationResult{
-		TableInput: tbl,
+		TableInput: tbl.clone(),
+	}
+
+	// Tables whose fingerprint tag matches their config are in sync, skip the full comparison.
+	if c.fastValidate {
+		if ok, err := c.matchesFingerprint(tbl); err == nil && ok {
+			result.FingerprintMatched = true
+			result.CanMigrate = true
+			return result, nil
+		}
 	}
 
 	// Check if table exists. If not, append input for table creation and return.
-	desc, err := c.describeTable(withPrefix(c.env, tbl.Title, tbl.TableName))
+	desc, err := c.describeTable(tbl)
 	if err != nil {
 		aerr, ok := err.(awserr.Error)
 		if ok {
 			// Table doesn't exist
 			if aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
-				result.CreateTableInput = CreateTableInput(tbl, c.env)
-				result.CanMigrate = true
-				result.Diff = fmt.Sprintf("missing table: %s", tbl.TableName)
-				return result, nil
+				return c.missingResult(tbl), nil
 			}
 		}
 		return nil, err
 	}
 
 	// Table exists, compare table description
+	result.TableDescription = desc
+	result.TableStatus = aws.StringValue(desc.TableStatus)
+	if result.TableStatus != dynamodb.TableStatusActive {
+		c.Log.Infof("Validate table [%s] with status: %s", tbl.TableName, result.TableStatus)
+	}
+
+	// Deprecated tables are only reported, or scheduled for deletion in prune mode.
+	if tbl.Deprecated {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("deprecated table exists: %s", tbl.TableName))
+		c.Log.Infof("Validate table [%s] with warning: table is deprecated", tbl.TableName)
+		result.CanMigrate = true
+		if c.prune {
+			result.DeleteTableInput = &dynamodb.DeleteTableInput{
+				TableName: aws.String(physicalName(c.env, tbl)),
+			}
+			result.Diff = fmt.Sprintf("deprecated table: %s", tbl.TableName)
+		}
+		return result, nil
+	}
+
+	// Compliance requirements apply regardless of the table's config, including its lifecycle.
+	complianceDiff, err := c.compareCompliance(tbl, desc, result)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create-only tables are never updated once they exist.
+	if tbl.Lifecycle == LifecycleCreateOnly {
+		result.Diff = complianceDiff
+		result.CanMigrate = true
+		detachInputs(result)
+		return result, nil
+	}
 	input := CreateTableInput(tbl, c.env)
+	df := c.differ()
+
+	ignore, unknown := newIgnoreSet(tbl.Ignore)
+	if len(unknown) > 0 {
+		result.Warnings = append(result.Warnings, unknownIgnoreWarning(unknown))
+	}
+	ignore.apply(desc, input)
 
-	if d := DiffAttributeDefinitions(desc.AttributeDefinitions, input.AttributeDefinitions); len(d) > 0 {
-		diff = fmt.Sprintf("Attribute Definition: %v", d)
+	if !ignore.has(IgnoreAttributeDefinitions) {
+		if d := df.attributeDefinitions(desc.AttributeDefinitions, input.AttributeDefinitions); len(d) > 0 {
+			diff = fmt.Sprintf("Attribute Definition: %v", d)
+		}
+		if extra := ExtraAttributeDefinitions(desc.AttributeDefinitions, input.AttributeDefinitions); len(extra) > 0 {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("unmanaged attribute definitions: %s", strings.Join(extra, ", ")))
+		}
 	}
 
-	d := DiffTableDesc(desc, input)
+	d := df.tableDesc(desc, input)
 	if len(d) > 0 {
 		// Table descriptions mismatch
 		// This is unlikely to happen
@@ -260,10 +807,26 @@ func (c *Controller) compare(tbl TableInfo) (*ValidationResult, error) {
 		diff = d
 	}
 
-	diffPt := DiffProvisionedThroughput(&dynamodb.ProvisionedThroughput{
-		ReadCapacityUnits:  desc.ProvisionedThroughput.ReadCapacityUnits,
-		WriteCapacityUnits: desc.ProvisionedThroughput.WriteCapacityUnits,
-	}, input.ProvisionedThroughput)
+	// Compare billing mode. A switch to PROVISIONED carries the configured throughput,
+	// so the throughput of the table and its GSIs is not compared again.
+	switchInput, err := c.billingModeSwitch(tbl, desc, input)
+	if err != nil {
+		diff = fmt.Sprintf("%v, Billing Mode: %s => %s", diff, currentBillingMode(desc), billingMode(tbl))
+		canMigrate = false
+		result.Error = err
+	} else if switchInput != nil {
+		diff = fmt.Sprintf("%v, Billing Mode: %s => %s", diff, currentBillingMode(desc), billingMode(tbl))
+		result.UpdateTableInput = append(result.UpdateTableInput, switchInput)
+		ignoreSet{IgnoreThroughput: true, IgnoreGSIThroughput: true}.apply(desc, input)
+	}
+
+	diffPt := ""
+	if input.ProvisionedThroughput != nil {
+		diffPt = df.throughput(&dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  desc.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: desc.ProvisionedThroughput.WriteCapacityUnits,
+		}, input.ProvisionedThroughput)
+	}
 	if len(diffPt) > 0 {
 		diff = fmt.Sprintf("%v, Throughput: %v", diff, diffPt)
 		updateTableInput := UpdateTableInputBase(tbl, c.env)
@@ -272,58 +835,146 @@ func (c *Controller) compare(tbl TableInfo) (*ValidationResult, error) {
 	}
 
 	// Compare GSI
-	diffGSI := DiffGSI(desc.GlobalSecondaryIndexes, input.GlobalSecondaryIndexes)
-	if diffGSI != nil {
-		if len(diffGSI.Diff) > 0 {
-			diff = fmt.Sprintf("%v, GSI: %v", diff, diffGSI.Diff)
-			canMigrate = diffGSI.CanMigrate
-
-			if canMigrate {
-				for _, input := range diffGSI.GSIInput {
-					updateTableInput := UpdateTableInputBase(tbl, c.env)
-					updateTableInput.GlobalSecondaryIndexUpdates = append(updateTableInput.GlobalSecondaryIndexUpdates, input)
-					result.UpdateTableInput = append(result.UpdateTableInput, updateTableInput)
-				}
+	// Indexes which cannot be migrated are reported as blocked, the changes of the
+	// other indexes are migrated regardless.
+	diffGSI := df.gsi(desc.GlobalSecondaryIndexes, input.GlobalSecondaryIndexes)
+	if len(diffGSI.Diff) > 0 {
+		diff = fmt.Sprintf("%v, GSI: %v", diff, diffGSI.Diff)
+		for _, index := range diffGSI.Indexes {
+			if !index.CanMigrate {
+				result.BlockedIndexes = append(result.BlockedIndexes, index)
 			}
 		}
+		for _, input := range diffGSI.GSIInput {
+			updateTableInput := UpdateTableInputBase(tbl, c.env)
+			updateTableInput.GlobalSecondaryIndexUpdates = append(updateTableInput.GlobalSecondaryIndexUpdates, input)
+			result.UpdateTableInput = append(result.UpdateTableInput, updateTableInput)
+		}
+	}
+
+	// Compare replicas of global tables.
+	// Replica changes cannot be migrated, missing or unhealthy replicas are reported only.
+	if len(tbl.Replicas) > 0 && !ignore.has(IgnoreReplicas) {
+		if d := DiffReplicas(desc.Replicas, tbl.Replicas); len(d) > 0 {
+			diff = fmt.Sprintf("%v, Replicas: %v", diff, d)
+			canMigrate = false
+		}
+	}
+
+	// Check sampled items for TTL values which would never expire anything
+	if tbl.TTL != nil && tbl.TTL.Enabled && c.ttlSampleSize > 0 {
+		warnings, err := c.sampleTTL(tbl)
+		if err != nil {
+			c.Log.Errorf("Sample TTL of table [%s] with error: %v", tbl.TableName, err)
+		}
+		result.Warnings = append(result.Warnings, warnings...)
 	}
 
 	// Compare TTL
-	if tbl.TTL != nil {
-		ttl, err := c.describeTTL(withPrefix(c.env, tbl.Title, tbl.TableName))
+	if tbl.TTL != nil && !ignore.has(IgnoreTTL) {
+		ttl, err := c.describeTTL(tbl)
 		if err != nil {
 			c.Log.Error(err.Error())
 			return result, err
 		}
+		result.TimeToLiveDescription = ttl
 		// Missing TTL
 		if ttl == nil {
 			result.UpdateTTLInput = NewUpdateTimeToLiveInput(tbl, c.env, tbl.TTL)
-			return result, nil
-		}
-		// TTL exists, compare TTLs
-		ttlStatus := "ENABLED"
-		if tbl.TTL.Enabled == false {
-			ttlStatus = "DISABLED"
-		}
-		expected := &dynamodb.TimeToLiveDescription{
-			AttributeName:    aws.String(tbl.TTL.AttributeName),
-			TimeToLiveStatus: aws.String(ttlStatus),
+		} else {
+			// TTL exists, compare TTLs
+			ttlStatus := "ENABLED"
+			if tbl.TTL.Enabled == false {
+				ttlStatus = "DISABLED"
+			}
+			expected := &dynamodb.TimeToLiveDescription{
+				AttributeName:    aws.String(tbl.TTL.AttributeName),
+				TimeToLiveStatus: aws.String(ttlStatus),
+			}
+			d := df.ttl(ttl, expected)
+			if len(d) > 0 {
+				// A frozen TTL is never changed once enabled, so its drift is no diff.
+				if tbl.TTL.Frozen && isTTLEnabled(ttl) {
+					result.Warnings = append(result.Warnings, fmt.Sprintf("TTL of table %s is frozen, ignoring TTL changes: %v", tbl.TableName, d))
+				} else {
+					diff = fmt.Sprintf("%v, TTL: %v", diff, d)
+					result.UpdateTTLInput = NewUpdateTimeToLiveInput(tbl, c.env, tbl.TTL)
+				}
+			}
 		}
-		d := DiffTTL(ttl, expected)
-		if len(d) > 0 {
-			diff = fmt.Sprintf("%v, TTL: %v", diff, d)
-			result.UpdateTTLInput = NewUpdateTimeToLiveInput(tbl, c.env, tbl.TTL)
+	}
+
+	if complianceDiff != "" {
+		diff = fmt.Sprintf("%v, %s", diff, complianceDiff)
+	}
+
+	// Advise on throughput based on consumed capacity, metrics which cannot be read are logged only.
+	if c.utilizationCloudWatch != nil && !ignore.has(IgnoreThroughput) {
+		advisories, err := c.utilizationAdvisories(context.Background(), tbl, desc)
+		if err != nil {
+			c.Log.Errorf("Utilization of table [%s] with error: %v", tbl.TableName, err)
 		}
+		result.Advisories = advisories
 	}
 
 	result.Diff = diff
 	result.CanMigrate = canMigrate
+	detachInputs(result)
 	return result, nil
 }
 
-func (c *Controller) describeTable(tblName string) (*dynamodb.TableDescription, error) {
-	output, err := c.DynamoDB.DescribeTable(&dynamodb.DescribeTableInput{
-		TableName: aws.String(tblName),
+// missingResult returns the Validation Result of a table which does not exist.
+func (c *Controller) missingResult(tbl TableInfo) *ValidationResult {
+	result := &ValidationResult{
+		TableInput: tbl.clone(),
+		CanMigrate: true,
+	}
+	// Deprecated tables are never recreated.
+	if tbl.Deprecated {
+		return result
+	}
+	result.CreateTableInput = CreateTableInput(tbl, c.env)
+	c.compliance.applyCreate(result.CreateTableInput)
+	result.UpdateTTLInput = NewUpdateTimeToLiveInput(tbl, c.env, tbl.TTL)
+	result.UpdateContinuousBackupsInput = c.compliance.continuousBackupsInput(tbl, c.env)
+	result.Diff = fmt.Sprintf("missing table: %s", tbl.TableName)
+	return result
+}
+
+// detachInputs replaces the inputs of the result with deep copies, so they share no pointers
+// with the table description, e.g. throughput taken over by ignore rules, or with each other.
+func detachInputs(result *ValidationResult) {
+	for i, input := range result.UpdateTableInput {
+		result.UpdateTableInput[i] = awsutil.CopyOf(input).(*dynamodb.UpdateTableInput)
+	}
+	if result.UpdateTTLInput != nil {
+		result.UpdateTTLInput = awsutil.CopyOf(result.UpdateTTLInput).(*dynamodb.UpdateTimeToLiveInput)
+	}
+	if result.UpdateContinuousBackupsInput != nil {
+		result.UpdateContinuousBackupsInput = awsutil.CopyOf(result.UpdateContinuousBackupsInput).(*dynamodb.UpdateContinuousBackupsInput)
+	}
+}
+
+// inSync returns true if a full comparison found an existing table matching its config.
+func (r *ValidationResult) inSync() bool {
+	return r.TableDescription != nil && !r.FingerprintMatched && !r.TableInput.Deprecated &&
+		r.Diff == "" && len(r.ResourceDiffs) == 0 && !r.incompatible()
+}
+
+// pending returns true if Migrate has changes to apply for the result.
+// Results of unmanaged tables are skipped even if they have a diff.
+func (r *ValidationResult) pending() bool {
+	return len(r.Diff) > 0 && !r.TableInput.Unmanaged
+}
+
+// incompatible returns true if the result contains changes which cannot be migrated.
+func (r *ValidationResult) incompatible() bool {
+	return !r.CanMigrate || len(r.BlockedIndexes) > 0
+}
+
+func (c *Controller) describeTable(ti TableInfo) (*dynamodb.TableDescription, error) {
+	output, err := c.client(ti).DescribeTable(&dynamodb.DescribeTableInput{
+		TableName: aws.String(physicalName(c.env, ti)),
 	})
 	if err != nil {
 		return nil, err
@@ -331,9 +982,9 @@ func (c *Controller) describeTable(tblName string) (*dynamodb.TableDescription,
 	return output.Table, nil
 }
 
-func (c *Controller) describeTTL(tblName string) (*dynamodb.TimeToLiveDescription, error) {
-	output, err := c.DynamoDB.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{
-		TableName: aws.String(tblName),
+func (c *Controller) describeTTL(ti TableInfo) (*dynamodb.TimeToLiveDescription, error) {
+	output, err := c.client(ti).DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{
+		TableName: aws.String(physicalName(c.env, ti)),
 	})
 	if err != nil {
 		return nil, err
@@ -341,73 +992,61 @@ func (c *Controller) describeTTL(tblName string) (*dynamodb.TimeToLiveDescriptio
 	return output.TimeToLiveDescription, nil
 }
 
-func (c *Controller) createTable(ti TableInfo) error {
-	input := CreateTableInput(ti, c.env)
-	if _, err := c.DynamoDB.CreateTable(input); err != nil {
-		return err
-	}
-
-	if ti.TTL != nil {
-		ttlInfo := NewUpdateTimeToLiveInput(ti, c.env, ti.TTL)
-		if err := c.updateTTL(ttlInfo); err != nil {
+func (c *Controller) createTable(ctx context.Context, ti TableInfo, input *dynamodb.CreateTableInput) error {
+	// Hooks adjust a copy, the inputs of the Validation Result are never modified.
+	input = awsutil.CopyOf(input).(*dynamodb.CreateTableInput)
+	c.addRunTags(ctx, input)
+	c.inputHooks.onCreateTable(input)
+	return c.hooks.run(ctx, ti, ChangeCreateTable, input, func() error {
+		if err := c.audit(ctx, ti, "CreateTable", input); err != nil {
 			return err
 		}
-	}
-	return nil
+		if _, err := c.client(ti).CreateTable(input); err != nil {
+			return err
+		}
+
+		// A just created table may not be visible yet, wait until it can be described
+		// so the following TTL update does not fail with ResourceNotFound.
+		return c.waitUntilTableExists(ctx, ti)
+	})
 }
 
-func (c *Controller) updateTTL(input *dynamodb.UpdateTimeToLiveInput) error {
-	for i := 0; i < MultiIndexUpdateRetryAttempts; i++ {
-		_, err := c.DynamoDB.UpdateTimeToLive(input)
-		if err == nil {
-			return nil
-		}
-		// error not nil
-		aerr, ok := err.(awserr.Error)
-		if ok {
-			if aerr.Code() == dynamodb.ErrCodeResourceInUseException {
-				time.Sleep(MultiIndexUpdateRetryInterval * time.Second)
-				continue
-			}
-			if aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
-				time.Sleep(MultiIndexUpdateRetryInterval * time.Second)
-				continue
+func (c *Controller) updateTTL(ctx context.Context, ti TableInfo, input *dynamodb.UpdateTimeToLiveInput) error {
+	input = awsutil.CopyOf(input).(*dynamodb.UpdateTimeToLiveInput)
+	c.inputHooks.onUpdateTTL(input)
+	return c.hooks.run(ctx, ti, ChangeUpdateTTL, input, func() error {
+		return c.withRetry(ctx, func() error {
+			if err := c.audit(ctx, ti, "UpdateTimeToLive", input); err != nil {
+				return err
 			}
+			_, err := c.client(ti).UpdateTimeToLive(input)
 			return err
-		}
-		return err
-	}
-	return ErrRequestWithMaxRetry
+		})
+	})
 }
 
-func (c *Controller) updateTable(ti TableInfo, input *dynamodb.UpdateTableInput) error {
-	for i := 0; i < MultiIndexUpdateRetryAttempts; i++ {
-		_, err := c.DynamoDB.UpdateTable(input)
-		if err == nil {
-			return nil
-		}
-		// error not nil
-		aerr, ok := err.(awserr.Error)
-		if ok {
-			if aerr.Code() == dynamodb.ErrCodeLimitExceededException {
-				time.Sleep(MultiIndexUpdateRetryInterval * time.Second)
-				continue
-			}
-			if aerr.Code() == dynamodb.ErrCodeResourceInUseException {
-				time.Sleep(MultiIndexUpdateRetryInterval * time.Second)
-				continue
+func (c *Controller) updateTable(ctx context.Context, ti TableInfo, input *dynamodb.UpdateTableInput) error {
+	input = awsutil.CopyOf(input).(*dynamodb.UpdateTableInput)
+	c.inputHooks.onUpdateTable(input)
+	return c.hooks.run(ctx, ti, ChangeUpdateTable, input, func() error {
+		return c.withRetry(ctx, func() error {
+			if err := c.audit(ctx, ti, "UpdateTable", input); err != nil {
+				return err
 			}
+			_, err := c.client(ti).UpdateTable(input)
 			return err
-		}
-		return err
-	}
-	return ErrRequestWithMaxRetry
+		}, dynamodb.ErrCodeResourceNotFoundException)
+	})
 }
 
-func (c *Controller) deleteTable(tableName string) error {
-	if _, err := c.DynamoDB.DeleteTable(&dynamodb.DeleteTableInput{
-		TableName: aws.String(tableName),
-	}); err != nil {
+func (c *Controller) deleteTable(ctx context.Context, ti TableInfo) error {
+	input := &dynamodb.DeleteTableInput{
+		TableName: aws.String(physicalName(c.env, ti)),
+	}
+	if err := c.audit(ctx, ti, "DeleteTable", input); err != nil {
+		return err
+	}
+	if _, err := c.client(ti).DeleteTable(input); err != nil {
 		return err
 	}
 	return nil
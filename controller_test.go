@@ -0,0 +1,1021 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// fakeClock advances time instantly on Sleep.
+type fakeClock struct {
+	now    time.Time
+	sleeps int
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeClock) Sleep(d time.Duration) {
+	f.sleeps++
+	f.now = f.now.Add(d)
+}
+
+// slowClock is a fakeClock which also passes a millisecond of real time per Sleep,
+// so context deadlines such as WithTableTimeout expire during long waits.
+type slowClock struct {
+	fakeClock
+}
+
+func (s *slowClock) Sleep(d time.Duration) {
+	s.fakeClock.Sleep(d)
+	time.Sleep(time.Millisecond)
+}
+
+// fakeDynamoDB stubs the DynamoDB calls used by the controller.
+type fakeDynamoDB struct {
+	DynamoDBAPI
+	describeTable   func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error)
+	updateTable     func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error)
+	updateTableCall int
+	describeTTL     func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error)
+	listTags        func(*dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error)
+	describeBackups func(*dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error)
+	createTable     func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error)
+	updateTTL       func(*dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error)
+	listTables      func(*dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error)
+	tagResource     func(*dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error)
+	deleteTable     func(*dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error)
+
+	mu      sync.Mutex
+	created map[string]bool
+}
+
+func (f *fakeDynamoDB) ListTablesPages(input *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool) error {
+	for {
+		output, err := f.listTables(input)
+		if err != nil {
+			return err
+		}
+		last := output.LastEvaluatedTableName == nil
+		if !fn(output, last) || last {
+			return nil
+		}
+		input = &dynamodb.ListTablesInput{ExclusiveStartTableName: output.LastEvaluatedTableName}
+	}
+}
+
+func (f *fakeDynamoDB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	output, err := f.createTable(input)
+	if err == nil {
+		f.mu.Lock()
+		if f.created == nil {
+			f.created = map[string]bool{}
+		}
+		f.created[aws.StringValue(input.TableName)] = true
+		f.mu.Unlock()
+	}
+	return output, err
+}
+
+func (f *fakeDynamoDB) UpdateTimeToLive(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	return f.updateTTL(input)
+}
+
+// DescribeTable reports a table created by the fake as CREATING once, so waiting for it to
+// become visible succeeds. Other descriptions are left to describeTable.
+func (f *fakeDynamoDB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	f.mu.Lock()
+	created := f.created[aws.StringValue(input.TableName)]
+	delete(f.created, aws.StringValue(input.TableName))
+	f.mu.Unlock()
+	if !created {
+		return f.describeTable(input)
+	}
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+		TableName:   input.TableName,
+		TableStatus: aws.String(dynamodb.TableStatusCreating),
+	}}, nil
+}
+
+func (f *fakeDynamoDB) UpdateTable(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	f.updateTableCall++
+	return f.updateTable(input)
+}
+
+func (f *fakeDynamoDB) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	return f.describeTTL(input)
+}
+
+func (f *fakeDynamoDB) ListTagsOfResource(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+	return f.listTags(input)
+}
+
+func (f *fakeDynamoDB) TagResource(input *dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error) {
+	return f.tagResource(input)
+}
+
+func (f *fakeDynamoDB) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	return f.deleteTable(input)
+}
+
+func (f *fakeDynamoDB) DescribeContinuousBackups(input *dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	return f.describeBackups(input)
+}
+
+func newTestController(t *testing.T, db DynamoDBAPI, clock Clock) *Controller {
+	c, err := NewController(db, "test", nil, nil, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestUpdateTableRetry(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		if db.updateTableCall < 3 {
+			return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "in use", nil)
+		}
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+
+	if err := c.updateTable(context.Background(), TableInfo{TableName: "test"}, &dynamodb.UpdateTableInput{}); err != nil {
+		t.Fatal(err)
+	}
+	if db.updateTableCall != 3 {
+		t.Fatalf("expected 3 calls but got %d", db.updateTableCall)
+	}
+	if clock.sleeps != 2 {
+		t.Fatalf("expected 2 sleeps but got %d", clock.sleeps)
+	}
+
+	db.updateTableCall = 0
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "in use", nil)
+	}
+	if err := c.updateTable(context.Background(), TableInfo{TableName: "test"}, &dynamodb.UpdateTableInput{}); err != ErrRequestWithMaxRetry {
+		t.Fatalf("expected ErrRequestWithMaxRetry but got %v", err)
+	}
+}
+
+func TestUpdateTableRetryPerCall(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+
+	// A missing table is reported right away.
+	err := c.updateTable(context.Background(), TableInfo{TableName: "test"}, &dynamodb.UpdateTableInput{})
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+		t.Fatalf("expected ResourceNotFound but got %v", err)
+	}
+	if db.updateTableCall != 1 || clock.sleeps != 0 {
+		t.Fatalf("expected 1 call without retry but got %d calls and %d sleeps", db.updateTableCall, clock.sleeps)
+	}
+
+	// Attempts of a call are limited across alternating error codes.
+	c, err = NewController(db, "test", nil, nil, WithClock(clock),
+		WithRetryPolicy(dynamodb.ErrCodeResourceInUseException, RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Second)}),
+		WithRetryPolicy(dynamodb.ErrCodeLimitExceededException, RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Second)}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.updateTableCall = 0
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		if db.updateTableCall%2 == 0 {
+			return nil, awserr.New(dynamodb.ErrCodeLimitExceededException, "limit", nil)
+		}
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "in use", nil)
+	}
+	if err := c.updateTable(context.Background(), TableInfo{TableName: "test"}, &dynamodb.UpdateTableInput{}); err != ErrRequestWithMaxRetry {
+		t.Fatalf("expected ErrRequestWithMaxRetry but got %v", err)
+	}
+	if db.updateTableCall != 3 {
+		t.Fatalf("expected 3 calls but got %d", db.updateTableCall)
+	}
+}
+
+func TestWaitForIndexesTimeout(t *testing.T) {
+	db := &fakeDynamoDB{
+		describeTable: func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{
+				Table: &dynamodb.TableDescription{
+					GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+						{
+							IndexName:   aws.String("test"),
+							IndexStatus: aws.String(dynamodb.IndexStatusCreating),
+						},
+					},
+				},
+			}, nil
+		},
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+
+	if err := c.waitForIndexes(context.Background(), TableInfo{TableName: "test"}, []string{"test"}, time.Minute); err != ErrWaitTimeout {
+		t.Fatalf("expected ErrWaitTimeout but got %v", err)
+	}
+	if clock.sleeps == 0 {
+		t.Fatal("expected fake clock to be used")
+	}
+}
+
+func TestWaitUntilTableExists(t *testing.T) {
+	describes := 0
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		describes++
+		if describes < 3 {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableStatus: aws.String(dynamodb.TableStatusCreating)}}, nil
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+
+	// Visible tables are not waited for until they are ACTIVE.
+	if err := c.waitUntilTableExists(context.Background(), TableInfo{TableName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if describes != 3 || clock.sleeps != 2 {
+		t.Fatalf("expected 3 describes and 2 sleeps but got %d and %d", describes, clock.sleeps)
+	}
+
+	describes, clock.sleeps = -100, 0
+	if err := c.waitUntilTableExists(context.Background(), TableInfo{TableName: "test"}); err != ErrWaitTimeout {
+		t.Fatalf("expected ErrWaitTimeout but got %v", err)
+	}
+	if clock.sleeps != tableVisibleAttempts-1 {
+		t.Fatalf("expected %d sleeps but got %d", tableVisibleAttempts-1, clock.sleeps)
+	}
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return nil, awserr.New(ErrCodeThrottlingException, "throttled", nil)
+	}
+	clock := &fakeClock{}
+	c, err := NewController(db, "test", nil, nil,
+		WithClock(clock),
+		WithRetryPolicy(ErrCodeThrottlingException, RetryPolicy{
+			MaxAttempts: 4,
+			Backoff:     ExponentialBackoff(time.Second, 3*time.Second),
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.updateTable(context.Background(), TableInfo{TableName: "test"}, &dynamodb.UpdateTableInput{}); err != ErrRequestWithMaxRetry {
+		t.Fatalf("expected ErrRequestWithMaxRetry but got %v", err)
+	}
+	if db.updateTableCall != 4 {
+		t.Fatalf("expected 4 calls but got %d", db.updateTableCall)
+	}
+	// 1s + 2s + 3s (capped)
+	if waited := clock.now.Sub(time.Time{}); waited != 6*time.Second {
+		t.Fatalf("expected 6s backoff but got %v", waited)
+	}
+}
+
+func TestMigrateWithContextCancelled(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	c := newTestController(t, db, &fakeClock{})
+
+	results := []*ValidationResult{
+		{
+			TableInput:       TableInfo{TableName: "test"},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String("test")}},
+			Diff:             "Throughput",
+			CanMigrate:       true,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ms := c.MigrateWithContext(ctx, results)
+	if ms[0].Status != StatusCancelled {
+		t.Fatalf("expected status %s but got %s", StatusCancelled, ms[0].Status)
+	}
+	if len(ms[0].Skipped) != 1 || len(ms[0].Applied) != 0 {
+		t.Fatalf("expected 1 skipped and 0 applied changes but got %v and %v", ms[0].Skipped, ms[0].Applied)
+	}
+	if db.updateTableCall != 0 {
+		t.Fatalf("expected no UpdateTable call but got %d", db.updateTableCall)
+	}
+
+	// Busy tables of a cancelled run are reported as cancelled, not busy.
+	busy := *results[0]
+	busy.TableStatus = dynamodb.TableStatusUpdating
+	ms = c.MigrateWithContext(ctx, []*ValidationResult{&busy})
+	if ms[0].Status != StatusCancelled || len(ms[0].Skipped) != 1 || ms[0].Errors[0] != context.Canceled {
+		t.Fatalf("expected busy table of a cancelled run to be cancelled but got %+v", ms[0])
+	}
+
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusApplied {
+		t.Fatalf("expected status %s but got %s", StatusApplied, ms[0].Status)
+	}
+}
+
+func TestMigrateStopOnError(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return nil, awserr.New("ValidationException", "invalid", nil)
+	}
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}), WithStopOnError())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := []*ValidationResult{}
+	for _, name := range []string{"first", "second"} {
+		results = append(results, &ValidationResult{
+			TableInput:       TableInfo{TableName: name},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String(name)}},
+			Diff:             "Throughput",
+			CanMigrate:       true,
+		})
+	}
+
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusFailed {
+		t.Fatalf("expected status %s but got %s", StatusFailed, ms[0].Status)
+	}
+	if ms[1].Status != StatusAborted {
+		t.Fatalf("expected status %s but got %s", StatusAborted, ms[1].Status)
+	}
+	if db.updateTableCall != 1 {
+		t.Fatalf("expected 1 UpdateTable call but got %d", db.updateTableCall)
+	}
+}
+
+func TestValidateTable(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	c, err := NewController(db, "test", nil, []TableInfo{
+		{Title: "shop", TableName: "orders", PrimaryKey: "id"},
+		{Title: "shop", TableName: "users", PrimaryKey: "id"},
+	}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"orders", "shop-test-orders"} {
+		r, err := c.ValidateTable(name)
+		if err != ErrBackwardCompatible {
+			t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+		}
+		if r.TableInput.TableName != "orders" || r.CreateTableInput == nil {
+			t.Fatalf("expected missing table orders but got %+v", r)
+		}
+	}
+
+	if _, err := c.ValidateTable("missing"); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("expected ErrTableNotFound but got %v", err)
+	}
+
+	c.tenants = []string{"acme", "globex"}
+	if _, err := c.ValidateTable("orders"); !errors.Is(err, ErrAmbiguousTableName) {
+		t.Fatalf("expected ErrAmbiguousTableName but got %v", err)
+	}
+	if r, err := c.ValidateTable("shop-test-acme-orders"); err != ErrBackwardCompatible || r.TableInput.Tenant != "acme" {
+		t.Fatalf("expected table of tenant acme but got %+v, %v", r, err)
+	}
+}
+
+func TestWaitForActive(t *testing.T) {
+	calls := 0
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		calls++
+		indexStatus := dynamodb.IndexStatusCreating
+		if calls > 2 {
+			indexStatus = dynamodb.IndexStatusActive
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+				{IndexName: aws.String("gsi"), IndexStatus: aws.String(indexStatus)},
+			},
+		}}, nil
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+	c.Tables = []TableInfo{{TableName: "orders"}}
+
+	if err := c.WaitForActive(context.Background(), "orders", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 DescribeTable calls but got %d", calls)
+	}
+
+	calls = -100
+	if err := c.WaitForActive(context.Background(), "orders", time.Second); err != ErrWaitTimeout {
+		t.Fatalf("expected ErrWaitTimeout but got %v", err)
+	}
+}
+
+func TestMigrateBlockedIndex(t *testing.T) {
+	current := CreateTableInput(TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1, Indexes: []IndexInfo{
+		{IndexName: "byUser", PrimaryKey: "user", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1},
+	}}, "")
+	desc := &dynamodb.TableDescription{
+		TableName:            current.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		AttributeDefinitions: current.AttributeDefinitions,
+		KeySchema:            current.KeySchema,
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(1),
+			WriteCapacityUnits: aws.Int64(1),
+		},
+	}
+	for _, gsi := range current.GlobalSecondaryIndexes {
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  gsi.IndexName,
+			KeySchema:  gsi.KeySchema,
+			Projection: gsi.Projection,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(1),
+				WriteCapacityUnits: aws.Int64(1),
+			},
+		})
+	}
+
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: desc}, nil
+	}
+	updates := []*dynamodb.UpdateTableInput{}
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		updates = append(updates, input)
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	c, err := NewController(db, "", nil, []TableInfo{{TableName: "orders", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1, Indexes: []IndexInfo{
+		{IndexName: "byUser", PrimaryKey: "user_id", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1},
+		{IndexName: "byEmail", PrimaryKey: "email", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1},
+	}}}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Validate()
+	if err != ErrBackwardIncompatible {
+		t.Fatalf("expected ErrBackwardIncompatible but got %v", err)
+	}
+	if r := results[0]; !r.CanMigrate || len(r.BlockedIndexes) != 1 || r.BlockedIndexes[0].IndexName != "byUser" {
+		t.Fatalf("expected byUser to be blocked only but got %+v", r)
+	}
+
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusApplied || len(updates) != 1 {
+		t.Fatalf("expected a single index creation but got %+v, %v", ms[0], updates)
+	}
+	if create := updates[0].GlobalSecondaryIndexUpdates[0].Create; create == nil || aws.StringValue(create.IndexName) != "byEmail" {
+		t.Fatalf("expected creation of byEmail but got %v", updates[0])
+	}
+}
+
+func TestMigrateSkipped(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := TableInfo{TableName: "orders", PrimaryKey: "id"}
+	results := []*ValidationResult{
+		{TableInput: TableInfo{TableName: "users", PrimaryKey: "id"}, CanMigrate: true},
+		{TableInput: orders, CreateTableInput: CreateTableInput(orders, "test"), Diff: "missing table: orders", CanMigrate: true},
+	}
+
+	ms := c.Migrate(results)
+	if len(ms) != 2 || ms[0] == nil || ms[0].Status != StatusSkipped || ms[0].TableInput.TableName != "users" {
+		t.Fatalf("expected users to be skipped but got %+v", ms)
+	}
+	if ms[1].Status != StatusApplied {
+		t.Fatalf("expected orders to be applied but got %+v", ms[1])
+	}
+
+	ms = c.MigrateFiltered(results, NameFilter("users"))
+	if ms[1] == nil || ms[1].Status != StatusSkipped || len(ms[1].Applied) > 0 {
+		t.Fatalf("expected unselected orders to be skipped but got %+v", ms[1])
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	tables := []TableInfo{}
+	for i := 0; i < 50; i++ {
+		tables = append(tables, TableInfo{TableName: fmt.Sprintf("table-%02d", 50-i), PrimaryKey: "id"})
+	}
+	c, err := NewController(db, "test", nil, tables, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := c.Validate()
+	for i, r := range results {
+		if r.TableInput.TableName != tables[i].TableName {
+			t.Fatalf("expected result %d to be %s but got %s", i, tables[i].TableName, r.TableInput.TableName)
+		}
+	}
+}
+
+func TestConcurrentValidateMigrate(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	db.updateTTL = func(*dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+	tables := []TableInfo{{
+		TableName:  "orders",
+		PrimaryKey: "id",
+		Indexes:    []IndexInfo{{IndexName: "byUser", PrimaryKey: "user_id", PrimaryKeyType: "S", ProjectedFields: []string{}}},
+		TTL:        &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true},
+		Ignore:     []string{},
+	}}
+	c, err := NewController(db, "test", nil, tables, WithClock(&fakeClock{}), WithCreateTableInputHook(func(input *dynamodb.CreateTableInput) {
+		input.SSESpecification = &dynamodb.SSESpecification{Enabled: aws.Bool(true)}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, _ := c.Validate()
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			c.Validate()
+			c.Migrate(results)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	r := results[0]
+	if r.CreateTableInput.SSESpecification != nil {
+		t.Fatal("expected input hooks not to modify the validation result")
+	}
+	r.TableInput.Indexes[0].IndexName = "changed"
+	r.TableInput.TTL.Enabled = false
+	if tables[0].Indexes[0].IndexName != "byUser" || !tables[0].TTL.Enabled {
+		t.Fatalf("expected config to be unaffected by changes to results but got %+v", tables[0])
+	}
+	if Fingerprint(r.TableInput, "test") == Fingerprint(tables[0], "test") {
+		t.Fatal("expected fingerprint of changed copy to differ")
+	}
+	if clone := tables[0].clone(); Fingerprint(clone, "test") != Fingerprint(tables[0], "test") {
+		t.Fatal("expected fingerprint of clone to match its table")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	c, err := NewController(db, "", nil, []TableInfo{{TableName: "orders"}}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, _ := c.Validate()
+			if len(results) != 1 && len(results) != 2 {
+				t.Errorf("expected results of either config but got %d", len(results))
+			}
+		}()
+	}
+	if err := c.Refresh([]TableInfo{{TableName: "orders"}, {TableName: "users", PrimaryKey: "name"}}); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	r, err := c.ValidateTable("users")
+	if r == nil {
+		t.Fatalf("expected refreshed table but got %v", err)
+	}
+	if len(r.Warnings) != 1 {
+		t.Fatalf("expected naming warning of the refreshed config but got %v", r.Warnings)
+	}
+	if err := c.Refresh([]TableInfo{{TableName: "carts", Region: "eu-west-1"}}); !errors.Is(err, ErrRegionWithoutClientFactory) {
+		t.Fatalf("expected ErrRegionWithoutClientFactory but got %v", err)
+	}
+	if _, err := c.ValidateTable("users"); errors.Is(err, ErrTableNotFound) {
+		t.Fatal("expected invalid config not to replace the current config")
+	}
+}
+
+func TestMigrateBusyTable(t *testing.T) {
+	describes := 0
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		describes++
+		status := dynamodb.TableStatusUpdating
+		if describes > 2 {
+			status = dynamodb.TableStatusActive
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableStatus: aws.String(status)}}, nil
+	}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	results := []*ValidationResult{{
+		TableInput:       TableInfo{TableName: "orders"},
+		TableStatus:      dynamodb.TableStatusUpdating,
+		UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String("orders")}},
+		Diff:             "Throughput",
+		CanMigrate:       true,
+	}}
+
+	// Busy tables are skipped by default.
+	c := newTestController(t, db, &fakeClock{})
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusBusy || len(ms[0].Errors) != 1 || ms[0].Errors[0] != ErrTableBusy {
+		t.Fatalf("expected busy table to be skipped but got %+v", ms[0])
+	}
+	if describes != 0 || db.updateTableCall != 0 {
+		t.Fatalf("expected no calls but got %d DescribeTable and %d UpdateTable", describes, db.updateTableCall)
+	}
+
+	// With WithWaitForBusyTables the table is migrated once it is ACTIVE.
+	clock := &fakeClock{}
+	c, err := NewController(db, "test", nil, nil, WithClock(clock), WithWaitForBusyTables(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusApplied || db.updateTableCall != 1 {
+		t.Fatalf("expected table to be migrated once ACTIVE but got %+v", ms[0])
+	}
+	if describes != 3 || clock.sleeps != 2 {
+		t.Fatalf("expected 3 describes and 2 sleeps but got %d and %d", describes, clock.sleeps)
+	}
+
+	// Tables still busy after the timeout are skipped.
+	describes, db.updateTableCall = -100, 0
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusBusy || len(ms[0].Errors) != 1 || ms[0].Errors[0] != ErrTableBusy {
+		t.Fatalf("expected busy table to be skipped after the timeout but got %+v", ms[0])
+	}
+	if db.updateTableCall != 0 {
+		t.Fatalf("expected no UpdateTable call but got %d", db.updateTableCall)
+	}
+}
+
+func TestMigrateTableTimeout(t *testing.T) {
+	describes := 0
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		describes++
+		status := dynamodb.TableStatusUpdating
+		if describes > 2 {
+			status = dynamodb.TableStatusActive
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableStatus: aws.String(status)}}, nil
+	}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	results := []*ValidationResult{{
+		TableInput:       TableInfo{TableName: "orders"},
+		TableStatus:      dynamodb.TableStatusUpdating,
+		UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String("orders")}},
+		Diff:             "Throughput",
+		CanMigrate:       true,
+	}}
+	clock := &slowClock{}
+	c, err := NewController(db, "test", nil, nil, WithClock(clock), WithWaitForBusyTables(time.Hour), WithTableTimeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tables migrated within the timeout are applied.
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusApplied || db.updateTableCall != 1 || clock.sleeps != 2 {
+		t.Fatalf("expected table to be migrated within the timeout but got %+v", ms[0])
+	}
+
+	// Tables exceeding the timeout are reported with their last status and not migrated.
+	c.tableTimeout = 20 * time.Millisecond
+	describes, db.updateTableCall = -1e6, 0
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusTimedOut || ms[0].TableStatus != dynamodb.TableStatusUpdating {
+		t.Fatalf("expected table to time out while UPDATING but got %+v", ms[0])
+	}
+	if db.updateTableCall != 0 {
+		t.Fatalf("expected no UpdateTable call but got %d", db.updateTableCall)
+	}
+
+	// Without the timeout the busy table is only skipped once the wait for it ends.
+	c.tableTimeout, c.busyWaitTimeout = 0, 10*time.Second
+	describes = -1e6
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusBusy {
+		t.Fatalf("expected busy table to be skipped but got %+v", ms[0])
+	}
+}
+
+func TestMigrateWaitForTTL(t *testing.T) {
+	statuses := []string{}
+	db := &fakeDynamoDB{}
+	db.updateTTL = func(*dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		status := dynamodb.TimeToLiveStatusEnabling
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(status)}}, nil
+	}
+	results := []*ValidationResult{{
+		TableInput: TableInfo{TableName: "orders"},
+		UpdateTTLInput: &dynamodb.UpdateTimeToLiveInput{
+			TableName:               aws.String("orders"),
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{AttributeName: aws.String("expires_at"), Enabled: aws.Bool(true)},
+		},
+		Diff:       "TTL",
+		CanMigrate: true,
+	}}
+	clock := &fakeClock{}
+	c, err := NewController(db, "test", nil, nil, WithClock(clock), WithWaitForTTL(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The TTL is polled until it is ENABLED.
+	statuses = []string{dynamodb.TimeToLiveStatusEnabling, dynamodb.TimeToLiveStatusEnabling, dynamodb.TimeToLiveStatusEnabled}
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusApplied || ms[0].TTLStatus != dynamodb.TimeToLiveStatusEnabled || clock.sleeps != 2 {
+		t.Fatalf("expected TTL to be ENABLED after 2 sleeps but got %+v after %d sleeps", ms[0], clock.sleeps)
+	}
+
+	// Transitions which do not complete within the timeout are reported with their last state.
+	clock.sleeps = 0
+	c.ttlWaitTimeout = 10 * time.Second
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusFailed || ms[0].TTLStatus != dynamodb.TimeToLiveStatusEnabling || !errors.Is(ms[0].Errors[0], ErrWaitTimeout) {
+		t.Fatalf("expected wait for TTL to time out while ENABLING but got %+v", ms[0])
+	}
+
+	// Without WithWaitForTTL the TTL is not polled.
+	clock.sleeps = 0
+	c.ttlWaitTimeout = 0
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		t.Error("expected no DescribeTimeToLive call")
+		return &dynamodb.DescribeTimeToLiveOutput{}, nil
+	}
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusApplied || ms[0].TTLStatus != "" || clock.sleeps != 0 {
+		t.Fatalf("expected TTL change to be applied without waiting but got %+v", ms[0])
+	}
+}
+
+func TestMigrateWaitForIndexes(t *testing.T) {
+	statuses := []string{}
+	describes := 0
+	db := &fakeDynamoDB{}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		describes++
+		status := dynamodb.IndexStatusCreating
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName:   input.TableName,
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+				{IndexName: aws.String("by_user"), IndexStatus: aws.String(status)},
+			},
+		}}, nil
+	}
+	results := []*ValidationResult{{
+		TableInput: TableInfo{TableName: "orders"},
+		UpdateTableInput: []*dynamodb.UpdateTableInput{{
+			TableName: aws.String("test-orders"),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+				Create: &dynamodb.CreateGlobalSecondaryIndexAction{IndexName: aws.String("by_user")},
+			}},
+		}},
+		Diff:       "GSI by_user missing",
+		CanMigrate: true,
+	}}
+	clock := &fakeClock{}
+	c, err := NewController(db, "test", nil, nil, WithClock(clock), WithWaitForIndexes(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrate returns once the created GSI is ACTIVE.
+	statuses = []string{dynamodb.IndexStatusCreating, dynamodb.IndexStatusCreating, dynamodb.IndexStatusActive}
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusApplied || len(statuses) != 0 || clock.sleeps != 2 {
+		t.Fatalf("expected GSI to be ACTIVE after 2 sleeps but got %+v after %d sleeps", ms[0], clock.sleeps)
+	}
+
+	// GSIs which do not become ACTIVE within the timeout fail the migration.
+	clock.sleeps = 0
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusFailed || len(ms[0].Errors) != 1 || !errors.Is(ms[0].Errors[0], ErrWaitTimeout) || clock.sleeps == 0 {
+		t.Fatalf("expected wait for GSI to time out while CREATING but got %+v", ms[0])
+	}
+
+	// Failed updates are not waited for.
+	clock.sleeps, describes = 0, 0
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return nil, errors.New("update failed")
+	}
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusFailed || describes != 0 || clock.sleeps != 0 {
+		t.Fatalf("expected failed update not to wait for GSIs but got %+v after %d describes", ms[0], describes)
+	}
+}
+
+func TestUnmanagedTables(t *testing.T) {
+	described := []string{}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		described = append(described, aws.StringValue(input.TableName))
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		t.Error("expected unmanaged table not to be created")
+		return nil, nil
+	}
+	legacy := TableInfo{TableName: "legacy", PrimaryKey: "id", Unmanaged: true}
+	c, err := NewController(db, "", nil, []TableInfo{legacy}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Validate()
+	if err != nil || len(results) != 0 || len(described) != 0 {
+		t.Fatalf("expected unmanaged table not to be validated but got %v, %v and described %v", results, err, described)
+	}
+
+	// Results of unmanaged tables, e.g. from an older config, are skipped.
+	ms := c.Migrate([]*ValidationResult{{
+		TableInput:       legacy,
+		CreateTableInput: CreateTableInput(legacy, ""),
+		Diff:             "missing table: legacy",
+		CanMigrate:       true,
+	}})
+	if ms[0].Status != StatusSkipped || len(ms[0].Applied) != 0 || len(ms[0].Errors) != 0 {
+		t.Fatalf("expected unmanaged table to be skipped but got %+v", ms[0])
+	}
+}
+
+func TestCreateOnlyTable(t *testing.T) {
+	var existing *dynamodb.TableDescription
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		if existing == nil {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: existing}, nil
+	}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	db.updateTable = func(*dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5, Lifecycle: LifecycleCreateOnly}
+	c, err := NewController(db, "", nil, []TableInfo{tbl}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing create-only tables are created.
+	results, err := c.Validate()
+	if err != ErrBackwardCompatible || results[0].CreateTableInput == nil {
+		t.Fatalf("expected missing table to be created but got %+v, %v", results[0], err)
+	}
+	if ms := c.Migrate(results); ms[0].Status != StatusApplied {
+		t.Fatalf("expected table to be created but got %+v", ms[0])
+	}
+
+	// Existing create-only tables are never updated, e.g. throughput managed by autoscaling.
+	existing = provisionedDescription(50, 20, "by_email")
+	results, err = c.Validate()
+	if err != nil || results[0].Diff != "" || len(results[0].UpdateTableInput) != 0 {
+		t.Fatalf("expected no changes to existing table but got %+v, %v", results[0], err)
+	}
+	if ms := c.Migrate(results); ms[0].Status != StatusSkipped || db.updateTableCall != 0 {
+		t.Fatalf("expected existing table not to be updated but got %+v", ms[0])
+	}
+
+	if _, err := NewController(db, "", nil, []TableInfo{{TableName: "orders", Lifecycle: "create-only"}}); !errors.Is(err, ErrInvalidLifecycle) {
+		t.Fatalf("expected ErrInvalidLifecycle but got %v", err)
+	}
+}
+
+func TestDeprecatedTables(t *testing.T) {
+	exists := map[string]bool{"test-orders": true}
+	deleted := []string{}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		if !exists[aws.StringValue(input.TableName)] {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: provisionedDescription(5, 5)}, nil
+	}
+	db.deleteTable = func(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+		deleted = append(deleted, aws.StringValue(input.TableName))
+		return &dynamodb.DeleteTableOutput{}, nil
+	}
+	tables := []TableInfo{
+		{TableName: "test-orders", PrimaryKey: "id", Deprecated: true},
+		{TableName: "test-users", PrimaryKey: "id", Deprecated: true},
+	}
+
+	// Without prune mode deprecated tables are only reported and never created.
+	c, err := NewController(db, "", nil, tables, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.Validate()
+	if err != nil {
+		t.Fatalf("expected no changes but got %v", err)
+	}
+	if r := results[0]; len(r.Warnings) != 1 || r.Warnings[0] != "deprecated table exists: test-orders" || r.DeleteTableInput != nil {
+		t.Fatalf("expected warning about existing deprecated table but got %+v", r)
+	}
+	if r := results[1]; r.CreateTableInput != nil || r.Diff != "" || len(r.Warnings) != 0 {
+		t.Fatalf("expected missing deprecated table not to be created but got %+v", r)
+	}
+
+	// Prune mode deletes deprecated tables once approved.
+	approve := false
+	var approved []Change
+	c, err = NewController(db, "", nil, tables, WithClock(&fakeClock{}), WithPrune(func(changes []Change) bool {
+		approved = changes
+		return approve
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = c.Validate()
+	if err != ErrBackwardCompatible || results[0].DeleteTableInput == nil || results[1].DeleteTableInput != nil {
+		t.Fatalf("expected deletion of the existing deprecated table but got %+v, %v", results, err)
+	}
+
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusFailed || len(ms[0].Skipped) != 1 || ms[0].Errors[0] != ErrDeletionNotApproved || len(deleted) != 0 {
+		t.Fatalf("expected denied deletion to be skipped but got %+v, deleted %v", ms[0], deleted)
+	}
+	if len(approved) != 1 || approved[0].Type != ChangeDeleteTable || approved[0].TableName != "test-orders" {
+		t.Fatalf("expected approver to receive the deletion but got %v", approved)
+	}
+
+	approve = true
+	ms = c.Migrate(results)
+	if ms[0].Status != StatusApplied || len(deleted) != 1 || deleted[0] != "test-orders" {
+		t.Fatalf("expected approved deletion to be applied but got %+v, deleted %v", ms[0], deleted)
+	}
+	if ms[1].Status != StatusSkipped {
+		t.Fatalf("expected missing deprecated table to be skipped but got %+v", ms[1])
+	}
+}
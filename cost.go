@@ -0,0 +1,114 @@
+package tables
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// HoursPerMonth is the number of hours monthly costs are estimated for.
+const HoursPerMonth = 730
+
+// CapacityPricing holds the hourly prices of a single provisioned capacity unit in USD.
+type CapacityPricing struct {
+	ReadUnitHour  float64 `json:"read_unit_hour" yaml:"read_unit_hour"`
+	WriteUnitHour float64 `json:"write_unit_hour" yaml:"write_unit_hour"`
+}
+
+// DefaultCapacityPricing are the prices of the standard table class in us-east-1.
+var DefaultCapacityPricing = CapacityPricing{
+	ReadUnitHour:  0.00013,
+	WriteUnitHour: 0.00065,
+}
+
+// CostReport lists the provisioned capacity and its estimated cost per environment.
+type CostReport struct {
+	Pricing      CapacityPricing   `json:"pricing" yaml:"pricing"`
+	Environments []EnvironmentCost `json:"environments" yaml:"environments"`
+}
+
+// EnvironmentCost is the provisioned capacity of all tables of an environment.
+type EnvironmentCost struct {
+	Env string `json:"env" yaml:"env"`
+	// ReadCapacity and WriteCapacity are the sums of the configured units of all tables and GSIs.
+	ReadCapacity  int64 `json:"read_capacity" yaml:"read_capacity"`
+	WriteCapacity int64 `json:"write_capacity" yaml:"write_capacity"`
+	// OnDemandTables is the number of PAY_PER_REQUEST tables, which are billed per request
+	// and not part of the estimate.
+	OnDemandTables int         `json:"on_demand_tables" yaml:"on_demand_tables"`
+	MonthlyCost    float64     `json:"monthly_cost" yaml:"monthly_cost"`
+	Tables         []TableCost `json:"tables" yaml:"tables"`
+}
+
+// TableCost is the provisioned capacity of a single table including its GSIs.
+type TableCost struct {
+	TableName     string  `json:"table_name" yaml:"table_name"`
+	BillingMode   string  `json:"billing_mode" yaml:"billing_mode"`
+	ReadCapacity  int64   `json:"read_capacity" yaml:"read_capacity"`
+	WriteCapacity int64   `json:"write_capacity" yaml:"write_capacity"`
+	MonthlyCost   float64 `json:"monthly_cost" yaml:"monthly_cost"`
+}
+
+// NewCostReport aggregates the configured capacity of the tables of each environment, e.g. loaded
+// with LoadEnv, and estimates its monthly cost. Environments are listed by name, tables in config order.
+// Unmanaged tables are skipped, deprecated tables are included as long as they exist.
+func NewCostReport(tablesByEnv map[string][]TableInfo, pricing CapacityPricing) *CostReport {
+	envs := make([]string, 0, len(tablesByEnv))
+	for env := range tablesByEnv {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+
+	report := &CostReport{Pricing: pricing, Environments: make([]EnvironmentCost, 0, len(envs))}
+	for _, env := range envs {
+		report.Environments = append(report.Environments, environmentCost(env, tablesByEnv[env], pricing))
+	}
+	return report
+}
+
+func environmentCost(env string, tables []TableInfo, pricing CapacityPricing) EnvironmentCost {
+	ec := EnvironmentCost{Env: env, Tables: []TableCost{}}
+	for _, tbl := range tables {
+		if tbl.Unmanaged {
+			continue
+		}
+		tc := TableCost{
+			TableName:   physicalName(env, tbl),
+			BillingMode: billingMode(tbl),
+		}
+		if tc.BillingMode == dynamodb.BillingModePayPerRequest {
+			ec.OnDemandTables++
+		} else {
+			tc.ReadCapacity, tc.WriteCapacity = tbl.ReadThroughput, tbl.WriteThroughput
+			for _, index := range tbl.Indexes {
+				tc.ReadCapacity += index.ReadThroughput
+				tc.WriteCapacity += index.WriteThroughput
+			}
+			tc.MonthlyCost = pricing.monthly(tc.ReadCapacity, tc.WriteCapacity)
+		}
+		ec.ReadCapacity += tc.ReadCapacity
+		ec.WriteCapacity += tc.WriteCapacity
+		ec.Tables = append(ec.Tables, tc)
+	}
+	ec.MonthlyCost = pricing.monthly(ec.ReadCapacity, ec.WriteCapacity)
+	return ec
+}
+
+func (p CapacityPricing) monthly(read, write int64) float64 {
+	return (float64(read)*p.ReadUnitHour + float64(write)*p.WriteUnitHour) * HoursPerMonth
+}
+
+// String lists the capacity of each environment on its own line,
+// e.g. "prod: 200 RCU, 100 WCU, $66.43/month".
+func (r *CostReport) String() string {
+	lines := make([]string, len(r.Environments))
+	for i, ec := range r.Environments {
+		lines[i] = fmt.Sprintf("%s: %d RCU, %d WCU, $%.2f/month", ec.Env, ec.ReadCapacity, ec.WriteCapacity, ec.MonthlyCost)
+		if ec.OnDemandTables > 0 {
+			lines[i] += fmt.Sprintf(" (+%d on-demand tables)", ec.OnDemandTables)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
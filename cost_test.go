@@ -0,0 +1,32 @@
+package tables
+
+import "testing"
+
+func TestNewCostReport(t *testing.T) {
+	prod := []TableInfo{
+		{Title: "shop", TableName: "orders", ReadThroughput: 100, WriteThroughput: 50, Indexes: []IndexInfo{
+			{IndexName: "by_user", ReadThroughput: 100, WriteThroughput: 50},
+		}},
+		{TableName: "events", BillingMode: "PAY_PER_REQUEST"},
+		{TableName: "legacy", ReadThroughput: 1000, WriteThroughput: 1000, Unmanaged: true},
+	}
+	dev := []TableInfo{
+		{Title: "shop", TableName: "orders", ReadThroughput: 1, WriteThroughput: 1},
+	}
+
+	report := NewCostReport(map[string][]TableInfo{"prod": prod, "dev": dev}, DefaultCapacityPricing)
+	if len(report.Environments) != 2 || report.Environments[0].Env != "dev" {
+		t.Fatalf("expected environments sorted by name but got %+v", report.Environments)
+	}
+	p := report.Environments[1]
+	if p.ReadCapacity != 200 || p.WriteCapacity != 100 || p.OnDemandTables != 1 || len(p.Tables) != 2 {
+		t.Fatalf("unexpected prod capacity %+v", p)
+	}
+	if p.Tables[0].TableName != "shop-prod-orders" {
+		t.Fatalf("expected physical table name but got %s", p.Tables[0].TableName)
+	}
+	want := "dev: 1 RCU, 1 WCU, $0.57/month\nprod: 200 RCU, 100 WCU, $66.43/month (+1 on-demand tables)"
+	if got := report.String(); got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,213 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dax"
+	"github.com/aws/aws-sdk-go/service/dax/daxiface"
+)
+
+// DAXInfo configures the DAX cluster a table requires. Tables may share a cluster.
+type DAXInfo struct {
+	// ClusterName is used as is, environments sharing an account need their own name, e.g. via
+	// environment settings. DAX limits cluster names to 20 characters.
+	ClusterName       string `yaml:"cluster_name"`
+	NodeType          string `yaml:"node_type"`
+	ReplicationFactor int64  `yaml:"replication_factor"`
+	// IAMRoleARN is the role DAX assumes to access the table, required to create the cluster.
+	IAMRoleARN       string   `yaml:"iam_role_arn"`
+	SecurityGroupIDs []string `yaml:"security_group_ids"`
+	// SubnetGroup defaults to the cluster name and is created from SubnetIDs if missing.
+	SubnetGroup string   `yaml:"subnet_group"`
+	SubnetIDs   []string `yaml:"subnet_ids"`
+	// ParameterGroup defaults to the cluster name and is created with default parameters if missing.
+	ParameterGroup string `yaml:"parameter_group"`
+}
+
+func (d DAXInfo) subnetGroup() string {
+	if d.SubnetGroup != "" {
+		return d.SubnetGroup
+	}
+	return d.ClusterName
+}
+
+func (d DAXInfo) parameterGroup() string {
+	if d.ParameterGroup != "" {
+		return d.ParameterGroup
+	}
+	return d.ClusterName
+}
+
+// DAXManager is a ResourceManager creating and validating the DAX cluster, subnet group and
+// parameter group configured in the dax section of each table. Missing resources and a differing
+// replication factor are migrated, a differing node type cannot be changed in place and fails
+// validation with ErrDAXNodeTypeMismatch.
+type DAXManager struct {
+	DAX daxiface.DAXAPI
+}
+
+// NewDAXManager returns a DAXManager. Register it with WithResourceManagers.
+func NewDAXManager(client daxiface.DAXAPI) *DAXManager {
+	return &DAXManager{DAX: client}
+}
+
+// Name implements ResourceManager.
+func (dm *DAXManager) Name() string {
+	return "dax"
+}
+
+// daxState is the current state of the DAX resources of a table, nil fields are missing.
+type daxState struct {
+	cluster        *dax.Cluster
+	subnetGroup    *dax.SubnetGroup
+	parameterGroup *dax.ParameterGroup
+}
+
+// Validate implements ResourceManager.
+func (dm *DAXManager) Validate(tbl TableInfo) (string, error) {
+	cfg := tbl.DAX
+	if cfg == nil {
+		return "", nil
+	}
+	s, err := dm.describe(*cfg)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{}
+	if s.subnetGroup == nil {
+		parts = append(parts, "missing subnet group: "+cfg.subnetGroup())
+	}
+	if s.parameterGroup == nil {
+		parts = append(parts, "missing parameter group: "+cfg.parameterGroup())
+	}
+	if s.cluster == nil {
+		parts = append(parts, "missing cluster: "+cfg.ClusterName)
+		return strings.Join(parts, ", "), nil
+	}
+	if nodeType := aws.StringValue(s.cluster.NodeType); nodeType != cfg.NodeType {
+		return "", fmt.Errorf("%w: cluster %s has %s, config %s", ErrDAXNodeTypeMismatch, cfg.ClusterName, nodeType, cfg.NodeType)
+	}
+	if nodes := aws.Int64Value(s.cluster.TotalNodes); nodes != cfg.ReplicationFactor {
+		parts = append(parts, fmt.Sprintf("replication factor of cluster %s: %d→%d", cfg.ClusterName, nodes, cfg.ReplicationFactor))
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+// Migrate implements ResourceManager.
+func (dm *DAXManager) Migrate(tbl TableInfo) error {
+	return dm.migrate(tbl, noAudit)
+}
+
+func (dm *DAXManager) migrate(tbl TableInfo, audit auditFunc) error {
+	cfg := tbl.DAX
+	if cfg == nil {
+		return nil
+	}
+	s, err := dm.describe(*cfg)
+	if err != nil {
+		return err
+	}
+	// Tables sharing a cluster are migrated concurrently, resources created in the meantime are fine.
+	if s.subnetGroup == nil {
+		input := &dax.CreateSubnetGroupInput{
+			SubnetGroupName: aws.String(cfg.subnetGroup()),
+			SubnetIds:       aws.StringSlice(cfg.SubnetIDs),
+		}
+		if err := audit("dax:CreateSubnetGroup", input); err != nil {
+			return err
+		}
+		_, err := dm.DAX.CreateSubnetGroup(input)
+		if err != nil && !isAWSError(err, dax.ErrCodeSubnetGroupAlreadyExistsFault) {
+			return err
+		}
+	}
+	if s.parameterGroup == nil {
+		input := &dax.CreateParameterGroupInput{
+			ParameterGroupName: aws.String(cfg.parameterGroup()),
+		}
+		if err := audit("dax:CreateParameterGroup", input); err != nil {
+			return err
+		}
+		_, err := dm.DAX.CreateParameterGroup(input)
+		if err != nil && !isAWSError(err, dax.ErrCodeParameterGroupAlreadyExistsFault) {
+			return err
+		}
+	}
+	if s.cluster == nil {
+		input := &dax.CreateClusterInput{
+			ClusterName:        aws.String(cfg.ClusterName),
+			NodeType:           aws.String(cfg.NodeType),
+			ReplicationFactor:  aws.Int64(cfg.ReplicationFactor),
+			IamRoleArn:         aws.String(cfg.IAMRoleARN),
+			SecurityGroupIds:   aws.StringSlice(cfg.SecurityGroupIDs),
+			SubnetGroupName:    aws.String(cfg.subnetGroup()),
+			ParameterGroupName: aws.String(cfg.parameterGroup()),
+		}
+		if err := audit("dax:CreateCluster", input); err != nil {
+			return err
+		}
+		_, err := dm.DAX.CreateCluster(input)
+		if err != nil && !isAWSError(err, dax.ErrCodeClusterAlreadyExistsFault) {
+			return err
+		}
+		return nil
+	}
+
+	switch nodes := aws.Int64Value(s.cluster.TotalNodes); {
+	case nodes < cfg.ReplicationFactor:
+		input := &dax.IncreaseReplicationFactorInput{
+			ClusterName:          aws.String(cfg.ClusterName),
+			NewReplicationFactor: aws.Int64(cfg.ReplicationFactor),
+		}
+		if err := audit("dax:IncreaseReplicationFactor", input); err != nil {
+			return err
+		}
+		_, err = dm.DAX.IncreaseReplicationFactor(input)
+	case nodes > cfg.ReplicationFactor:
+		input := &dax.DecreaseReplicationFactorInput{
+			ClusterName:          aws.String(cfg.ClusterName),
+			NewReplicationFactor: aws.Int64(cfg.ReplicationFactor),
+		}
+		if err := audit("dax:DecreaseReplicationFactor", input); err != nil {
+			return err
+		}
+		_, err = dm.DAX.DecreaseReplicationFactor(input)
+	}
+	return err
+}
+
+// describe returns the current state of the DAX resources of the config.
+func (dm *DAXManager) describe(cfg DAXInfo) (*daxState, error) {
+	s := &daxState{}
+	clusters, err := dm.DAX.DescribeClusters(&dax.DescribeClustersInput{ClusterNames: aws.StringSlice([]string{cfg.ClusterName})})
+	if err != nil && !isAWSError(err, dax.ErrCodeClusterNotFoundFault) {
+		return nil, err
+	}
+	if err == nil && len(clusters.Clusters) > 0 {
+		s.cluster = clusters.Clusters[0]
+	}
+	subnets, err := dm.DAX.DescribeSubnetGroups(&dax.DescribeSubnetGroupsInput{SubnetGroupNames: aws.StringSlice([]string{cfg.subnetGroup()})})
+	if err != nil && !isAWSError(err, dax.ErrCodeSubnetGroupNotFoundFault) {
+		return nil, err
+	}
+	if err == nil && len(subnets.SubnetGroups) > 0 {
+		s.subnetGroup = subnets.SubnetGroups[0]
+	}
+	params, err := dm.DAX.DescribeParameterGroups(&dax.DescribeParameterGroupsInput{ParameterGroupNames: aws.StringSlice([]string{cfg.parameterGroup()})})
+	if err != nil && !isAWSError(err, dax.ErrCodeParameterGroupNotFoundFault) {
+		return nil, err
+	}
+	if err == nil && len(params.ParameterGroups) > 0 {
+		s.parameterGroup = params.ParameterGroups[0]
+	}
+	return s, nil
+}
+
+// isAWSError reports whether err is an AWS error with the given code.
+func isAWSError(err error, code string) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == code
+}
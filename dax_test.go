@@ -0,0 +1,108 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dax"
+	"github.com/aws/aws-sdk-go/service/dax/daxiface"
+)
+
+// fakeDAX keeps clusters, subnet groups and parameter groups in memory.
+type fakeDAX struct {
+	daxiface.DAXAPI
+	clusters        map[string]*dax.Cluster
+	subnetGroups    map[string]bool
+	parameterGroups map[string]bool
+}
+
+func (f *fakeDAX) DescribeClusters(input *dax.DescribeClustersInput) (*dax.DescribeClustersOutput, error) {
+	c, ok := f.clusters[aws.StringValue(input.ClusterNames[0])]
+	if !ok {
+		return nil, awserr.New(dax.ErrCodeClusterNotFoundFault, "not found", nil)
+	}
+	return &dax.DescribeClustersOutput{Clusters: []*dax.Cluster{c}}, nil
+}
+
+func (f *fakeDAX) DescribeSubnetGroups(input *dax.DescribeSubnetGroupsInput) (*dax.DescribeSubnetGroupsOutput, error) {
+	name := aws.StringValue(input.SubnetGroupNames[0])
+	if !f.subnetGroups[name] {
+		return nil, awserr.New(dax.ErrCodeSubnetGroupNotFoundFault, "not found", nil)
+	}
+	return &dax.DescribeSubnetGroupsOutput{SubnetGroups: []*dax.SubnetGroup{{SubnetGroupName: aws.String(name)}}}, nil
+}
+
+func (f *fakeDAX) DescribeParameterGroups(input *dax.DescribeParameterGroupsInput) (*dax.DescribeParameterGroupsOutput, error) {
+	name := aws.StringValue(input.ParameterGroupNames[0])
+	if !f.parameterGroups[name] {
+		return nil, awserr.New(dax.ErrCodeParameterGroupNotFoundFault, "not found", nil)
+	}
+	return &dax.DescribeParameterGroupsOutput{ParameterGroups: []*dax.ParameterGroup{{ParameterGroupName: aws.String(name)}}}, nil
+}
+
+func (f *fakeDAX) CreateSubnetGroup(input *dax.CreateSubnetGroupInput) (*dax.CreateSubnetGroupOutput, error) {
+	f.subnetGroups[aws.StringValue(input.SubnetGroupName)] = true
+	return &dax.CreateSubnetGroupOutput{}, nil
+}
+
+func (f *fakeDAX) CreateParameterGroup(input *dax.CreateParameterGroupInput) (*dax.CreateParameterGroupOutput, error) {
+	f.parameterGroups[aws.StringValue(input.ParameterGroupName)] = true
+	return &dax.CreateParameterGroupOutput{}, nil
+}
+
+func (f *fakeDAX) CreateCluster(input *dax.CreateClusterInput) (*dax.CreateClusterOutput, error) {
+	f.clusters[aws.StringValue(input.ClusterName)] = &dax.Cluster{
+		ClusterName: input.ClusterName,
+		NodeType:    input.NodeType,
+		TotalNodes:  input.ReplicationFactor,
+	}
+	return &dax.CreateClusterOutput{}, nil
+}
+
+func (f *fakeDAX) IncreaseReplicationFactor(input *dax.IncreaseReplicationFactorInput) (*dax.IncreaseReplicationFactorOutput, error) {
+	f.clusters[aws.StringValue(input.ClusterName)].TotalNodes = input.NewReplicationFactor
+	return &dax.IncreaseReplicationFactorOutput{}, nil
+}
+
+func TestDAXManager(t *testing.T) {
+	client := &fakeDAX{clusters: map[string]*dax.Cluster{}, subnetGroups: map[string]bool{}, parameterGroups: map[string]bool{"shared": true}}
+	dm := NewDAXManager(client)
+	tbl := TableInfo{TableName: "orders", DAX: &DAXInfo{
+		ClusterName:       "orders-cache",
+		NodeType:          "dax.r5.large",
+		ReplicationFactor: 1,
+		IAMRoleARN:        "arn:aws:iam::123456789012:role/dax",
+		SubnetIDs:         []string{"subnet-1"},
+		ParameterGroup:    "shared",
+	}}
+
+	diff, err := dm.Validate(tbl)
+	if want := "missing subnet group: orders-cache, missing cluster: orders-cache"; err != nil || diff != want {
+		t.Fatalf("expected %q but got %q, %v", want, diff, err)
+	}
+	if err := dm.Migrate(tbl); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := dm.Validate(tbl); err != nil || diff != "" {
+		t.Fatalf("expected DAX resources in sync but got %q, %v", diff, err)
+	}
+
+	tbl.DAX.ReplicationFactor = 3
+	diff, err = dm.Validate(tbl)
+	if want := "replication factor of cluster orders-cache: 1→3"; err != nil || diff != want {
+		t.Fatalf("expected %q but got %q, %v", want, diff, err)
+	}
+	if err := dm.Migrate(tbl); err != nil || aws.Int64Value(client.clusters["orders-cache"].TotalNodes) != 3 {
+		t.Fatalf("expected 3 nodes but got %v, %v", client.clusters["orders-cache"], err)
+	}
+
+	tbl.DAX.NodeType = "dax.r5.xlarge"
+	if _, err := dm.Validate(tbl); !errors.Is(err, ErrDAXNodeTypeMismatch) {
+		t.Fatalf("expected ErrDAXNodeTypeMismatch but got %v", err)
+	}
+	if diff, err := dm.Validate(TableInfo{TableName: "users"}); err != nil || diff != "" {
+		t.Fatalf("expected tables without DAX to be in sync but got %q, %v", diff, err)
+	}
+}
@@ -0,0 +1,179 @@
+package tables
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DefaultDescribeCacheTTL is the default time Describe caches table states.
+const DefaultDescribeCacheTTL = 30 * time.Second
+
+// KeyAttribute is a key attribute of a table or index.
+type KeyAttribute struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is the attribute type, S, N or B.
+	Type string `json:"type" yaml:"type"`
+}
+
+// IndexState is the current state of a GSI.
+type IndexState struct {
+	Name         string        `json:"name" yaml:"name"`
+	Status       string        `json:"status" yaml:"status"`
+	PartitionKey KeyAttribute  `json:"partition_key" yaml:"partition_key"`
+	SortKey      *KeyAttribute `json:"sort_key,omitempty" yaml:"sort_key,omitempty"`
+	// ProjectionType is ALL, KEYS_ONLY or INCLUDE with the NonKeyAttributes.
+	ProjectionType   string   `json:"projection_type" yaml:"projection_type"`
+	NonKeyAttributes []string `json:"non_key_attributes,omitempty" yaml:"non_key_attributes,omitempty"`
+	ReadCapacity     int64    `json:"read_capacity" yaml:"read_capacity"`
+	WriteCapacity    int64    `json:"write_capacity" yaml:"write_capacity"`
+}
+
+// TableState is the normalized current state of a table in DynamoDB.
+type TableState struct {
+	TableName    string        `json:"table_name" yaml:"table_name"`
+	Status       string        `json:"status" yaml:"status"`
+	PartitionKey KeyAttribute  `json:"partition_key" yaml:"partition_key"`
+	SortKey      *KeyAttribute `json:"sort_key,omitempty" yaml:"sort_key,omitempty"`
+	Indexes      []IndexState  `json:"indexes,omitempty" yaml:"indexes,omitempty"`
+	// BillingMode is PROVISIONED or PAY_PER_REQUEST.
+	BillingMode   string `json:"billing_mode" yaml:"billing_mode"`
+	ReadCapacity  int64  `json:"read_capacity" yaml:"read_capacity"`
+	WriteCapacity int64  `json:"write_capacity" yaml:"write_capacity"`
+	// TTLAttribute is empty if TTL is disabled.
+	TTLAttribute string `json:"ttl_attribute,omitempty" yaml:"ttl_attribute,omitempty"`
+	TTLStatus    string `json:"ttl_status" yaml:"ttl_status"`
+	// StreamARN is empty if streams are disabled.
+	StreamARN      string `json:"stream_arn,omitempty" yaml:"stream_arn,omitempty"`
+	StreamViewType string `json:"stream_view_type,omitempty" yaml:"stream_view_type,omitempty"`
+}
+
+// describeCache holds recently described table states keyed by table name in DynamoDB.
+type describeCache struct {
+	mu      sync.Mutex
+	entries map[string]describeEntry
+}
+
+type describeEntry struct {
+	state   *TableState
+	expires time.Time
+}
+
+func (dc *describeCache) get(name string, now time.Time) *TableState {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	e, ok := dc.entries[name]
+	if !ok || !now.Before(e.expires) {
+		return nil
+	}
+	return e.state
+}
+
+func (dc *describeCache) put(name string, state *TableState, expires time.Time) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	if dc.entries == nil {
+		dc.entries = make(map[string]describeEntry)
+	}
+	dc.entries[name] = describeEntry{state: state, expires: expires}
+}
+
+func (dc *describeCache) invalidate(name string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	delete(dc.entries, name)
+}
+
+// Describe returns the current state of a managed table, identified by its name in the config
+// or in DynamoDB. States are cached for WithDescribeCacheTTL, DefaultDescribeCacheTTL by default,
+// and invalidated when Migrate changes the table. The returned state must not be modified.
+func (c *Controller) Describe(name string) (*TableState, error) {
+	tbl, err := c.lookupTable(name)
+	if err != nil {
+		return nil, err
+	}
+	physical := physicalName(c.env, tbl)
+	if state := c.describeCache.get(physical, c.clock.Now()); state != nil {
+		return state, nil
+	}
+
+	desc, err := c.describeTable(tbl)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := c.describeTTL(tbl)
+	if err != nil {
+		return nil, err
+	}
+	state := NewTableState(desc, ttl)
+	if c.describeCacheTTL > 0 {
+		c.describeCache.put(physical, state, c.clock.Now().Add(c.describeCacheTTL))
+	}
+	return state, nil
+}
+
+// NewTableState normalizes a table and TTL description. ttl may be nil.
+func NewTableState(desc *dynamodb.TableDescription, ttl *dynamodb.TimeToLiveDescription) *TableState {
+	types := make(map[string]string, len(desc.AttributeDefinitions))
+	for _, a := range desc.AttributeDefinitions {
+		types[aws.StringValue(a.AttributeName)] = aws.StringValue(a.AttributeType)
+	}
+
+	s := &TableState{
+		TableName:   aws.StringValue(desc.TableName),
+		Status:      aws.StringValue(desc.TableStatus),
+		BillingMode: currentBillingMode(desc),
+		StreamARN:   aws.StringValue(desc.LatestStreamArn),
+	}
+	s.PartitionKey, s.SortKey = keyAttributes(desc.KeySchema, types)
+	if pt := desc.ProvisionedThroughput; pt != nil {
+		s.ReadCapacity = aws.Int64Value(pt.ReadCapacityUnits)
+		s.WriteCapacity = aws.Int64Value(pt.WriteCapacityUnits)
+	}
+	if spec := desc.StreamSpecification; spec != nil && aws.BoolValue(spec.StreamEnabled) {
+		s.StreamViewType = aws.StringValue(spec.StreamViewType)
+	}
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		index := IndexState{
+			Name:   aws.StringValue(gsi.IndexName),
+			Status: aws.StringValue(gsi.IndexStatus),
+		}
+		index.PartitionKey, index.SortKey = keyAttributes(gsi.KeySchema, types)
+		if p := gsi.Projection; p != nil {
+			index.ProjectionType = aws.StringValue(p.ProjectionType)
+			index.NonKeyAttributes = aws.StringValueSlice(p.NonKeyAttributes)
+		}
+		if pt := gsi.ProvisionedThroughput; pt != nil {
+			index.ReadCapacity = aws.Int64Value(pt.ReadCapacityUnits)
+			index.WriteCapacity = aws.Int64Value(pt.WriteCapacityUnits)
+		}
+		s.Indexes = append(s.Indexes, index)
+	}
+	if ttl != nil {
+		s.TTLStatus = aws.StringValue(ttl.TimeToLiveStatus)
+		if s.TTLStatus != dynamodb.TimeToLiveStatusDisabled {
+			s.TTLAttribute = aws.StringValue(ttl.AttributeName)
+		}
+	}
+	return s
+}
+
+// keyAttributes returns the HASH and RANGE attributes of a key schema.
+func keyAttributes(schema []*dynamodb.KeySchemaElement, types map[string]string) (KeyAttribute, *KeyAttribute) {
+	var partition KeyAttribute
+	var sort *KeyAttribute
+	for _, k := range schema {
+		attr := KeyAttribute{
+			Name: aws.StringValue(k.AttributeName),
+			Type: types[aws.StringValue(k.AttributeName)],
+		}
+		if aws.StringValue(k.KeyType) == dynamodb.KeyTypeRange {
+			sort = &attr
+		} else {
+			partition = attr
+		}
+	}
+	return partition, sort
+}
@@ -0,0 +1,70 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestDescribe(t *testing.T) {
+	calls := 0
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		calls++
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName:   input.TableName,
+			TableStatus: aws.String(dynamodb.TableStatusActive),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+				{AttributeName: aws.String("created"), AttributeType: aws.String("N")},
+			},
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			},
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+				{
+					IndexName:   aws.String("byCreated"),
+					IndexStatus: aws.String(dynamodb.IndexStatusActive),
+					KeySchema: []*dynamodb.KeySchemaElement{
+						{AttributeName: aws.String("id"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+						{AttributeName: aws.String("created"), KeyType: aws.String(dynamodb.KeyTypeRange)},
+					},
+					Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly)},
+				},
+			},
+			LatestStreamArn: aws.String("arn:stream"),
+		}}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			AttributeName:    aws.String("expires_at"),
+			TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+		}}, nil
+	}
+	clock := &fakeClock{}
+	c := newTestController(t, db, clock)
+	c.Tables = []TableInfo{{TableName: "orders"}}
+
+	s, err := c.Describe("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.PartitionKey.Name != "id" || s.SortKey != nil || s.BillingMode != dynamodb.BillingModeProvisioned {
+		t.Fatalf("unexpected table state %+v", s)
+	}
+	if len(s.Indexes) != 1 || s.Indexes[0].SortKey == nil || s.Indexes[0].SortKey.Type != "N" {
+		t.Fatalf("unexpected index state %+v", s.Indexes)
+	}
+	if s.TTLAttribute != "expires_at" || s.StreamARN != "arn:stream" {
+		t.Fatalf("unexpected TTL or stream state %+v", s)
+	}
+
+	if _, err := c.Describe("orders"); err != nil || calls != 1 {
+		t.Fatalf("expected cached state but got %d calls, %v", calls, err)
+	}
+	clock.now = clock.now.Add(DefaultDescribeCacheTTL)
+	if _, err := c.Describe("orders"); err != nil || calls != 2 {
+		t.Fatalf("expected expired state to be described again but got %d calls, %v", calls, err)
+	}
+}
@@ -3,6 +3,7 @@ package tables
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -10,17 +11,41 @@ import (
 	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
+// withDefaultOptions prepends the options used by every comparison to the custom options.
+func withDefaultOptions(opts []cmp.Option) []cmp.Option {
+	return append([]cmp.Option{cmpopts.IgnoreTypes(struct{}{})}, opts...)
+}
+
+// GSIResult is the result of comparing the GSIs of a table.
 type GSIResult struct {
-	GSIInput   []*dynamodb.GlobalSecondaryIndexUpdate
-	Diff       string
+	// GSIInput contains the inputs of all indexes which can be migrated.
+	GSIInput []*dynamodb.GlobalSecondaryIndexUpdate
+	Diff     string
+	// CanMigrate is false if any index cannot be migrated, see Indexes.
+	CanMigrate bool
+	// Indexes contains the results of the indexes with diffs in input order.
+	Indexes []*GSIIndexResult
+}
+
+// GSIIndexResult is the result of comparing a single GSI.
+type GSIIndexResult struct {
+	IndexName string
+	Diff      string
+	// Input creates or updates the index, nil if the index cannot be migrated.
+	Input      *dynamodb.GlobalSecondaryIndexUpdate
 	CanMigrate bool
 }
 
 // DiffTableDesc gets the diff string of two table descriptions
-func DiffTableDesc(desc *dynamodb.TableDescription, input *dynamodb.CreateTableInput) string {
+// All Diff functions accept additional cmp.Options to customise the comparison.
+func DiffTableDesc(desc *dynamodb.TableDescription, input *dynamodb.CreateTableInput, opts ...cmp.Option) string {
+	return differ{opts: opts}.tableDesc(desc, input)
+}
+
+func (df differ) tableDesc(desc *dynamodb.TableDescription, input *dynamodb.CreateTableInput) string {
 	diff := ""
 
-	if d := DiffKeySchema(desc.KeySchema, input.KeySchema); len(d) > 0 {
+	if d := df.keySchema(desc.KeySchema, input.KeySchema); len(d) > 0 {
 		diff = fmt.Sprintf("Key Schedma: %v%v", diff, d)
 	}
 
@@ -33,8 +58,7 @@ func DiffTableDesc(desc *dynamodb.TableDescription, input *dynamodb.CreateTableI
 				Projection: i.Projection,
 			})
 		}
-		d := DiffLSI(lsi, input.LocalSecondaryIndexes)
-		if len(d) > 0 {
+		if d := df.lsi(lsi, input.LocalSecondaryIndexes); len(d) > 0 {
 			diff = fmt.Sprintf("LSI: %v%v", diff, d)
 		}
 	}
@@ -43,11 +67,17 @@ func DiffTableDesc(desc *dynamodb.TableDescription, input *dynamodb.CreateTableI
 
 // DiffGSI compares two GlobalSecondaryIndexDescription slices and returns the diff string.
 // GSIResult also contains a list GSIInput. This data is used for Migrate() and only
-// overridable GSIInputs are appended to the list.
-func DiffGSI(desc []*dynamodb.GlobalSecondaryIndexDescription, input []*dynamodb.GlobalSecondaryIndex) *GSIResult {
-	diff := ""
-	canMigrate := true
-	result := &GSIResult{}
+// overridable GSIInputs are appended to the list. Indexes are judged one by one, an index
+// which cannot be migrated does not block the inputs of the others.
+// GSIs without ProvisionedThroughput in input belong to PAY_PER_REQUEST tables, their throughput
+// is not compared since DynamoDB reports zero provisioned throughput for them.
+func DiffGSI(desc []*dynamodb.GlobalSecondaryIndexDescription, input []*dynamodb.GlobalSecondaryIndex, opts ...cmp.Option) *GSIResult {
+	return differ{opts: opts}.gsi(desc, input)
+}
+
+func (df differ) gsi(desc []*dynamodb.GlobalSecondaryIndexDescription, input []*dynamodb.GlobalSecondaryIndex) *GSIResult {
+	result := &GSIResult{CanMigrate: true}
+	diffs := []string{}
 
 	if len(desc) == 0 && len(input) == 0 {
 		return result
@@ -56,136 +86,253 @@ func DiffGSI(desc []*dynamodb.GlobalSecondaryIndexDescription, input []*dynamodb
 	newObj := make(map[string]*dynamodb.GlobalSecondaryIndex, len(input))
 
 	for _, gsi := range desc {
-		newObj[aws.StringValue(gsi.IndexName)] = &dynamodb.GlobalSecondaryIndex{
+		obj := &dynamodb.GlobalSecondaryIndex{
 			IndexName:  gsi.IndexName,
 			KeySchema:  gsi.KeySchema,
 			Projection: gsi.Projection,
-			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+		}
+		if gsi.ProvisionedThroughput != nil {
+			obj.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
 				ReadCapacityUnits:  gsi.ProvisionedThroughput.ReadCapacityUnits,
 				WriteCapacityUnits: gsi.ProvisionedThroughput.WriteCapacityUnits,
-			},
+			}
 		}
+		newObj[aws.StringValue(gsi.IndexName)] = obj
 	}
 
 	for _, gsi := range input {
-		obj, ok := newObj[aws.StringValue(gsi.IndexName)]
-		if !ok {
-			// Index does not exist in dynamoDB, we queue an input to create missing index.
-			result.GSIInput = append(result.GSIInput, &dynamodb.GlobalSecondaryIndexUpdate{
-				Create: &dynamodb.CreateGlobalSecondaryIndexAction{
-					IndexName:             gsi.IndexName,
-					KeySchema:             gsi.KeySchema,
-					Projection:            gsi.Projection,
-					ProvisionedThroughput: gsi.ProvisionedThroughput,
-				},
-			})
-
-			diff = fmt.Sprintf("missing index: %s", aws.StringValue(gsi.IndexName))
-			result.Diff = diff
+		index := df.index(newObj[aws.StringValue(gsi.IndexName)], gsi)
+		if index == nil {
 			continue
 		}
-
-		if d := DiffIndexName(obj.IndexName, gsi.IndexName); len(d) > 0 {
-			canMigrate = false
-			diff = fmt.Sprintf("%v%v", diff, d)
+		result.Indexes = append(result.Indexes, index)
+		diffs = append(diffs, index.Diff)
+		if !index.CanMigrate {
+			result.CanMigrate = false
+			continue
 		}
-		if d := DiffKeySchema(obj.KeySchema, gsi.KeySchema); len(d) > 0 {
-			canMigrate = false
-			diff = fmt.Sprintf("%v%v", diff, d)
+		if index.Input != nil {
+			result.GSIInput = append(result.GSIInput, index.Input)
 		}
-		if d := DiffProjection(obj.Projection, gsi.Projection); len(d) > 0 {
-			canMigrate = false
-			diff = fmt.Sprintf("%v%v", diff, d)
+	}
+	result.Diff = strings.Join(diffs, "; ")
+	return result
+}
+
+// index compares the existing index obj, nil if missing, to the expected index gsi.
+// nil is returned if both are the same.
+func (df differ) index(obj, gsi *dynamodb.GlobalSecondaryIndex) *GSIIndexResult {
+	index := &GSIIndexResult{
+		IndexName:  aws.StringValue(gsi.IndexName),
+		CanMigrate: true,
+	}
+	if obj == nil {
+		// Index does not exist in dynamoDB, we queue an input to create missing index.
+		index.Input = &dynamodb.GlobalSecondaryIndexUpdate{
+			Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+				IndexName:             gsi.IndexName,
+				KeySchema:             gsi.KeySchema,
+				Projection:            gsi.Projection,
+				ProvisionedThroughput: gsi.ProvisionedThroughput,
+			},
 		}
+		index.Diff = fmt.Sprintf("missing index: %s", index.IndexName)
+		return index
+	}
 
-		if d := DiffProvisionedThroughput(obj.ProvisionedThroughput, &dynamodb.ProvisionedThroughput{
+	if d := df.indexName(obj.IndexName, gsi.IndexName); len(d) > 0 {
+		index.CanMigrate = false
+		index.Diff = fmt.Sprintf("%v%v", index.Diff, d)
+	}
+	if d := df.keySchema(obj.KeySchema, gsi.KeySchema); len(d) > 0 {
+		index.CanMigrate = false
+		index.Diff = fmt.Sprintf("%v%v", index.Diff, d)
+	}
+	if d := df.projection(obj.Projection, gsi.Projection); len(d) > 0 {
+		index.CanMigrate = false
+		index.Diff = fmt.Sprintf("%v%v", index.Diff, d)
+	}
+
+	// On-demand GSIs have no throughput to compare.
+	if gsi.ProvisionedThroughput != nil {
+		if d := df.throughput(obj.ProvisionedThroughput, &dynamodb.ProvisionedThroughput{
 			ReadCapacityUnits:  gsi.ProvisionedThroughput.ReadCapacityUnits,
 			WriteCapacityUnits: gsi.ProvisionedThroughput.WriteCapacityUnits,
 		}); len(d) > 0 {
-			diff = fmt.Sprintf("%v%v", diff, d)
-			result.GSIInput = append(result.GSIInput, &dynamodb.GlobalSecondaryIndexUpdate{
-				Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
-					IndexName:             gsi.IndexName,
-					ProvisionedThroughput: gsi.ProvisionedThroughput,
-				},
-			})
+			index.Diff = fmt.Sprintf("%v%v", index.Diff, d)
+			if index.CanMigrate {
+				index.Input = &dynamodb.GlobalSecondaryIndexUpdate{
+					Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+						IndexName:             gsi.IndexName,
+						ProvisionedThroughput: gsi.ProvisionedThroughput,
+					},
+				}
+			}
 		}
 	}
-	if len(diff) > 0 {
-		result.Diff = diff
+	if index.Diff == "" {
+		return nil
 	}
-	result.CanMigrate = canMigrate
-	return result
+	return index
 }
 
 // DiffIndexName gets the diff string of two index names
-func DiffIndexName(name1, name2 *string) string {
-	return cmp.Diff(name1, name2)
+func DiffIndexName(name1, name2 *string, opts ...cmp.Option) string {
+	return cmp.Diff(name1, name2, opts...)
 }
 
 // DiffProvisionedThroughput gets the diff string of two ProvisionedThroughputs
-func DiffProvisionedThroughput(pt1, pt2 *dynamodb.ProvisionedThroughput) string {
+func DiffProvisionedThroughput(pt1, pt2 *dynamodb.ProvisionedThroughput, opts ...cmp.Option) string {
 	return cmp.Diff(
 		pt1,
 		pt2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		withDefaultOptions(opts)...,
 	)
 }
 
 // DiffKeySchema gets the diff string of two KeySchema slices
-func DiffKeySchema(obj1, obj2 []*dynamodb.KeySchemaElement) string {
+func DiffKeySchema(obj1, obj2 []*dynamodb.KeySchemaElement, opts ...cmp.Option) string {
 	return cmp.Diff(
 		obj1,
 		obj2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		withDefaultOptions(opts)...,
 	)
 }
 
 // DiffAttributeDefinitions gets the diff string of two AttributeDefinition slices.
 // If two slices have same values but in different orders, the result will be the same.
-func DiffAttributeDefinitions(obj1, obj2 []*dynamodb.AttributeDefinition) string {
-	sort.Slice(obj1, func(i, j int) bool {
-		return aws.StringValue(obj1[i].AttributeName) < aws.StringValue(obj1[j].AttributeName)
-	})
-	sort.Slice(obj2, func(i, j int) bool {
-		return aws.StringValue(obj2[i].AttributeName) < aws.StringValue(obj2[j].AttributeName)
-	})
+// Sorted copies are compared, the given slices are not modified.
+// Only attributes defined in obj2, the expected definitions derived from the config's key schemas
+// and indexes, are compared. Extra attributes in obj1, e.g. belonging to manually created GSIs,
+// are ignored, see ExtraAttributeDefinitions.
+func DiffAttributeDefinitions(obj1, obj2 []*dynamodb.AttributeDefinition, opts ...cmp.Option) string {
+	managed := make(map[string]bool, len(obj2))
+	for _, a := range obj2 {
+		managed[aws.StringValue(a.AttributeName)] = true
+	}
+	scoped := []*dynamodb.AttributeDefinition{}
+	for _, a := range obj1 {
+		if managed[aws.StringValue(a.AttributeName)] {
+			scoped = append(scoped, a)
+		}
+	}
 	return cmp.Diff(
-		obj1,
-		obj2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		sortedAttributeDefinitions(scoped),
+		sortedAttributeDefinitions(obj2),
+		withDefaultOptions(opts)...,
 	)
 }
 
-// DiffProject gets the diff string of two Projects objects
-func DiffProjection(p1, p2 *dynamodb.Projection) string {
-	sort.Slice(p1.NonKeyAttributes, func(i, j int) bool {
-		return aws.StringValue(p1.NonKeyAttributes[i]) < aws.StringValue(p1.NonKeyAttributes[j])
-	})
-	sort.Slice(p2.NonKeyAttributes, func(i, j int) bool {
-		return aws.StringValue(p2.NonKeyAttributes[i]) < aws.StringValue(p2.NonKeyAttributes[j])
+// sortedAttributeDefinitions returns a copy of attrs sorted by attribute name.
+func sortedAttributeDefinitions(attrs []*dynamodb.AttributeDefinition) []*dynamodb.AttributeDefinition {
+	sorted := append([]*dynamodb.AttributeDefinition{}, attrs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return aws.StringValue(sorted[i].AttributeName) < aws.StringValue(sorted[j].AttributeName)
 	})
+	return sorted
+}
+
+// ExtraAttributeDefinitions returns the names of the attributes defined in obj1 but not in obj2.
+func ExtraAttributeDefinitions(obj1, obj2 []*dynamodb.AttributeDefinition) []string {
+	managed := make(map[string]bool, len(obj2))
+	for _, a := range obj2 {
+		managed[aws.StringValue(a.AttributeName)] = true
+	}
+	extra := []string{}
+	for _, a := range obj1 {
+		if name := aws.StringValue(a.AttributeName); !managed[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return extra
+}
+
+// DiffProjection gets the diff string of two Projection objects.
+// NonKeyAttributes are compared regardless of their order and only for INCLUDE projections,
+// since ALL and KEYS_ONLY projections have no NonKeyAttributes. Nil projections are supported.
+// Normalized copies are compared, the given projections are not modified.
+func DiffProjection(p1, p2 *dynamodb.Projection, opts ...cmp.Option) string {
 	return cmp.Diff(
-		p1,
-		p2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		normalizeProjection(p1),
+		normalizeProjection(p2),
+		// opts may be shared by concurrent comparisons, so it is copied rather than appended to.
+		append(withDefaultOptions(opts), cmpopts.EquateEmpty())...,
 	)
 }
 
+// normalizeProjection returns a copy of p with sorted NonKeyAttributes,
+// dropping them for projection types other than INCLUDE.
+func normalizeProjection(p *dynamodb.Projection) *dynamodb.Projection {
+	if p == nil {
+		return nil
+	}
+	n := &dynamodb.Projection{
+		ProjectionType: p.ProjectionType,
+	}
+	if aws.StringValue(p.ProjectionType) == dynamodb.ProjectionTypeAll || aws.StringValue(p.ProjectionType) == dynamodb.ProjectionTypeKeysOnly {
+		return n
+	}
+	n.NonKeyAttributes = append([]*string{}, p.NonKeyAttributes...)
+	sort.Slice(n.NonKeyAttributes, func(i, j int) bool {
+		return aws.StringValue(n.NonKeyAttributes[i]) < aws.StringValue(n.NonKeyAttributes[j])
+	})
+	return n
+}
+
 // DiffLSI gets the diff string of two LocalSecondaryIndexDescription slices
-func DiffLSI(input1, input2 []*dynamodb.LocalSecondaryIndex) string {
+func DiffLSI(input1, input2 []*dynamodb.LocalSecondaryIndex, opts ...cmp.Option) string {
 	return cmp.Diff(
 		input1,
 		input2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		withDefaultOptions(opts)...,
 	)
 }
 
+// DiffReplicas gets the diff string of the replicas of a global table and the configured replicas.
+// Missing replicas, replicas which are not ACTIVE (e.g. a partially created global table) and
+// replicas with a mismatched table class or KMS key are all reported.
+func DiffReplicas(desc []*dynamodb.ReplicaDescription, replicas []ReplicaInfo) string {
+	diff := ""
+
+	byRegion := make(map[string]*dynamodb.ReplicaDescription, len(desc))
+	for _, r := range desc {
+		byRegion[aws.StringValue(r.RegionName)] = r
+	}
+
+	for _, replica := range replicas {
+		r, ok := byRegion[replica.Region]
+		if !ok {
+			diff = fmt.Sprintf("%vmissing replica: %s; ", diff, replica.Region)
+			continue
+		}
+		if status := aws.StringValue(r.ReplicaStatus); status != dynamodb.ReplicaStatusActive {
+			diff = fmt.Sprintf("%vreplica %s status: %s; ", diff, replica.Region, status)
+		}
+		if replica.TableClass != "" {
+			tableClass := dynamodb.TableClassStandard
+			if r.ReplicaTableClassSummary != nil && r.ReplicaTableClassSummary.TableClass != nil {
+				tableClass = aws.StringValue(r.ReplicaTableClassSummary.TableClass)
+			}
+			if tableClass != replica.TableClass {
+				diff = fmt.Sprintf("%vreplica %s table class: %s, expected %s; ", diff, replica.Region, tableClass, replica.TableClass)
+			}
+		}
+		if replica.KMSMasterKeyID != "" {
+			key := aws.StringValue(r.KMSMasterKeyId)
+			if key != replica.KMSMasterKeyID && !strings.HasSuffix(key, "/"+replica.KMSMasterKeyID) {
+				diff = fmt.Sprintf("%vreplica %s kms key: %s, expected %s; ", diff, replica.Region, key, replica.KMSMasterKeyID)
+			}
+		}
+	}
+	return strings.TrimSuffix(diff, "; ")
+}
+
 // DiffTTL gets the diff string of two TimeToLiveDescription objects
-func DiffTTL(desc1, desc2 *dynamodb.TimeToLiveDescription) string {
+func DiffTTL(desc1, desc2 *dynamodb.TimeToLiveDescription, opts ...cmp.Option) string {
 	return cmp.Diff(
 		desc1,
 		desc2,
-		cmpopts.IgnoreTypes(struct{}{}),
+		withDefaultOptions(opts)...,
 	)
 }
@@ -1,9 +1,14 @@
 package tables
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"testing"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestDiffIndexName(t *testing.T) {
@@ -100,6 +105,20 @@ func TestDiffAttributeDefinitions(t *testing.T) {
 	if diff := DiffAttributeDefinitions(obj1, obj3); diff == "" {
 		t.Fatal("expected valid diff but got empty diff")
 	}
+
+	// Extra attributes of unmanaged indexes are ignored
+	obj4 := []*dynamodb.AttributeDefinition{
+		{
+			AttributeName: aws.String("test1"),
+			AttributeType: aws.String("test1"),
+		},
+	}
+	if diff := DiffAttributeDefinitions(obj1, obj4); diff != "" {
+		t.Fatalf("expected empty diff but got %s", diff)
+	}
+	if extra := ExtraAttributeDefinitions(obj1, obj4); len(extra) != 1 || extra[0] != "test2" {
+		t.Fatalf("expected extra attribute test2 but got %v", extra)
+	}
 }
 
 func TestDiffProjection(t *testing.T) {
@@ -140,6 +159,31 @@ func TestDiffProjection(t *testing.T) {
 	}
 }
 
+func TestDiffProjectionTypes(t *testing.T) {
+	all := &dynamodb.Projection{
+		ProjectionType: aws.String(dynamodb.ProjectionTypeAll),
+	}
+	allWithAttributes := &dynamodb.Projection{
+		NonKeyAttributes: []*string{},
+		ProjectionType:   aws.String(dynamodb.ProjectionTypeAll),
+	}
+	keysOnly := &dynamodb.Projection{
+		ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly),
+	}
+
+	if diff := DiffProjection(all, allWithAttributes); diff != "" {
+		t.Fatalf("expected empty diff but got %s", diff)
+	}
+
+	if diff := DiffProjection(all, keysOnly); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+
+	if diff := DiffProjection(nil, all); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+}
+
 func TestDiffGSI(t *testing.T) {
 	obj1 := []*dynamodb.GlobalSecondaryIndex{
 		{
@@ -270,6 +314,38 @@ func TestDiffGSI(t *testing.T) {
 	}
 }
 
+func TestDiffGSIOnDemand(t *testing.T) {
+	tbl := TableInfo{
+		TableName:   "test",
+		PrimaryKey:  "id",
+		BillingMode: dynamodb.BillingModePayPerRequest,
+		Indexes: []IndexInfo{
+			{IndexName: "gsi", PrimaryKey: "name", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1},
+		},
+	}
+	input := CreateTableInput(tbl, "")
+	gsi := input.GlobalSecondaryIndexes[0]
+	desc := []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:  gsi.IndexName,
+			KeySchema:  gsi.KeySchema,
+			Projection: gsi.Projection,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(0),
+				WriteCapacityUnits: aws.Int64(0),
+			},
+		},
+	}
+
+	res := DiffGSI(desc, input.GlobalSecondaryIndexes)
+	if len(res.Diff) > 0 {
+		t.Fatalf("expected empty diff but got %s", res.Diff)
+	}
+	if len(res.GSIInput) > 0 {
+		t.Fatalf("expected no GSIInput but got %v", res.GSIInput)
+	}
+}
+
 func TestDiffLSI(t *testing.T) {
 	obj1 := []*dynamodb.LocalSecondaryIndex{
 		{
@@ -338,3 +414,354 @@ func TestDiffLSI(t *testing.T) {
 		t.Fatal("expected valid diff but got empty")
 	}
 }
+
+func TestDiffReplicas(t *testing.T) {
+	desc := []*dynamodb.ReplicaDescription{
+		{
+			RegionName:     aws.String("us-east-1"),
+			ReplicaStatus:  aws.String(dynamodb.ReplicaStatusActive),
+			KMSMasterKeyId: aws.String("arn:aws:kms:us-east-1:123456789012:key/test"),
+		},
+		{
+			RegionName:    aws.String("eu-west-1"),
+			ReplicaStatus: aws.String(dynamodb.ReplicaStatusCreating),
+		},
+	}
+
+	ok := []ReplicaInfo{
+		{
+			Region:         "us-east-1",
+			TableClass:     dynamodb.TableClassStandard,
+			KMSMasterKeyID: "test",
+		},
+	}
+	if diff := DiffReplicas(desc, ok); diff != "" {
+		t.Fatalf("expected empty diff but got %s", diff)
+	}
+
+	partial := []ReplicaInfo{
+		{Region: "us-east-1"},
+		{Region: "eu-west-1"},
+	}
+	if diff := DiffReplicas(desc, partial); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+
+	missing := []ReplicaInfo{
+		{Region: "ap-southeast-2"},
+	}
+	if diff := DiffReplicas(desc, missing); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+
+	mismatch := []ReplicaInfo{
+		{
+			Region:         "us-east-1",
+			TableClass:     dynamodb.TableClassStandardInfrequentAccess,
+			KMSMasterKeyID: "other",
+		},
+	}
+	if diff := DiffReplicas(desc, mismatch); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+}
+
+func TestDiffProjectionWithOptions(t *testing.T) {
+	obj1 := &dynamodb.Projection{
+		NonKeyAttributes: []*string{
+			aws.String("test1"),
+			aws.String("ignored"),
+		},
+		ProjectionType: aws.String("test"),
+	}
+
+	obj2 := &dynamodb.Projection{
+		NonKeyAttributes: []*string{
+			aws.String("test1"),
+		},
+		ProjectionType: aws.String("test"),
+	}
+
+	if diff := DiffProjection(obj1, obj2); diff == "" {
+		t.Fatal("expected valid diff but got empty diff")
+	}
+
+	ignore := cmpopts.IgnoreSliceElements(func(s *string) bool {
+		return aws.StringValue(s) == "ignored"
+	})
+	if diff := DiffProjection(obj1, obj2, ignore); diff != "" {
+		t.Fatalf("expected empty diff but got %s", diff)
+	}
+}
+
+func TestDiffGSIPerIndex(t *testing.T) {
+	existing := NewGlobalSecondaryIndex(IndexInfo{IndexName: "byUser", PrimaryKey: "user", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1})
+	desc := []*dynamodb.GlobalSecondaryIndexDescription{
+		{
+			IndexName:  existing.IndexName,
+			KeySchema:  existing.KeySchema,
+			Projection: existing.Projection,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(1),
+				WriteCapacityUnits: aws.Int64(1),
+			},
+		},
+	}
+	input := []*dynamodb.GlobalSecondaryIndex{
+		NewGlobalSecondaryIndex(IndexInfo{IndexName: "byUser", PrimaryKey: "user_id", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1}),
+		NewGlobalSecondaryIndex(IndexInfo{IndexName: "byEmail", PrimaryKey: "email", PrimaryKeyType: "S", ReadThroughput: 1, WriteThroughput: 1}),
+	}
+
+	res := DiffGSI(desc, input)
+	if res.CanMigrate {
+		t.Fatal("expected CanMigrate false but got true")
+	}
+	if len(res.Indexes) != 2 || res.Indexes[0].CanMigrate || !res.Indexes[1].CanMigrate {
+		t.Fatalf("expected byUser to be blocked and byEmail to be migratable but got %+v", res.Indexes)
+	}
+	if len(res.GSIInput) != 1 || res.GSIInput[0].Create == nil || aws.StringValue(res.GSIInput[0].Create.IndexName) != "byEmail" {
+		t.Fatalf("expected creation of byEmail only but got %v", res.GSIInput)
+	}
+}
+
+func TestDiffDoesNotModifyInputs(t *testing.T) {
+	desc := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("name"), AttributeType: aws.String("S")},
+		{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+	}
+	input := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String("name"), AttributeType: aws.String("S")},
+		{AttributeName: aws.String("id"), AttributeType: aws.String("S")},
+	}
+	if d := DiffAttributeDefinitions(desc, input); len(d) > 0 {
+		t.Fatalf("expected empty diff but got %s", d)
+	}
+	if aws.StringValue(desc[0].AttributeName) != "name" || aws.StringValue(input[0].AttributeName) != "name" {
+		t.Fatal("expected attribute definitions to keep their order")
+	}
+
+	p1 := &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeInclude), NonKeyAttributes: aws.StringSlice([]string{"b", "a"})}
+	p2 := &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeInclude), NonKeyAttributes: aws.StringSlice([]string{"a", "b"})}
+	if d := DiffProjection(p1, p2); len(d) > 0 {
+		t.Fatalf("expected empty diff but got %s", d)
+	}
+	if aws.StringValue(p1.NonKeyAttributes[0]) != "b" {
+		t.Fatal("expected projection attributes to keep their order")
+	}
+}
+
+// generateGSIs returns the descriptions of n existing GSIs and the expected GSIs, in sync
+// unless drift is set, which changes the throughput of every other index and the key schema
+// and projection of every tenth.
+func generateGSIs(n int, drift bool) ([]*dynamodb.GlobalSecondaryIndexDescription, []*dynamodb.GlobalSecondaryIndex) {
+	desc := make([]*dynamodb.GlobalSecondaryIndexDescription, n)
+	input := make([]*dynamodb.GlobalSecondaryIndex, n)
+	for i := range input {
+		index := IndexInfo{
+			IndexName:       fmt.Sprintf("by_attr%04d", i),
+			PrimaryKey:      fmt.Sprintf("attr%04d", i),
+			PrimaryKeyType:  "S",
+			SortKey:         "created",
+			SortKeyType:     "N",
+			ReadThroughput:  1,
+			WriteThroughput: 1,
+			ProjectedFields: []string{"status", "owner"},
+		}
+		existing := NewGlobalSecondaryIndex(index)
+		desc[i] = &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  existing.IndexName,
+			KeySchema:  existing.KeySchema,
+			Projection: existing.Projection,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(1),
+				WriteCapacityUnits: aws.Int64(1),
+			},
+		}
+		if drift && i%2 == 0 {
+			index.ReadThroughput = 2
+		}
+		if drift && i%10 == 0 {
+			index.SortKey = "updated"
+			index.ProjectedFields = []string{"owner", "status", "region"}
+		}
+		input[i] = NewGlobalSecondaryIndex(index)
+	}
+	if drift {
+		// Reordered attributes do not differ.
+		desc[1].Projection.NonKeyAttributes = aws.StringSlice([]string{"owner", "status"})
+		input = append(input, NewGlobalSecondaryIndex(IndexInfo{IndexName: "missing", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1}))
+	}
+	return desc, input
+}
+
+func TestFastDiffParity(t *testing.T) {
+	for _, drift := range []bool{false, true} {
+		desc, input := generateGSIs(50, drift)
+		expected := DiffGSI(desc, input)
+		if got := (differ{fast: true}).gsi(desc, input); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("drift %v: expected %+v but got %+v", drift, expected, got)
+		}
+	}
+
+	s := aws.String
+	fast, slow := differ{fast: true}, differ{}
+	pairs := []struct {
+		name string
+		diff func(differ) string
+	}{
+		{"nil names", func(df differ) string { return df.indexName(nil, s("a")) }},
+		{"nil throughput", func(df differ) string {
+			return df.throughput(nil, &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(1)})
+		}},
+		{"empty key schema", func(df differ) string { return df.keySchema(nil, []*dynamodb.KeySchemaElement{}) }},
+		{"nil projection", func(df differ) string { return df.projection(nil, &dynamodb.Projection{}) }},
+		{"empty projection attributes", func(df differ) string {
+			return df.projection(&dynamodb.Projection{NonKeyAttributes: []*string{}}, &dynamodb.Projection{})
+		}},
+		{"keys only projection", func(df differ) string {
+			return df.projection(
+				&dynamodb.Projection{ProjectionType: s(dynamodb.ProjectionTypeKeysOnly), NonKeyAttributes: aws.StringSlice([]string{"a"})},
+				&dynamodb.Projection{ProjectionType: s(dynamodb.ProjectionTypeKeysOnly)})
+		}},
+		{"duplicate projection attributes", func(df differ) string {
+			return df.projection(
+				&dynamodb.Projection{NonKeyAttributes: aws.StringSlice([]string{"a", "a", "b"})},
+				&dynamodb.Projection{NonKeyAttributes: aws.StringSlice([]string{"a", "b", "b"})})
+		}},
+		{"extra attribute definitions", func(df differ) string {
+			return df.attributeDefinitions(
+				[]*dynamodb.AttributeDefinition{{AttributeName: s("b"), AttributeType: s("S")}, {AttributeName: s("a"), AttributeType: s("N")}},
+				[]*dynamodb.AttributeDefinition{{AttributeName: s("a"), AttributeType: s("N")}})
+		}},
+		{"changed attribute definitions", func(df differ) string {
+			return df.attributeDefinitions(
+				[]*dynamodb.AttributeDefinition{{AttributeName: s("a"), AttributeType: s("S")}},
+				[]*dynamodb.AttributeDefinition{{AttributeName: s("a"), AttributeType: s("N")}, {AttributeName: s("b"), AttributeType: s("S")}})
+		}},
+		{"lsi projection order", func(df differ) string {
+			return df.lsi(
+				[]*dynamodb.LocalSecondaryIndex{{IndexName: s("lsi"), Projection: &dynamodb.Projection{NonKeyAttributes: aws.StringSlice([]string{"a", "b"})}}},
+				[]*dynamodb.LocalSecondaryIndex{{IndexName: s("lsi"), Projection: &dynamodb.Projection{NonKeyAttributes: aws.StringSlice([]string{"b", "a"})}}})
+		}},
+		{"ttl status", func(df differ) string {
+			return df.ttl(
+				&dynamodb.TimeToLiveDescription{AttributeName: s("expiry"), TimeToLiveStatus: s("DISABLED")},
+				&dynamodb.TimeToLiveDescription{AttributeName: s("expiry"), TimeToLiveStatus: s("ENABLED")})
+		}},
+		{"same ttl", func(df differ) string {
+			return df.ttl(
+				&dynamodb.TimeToLiveDescription{AttributeName: s("expiry"), TimeToLiveStatus: s("ENABLED")},
+				&dynamodb.TimeToLiveDescription{AttributeName: s("expiry"), TimeToLiveStatus: s("ENABLED")})
+		}},
+	}
+	for _, p := range pairs {
+		if expected, got := p.diff(slow), p.diff(fast); got != expected {
+			t.Errorf("%s: expected diff %q but got %q", p.name, expected, got)
+		}
+	}
+}
+
+func TestFastDiffAllocations(t *testing.T) {
+	desc, input := generateGSIs(100, false)
+	slow := testing.AllocsPerRun(5, func() { differ{}.gsi(desc, input) })
+	fast := testing.AllocsPerRun(5, func() { differ{fast: true}.gsi(desc, input) })
+	if fast*10 > slow {
+		t.Fatalf("expected fast diff to allocate an order of magnitude less but got %.0f against %.0f", fast, slow)
+	}
+}
+
+func BenchmarkDiffGSI(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		for _, drift := range []bool{false, true} {
+			desc, input := generateGSIs(n, drift)
+			for _, df := range []differ{{}, {fast: true}} {
+				name := fmt.Sprintf("gsis=%d/drift=%v/fast=%v", n, drift, df.fast)
+				b.Run(name, func(b *testing.B) {
+					b.ReportAllocs()
+					for i := 0; i < b.N; i++ {
+						df.gsi(desc, input)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestValidateWithCmpOptionsConcurrent is meant to be run with -race: the cmp options of the
+// controller are shared by all comparisons and have spare capacity after repeated options.
+func TestValidateWithCmpOptionsConcurrent(t *testing.T) {
+	tables := []TableInfo{}
+	for i := 0; i < 8; i++ {
+		tables = append(tables, TableInfo{
+			TableName:       fmt.Sprintf("table-%d", i),
+			PrimaryKey:      "id",
+			ReadThroughput:  1,
+			WriteThroughput: 1,
+			Indexes: []IndexInfo{
+				{IndexName: "by_user", PrimaryKey: "user_id", PrimaryKeyType: "S", ProjectedFields: []string{"name"}, ReadThroughput: 1, WriteThroughput: 1},
+			},
+		})
+	}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		for _, tbl := range tables {
+			if tbl.TableName == aws.StringValue(input.TableName) {
+				return &dynamodb.DescribeTableOutput{Table: describeInput(CreateTableInput(tbl, ""))}, nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected table %s", aws.StringValue(input.TableName))
+	}
+	ignored := func(name string) interface{} {
+		return func(s *string) bool { return aws.StringValue(s) == name }
+	}
+	c, err := NewController(db, "", nil, tables, WithClock(&fakeClock{}),
+		WithCmpOptions(cmpopts.IgnoreSliceElements(ignored("a"))),
+		WithCmpOptions(cmpopts.IgnoreSliceElements(ignored("b"))),
+		WithCmpOptions(cmpopts.IgnoreSliceElements(ignored("c"))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.cmpOptions) == cap(c.cmpOptions) {
+		t.Fatalf("expected cmp options with spare capacity but got len %d and cap %d", len(c.cmpOptions), cap(c.cmpOptions))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Validate(); err != nil {
+				t.Errorf("expected tables in sync but got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// describeInput returns the description of an ACTIVE table created with input.
+func describeInput(input *dynamodb.CreateTableInput) *dynamodb.TableDescription {
+	desc := &dynamodb.TableDescription{
+		TableName:            input.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		AttributeDefinitions: input.AttributeDefinitions,
+		KeySchema:            input.KeySchema,
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+		},
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  gsi.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: gsi.ProvisionedThroughput.WriteCapacityUnits,
+			},
+		})
+	}
+	return desc
+}
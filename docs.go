@@ -0,0 +1,197 @@
+package tables
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DocsTable is a table as shown on its documentation page.
+type DocsTable struct {
+	TableInfo
+	// Keys lists the key attributes of the table and its indexes with their types.
+	Keys []AttributeInfo
+	// References are the relationships of the table to others, see Relationship.
+	References []Relationship
+	// ReferencedBy are the relationships of other tables to this one.
+	ReferencedBy []Relationship
+}
+
+// Relationship is a relationship between two tables inferred from a shared attribute: an attribute
+// which is the partition key of exactly one table and a key or declared attribute of another,
+// e.g. orders.user_id referencing users.
+type Relationship struct {
+	Attribute string
+	From      string
+	To        string
+}
+
+// Relationships infers the relationships between the given tables, sorted by table and attribute.
+// Partition keys shared by several tables, e.g. id, identify no table and are ignored.
+func Relationships(tables []TableInfo) []Relationship {
+	owners := map[string][]string{}
+	for _, tbl := range tables {
+		owners[tbl.PrimaryKey] = append(owners[tbl.PrimaryKey], tbl.TableName)
+	}
+	relationships := []Relationship{}
+	for _, tbl := range tables {
+		seen := map[string]bool{tbl.PrimaryKey: true}
+		for _, attr := range tableAttributes(tbl) {
+			if seen[attr] || len(owners[attr]) != 1 {
+				continue
+			}
+			seen[attr] = true
+			relationships = append(relationships, Relationship{Attribute: attr, From: tbl.TableName, To: owners[attr][0]})
+		}
+	}
+	sort.Slice(relationships, func(i, j int) bool {
+		if relationships[i].From != relationships[j].From {
+			return relationships[i].From < relationships[j].From
+		}
+		return relationships[i].Attribute < relationships[j].Attribute
+	})
+	return relationships
+}
+
+// tableAttributes returns the key attributes of the table and its indexes and its declared attributes.
+func tableAttributes(tbl TableInfo) []string {
+	attrs := []string{tbl.SortKey}
+	for _, index := range tbl.Indexes {
+		attrs = append(attrs, index.PrimaryKey, index.SortKey)
+	}
+	for _, a := range tbl.Attributes {
+		attrs = append(attrs, a.Name)
+	}
+	return attrs
+}
+
+// docsKeys returns the key attributes of the table and its indexes with their types, in order of appearance.
+func docsKeys(tbl TableInfo) []AttributeInfo {
+	keys := []AttributeInfo{}
+	seen := map[string]bool{}
+	add := func(name, typ string) {
+		if name == "" || seen[name] {
+			return
+		}
+		seen[name] = true
+		keys = append(keys, AttributeInfo{Name: name, Type: typ})
+	}
+	// The partition key of a table is always a string, see CreateTableInput.
+	add(tbl.PrimaryKey, "S")
+	add(tbl.SortKey, tbl.SortKeyType)
+	for _, index := range tbl.Indexes {
+		add(index.PrimaryKey, index.PrimaryKeyType)
+		add(index.SortKey, index.SortKeyType)
+	}
+	return keys
+}
+
+// ExportDocs renders the tables into a static HTML site in dir: an index.html listing all tables
+// and their relationships, and a page per table with its keys, indexes, TTL and relationships.
+// dir is created if missing, existing pages are overwritten.
+func ExportDocs(dir string, tables []TableInfo) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	relationships := Relationships(tables)
+	pages := make([]DocsTable, len(tables))
+	for i, tbl := range tables {
+		pages[i] = DocsTable{TableInfo: tbl, Keys: docsKeys(tbl)}
+		for _, r := range relationships {
+			if r.From == tbl.TableName {
+				pages[i].References = append(pages[i].References, r)
+			}
+			if r.To == tbl.TableName {
+				pages[i].ReferencedBy = append(pages[i].ReferencedBy, r)
+			}
+		}
+	}
+
+	if err := writeDocsPage(filepath.Join(dir, "index.html"), "index", struct {
+		Tables        []DocsTable
+		Relationships []Relationship
+	}{pages, relationships}); err != nil {
+		return err
+	}
+	for _, page := range pages {
+		if err := writeDocsPage(filepath.Join(dir, page.TableName+".html"), "table", page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDocsPage(path, name string, data interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := docsTemplates.ExecuteTemplate(f, name, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var docsTemplates = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"billingMode": billingMode,
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f3f3f3; }
+</style>
+</head>
+<body>
+{{end}}
+
+{{define "index"}}{{template "head" "Tables"}}<h1>Tables</h1>
+<table>
+<tr><th>Table</th><th>Partition Key</th><th>Sort Key</th><th>Indexes</th><th>Description</th></tr>
+{{range .Tables}}<tr><td><a href="{{.TableName}}.html">{{.TableName}}</a>{{if .Deprecated}} (deprecated){{end}}</td><td>{{.PrimaryKey}}</td><td>{{.SortKey}}</td><td>{{len .Indexes}}</td><td>{{.Description}}</td></tr>
+{{end}}</table>
+{{if .Relationships}}<h2>Relationships</h2>
+<table>
+<tr><th>From</th><th>Attribute</th><th>To</th></tr>
+{{range .Relationships}}<tr><td><a href="{{.From}}.html">{{.From}}</a></td><td>{{.Attribute}}</td><td><a href="{{.To}}.html">{{.To}}</a></td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+{{end}}
+
+{{define "table"}}{{template "head" .TableName}}<p><a href="index.html">All tables</a></p>
+<h1>{{.TableName}}</h1>
+{{if .Description}}<p>{{.Description}}</p>
+{{end}}<table>
+<tr><th>Billing Mode</th><td>{{billingMode .TableInfo}}</td></tr>
+{{if ne (billingMode .TableInfo) "PAY_PER_REQUEST"}}<tr><th>Throughput</th><td>{{.ReadThroughput}} RCU / {{.WriteThroughput}} WCU</td></tr>
+{{end}}{{if .TTL}}<tr><th>TTL</th><td>{{.TTL.AttributeName}}{{if not .TTL.Enabled}} (disabled){{end}}</td></tr>
+{{end}}{{if .Region}}<tr><th>Region</th><td>{{.Region}}</td></tr>
+{{end}}{{range $k, $v := .Labels}}<tr><th>{{$k}}</th><td>{{$v}}</td></tr>
+{{end}}</table>
+<h2>Keys</h2>
+<table>
+<tr><th>Attribute</th><th>Type</th></tr>
+{{range .Keys}}<tr><td>{{.Name}}</td><td>{{.Type}}</td></tr>
+{{end}}</table>
+{{if .Indexes}}<h2>Indexes</h2>
+<table>
+<tr><th>Index</th><th>Partition Key</th><th>Sort Key</th><th>Projected Fields</th></tr>
+{{range .Indexes}}<tr><td>{{.IndexName}}</td><td>{{.PrimaryKey}}</td><td>{{.SortKey}}</td><td>{{range $i, $f := .ProjectedFields}}{{if $i}}, {{end}}{{$f}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}{{if or .References .ReferencedBy}}<h2>Relationships</h2>
+<ul>
+{{range .References}}<li>{{.Attribute}} references <a href="{{.To}}.html">{{.To}}</a></li>
+{{end}}{{range .ReferencedBy}}<li>referenced by <a href="{{.From}}.html">{{.From}}</a> via {{.Attribute}}</li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+{{end}}
+`))
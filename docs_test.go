@@ -0,0 +1,52 @@
+package tables
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportDocs(t *testing.T) {
+	data := []TableInfo{
+		{TableName: "users", PrimaryKey: "user_id", Description: "Registered <users>"},
+		{TableName: "orders", PrimaryKey: "order_id", Indexes: []IndexInfo{
+			{IndexName: "by_user", PrimaryKey: "user_id", PrimaryKeyType: "S", SortKey: "created", SortKeyType: "N"},
+		}},
+		{TableName: "events", PrimaryKey: "id", Attributes: []AttributeInfo{{Name: "order_id", Type: "S"}}},
+		{TableName: "audit", PrimaryKey: "id"},
+	}
+
+	want := []Relationship{
+		{Attribute: "order_id", From: "events", To: "orders"},
+		{Attribute: "user_id", From: "orders", To: "users"},
+	}
+	if got := Relationships(data); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+
+	dir, err := ioutil.TempDir("", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ExportDocs(dir, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "users.html", "orders.html", "events.html", "audit.html"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("expected page %s: %v", name, err)
+		}
+	}
+	users, err := ioutil.ReadFile(filepath.Join(dir, "users.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Registered &lt;users&gt;", `referenced by <a href="orders.html">orders</a> via user_id`} {
+		if !strings.Contains(string(users), s) {
+			t.Fatalf("expected %q in users page:\n%s", s, users)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package tables
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// environments holds the per-environment settings of a config file:
+//
+//	environments:
+//	  dev:
+//	    tables:
+//	      orders:
+//	        read_throughput: 1
+//	        write_throughput: 1
+//	env_aliases:
+//	  "pr-*": "dev"
+//
+// The settings of an environment override the values of the named tables, which must be
+// in the config. Environments without own settings use the settings of the longest alias
+// pattern they match, see resolveEnv and path.Match for the pattern syntax.
+type environments struct {
+	Environments map[string]struct {
+		Tables map[string]yamlv3.Node `yaml:"tables"`
+	} `yaml:"environments"`
+	EnvAliases map[string]string `yaml:"env_aliases"`
+}
+
+// resolveEnv returns the name of the environment whose settings apply to env, empty if none.
+// Aliases are matched in order of decreasing pattern length so that more specific patterns win,
+// patterns of the same length in lexical order.
+func (e environments) resolveEnv(env string) (string, error) {
+	if env == "" {
+		return "", nil
+	}
+	if _, ok := e.Environments[env]; ok {
+		return env, nil
+	}
+	patterns := make([]string, 0, len(e.EnvAliases))
+	for p := range e.EnvAliases {
+		patterns = append(patterns, p)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, p := range patterns {
+		ok, err := path.Match(p, env)
+		if err != nil {
+			return "", fmt.Errorf("env alias %q: %w", p, err)
+		}
+		if !ok {
+			continue
+		}
+		target := e.EnvAliases[p]
+		if _, exists := e.Environments[target]; !exists {
+			return "", fmt.Errorf("env alias %q: %w: %q", p, ErrUnknownEnvironment, target)
+		}
+		return target, nil
+	}
+	return "", nil
+}
+
+// applyEnv overrides the tables with the settings of the environment applying to env.
+// Settings of tables which are not in the config are rejected with ErrTableNotFound.
+func applyEnv(data []byte, tables []TableInfo, env string) error {
+	if env == "" {
+		return nil
+	}
+	e := environments{}
+	if err := yamlv3.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	name, err := e.resolveEnv(env)
+	if err != nil || name == "" {
+		return err
+	}
+
+	settings := e.Environments[name].Tables
+	names := make([]string, 0, len(settings))
+	for tableName := range settings {
+		names = append(names, tableName)
+	}
+	sort.Strings(names)
+	for _, tableName := range names {
+		tbl := findTable(tables, tableName)
+		if tbl == nil {
+			return fmt.Errorf("environment %s: %w: %s", name, ErrTableNotFound, tableName)
+		}
+		n := settings[tableName]
+		if err := decodeOver(&n, tbl); err != nil {
+			return fmt.Errorf("environment %s: table %s: %w", name, tableName, err)
+		}
+	}
+	return nil
+}
+
+// overlay is an environment overlay file next to a config file, e.g. tables.prod.yaml
+// for the prod environment of tables.yaml. Tables and their indexes are keyed by name:
+//
+//	tables:
+//	  orders:
+//	    read_throughput: 50
+//	    indexes:
+//	      by_customer:
+//	        read_throughput: 20
+//
+// Only the keys an overlay contains are overridden, like the settings of environments.
+type overlay struct {
+	Tables map[string]yamlv3.Node `yaml:"tables"`
+}
+
+// overlayPath returns the path of the overlay file of env for the config file at p.
+func overlayPath(p, env string) string {
+	ext := filepath.Ext(p)
+	return strings.TrimSuffix(p, ext) + "." + env + ext
+}
+
+// applyOverlay overrides the tables with the settings of an overlay file, nil if there is none.
+// Tables are overridden in order of their names and fields in the order they are written,
+// tables and indexes which are not in the config are rejected, and with strict unknown fields.
+func applyOverlay(data []byte, tables []TableInfo, strict bool) error {
+	if data == nil {
+		return nil
+	}
+	o := overlay{}
+	if err := yamlv3.Unmarshal(data, &o); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOverlay, err)
+	}
+	if strict {
+		if err := checkOverlayFields(data); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidOverlay, err)
+		}
+	}
+	names := make([]string, 0, len(o.Tables))
+	for name := range o.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		tbl := findTable(tables, name)
+		if tbl == nil {
+			return fmt.Errorf("%w: table %s not found in config", ErrInvalidOverlay, name)
+		}
+		n := o.Tables[name]
+		if n.Kind != yamlv3.MappingNode {
+			return fmt.Errorf("%w: table %s must be a mapping", ErrInvalidOverlay, name)
+		}
+		settings := n
+		settings.Content = []*yamlv3.Node{}
+		var indexes *yamlv3.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == "indexes" {
+				indexes = n.Content[i+1]
+				continue
+			}
+			settings.Content = append(settings.Content, n.Content[i], n.Content[i+1])
+		}
+		if err := decodeOver(&settings, tbl); err != nil {
+			return fmt.Errorf("%w: table %s: %v", ErrInvalidOverlay, name, err)
+		}
+		if indexes == nil {
+			continue
+		}
+		if indexes.Kind != yamlv3.MappingNode {
+			return fmt.Errorf("%w: indexes of table %s must be a mapping of index names", ErrInvalidOverlay, name)
+		}
+		for i := 0; i+1 < len(indexes.Content); i += 2 {
+			indexName := indexes.Content[i].Value
+			index := findIndex(tbl.Indexes, indexName)
+			if index == nil {
+				return fmt.Errorf("%w: index %s of table %s not found in config", ErrInvalidOverlay, indexName, name)
+			}
+			if err := decodeOver(indexes.Content[i+1], index); err != nil {
+				return fmt.Errorf("%w: index %s of table %s: %v", ErrInvalidOverlay, indexName, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkOverlayFields is checkKnownFields for overlay files, whose indexes are keyed by name.
+func checkOverlayFields(data []byte) error {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, doc); err != nil || len(doc.Content) == 0 {
+		return err
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	tableFields := yamlFields(reflect.TypeOf(TableInfo{}))
+	unknown := []string{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if key := root.Content[i].Value; key != "tables" {
+			unknown = append(unknown, key)
+			continue
+		}
+		tables := root.Content[i+1]
+		if tables.Kind != yamlv3.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(tables.Content); j += 2 {
+			path := "tables[" + tables.Content[j].Value + "]"
+			settings := tables.Content[j+1]
+			if settings.Kind != yamlv3.MappingNode {
+				continue
+			}
+			for k := 0; k+1 < len(settings.Content); k += 2 {
+				key, value := settings.Content[k].Value, settings.Content[k+1]
+				if key == "indexes" {
+					unknownFields(value, reflect.TypeOf(map[string]IndexInfo{}), fieldPath(path, key), &unknown)
+					continue
+				}
+				field, ok := tableFields[key]
+				if !ok {
+					unknown = append(unknown, fieldPath(path, key))
+					continue
+				}
+				unknownFields(value, field, fieldPath(path, key), &unknown)
+			}
+		}
+	}
+	return unknownFieldsError(unknown)
+}
+
+// decodeOver re-encodes the settings as written and decodes them over v,
+// so only the keys they contain are overridden.
+func decodeOver(settings *yamlv3.Node, v interface{}) error {
+	b, err := yamlv3.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+func findTable(tables []TableInfo, name string) *TableInfo {
+	for i := range tables {
+		if tables[i].TableName == name {
+			return &tables[i]
+		}
+	}
+	return nil
+}
+
+// findIndex returns the index of the given name. Indexes without a name are named after their template.
+func findIndex(indexes []IndexInfo, name string) *IndexInfo {
+	for i := range indexes {
+		if indexes[i].IndexName == name || indexes[i].IndexName == "" && indexes[i].Template == name {
+			return &indexes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package tables
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFileEnvAliases(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+environments:
+  dev:
+    tables:
+      orders:
+        read_throughput: 1
+        sort_key_type: N
+  prod:
+    tables:
+      orders:
+        read_throughput: 100
+env_aliases:
+  "pr-*": dev
+  "pr-load-*": prod
+tables:
+  - table_name: orders
+    primary_key: id
+    sort_key: created
+    sort_key_type: S
+    read_throughput: 10
+    write_throughput: 10
+`,
+		"unknown.yaml": `
+env_aliases:
+  "pr-*": staging
+tables: []
+`,
+		"typo.yaml": `
+environments:
+  dev:
+    tables:
+      order:
+        read_throughput: 1
+tables:
+  - table_name: orders
+    primary_key: id
+`,
+	})
+	path := filepath.Join(dir, "tables.yaml")
+
+	cases := map[string]int64{
+		"":           10,
+		"prod":       100,
+		"pr-123":     1,
+		"pr-load-42": 100,
+		"staging":    10,
+	}
+	for env, expected := range cases {
+		tables, err := loadFile(path, env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tables[0].ReadThroughput != expected || tables[0].WriteThroughput != 10 {
+			t.Errorf("env %q: expected read throughput %d but got %+v", env, expected, tables[0])
+		}
+	}
+
+	tables, err := loadFile(path, "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables[0].SortKeyType != "N" {
+		t.Fatalf("expected sort key type N but got %q", tables[0].SortKeyType)
+	}
+
+	if _, err := loadFile(filepath.Join(dir, "unknown.yaml"), "pr-1"); !errors.Is(err, ErrUnknownEnvironment) {
+		t.Fatalf("expected ErrUnknownEnvironment but got %v", err)
+	}
+	if _, err := loadFile(filepath.Join(dir, "typo.yaml"), "dev"); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("expected ErrTableNotFound for settings of unknown table but got %v", err)
+	}
+}
+
+func TestLoadFileEnvOverlay(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+environments:
+  prod:
+    tables:
+      orders:
+        read_throughput: 20
+        write_throughput: 20
+tables:
+  - table_name: orders
+    primary_key: id
+    read_throughput: 5
+    write_throughput: 5
+    indexes:
+      - index_name: by_customer
+        primary_key: customer_id
+        primary_key_type: S
+      - index_name: by_status
+        primary_key: status
+        primary_key_type: S
+  - table_name: users
+    primary_key: id
+    read_throughput: 5
+    write_throughput: 5
+`,
+		"tables.prod.yaml": `
+tables:
+  users:
+    ttl:
+      attribute_name: expiry
+      enabled: true
+  orders:
+    read_throughput: 100
+    indexes:
+      by_customer:
+        read_throughput: 50
+        write_throughput: 10
+`,
+		"broken.yaml":      "- table_name: orders\n  primary_key: id\n",
+		"broken.prod.yaml": "tables:\n  orders:\n    indexes:\n      by_email:\n        read_throughput: 5\n",
+	})
+	prod, err := LoadFileEnv(filepath.Join(dir, "tables.yaml"), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, users := prod[0], prod[1]
+	if orders.ReadThroughput != 100 || orders.WriteThroughput != 20 {
+		t.Fatalf("expected overlay on top of environment settings but got %+v", orders)
+	}
+	if index := orders.Indexes[0]; index.ReadThroughput != 50 || index.WriteThroughput != 10 || index.PrimaryKey != "customer_id" {
+		t.Fatalf("expected overridden index throughput but got %+v", index)
+	}
+	if index := orders.Indexes[1]; index.ReadThroughput != 100 || index.WriteThroughput != 20 {
+		t.Fatalf("expected index to inherit overridden table throughput but got %+v", index)
+	}
+	if users.TTL == nil || users.TTL.AttributeName != "expiry" || users.ReadThroughput != 5 {
+		t.Fatalf("expected TTL from overlay but got %+v", users)
+	}
+
+	dev, err := LoadFileEnv(filepath.Join(dir, "tables.yaml"), "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev[0].ReadThroughput != 5 || dev[1].TTL != nil {
+		t.Fatalf("expected base config without overlay but got %+v", dev)
+	}
+
+	if _, err := LoadFileEnv(filepath.Join(dir, "broken.yaml"), "prod"); !errors.Is(err, ErrInvalidOverlay) {
+		t.Fatalf("expected invalid overlay for unknown index but got %v", err)
+	}
+}
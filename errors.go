@@ -12,6 +12,70 @@ var (
 	ErrRequestWithMaxRetry = errors.New("request has reached the maximum number of retry attempts")
 
 	ErrInvalidMigrationInput = errors.New("cannot migrate table input with unrecoverable errors")
+
+	ErrMissingClient = errors.New("either a DynamoDB client or a client factory is required")
+
+	ErrRegionWithoutClientFactory = errors.New("per-table region override requires a client factory")
+
+	ErrChangeBudgetExceeded = errors.New("migration exceeds the change budget")
+
+	ErrCapacityDecreaseNotApproved = errors.New("capacity decrease requires approval")
+
+	ErrWaitTimeout = errors.New("timed out waiting for table or index to become active")
+
+	ErrTableBusy = errors.New("table is not active")
+
+	ErrMigrationAborted = errors.New("migration aborted after an earlier table failed")
+
+	ErrInvalidSelector = errors.New("invalid label selector")
+
+	ErrDeletionNotApproved = errors.New("table deletion requires approval")
+
+	ErrTTLChangeNotApproved = errors.New("disabling or switching TTL requires approval")
+
+	ErrDAXNodeTypeMismatch = errors.New("DAX node type cannot be changed in place")
+
+	ErrRunNotApproved  = errors.New("migration run was not approved")
+	ErrApprovalTimeout = errors.New("timed out waiting for approval")
+	ErrNoApprovers     = errors.New("no approvers allowed")
+
+	ErrBillingModeSwitchLimit = errors.New("billing mode switch limit exceeded")
+
+	ErrMissingTableDescription = errors.New("missing table description")
+
+	ErrIncludeCycle   = errors.New("include cycle")
+	ErrInvalidInclude = errors.New("invalid include")
+
+	ErrUnknownIndexTemplate = errors.New("unknown index template")
+
+	ErrInvalidAttributeType     = errors.New("invalid attribute type")
+	ErrUndeclaredAttributeType  = errors.New("key attribute without type")
+	ErrConflictingAttributeType = errors.New("conflicting attribute types")
+	ErrBillingModeConflict      = errors.New("throughput contradicts billing mode")
+	ErrInvalidProjectionType    = errors.New("invalid projection type")
+	ErrInvalidLifecycle         = errors.New("invalid lifecycle")
+	ErrTagsNotCompared          = errors.New("tags are not compared and cannot be ignored")
+	ErrUnknownField             = errors.New("unknown field")
+
+	ErrUnknownEnvironment = errors.New("unknown environment")
+	ErrInvalidOverlay     = errors.New("invalid config overlay")
+
+	ErrTableNotFound = errors.New("table not found in config")
+
+	ErrAmbiguousTableName = errors.New("ambiguous table name")
+
+	ErrInvalidMigration    = errors.New("invalid migration")
+	ErrDuplicateMigration  = errors.New("duplicate migration version")
+	ErrUnknownMigration    = errors.New("applied migration not found")
+	ErrNoMigrationToRevert = errors.New("no applied migration to revert")
+	ErrUnknownDataStep     = errors.New("unknown data step")
+
+	ErrUnknownPolicyAccess = errors.New("unknown policy access")
+
+	ErrInvalidNamingConvention = errors.New("invalid naming convention")
+
+	ErrEncryptedPlan = errors.New("plan file is encrypted, a KMS client is required")
+	ErrInvalidS3URI  = errors.New("invalid S3 URI")
 )
 
 func IsErrBackwardIncompatible(err error) bool {
@@ -0,0 +1,239 @@
+package tables
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-cmp/cmp"
+)
+
+// differ runs the schema comparisons of a controller. With fast set, hand-written comparers
+// check the values first and cmp.Diff only renders the diff of values which differ, so
+// comparing schemas which are in sync allocates next to nothing, see WithFastDiff.
+//
+// The comparers are conservative: they may report a difference cmp does not see, which
+// costs a cmp.Diff returning an empty diff, but never miss one. Results are therefore the
+// same with and without fast.
+type differ struct {
+	opts []cmp.Option
+	fast bool
+}
+
+// differ returns the differ of the controller. Custom cmp.Options may equate or distinguish
+// anything, the fast comparers are not used with them.
+func (c *Controller) differ() differ {
+	return differ{opts: c.cmpOptions, fast: c.fastDiff && len(c.cmpOptions) == 0}
+}
+
+func (df differ) indexName(name1, name2 *string) string {
+	if df.fast && equalString(name1, name2) {
+		return ""
+	}
+	return DiffIndexName(name1, name2, df.opts...)
+}
+
+func (df differ) throughput(pt1, pt2 *dynamodb.ProvisionedThroughput) string {
+	if df.fast && equalThroughput(pt1, pt2) {
+		return ""
+	}
+	return DiffProvisionedThroughput(pt1, pt2, df.opts...)
+}
+
+func (df differ) keySchema(obj1, obj2 []*dynamodb.KeySchemaElement) string {
+	if df.fast && equalKeySchema(obj1, obj2) {
+		return ""
+	}
+	return DiffKeySchema(obj1, obj2, df.opts...)
+}
+
+func (df differ) attributeDefinitions(obj1, obj2 []*dynamodb.AttributeDefinition) string {
+	if df.fast && equalAttributeDefinitions(obj1, obj2) {
+		return ""
+	}
+	return DiffAttributeDefinitions(obj1, obj2, df.opts...)
+}
+
+func (df differ) projection(p1, p2 *dynamodb.Projection) string {
+	if df.fast && equalNormalizedProjection(p1, p2) {
+		return ""
+	}
+	return DiffProjection(p1, p2, df.opts...)
+}
+
+func (df differ) lsi(input1, input2 []*dynamodb.LocalSecondaryIndex) string {
+	if df.fast && equalLSI(input1, input2) {
+		return ""
+	}
+	return DiffLSI(input1, input2, df.opts...)
+}
+
+func (df differ) ttl(desc1, desc2 *dynamodb.TimeToLiveDescription) string {
+	if df.fast && equalTTL(desc1, desc2) {
+		return ""
+	}
+	return DiffTTL(desc1, desc2, df.opts...)
+}
+
+// equalString reports whether both are nil or point to equal strings, like cmp.Equal.
+func equalString(s1, s2 *string) bool {
+	if s1 == nil || s2 == nil {
+		return s1 == s2
+	}
+	return *s1 == *s2
+}
+
+func equalInt64(i1, i2 *int64) bool {
+	if i1 == nil || i2 == nil {
+		return i1 == i2
+	}
+	return *i1 == *i2
+}
+
+func equalThroughput(pt1, pt2 *dynamodb.ProvisionedThroughput) bool {
+	if pt1 == nil || pt2 == nil {
+		return pt1 == pt2
+	}
+	return equalInt64(pt1.ReadCapacityUnits, pt2.ReadCapacityUnits) &&
+		equalInt64(pt1.WriteCapacityUnits, pt2.WriteCapacityUnits)
+}
+
+// equalKeySchema compares in order. Like cmp, a nil slice differs from an empty one.
+func equalKeySchema(obj1, obj2 []*dynamodb.KeySchemaElement) bool {
+	if (obj1 == nil) != (obj2 == nil) || len(obj1) != len(obj2) {
+		return false
+	}
+	for i := range obj1 {
+		e1, e2 := obj1[i], obj2[i]
+		if e1 == nil || e2 == nil {
+			if e1 != e2 {
+				return false
+			}
+			continue
+		}
+		if !equalString(e1.AttributeName, e2.AttributeName) || !equalString(e1.KeyType, e2.KeyType) {
+			return false
+		}
+	}
+	return true
+}
+
+// equalAttributeDefinitions matches the definitions of obj2 to those of obj1 by name, ignoring
+// the extra definitions of obj1 like DiffAttributeDefinitions. Nil elements or names and
+// duplicate names are left to cmp, since their sort order is not defined.
+func equalAttributeDefinitions(obj1, obj2 []*dynamodb.AttributeDefinition) bool {
+	for i, a := range obj2 {
+		if a == nil || a.AttributeName == nil {
+			return false
+		}
+		for _, b := range obj2[:i] {
+			if *b.AttributeName == *a.AttributeName {
+				return false
+			}
+		}
+	}
+	scoped := 0
+	for _, a := range obj1 {
+		if a == nil || a.AttributeName == nil {
+			return false
+		}
+		var expected *dynamodb.AttributeDefinition
+		for _, b := range obj2 {
+			if *b.AttributeName == *a.AttributeName {
+				expected = b
+				break
+			}
+		}
+		if expected == nil {
+			continue
+		}
+		if !equalString(a.AttributeType, expected.AttributeType) {
+			return false
+		}
+		scoped++
+	}
+	return scoped == len(obj2)
+}
+
+// equalNormalizedProjection compares like DiffProjection: NonKeyAttributes are compared
+// regardless of their order and only for INCLUDE projections, nil and empty are equal.
+// Nil attributes are left to cmp, since their sort order is not defined.
+func equalNormalizedProjection(p1, p2 *dynamodb.Projection) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == p2
+	}
+	if !equalString(p1.ProjectionType, p2.ProjectionType) {
+		return false
+	}
+	if t := aws.StringValue(p1.ProjectionType); t == dynamodb.ProjectionTypeAll || t == dynamodb.ProjectionTypeKeysOnly {
+		return true
+	}
+	if len(p1.NonKeyAttributes) != len(p2.NonKeyAttributes) {
+		return false
+	}
+	for _, a := range p1.NonKeyAttributes {
+		if a == nil {
+			return false
+		}
+		if countAttribute(p1.NonKeyAttributes, *a) != countAttribute(p2.NonKeyAttributes, *a) {
+			return false
+		}
+	}
+	return true
+}
+
+// countAttribute returns the number of non-nil elements of attrs equal to s.
+func countAttribute(attrs []*string, s string) int {
+	n := 0
+	for _, a := range attrs {
+		if a != nil && *a == s {
+			n++
+		}
+	}
+	return n
+}
+
+// equalProjection compares in order, like cmp without options.
+func equalProjection(p1, p2 *dynamodb.Projection) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == p2
+	}
+	if !equalString(p1.ProjectionType, p2.ProjectionType) {
+		return false
+	}
+	if (p1.NonKeyAttributes == nil) != (p2.NonKeyAttributes == nil) || len(p1.NonKeyAttributes) != len(p2.NonKeyAttributes) {
+		return false
+	}
+	for i := range p1.NonKeyAttributes {
+		if !equalString(p1.NonKeyAttributes[i], p2.NonKeyAttributes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalLSI(input1, input2 []*dynamodb.LocalSecondaryIndex) bool {
+	if (input1 == nil) != (input2 == nil) || len(input1) != len(input2) {
+		return false
+	}
+	for i := range input1 {
+		l1, l2 := input1[i], input2[i]
+		if l1 == nil || l2 == nil {
+			if l1 != l2 {
+				return false
+			}
+			continue
+		}
+		if !equalString(l1.IndexName, l2.IndexName) || !equalKeySchema(l1.KeySchema, l2.KeySchema) ||
+			!equalProjection(l1.Projection, l2.Projection) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalTTL(desc1, desc2 *dynamodb.TimeToLiveDescription) bool {
+	if desc1 == nil || desc2 == nil {
+		return desc1 == desc2
+	}
+	return equalString(desc1.AttributeName, desc2.AttributeName) &&
+		equalString(desc1.TimeToLiveStatus, desc2.TimeToLiveStatus)
+}
@@ -0,0 +1,182 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter selects the tables a Validate or Migrate run operates on.
+type Filter func(TableInfo) bool
+
+// NameFilter selects tables whose name exactly matches one of the given names.
+func NameFilter(names ...string) Filter {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return func(tbl TableInfo) bool {
+		return set[tbl.TableName]
+	}
+}
+
+// GlobFilter selects tables whose name matches one of the given glob patterns, e.g. "orders-*".
+// The pattern syntax is the one of path.Match.
+func GlobFilter(patterns ...string) (Filter, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, err
+		}
+	}
+	return func(tbl TableInfo) bool {
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, tbl.TableName); ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// RegexFilter selects tables whose name matches the given regular expression.
+func RegexFilter(expr string) (Filter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(tbl TableInfo) bool {
+		return re.MatchString(tbl.TableName)
+	}, nil
+}
+
+// LabelFilter selects tables matching a label selector such as "team=payments,tier!=critical".
+// Requirements are separated by commas and all of them must match.
+// A requirement is either key=value, key!=value or a bare key requiring the label to be present.
+func LabelFilter(selector string) (Filter, error) {
+	type requirement struct {
+		key, value string
+		op         string
+	}
+	reqs := []requirement{}
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r requirement
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = requirement{key: kv[0], value: kv[1], op: "!="}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = requirement{key: kv[0], value: kv[1], op: "="}
+		default:
+			r = requirement{key: part, op: "exists"}
+		}
+		r.key = strings.TrimSpace(r.key)
+		r.value = strings.TrimSpace(r.value)
+		if r.key == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSelector, selector)
+		}
+		reqs = append(reqs, r)
+	}
+
+	return func(tbl TableInfo) bool {
+		for _, r := range reqs {
+			v, ok := tbl.Labels[r.key]
+			switch r.op {
+			case "=":
+				if !ok || v != r.value {
+					return false
+				}
+			case "!=":
+				if ok && v == r.value {
+					return false
+				}
+			default:
+				if !ok {
+					return false
+				}
+			}
+		}
+		return true
+	}, nil
+}
+
+// filterTables returns the tables selected by f, all tables if f is nil.
+func filterTables(tables []TableInfo, f Filter) []TableInfo {
+	if f == nil {
+		return tables
+	}
+	selected := []TableInfo{}
+	for _, tbl := range tables {
+		if f(tbl) {
+			selected = append(selected, tbl)
+		}
+	}
+	return selected
+}
+
+// managedTables returns the tables which are not marked as unmanaged.
+func managedTables(tables []TableInfo) []TableInfo {
+	return filterTables(tables, func(tbl TableInfo) bool {
+		return !tbl.Unmanaged
+	})
+}
+
+// ValidateFiltered is the same as Validate but only compares the tables selected by f.
+// Validation notifiers are told about the results as a partial validation.
+func (c *Controller) ValidateFiltered(f Filter) ([]*ValidationResult, error) {
+	res, err := c.validate(filterTables(filterTables(c.tenantTables(), c.filter), f))
+	c.notifyValidation(&ValidationRunReport{Env: c.env, Results: res, Partial: true})
+	return res, err
+}
+
+// MigrateFiltered is the same as Migrate but only migrates the results of tables selected by f.
+// Entries of results not selected by f have StatusSkipped in the returned slice.
+func (c *Controller) MigrateFiltered(results []*ValidationResult, f Filter) []*MigrationResult {
+	selected := []*ValidationResult{}
+	index := []int{}
+	for i, r := range results {
+		if f == nil || f(r.TableInput) {
+			selected = append(selected, r)
+			index = append(index, i)
+		}
+	}
+
+	ctx, runID := c.startRun(context.Background())
+	ms := make([]*MigrationResult, len(results))
+	for i, r := range results {
+		ms[i] = &MigrationResult{TableInput: r.TableInput, Status: StatusSkipped, RunID: runID}
+	}
+	for i, m := range c.MigrateWithContext(ctx, selected) {
+		ms[index[i]] = m
+	}
+	return ms
+}
+
+// tenantTables returns the configured tables with a copy of each table per tenant
+// set via WithTenants.
+func (c *Controller) tenantTables() []TableInfo {
+	c.mu.RLock()
+	config := c.Tables
+	c.mu.RUnlock()
+	if len(c.tenants) == 0 {
+		return config
+	}
+	tables := []TableInfo{}
+	for _, tbl := range config {
+		if tbl.Tenant != "" {
+			tables = append(tables, tbl)
+			continue
+		}
+		for _, tenant := range c.tenants {
+			tbl.Tenant = tenant
+			tables = append(tables, tbl)
+		}
+	}
+	return tables
+}
@@ -0,0 +1,105 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	tables := []TableInfo{
+		{TableName: "orders"},
+		{TableName: "orders-archive"},
+		{TableName: "users"},
+	}
+
+	if l := len(filterTables(tables, NameFilter("orders"))); l != 1 {
+		t.Fatalf("expected 1 table but got %d", l)
+	}
+
+	glob, err := GlobFilter("orders*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(filterTables(tables, glob)); l != 2 {
+		t.Fatalf("expected 2 tables but got %d", l)
+	}
+
+	re, err := RegexFilter("^users$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(filterTables(tables, re)); l != 1 {
+		t.Fatalf("expected 1 table but got %d", l)
+	}
+
+	if _, err := GlobFilter("[invalid"); err == nil {
+		t.Fatal("expected error for invalid glob pattern")
+	}
+	if _, err := RegexFilter("(invalid"); err == nil {
+		t.Fatal("expected error for invalid regex")
+	}
+}
+
+func TestLabelFilter(t *testing.T) {
+	tables := []TableInfo{
+		{TableName: "orders", Labels: map[string]string{"team": "payments", "tier": "critical"}},
+		{TableName: "invoices", Labels: map[string]string{"team": "payments"}},
+		{TableName: "users", Labels: map[string]string{"team": "identity"}},
+		{TableName: "unlabelled"},
+	}
+
+	f, err := LabelFilter("team=payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(filterTables(tables, f)); l != 2 {
+		t.Fatalf("expected 2 tables but got %d", l)
+	}
+
+	f, err = LabelFilter("team=payments, tier!=critical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(filterTables(tables, f)); l != 1 {
+		t.Fatalf("expected 1 table but got %d", l)
+	}
+
+	f, err = LabelFilter("team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(filterTables(tables, f)); l != 3 {
+		t.Fatalf("expected 3 tables but got %d", l)
+	}
+
+	if _, err := LabelFilter("=payments"); err == nil {
+		t.Fatal("expected error for invalid selector")
+	}
+	if _, err := NewController(&fakeDynamoDB{}, "test", nil, tables, WithSelector("=payments")); !errors.Is(err, ErrInvalidSelector) {
+		t.Fatalf("expected ErrInvalidSelector from NewController but got %v", err)
+	}
+}
+
+func TestWithTenants(t *testing.T) {
+	c, err := NewController(&fakeDynamoDB{}, "prod", nil, []TableInfo{
+		{Title: "shop", TableName: "orders"},
+		{Title: "shop", TableName: "audit", Tenant: "shared"},
+	}, WithTenants("acme", "globex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, tbl := range c.tenantTables() {
+		names = append(names, physicalName(c.env, tbl))
+	}
+	expected := []string{"shop-prod-acme-orders", "shop-prod-globex-orders", "shop-prod-shared-audit"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected tables %v but got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected tables %v but got %v", expected, names)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tables
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// FingerprintTagKey is the tag holding the fingerprint of the config a table was last migrated to.
+	FingerprintTagKey = "tables:fingerprint"
+	// FingerprintTimeTagKey is the tag holding the time the fingerprint was stored, in RFC 3339 format.
+	FingerprintTimeTagKey = "tables:fingerprint_time"
+)
+
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+// Fingerprint returns a hash of the resolved config of the table in the given environment.
+func Fingerprint(tbl TableInfo, env string) string {
+	// TableInfo only holds plain values and never fails to marshal.
+	b, _ := json.Marshal(struct {
+		Env   string
+		Table TableInfo
+	}{env, tbl})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprint returns the fingerprint of the table, which covers the compliance requirements if set,
+// so tables are compared again once the requirements change.
+func (c *Controller) fingerprint(tbl TableInfo) string {
+	if c.compliance == nil {
+		return Fingerprint(tbl, c.env)
+	}
+	b, _ := json.Marshal(struct {
+		Fingerprint string
+		Compliance  Compliance
+	}{Fingerprint(tbl, c.env), *c.compliance})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// tableARN returns the ARN of the table. It is derived from the region and account ID
+// if both are known and the region belongs to a known partition, e.g. aws-cn, otherwise
+// the table is described.
+func (c *Controller) tableARN(tbl TableInfo) (*string, error) {
+	if region := c.tableRegion(tbl); region != "" && accountIDPattern.MatchString(c.account) {
+		if p, ok := endpoints.PartitionForRegion(endpoints.DefaultPartitions(), region); ok {
+			return aws.String(fmt.Sprintf("arn:%s:dynamodb:%s:%s:table/%s", p.ID(), region, c.account, physicalName(c.env, tbl))), nil
+		}
+	}
+	desc, err := c.describeTable(tbl)
+	if err != nil {
+		return nil, err
+	}
+	return desc.TableArn, nil
+}
+
+// matchesFingerprint reports whether the fingerprint tag of the table matches its config
+// and is not older than the full validation interval.
+func (c *Controller) matchesFingerprint(tbl TableInfo) (bool, error) {
+	arn, err := c.tableARN(tbl)
+	if err != nil {
+		return false, err
+	}
+	tags, err := c.listTags(tbl, arn)
+	if err != nil {
+		return false, err
+	}
+	values := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		values[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+	if values[FingerprintTagKey] != c.fingerprint(tbl) {
+		return false, nil
+	}
+	if c.fullValidationInterval <= 0 {
+		return true, nil
+	}
+	tagged, err := time.Parse(time.RFC3339, values[FingerprintTimeTagKey])
+	if err != nil {
+		return false, nil
+	}
+	return c.clock.Now().Sub(tagged) < c.fullValidationInterval, nil
+}
+
+// tagFingerprint stores the fingerprint of the table's config as a table tag.
+func (c *Controller) tagFingerprint(ctx context.Context, tbl TableInfo) error {
+	arn, err := c.tableARN(tbl)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.TagResourceInput{
+		ResourceArn: arn,
+		Tags: append([]*dynamodb.Tag{
+			{
+				Key:   aws.String(FingerprintTagKey),
+				Value: aws.String(c.fingerprint(tbl)),
+			},
+			{
+				Key:   aws.String(FingerprintTimeTagKey),
+				Value: aws.String(c.clock.Now().UTC().Format(time.RFC3339)),
+			},
+		}, c.runTags(ctx)...),
+	}
+	return c.withRetry(ctx, func() error {
+		if err := c.audit(ctx, tbl, "TagResource", input); err != nil {
+			return err
+		}
+		_, err := c.client(tbl).TagResource(input)
+		return err
+	})
+}
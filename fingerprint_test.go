@@ -0,0 +1,199 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestFastValidate(t *testing.T) {
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1}
+	now := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	tags := []*dynamodb.Tag{
+		{Key: aws.String(FingerprintTagKey), Value: aws.String(Fingerprint(tbl, "test"))},
+		{Key: aws.String(FingerprintTimeTagKey), Value: aws.String("2020-01-01T00:00:00Z")},
+	}
+
+	// describeTable is not stubbed, the table must not be described.
+	db := &fakeDynamoDB{}
+	db.listTags = func(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+		if arn := aws.StringValue(input.ResourceArn); arn != "arn:aws:dynamodb:us-east-1:123456789012:table/orders" {
+			t.Fatalf("unexpected ARN %s", arn)
+		}
+		return &dynamodb.ListTagsOfResourceOutput{Tags: tags}, nil
+	}
+	clock := &fakeClock{now: now}
+	c, err := NewController(db, "test", nil, nil, WithFastValidate(48*time.Hour), WithClock(clock),
+		WithRegion("us-east-1"), WithAccount("123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.compare(tbl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.FingerprintMatched || !r.CanMigrate || r.Diff != "" {
+		t.Fatalf("expected fingerprint match but got %+v", r)
+	}
+
+	// Old fingerprints force a full comparison.
+	clock.now = now.Add(48 * time.Hour)
+	if ok, err := c.matchesFingerprint(tbl); err != nil || ok {
+		t.Fatalf("expected outdated fingerprint to mismatch but got %v, %v", ok, err)
+	}
+	clock.now = now
+
+	tbl.ReadThroughput = 2
+	if Fingerprint(tbl, "test") == aws.StringValue(tags[0].Value) {
+		t.Fatal("expected fingerprint to change with the config")
+	}
+	if ok, err := c.matchesFingerprint(tbl); err != nil || ok {
+		t.Fatalf("expected fingerprint mismatch but got %v, %v", ok, err)
+	}
+}
+
+func TestTableARNPartition(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableArn: aws.String("arn:aws-private:dynamodb:xx-private-1:123456789012:table/orders"),
+		}}, nil
+	}
+	c, err := NewController(db, "", nil, nil, WithClock(&fakeClock{}), WithRegion("cn-north-1"), WithAccount("123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := TableInfo{TableName: "orders"}
+	if arn, err := c.tableARN(tbl); err != nil || aws.StringValue(arn) != "arn:aws-cn:dynamodb:cn-north-1:123456789012:table/orders" {
+		t.Fatalf("expected ARN in the aws-cn partition but got %s, %v", aws.StringValue(arn), err)
+	}
+
+	// Regions of unknown partitions use the ARN of the table description.
+	tbl.Region = "xx-private-1"
+	if arn, err := c.tableARN(tbl); err != nil || aws.StringValue(arn) != "arn:aws-private:dynamodb:xx-private-1:123456789012:table/orders" {
+		t.Fatalf("expected described ARN but got %s, %v", aws.StringValue(arn), err)
+	}
+}
+
+func TestFingerprintIgnoresDescription(t *testing.T) {
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id"}
+	described := tbl
+	described.Description = "add orders"
+	if Fingerprint(tbl, "test") != Fingerprint(described, "test") {
+		t.Fatal("expected description not to change the fingerprint")
+	}
+}
+
+// inSyncDynamoDB returns a fake describing tbl in sync with its config and recording tag inputs.
+func inSyncDynamoDB(tbl TableInfo, tagged *[]*dynamodb.TagResourceInput) *fakeDynamoDB {
+	input := CreateTableInput(tbl, "test")
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName:            input.TableName,
+			TableStatus:          aws.String(dynamodb.TableStatusActive),
+			KeySchema:            input.KeySchema,
+			AttributeDefinitions: input.AttributeDefinitions,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+			},
+		}}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled),
+		}}, nil
+	}
+	db.tagResource = func(input *dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error) {
+		*tagged = append(*tagged, input)
+		return &dynamodb.TagResourceOutput{}, nil
+	}
+	return db
+}
+
+func TestValidateTagsInSyncTables(t *testing.T) {
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1}
+	tagged := []*dynamodb.TagResourceInput{}
+	db := inSyncDynamoDB(tbl, &tagged)
+	c, err := NewController(db, "test", nil, []TableInfo{tbl}, WithFingerprints(), WithClock(&fakeClock{}),
+		WithRegion("us-east-1"), WithAccount("123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Validate makes no changes unless fingerprint refresh is enabled.
+	if _, err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 0 {
+		t.Fatalf("expected no TagResource call but got %v", tagged)
+	}
+
+	c.refreshFingerprints = true
+	if _, err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 1 || aws.StringValue(tagged[0].Tags[0].Value) != Fingerprint(tbl, "test") {
+		t.Fatalf("expected in-sync table to be tagged with its fingerprint but got %v", tagged)
+	}
+
+	// Tables with a diff are tagged by Migrate only.
+	c.Tables[0].ReadThroughput = 2
+	if _, err := c.Validate(); err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	if len(tagged) != 1 {
+		t.Fatalf("expected table with a diff not to be tagged but got %v", tagged)
+	}
+}
+
+func TestFastValidateRefreshesFingerprint(t *testing.T) {
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tagged := []*dynamodb.TagResourceInput{}
+	db := inSyncDynamoDB(tbl, &tagged)
+	db.listTags = func(*dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+		if len(tagged) == 0 {
+			return &dynamodb.ListTagsOfResourceOutput{}, nil
+		}
+		return &dynamodb.ListTagsOfResourceOutput{Tags: tagged[len(tagged)-1].Tags}, nil
+	}
+	clock := &fakeClock{now: now}
+	c, err := NewController(db, "test", nil, []TableInfo{tbl}, WithFastValidate(48*time.Hour), WithFingerprintRefresh(),
+		WithClock(clock), WithRegion("us-east-1"), WithAccount("123456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each full comparison refreshes the fingerprint time, so the following run is fast again.
+	for i, step := range []struct {
+		after   time.Duration
+		matched bool
+	}{
+		{0, false},
+		{time.Hour, true},
+		{49 * time.Hour, false},
+		{50 * time.Hour, true},
+		{98 * time.Hour, false},
+		{99 * time.Hour, true},
+	} {
+		clock.now = now.Add(step.after)
+		results, err := c.Validate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0].FingerprintMatched != step.matched {
+			t.Fatalf("run %d: expected fingerprint matched %v but got %+v", i, step.matched, results[0])
+		}
+	}
+	if len(tagged) != 3 {
+		t.Fatalf("expected a tag per full comparison but got %d", len(tagged))
+	}
+	if v := aws.StringValue(tagged[2].Tags[1].Value); v != "2020-01-05T02:00:00Z" {
+		t.Fatalf("expected fingerprint time of the last full comparison but got %s", v)
+	}
+}
@@ -0,0 +1,203 @@
+package tables
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DefaultFleetConcurrency is the maximum number of tables ValidateFleet compares at the same
+// time unless set via WithFleetConcurrency.
+const DefaultFleetConcurrency = 32
+
+// fleetMaxAttempts is the number of times a throttled table is compared before its error is reported.
+const fleetMaxAttempts = 5
+
+// fleetBackoff returns the delay before comparing a throttled table again.
+var fleetBackoff = ExponentialBackoff(time.Second, 30*time.Second)
+
+// ValidateFleet compares the managed tables like Validate but is tuned for configs with
+// thousands of tables:
+//   - the existing tables are primed with paginated ListTables calls per region, so missing
+//     tables are reported without describing them,
+//   - tables are compared by a bounded number of workers whose concurrency is halved when
+//     DynamoDB throttles and grows again with successful comparisons,
+//   - results are passed to fn as soon as they are available instead of being collected,
+//     so memory does not grow with the number of tables unless a ValidationNotifier is registered.
+//
+// fn is called from the calling goroutine in completion order. The returned error is the same
+// as Validate's, or the error of ctx if it is done before all tables were compared.
+// Tables created between priming and their comparison are reported as missing.
+// The results are reported to every registered ValidationNotifier like those of Validate.
+func (c *Controller) ValidateFleet(ctx context.Context, fn func(*ValidationResult)) error {
+	tables := managedTables(filterTables(c.tenantTables(), c.filter))
+	existing := c.listExisting(tables)
+	limiter := newAdaptiveLimiter(c.fleetConcurrency)
+
+	work := make(chan TableInfo)
+	go func() {
+		defer close(work)
+		for _, tbl := range tables {
+			select {
+			case work <- tbl:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan *ValidationResult, c.fleetConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < c.fleetConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tbl := range work {
+				results <- c.validateWith(ctx, tbl, func(tbl TableInfo) (*ValidationResult, error) {
+					return c.compareFleet(ctx, tbl, existing, limiter)
+				})
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Results are only kept for validation notifiers, if any.
+	notify := c.hasValidationNotifiers()
+	report := &ValidationRunReport{Env: c.env}
+	outcome := validationOutcome{}
+	for r := range results {
+		outcome.add(r)
+		if notify {
+			report.Results = append(report.Results, r)
+		}
+		fn(r)
+	}
+	report.Partial = ctx.Err() != nil
+	c.notifyValidation(report)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return outcome.err()
+}
+
+// compareFleet compares a table unless priming found it missing. Throttled comparisons are
+// retried with backoff and reduce the concurrency of the limiter.
+func (c *Controller) compareFleet(ctx context.Context, tbl TableInfo, existing map[string]map[string]bool, limiter *adaptiveLimiter) (*ValidationResult, error) {
+	if names, ok := existing[c.tableRegion(tbl)]; ok && !names[physicalName(c.env, tbl)] {
+		return c.missingResult(tbl), nil
+	}
+	for attempt := 1; ; attempt++ {
+		limiter.acquire()
+		result, err := c.compare(tbl)
+		throttled := isThrottling(err)
+		limiter.release(throttled)
+		if !throttled || attempt >= fleetMaxAttempts {
+			return result, err
+		}
+		if err := c.sleepWithContext(ctx, fleetBackoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// listExisting returns the names of the given tables which exist in DynamoDB keyed by region.
+// Regions whose tables cannot be listed are left out, their tables are described instead.
+func (c *Controller) listExisting(tables []TableInfo) map[string]map[string]bool {
+	managed := map[string]map[string]bool{}
+	clients := map[string]DynamoDBAPI{}
+	for _, tbl := range tables {
+		region := c.tableRegion(tbl)
+		if managed[region] == nil {
+			managed[region] = map[string]bool{}
+			clients[region] = c.client(tbl)
+		}
+		managed[region][physicalName(c.env, tbl)] = true
+	}
+
+	existing := make(map[string]map[string]bool, len(clients))
+	for region, client := range clients {
+		names := map[string]bool{}
+		err := client.ListTablesPages(&dynamodb.ListTablesInput{}, func(page *dynamodb.ListTablesOutput, last bool) bool {
+			for _, name := range page.TableNames {
+				if managed[region][aws.StringValue(name)] {
+					names[aws.StringValue(name)] = true
+				}
+			}
+			return true
+		})
+		if err != nil {
+			c.Log.Errorf("List tables in region [%s] with error: %v", region, err)
+			continue
+		}
+		existing[region] = names
+	}
+	return existing
+}
+
+// isThrottling reports whether err is caused by exceeding the control plane request rate.
+func isThrottling(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case ErrCodeThrottlingException, dynamodb.ErrCodeRequestLimitExceeded:
+		return true
+	}
+	return false
+}
+
+// adaptiveLimiter bounds the number of concurrent requests. The limit is halved on throttling
+// and grows by one after as many successful requests as the current limit, up to max.
+type adaptiveLimiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+}
+
+func newAdaptiveLimiter(max int) *adaptiveLimiter {
+	l := &adaptiveLimiter{limit: max, max: max}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+func (l *adaptiveLimiter) acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for l.inFlight >= l.limit {
+		l.cond.Wait()
+	}
+	l.inFlight++
+}
+
+func (l *adaptiveLimiter) release(throttled bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if throttled {
+		l.successes = 0
+		if l.limit > 1 {
+			l.limit /= 2
+		}
+	} else if l.successes++; l.successes >= l.limit && l.limit < l.max {
+		l.successes = 0
+		l.limit++
+	}
+	l.cond.Broadcast()
+}
+
+func (l *adaptiveLimiter) current() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
@@ -0,0 +1,153 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Info(args ...interface{})                    {}
+func (nopLogger) Infof(template string, args ...interface{})  {}
+func (nopLogger) Error(args ...interface{})                   {}
+func (nopLogger) Errorf(template string, args ...interface{}) {}
+
+// fleetDynamoDB serves the given existing tables in pages of two table names.
+func fleetDynamoDB(existing []TableInfo) *fakeDynamoDB {
+	descs := map[string]*dynamodb.TableDescription{}
+	names := []string{}
+	index := map[string]int{}
+	for _, tbl := range existing {
+		input := CreateTableInput(tbl, "")
+		name := aws.StringValue(input.TableName)
+		index[name] = len(names)
+		names = append(names, name)
+		descs[name] = &dynamodb.TableDescription{
+			TableName:            input.TableName,
+			TableStatus:          aws.String(dynamodb.TableStatusActive),
+			AttributeDefinitions: input.AttributeDefinitions,
+			KeySchema:            input.KeySchema,
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  input.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: input.ProvisionedThroughput.WriteCapacityUnits,
+			},
+		}
+	}
+
+	db := &fakeDynamoDB{}
+	db.listTables = func(input *dynamodb.ListTablesInput) (*dynamodb.ListTablesOutput, error) {
+		start := 0
+		if input.ExclusiveStartTableName != nil {
+			start = index[aws.StringValue(input.ExclusiveStartTableName)] + 1
+		}
+		end := start + 2
+		output := &dynamodb.ListTablesOutput{}
+		if end < len(names) {
+			output.LastEvaluatedTableName = aws.String(names[end-1])
+		} else {
+			end = len(names)
+		}
+		output.TableNames = aws.StringSlice(names[start:end])
+		return output, nil
+	}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		desc, ok := descs[aws.StringValue(input.TableName)]
+		if !ok {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: desc}, nil
+	}
+	return db
+}
+
+func TestValidateFleet(t *testing.T) {
+	tables := []TableInfo{}
+	for i := 0; i < 5; i++ {
+		tables = append(tables, TableInfo{TableName: fmt.Sprintf("table-%d", i), PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1})
+	}
+	db := fleetDynamoDB(tables[:4])
+	var mu sync.Mutex
+	described := map[string]int{}
+	describe := db.describeTable
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		name := aws.StringValue(input.TableName)
+		described[name]++
+		// table-0 is throttled twice before it is described
+		if name == "table-0" && described[name] <= 2 {
+			return nil, awserr.New(ErrCodeThrottlingException, "rate exceeded", nil)
+		}
+		return describe(input)
+	}
+	c, err := NewController(db, "", nopLogger{}, tables, WithClock(&fakeClock{}), WithFleetConcurrency(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]*ValidationResult{}
+	err = c.ValidateFleet(context.Background(), func(r *ValidationResult) {
+		results[r.TableInput.TableName] = r
+	})
+	if err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results but got %d", len(results))
+	}
+	if r := results["table-4"]; r.CreateTableInput == nil || described["table-4"] > 0 {
+		t.Fatalf("expected primed missing table without describe but got %+v, %d describes", r, described["table-4"])
+	}
+	if r := results["table-0"]; r.Error != nil || r.Diff != "" || described["table-0"] != 3 {
+		t.Fatalf("expected throttled table to be compared after retries but got %+v, %d describes", r, described["table-0"])
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+	l.acquire()
+	l.release(true)
+	l.acquire()
+	l.release(true)
+	if n := l.current(); n != 2 {
+		t.Fatalf("expected limit to be halved twice but got %d", n)
+	}
+	for i := 0; i < 2; i++ {
+		l.acquire()
+		l.release(false)
+	}
+	if n := l.current(); n != 3 {
+		t.Fatalf("expected limit to grow after successes but got %d", n)
+	}
+}
+
+func BenchmarkValidateFleet(b *testing.B) {
+	for _, n := range []int{100, 1000, 5000} {
+		tables := make([]TableInfo, n)
+		for i := range tables {
+			tables[i] = TableInfo{TableName: fmt.Sprintf("table-%05d", i), PrimaryKey: "id", ReadThroughput: 1, WriteThroughput: 1}
+		}
+		// Half of the tables exist.
+		db := fleetDynamoDB(tables[:n/2])
+		c, err := NewController(db, "", nopLogger{}, tables)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprintf("tables=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				count := 0
+				c.ValidateFleet(context.Background(), func(*ValidationResult) { count++ })
+				if count != n {
+					b.Fatalf("expected %d results but got %d", n, count)
+				}
+			}
+		})
+	}
+}
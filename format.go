@@ -0,0 +1,136 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// String returns a concise one-line description of the result,
+// e.g. "orders: +1 GSI, throughput 5/5→10/10 (compatible)".
+func (r *ValidationResult) String() string {
+	name := r.TableInput.TableName
+	if r.Error != nil {
+		return fmt.Sprintf("%s: error: %v", name, r.Error)
+	}
+	if r.incompatible() {
+		return fmt.Sprintf("%s: %s (incompatible)", name, r.Diff)
+	}
+
+	parts := []string{}
+	if r.CreateTableInput != nil {
+		parts = append(parts, "create table")
+	}
+	newIndexes := 0
+	for _, input := range r.UpdateTableInput {
+		if pt := input.ProvisionedThroughput; pt != nil {
+			parts = append(parts, fmt.Sprintf("throughput %s→%s", currentThroughput(r.TableDescription), formatThroughput(pt)))
+		}
+		if aws.BoolValue(input.DeletionProtectionEnabled) {
+			parts = append(parts, "enable deletion protection")
+		}
+		if sse := input.SSESpecification; sse != nil {
+			parts = append(parts, "encrypt with "+aws.StringValue(sse.KMSMasterKeyId))
+		}
+		for _, u := range input.GlobalSecondaryIndexUpdates {
+			if u.Create != nil {
+				newIndexes++
+			}
+			if u.Update != nil {
+				parts = append(parts, fmt.Sprintf("GSI %s throughput %s→%s",
+					aws.StringValue(u.Update.IndexName),
+					currentIndexThroughput(r.TableDescription, aws.StringValue(u.Update.IndexName)),
+					formatThroughput(u.Update.ProvisionedThroughput),
+				))
+			}
+		}
+	}
+	if newIndexes > 0 {
+		parts = append(parts, fmt.Sprintf("+%d GSI", newIndexes))
+	}
+	if r.UpdateTTLInput != nil && r.UpdateTTLInput.TimeToLiveSpecification != nil {
+		spec := r.UpdateTTLInput.TimeToLiveSpecification
+		state := "disabled"
+		if aws.BoolValue(spec.Enabled) {
+			state = "enabled"
+		}
+		parts = append(parts, fmt.Sprintf("TTL %s on %s", state, aws.StringValue(spec.AttributeName)))
+	}
+	if r.UpdateContinuousBackupsInput != nil {
+		parts = append(parts, "enable point in time recovery")
+	}
+	if r.DeleteTableInput != nil {
+		parts = append(parts, "delete deprecated table")
+	}
+
+	if len(parts) == 0 {
+		if len(r.Warnings) > 0 {
+			return fmt.Sprintf("%s: in sync, %s", name, strings.Join(r.Warnings, ", "))
+		}
+		return fmt.Sprintf("%s: in sync", name)
+	}
+	return fmt.Sprintf("%s: %s (compatible)", name, strings.Join(parts, ", "))
+}
+
+// String returns a concise one-line description of the result,
+// e.g. "orders: Applied 2 changes" or "orders: Failed: <errors>".
+func (m *MigrationResult) String() string {
+	name := m.TableInput.TableName
+	s := fmt.Sprintf("%s: %s %d changes", name, m.Status, len(m.Applied))
+	if len(m.Skipped) > 0 {
+		s = fmt.Sprintf("%s, skipped %d", s, len(m.Skipped))
+	}
+	if len(m.Errors) > 0 {
+		errs := make([]string, len(m.Errors))
+		for i, err := range m.Errors {
+			errs[i] = err.Error()
+		}
+		s = fmt.Sprintf("%s: %s", s, strings.Join(errs, "; "))
+	}
+	return s
+}
+
+// String returns one line per table followed by the number of warnings and errors.
+func (s *ValidationSummary) String() string {
+	lines := []string{}
+	for _, r := range s.Results {
+		lines = append(lines, r.String())
+	}
+	lines = append(lines, fmt.Sprintf("%d warnings, %d errors", len(s.Warnings), len(s.Errors)))
+	return strings.Join(lines, "\n")
+}
+
+func formatThroughput(pt *dynamodb.ProvisionedThroughput) string {
+	if pt == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", aws.Int64Value(pt.ReadCapacityUnits), aws.Int64Value(pt.WriteCapacityUnits))
+}
+
+func formatThroughputDescription(pt *dynamodb.ProvisionedThroughputDescription) string {
+	if pt == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", aws.Int64Value(pt.ReadCapacityUnits), aws.Int64Value(pt.WriteCapacityUnits))
+}
+
+func currentThroughput(desc *dynamodb.TableDescription) string {
+	if desc == nil {
+		return "-"
+	}
+	return formatThroughputDescription(desc.ProvisionedThroughput)
+}
+
+func currentIndexThroughput(desc *dynamodb.TableDescription, indexName string) string {
+	if desc == nil {
+		return "-"
+	}
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexName) == indexName {
+			return formatThroughputDescription(gsi.ProvisionedThroughput)
+		}
+	}
+	return "-"
+}
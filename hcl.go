@@ -0,0 +1,94 @@
+package tables
+
+import (
+	"io/ioutil"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// hclConfig is the HCL form of a config file, e.g.
+//
+//	attribute_types = {
+//	  timestamp = "N"
+//	}
+//
+//	index_template "by_created" {
+//	  primary_key      = "created"
+//	  primary_key_type = "timestamp"
+//	}
+//
+//	table "orders" {
+//	  primary_key      = "id"
+//	  read_throughput  = 5
+//	  write_throughput = 5
+//
+//	  index "by_customer" {
+//	    primary_key      = "customer_id"
+//	    primary_key_type = "S"
+//	  }
+//
+//	  ttl {
+//	    attribute_name = "expiry"
+//	    enabled        = true
+//	  }
+//	}
+//
+// A defaults block sets the same defaults as the defaults section of yaml configs.
+// Block labels are the names of tables, indexes, attributes and the regions of replicas.
+// Environments, alarms and DAX clusters are only supported by yaml configs.
+type hclConfig struct {
+	AttributeTypes map[string]string `hcl:"attribute_types,optional"`
+	IndexTemplates []IndexInfo       `hcl:"index_template,block"`
+	Defaults       *configDefaults   `hcl:"defaults,block"`
+	Tables         []TableInfo       `hcl:"table,block"`
+}
+
+// LoadHCL loads the HCL config file at path, see DecodeHCL.
+func LoadHCL(path string) ([]TableInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeHCL(data, path)
+}
+
+// DecodeHCL decodes a config of table blocks with nested index and ttl blocks, so schemas can
+// live next to Terraform-style files. The filename is used in error messages only.
+// Unknown attributes and blocks are errors.
+func DecodeHCL(data []byte, filename string) ([]TableInfo, error) {
+	file, diags := hclparse.NewParser().ParseHCL(data, filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	cfg := hclConfig{}
+	if diags := gohcl.DecodeBody(file.Body, nil, &cfg); diags.HasErrors() {
+		return nil, diags
+	}
+
+	templates := make(map[string]IndexInfo, len(cfg.IndexTemplates))
+	for _, tpl := range cfg.IndexTemplates {
+		templates[tpl.IndexName] = tpl
+	}
+	tables := cfg.Tables
+	if tables == nil {
+		tables = []TableInfo{}
+	}
+	// Missing blocks decode as empty slices, yaml configs leave them nil.
+	for i := range tables {
+		tbl := &tables[i]
+		if len(tbl.Indexes) == 0 {
+			tbl.Indexes = nil
+		}
+		if len(tbl.Replicas) == 0 {
+			tbl.Replicas = nil
+		}
+		if len(tbl.Attributes) == 0 {
+			tbl.Attributes = nil
+		}
+	}
+	if err := prepareTables(tables, cfg.Defaults, templates, cfg.AttributeTypes); err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
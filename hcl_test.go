@@ -0,0 +1,90 @@
+package tables
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeHCL(t *testing.T) {
+	config := `
+attribute_types = {
+  timestamp = "N"
+}
+
+index_template "by_created" {
+  primary_key      = "created"
+  primary_key_type = "timestamp"
+}
+
+table "orders" {
+  primary_key      = "id"
+  sort_key         = "created"
+  sort_key_type    = "timestamp"
+  read_throughput  = 5
+  write_throughput = 2
+  labels = {
+    team = "payments"
+  }
+
+  index "by_customer" {
+    primary_key       = "customer_id"
+    primary_key_type  = "S"
+    projection_fields = ["status"]
+  }
+
+  index "by_created" {
+    template = "by_created"
+  }
+
+  ttl {
+    attribute_name = "expiry"
+    enabled        = true
+  }
+}
+
+table "users" {
+  primary_key  = "id"
+  billing_mode = "PAY_PER_REQUEST"
+}
+`
+	tbl, err := DecodeHCL([]byte(config), "tables.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbl) != 2 || tbl[0].TableName != "orders" || tbl[1].TableName != "users" {
+		t.Fatalf("expected orders and users but got %+v", tbl)
+	}
+	orders := tbl[0]
+	if orders.SortKeyType != "N" || orders.ReadThroughput != 5 || orders.Labels["team"] != "payments" {
+		t.Fatalf("unexpected table %+v", orders)
+	}
+	if len(orders.Indexes) != 2 {
+		t.Fatalf("expected 2 indexes but got %+v", orders.Indexes)
+	}
+	if index := orders.Indexes[0]; index.IndexName != "by_customer" || index.ReadThroughput != 5 || index.WriteThroughput != 2 ||
+		len(index.ProjectedFields) != 1 {
+		t.Fatalf("unexpected index %+v", index)
+	}
+	if index := orders.Indexes[1]; index.IndexName != "by_created" || index.PrimaryKey != "created" || index.PrimaryKeyType != "N" {
+		t.Fatalf("expected templated index but got %+v", index)
+	}
+	if orders.TTL == nil || orders.TTL.AttributeName != "expiry" || !orders.TTL.Enabled {
+		t.Fatalf("unexpected ttl %+v", orders.TTL)
+	}
+	if users := tbl[1]; users.Indexes != nil || users.TTL != nil {
+		t.Fatalf("expected users without indexes and ttl but got %+v", users)
+	}
+}
+
+func TestDecodeHCLErrors(t *testing.T) {
+	_, err := DecodeHCL([]byte("table \"orders\" {\n  primary_key = \"id\"\n  read_thoughput = 5\n}\n"), "tables.hcl")
+	if err == nil || !strings.Contains(err.Error(), "read_thoughput") || !strings.Contains(err.Error(), "tables.hcl:3") {
+		t.Fatalf("expected unknown attribute error but got %v", err)
+	}
+
+	_, err = DecodeHCL([]byte("table \"orders\" {\n  primary_key = \"id\"\n  billing_mode = \"PAY_PER_REQUEST\"\n  read_throughput = 5\n}\n"), "tables.hcl")
+	if !errors.Is(err, ErrBillingModeConflict) {
+		t.Fatalf("expected billing mode conflict but got %v", err)
+	}
+}
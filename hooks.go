@@ -0,0 +1,98 @@
+package tables
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// CreateTableInputHook adjusts a CreateTableInput right before it is sent,
+// e.g. to inject an SSESpecification or tags.
+type CreateTableInputHook func(*dynamodb.CreateTableInput)
+
+// UpdateTableInputHook adjusts an UpdateTableInput right before it is sent.
+type UpdateTableInputHook func(*dynamodb.UpdateTableInput)
+
+// UpdateTTLInputHook adjusts an UpdateTimeToLiveInput right before it is sent.
+type UpdateTTLInputHook func(*dynamodb.UpdateTimeToLiveInput)
+
+// inputHooks holds the input hooks registered via options, called in registration order.
+type inputHooks struct {
+	createTable []CreateTableInputHook
+	updateTable []UpdateTableInputHook
+	updateTTL   []UpdateTTLInputHook
+}
+
+func (h inputHooks) onCreateTable(input *dynamodb.CreateTableInput) {
+	for _, hook := range h.createTable {
+		hook(input)
+	}
+}
+
+func (h inputHooks) onUpdateTable(input *dynamodb.UpdateTableInput) {
+	for _, hook := range h.updateTable {
+		hook(input)
+	}
+}
+
+func (h inputHooks) onUpdateTTL(input *dynamodb.UpdateTimeToLiveInput) {
+	for _, hook := range h.updateTTL {
+		hook(input)
+	}
+}
+
+// HookEvent describes a change passed to lifecycle hooks.
+type HookEvent struct {
+	// TableName is the name of the table in the config.
+	TableName string
+	Table     TableInfo
+	Type      ChangeType
+	// Input is the AWS request input of the change, e.g. *dynamodb.UpdateTableInput.
+	Input interface{}
+	// Error is set for OnError.
+	Error error
+	// RunID is the ID of the run applying the change, see ContextWithRunID.
+	RunID string
+	// Description is the stated intent of the change, see TableInfo.Description.
+	Description string
+}
+
+// Hooks are lifecycle callbacks for custom side effects per table, such as cache warmup,
+// notifications or permission grants. Create hooks are called around CreateTable until the
+// table is ACTIVE, update hooks around UpdateTable and UpdateTimeToLive. OnError is called
+// instead of the after hook if a change fails. Nil hooks are skipped.
+type Hooks struct {
+	BeforeCreate func(HookEvent)
+	AfterCreate  func(HookEvent)
+	BeforeUpdate func(HookEvent)
+	AfterUpdate  func(HookEvent)
+	OnError      func(HookEvent)
+}
+
+// run calls the hooks of the change type around op.
+func (h Hooks) run(ctx context.Context, tbl TableInfo, typ ChangeType, input interface{}, op func() error) error {
+	before, after := h.BeforeUpdate, h.AfterUpdate
+	if typ == ChangeCreateTable {
+		before, after = h.BeforeCreate, h.AfterCreate
+	}
+	ev := HookEvent{TableName: tbl.TableName, Table: tbl, Type: typ, Input: input, RunID: RunIDFromContext(ctx),
+		Description: changeDescription(ctx, tbl.Description)}
+	if before != nil {
+		before(ev)
+	}
+	if err := op(); err != nil {
+		// Tables found existing by CreateAll were neither created nor failed.
+		if err == errTableExists {
+			return err
+		}
+		ev.Error = err
+		if h.OnError != nil {
+			h.OnError(ev)
+		}
+		return err
+	}
+	if after != nil {
+		after(ev)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package tables
+
+import "fmt"
+
+// PolicyAccess selects the permissions of a generated IAM policy.
+type PolicyAccess string
+
+const (
+	// PolicyDescribe allows describing the tables, their TTL, backups and tags.
+	PolicyDescribe PolicyAccess = "describe"
+	// PolicyCRUD allows reading and writing items of the tables and querying their indexes.
+	PolicyCRUD PolicyAccess = "crud"
+	// PolicyStreams allows reading the streams of the tables.
+	PolicyStreams PolicyAccess = "streams"
+	// PolicyMigrate allows everything Validate and Migrate need, including the migrations table.
+	// ListTables does not support resource-level permissions and is not part of the policy,
+	// neither is Scan, which is only needed for WithTTLSampling.
+	PolicyMigrate PolicyAccess = "migrate"
+)
+
+var policyActions = map[PolicyAccess][]string{
+	PolicyDescribe: {
+		"dynamodb:DescribeTable",
+		"dynamodb:DescribeTimeToLive",
+		"dynamodb:DescribeContinuousBackups",
+		"dynamodb:ListTagsOfResource",
+	},
+	PolicyCRUD: {
+		"dynamodb:GetItem",
+		"dynamodb:BatchGetItem",
+		"dynamodb:Query",
+		"dynamodb:Scan",
+		"dynamodb:PutItem",
+		"dynamodb:UpdateItem",
+		"dynamodb:DeleteItem",
+		"dynamodb:BatchWriteItem",
+		"dynamodb:ConditionCheckItem",
+	},
+	PolicyStreams: {
+		"dynamodb:DescribeStream",
+		"dynamodb:GetRecords",
+		"dynamodb:GetShardIterator",
+	},
+	PolicyMigrate: {
+		"dynamodb:DescribeTable",
+		"dynamodb:DescribeTimeToLive",
+		"dynamodb:DescribeContinuousBackups",
+		"dynamodb:ListTagsOfResource",
+		"dynamodb:CreateTable",
+		"dynamodb:UpdateTable",
+		"dynamodb:DeleteTable",
+		"dynamodb:UpdateTimeToLive",
+		"dynamodb:UpdateContinuousBackups",
+		"dynamodb:TagResource",
+		"dynamodb:UntagResource",
+	},
+}
+
+// migrationsTableActions are the actions on the migrations table, see ApplyMigrations.
+var migrationsTableActions = []string{
+	"dynamodb:DescribeTable",
+	"dynamodb:CreateTable",
+	"dynamodb:Scan",
+	"dynamodb:PutItem",
+	"dynamodb:DeleteItem",
+}
+
+// PolicyDocument is an IAM policy document, marshal it with encoding/json.
+type PolicyDocument struct {
+	Version   string
+	Statement []PolicyStatement
+}
+
+// PolicyStatement is a single statement of a PolicyDocument.
+type PolicyStatement struct {
+	Sid      string
+	Effect   string
+	Action   []string
+	Resource []string
+}
+
+// IAMPolicy generates a least-privilege IAM policy granting the selected access to exactly the
+// managed tables of the controller, their indexes and streams, with one statement per access.
+// ARNs use the region of each table and the account set via WithAccount, a wildcard if unknown.
+// Stream ARNs end with a wildcard, as the stream label changes whenever a stream is re-enabled.
+func (c *Controller) IAMPolicy(access ...PolicyAccess) (*PolicyDocument, error) {
+	doc := &PolicyDocument{Version: "2012-10-17", Statement: []PolicyStatement{}}
+	tables := managedTables(c.tenantTables())
+	for _, a := range access {
+		actions, ok := policyActions[a]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPolicyAccess, a)
+		}
+		resources := []string{}
+		for _, tbl := range tables {
+			arn := c.policyTableARN(c.tableRegion(tbl), physicalName(c.env, tbl))
+			switch a {
+			case PolicyStreams:
+				resources = append(resources, arn+"/stream/*")
+			case PolicyCRUD:
+				resources = append(resources, arn)
+				for _, index := range tbl.Indexes {
+					resources = append(resources, arn+"/index/"+index.IndexName)
+				}
+			default:
+				resources = append(resources, arn)
+			}
+		}
+		if len(resources) == 0 {
+			continue
+		}
+		doc.Statement = append(doc.Statement, PolicyStatement{
+			Sid:      policySid(a),
+			Effect:   "Allow",
+			Action:   actions,
+			Resource: resources,
+		})
+		if a == PolicyMigrate {
+			doc.Statement = append(doc.Statement, PolicyStatement{
+				Sid:      "TablesMigrations",
+				Effect:   "Allow",
+				Action:   migrationsTableActions,
+				Resource: []string{c.policyTableARN(c.region, c.migrationsTableName())},
+			})
+		}
+	}
+	return doc, nil
+}
+
+// policyTableARN returns the ARN of a table, with wildcards for an unknown region or account.
+func (c *Controller) policyTableARN(region, name string) string {
+	account := c.account
+	if region == "" {
+		region = "*"
+	}
+	if !accountIDPattern.MatchString(account) {
+		account = "*"
+	}
+	return fmt.Sprintf("arn:aws:dynamodb:%s:%s:table/%s", region, account, name)
+}
+
+// policySid returns the statement ID of an access, e.g. TablesCRUD.
+func policySid(a PolicyAccess) string {
+	switch a {
+	case PolicyCRUD:
+		return "TablesCRUD"
+	case PolicyDescribe:
+		return "TablesDescribe"
+	case PolicyStreams:
+		return "TablesStreams"
+	}
+	return "TablesMigrate"
+}
@@ -0,0 +1,43 @@
+package tables
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestIAMPolicy(t *testing.T) {
+	c, err := NewController(&fakeDynamoDB{}, "prod", nil, []TableInfo{
+		{Title: "shop", TableName: "orders", Indexes: []IndexInfo{{IndexName: "by_user"}}},
+		{Title: "shop", TableName: "users", Region: "eu-west-1"},
+		{TableName: "legacy", Unmanaged: true},
+	}, WithClock(&fakeClock{}), WithRegion("us-east-1"), WithAccount("123456789012"), WithClientFactory(func(region, account string) DynamoDBAPI {
+		return &fakeDynamoDB{}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := c.IAMPolicy(PolicyCRUD, PolicyMigrate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Statement) != 3 {
+		t.Fatalf("expected crud, migrate and migrations table statements but got %+v", doc.Statement)
+	}
+	wantCRUD := []string{
+		"arn:aws:dynamodb:us-east-1:123456789012:table/shop-prod-orders",
+		"arn:aws:dynamodb:us-east-1:123456789012:table/shop-prod-orders/index/by_user",
+		"arn:aws:dynamodb:eu-west-1:123456789012:table/shop-prod-users",
+	}
+	if !reflect.DeepEqual(doc.Statement[0].Resource, wantCRUD) {
+		t.Fatalf("expected %v but got %v", wantCRUD, doc.Statement[0].Resource)
+	}
+	if got := doc.Statement[2].Resource; len(got) != 1 || got[0] != "arn:aws:dynamodb:us-east-1:123456789012:table/prod-schema_migrations" {
+		t.Fatalf("unexpected migrations table resource %v", got)
+	}
+
+	if _, err := c.IAMPolicy("admin"); !errors.Is(err, ErrUnknownPolicyAccess) {
+		t.Fatalf("expected ErrUnknownPolicyAccess but got %v", err)
+	}
+}
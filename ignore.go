@@ -0,0 +1,104 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Aspects of a table which can be excluded from validation via TableInfo.Ignore.
+const (
+	IgnoreThroughput           = "throughput"
+	IgnoreReadThroughput       = "read_throughput"
+	IgnoreWriteThroughput      = "write_throughput"
+	IgnoreGSIThroughput        = "gsi.throughput"
+	IgnoreGSIReadThroughput    = "gsi.read_throughput"
+	IgnoreGSIWriteThroughput   = "gsi.write_throughput"
+	IgnoreAttributeDefinitions = "attribute_definitions"
+	IgnoreTTL                  = "ttl"
+	IgnoreReplicas             = "replicas"
+	// IgnoreTagPrefix starts rules for single tags, e.g. tags.CostCenter. Tags are not part of the
+	// config and never compared, so such rules are rejected with ErrTagsNotCompared.
+	IgnoreTagPrefix = "tags."
+)
+
+var ignoreKeys = map[string]bool{
+	IgnoreThroughput:           true,
+	IgnoreReadThroughput:       true,
+	IgnoreWriteThroughput:      true,
+	IgnoreGSIThroughput:        true,
+	IgnoreGSIReadThroughput:    true,
+	IgnoreGSIWriteThroughput:   true,
+	IgnoreAttributeDefinitions: true,
+	IgnoreTTL:                  true,
+	IgnoreReplicas:             true,
+}
+
+// ignoreSet holds the ignore rules of a single table.
+type ignoreSet map[string]bool
+
+func newIgnoreSet(rules []string) (ignoreSet, []string) {
+	set := ignoreSet{}
+	unknown := []string{}
+	for _, r := range rules {
+		if !ignoreKeys[r] {
+			unknown = append(unknown, r)
+			continue
+		}
+		set[r] = true
+	}
+	return set, unknown
+}
+
+func (s ignoreSet) has(rule string) bool {
+	return s[rule]
+}
+
+// apply overwrites the ignored aspects of the expected input with the current values
+// of the table description, so they never produce a diff nor get updated.
+func (s ignoreSet) apply(desc *dynamodb.TableDescription, input *dynamodb.CreateTableInput) {
+	if pt := desc.ProvisionedThroughput; pt != nil && input.ProvisionedThroughput != nil {
+		if s.has(IgnoreThroughput) || s.has(IgnoreReadThroughput) {
+			input.ProvisionedThroughput.ReadCapacityUnits = pt.ReadCapacityUnits
+		}
+		if s.has(IgnoreThroughput) || s.has(IgnoreWriteThroughput) {
+			input.ProvisionedThroughput.WriteCapacityUnits = pt.WriteCapacityUnits
+		}
+	}
+
+	current := make(map[string]*dynamodb.ProvisionedThroughputDescription, len(desc.GlobalSecondaryIndexes))
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		current[aws.StringValue(gsi.IndexName)] = gsi.ProvisionedThroughput
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		pt, ok := current[aws.StringValue(gsi.IndexName)]
+		if !ok || pt == nil || gsi.ProvisionedThroughput == nil {
+			continue
+		}
+		if s.has(IgnoreGSIThroughput) || s.has(IgnoreGSIReadThroughput) {
+			gsi.ProvisionedThroughput.ReadCapacityUnits = pt.ReadCapacityUnits
+		}
+		if s.has(IgnoreGSIThroughput) || s.has(IgnoreGSIWriteThroughput) {
+			gsi.ProvisionedThroughput.WriteCapacityUnits = pt.WriteCapacityUnits
+		}
+	}
+}
+
+// checkIgnoreRules rejects tag ignore rules, which would have no effect since tags are not compared.
+func checkIgnoreRules(tables []TableInfo) error {
+	for _, tbl := range tables {
+		for _, rule := range tbl.Ignore {
+			if strings.HasPrefix(rule, IgnoreTagPrefix) {
+				return fmt.Errorf("table %s: ignore %s: %w", tbl.TableName, rule, ErrTagsNotCompared)
+			}
+		}
+	}
+	return nil
+}
+
+// unknownIgnoreWarning formats a warning for ignore rules which are not supported.
+func unknownIgnoreWarning(unknown []string) string {
+	return fmt.Sprintf("unknown ignore rules: %s", strings.Join(unknown, ", "))
+}
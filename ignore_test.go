@@ -0,0 +1,75 @@
+package tables
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestIgnoreSet(t *testing.T) {
+	tbl := TableInfo{
+		TableName:       "test",
+		PrimaryKey:      "id",
+		ReadThroughput:  10,
+		WriteThroughput: 10,
+		Indexes: []IndexInfo{
+			{
+				IndexName:       "test-index",
+				PrimaryKey:      "index_id",
+				PrimaryKeyType:  "S",
+				ReadThroughput:  10,
+				WriteThroughput: 10,
+			},
+		},
+	}
+	desc := &dynamodb.TableDescription{
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(50),
+			WriteCapacityUnits: aws.Int64(5),
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{
+				IndexName: aws.String("test-index"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+					ReadCapacityUnits:  aws.Int64(20),
+					WriteCapacityUnits: aws.Int64(20),
+				},
+			},
+		},
+	}
+
+	ignore, unknown := newIgnoreSet([]string{IgnoreReadThroughput, IgnoreGSIThroughput, "typo"})
+	if len(unknown) != 1 || unknown[0] != "typo" {
+		t.Fatalf("expected unknown rule typo but got %v", unknown)
+	}
+
+	input := CreateTableInput(tbl, "")
+	ignore.apply(desc, input)
+
+	if r := aws.Int64Value(input.ProvisionedThroughput.ReadCapacityUnits); r != 50 {
+		t.Fatalf("expected ignored read throughput 50 but got %d", r)
+	}
+	if w := aws.Int64Value(input.ProvisionedThroughput.WriteCapacityUnits); w != 10 {
+		t.Fatalf("expected managed write throughput 10 but got %d", w)
+	}
+	pt := input.GlobalSecondaryIndexes[0].ProvisionedThroughput
+	if aws.Int64Value(pt.ReadCapacityUnits) != 20 || aws.Int64Value(pt.WriteCapacityUnits) != 20 {
+		t.Fatalf("expected ignored GSI throughput 20/20 but got %v", pt)
+	}
+}
+
+func TestIgnoreTagsRejected(t *testing.T) {
+	tables := []TableInfo{{TableName: "orders", PrimaryKey: "id", BillingMode: "PAY_PER_REQUEST", Ignore: []string{"tags.CostCenter"}}}
+	if err := checkIgnoreRules(tables); !errors.Is(err, ErrTagsNotCompared) {
+		t.Fatalf("expected ErrTagsNotCompared but got %v", err)
+	}
+	if _, err := LoadFromReader(strings.NewReader("- table_name: orders\n  primary_key: id\n  ignore: [tags.CostCenter]\n")); !errors.Is(err, ErrTagsNotCompared) {
+		t.Fatalf("expected ErrTagsNotCompared but got %v", err)
+	}
+	if errs := ValidateConfig(tables); len(errs) != 1 || errs[0].Field != "ignore" {
+		t.Fatalf("expected tag ignore rule to be reported but got %v", errs)
+	}
+}
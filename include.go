@@ -0,0 +1,311 @@
+package tables
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// includeKey is the key of the include directive. Its value is a file path or a list of
+// file paths relative to the including file.
+//
+// A list item consisting of an include directive only is replaced by the items of the
+// included files, or by the included mapping, e.g. tables shared by several configs.
+// In a mapping, the included mappings provide defaults, e.g. common indexes of a table.
+// Values of the including mapping and of later includes take precedence, lists are concatenated.
+//
+// Includes are resolved on yaml.v3 nodes which keep the scalars as written, so that
+// values like N or yes decode exactly as they would without includes.
+const includeKey = "include"
+
+// configFiles reads config files and the files they include from fsys, or from the
+// local file system if fsys is nil. Paths of fsys are slash-separated, see fs.ValidPath.
+type configFiles struct {
+	fsys   fs.FS
+	strict bool
+}
+
+// loadFile reads the config file at path, resolves its include directives
+// and applies the settings of the given environment.
+func loadFile(path, env string) ([]TableInfo, error) {
+	return configFiles{}.load(path, env)
+}
+
+func (f configFiles) load(path, env string) ([]TableInfo, error) {
+	data, err := f.readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	var overlay []byte
+	if env != "" {
+		overlay, err = f.readConfig(overlayPath(path, env))
+		if errors.Is(err, fs.ErrNotExist) {
+			overlay, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	tables, err := decodeConfigOverlay(data, overlay, env, f.strict)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tables, nil
+}
+
+// readConfig reads the yaml file at path with its include directives resolved.
+func (f configFiles) readConfig(path string) ([]byte, error) {
+	n, err := f.read(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return []byte{}, nil
+	}
+	return yamlv3.Marshal(n)
+}
+
+// read reads a yaml file and resolves its include directives.
+// stack holds the files currently being included to detect cycles.
+// nil is returned for empty files.
+func (f configFiles) read(path string, stack []string) (*yamlv3.Node, error) {
+	abs, err := f.abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("%w: %s", ErrIncludeCycle, strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	data, err := f.readFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", abs, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return f.resolveIncludes(doc.Content[0], f.dir(abs), stack)
+}
+
+func (f configFiles) abs(p string) (string, error) {
+	if f.fsys == nil {
+		return filepath.Abs(p)
+	}
+	if !fs.ValidPath(p) {
+		return "", &fs.PathError{Op: "open", Path: p, Err: fs.ErrInvalid}
+	}
+	return p, nil
+}
+
+func (f configFiles) readFile(p string) ([]byte, error) {
+	if f.fsys == nil {
+		return ioutil.ReadFile(p)
+	}
+	return fs.ReadFile(f.fsys, p)
+}
+
+func (f configFiles) dir(p string) string {
+	if f.fsys == nil {
+		return filepath.Dir(p)
+	}
+	return path.Dir(p)
+}
+
+// join returns the path of the included file p relative to dir.
+// Absolute paths are supported on the local file system only.
+func (f configFiles) join(dir, p string) string {
+	if f.fsys == nil {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	return path.Join(dir, p)
+}
+
+func (f configFiles) resolveIncludes(n *yamlv3.Node, dir string, stack []string) (*yamlv3.Node, error) {
+	switch n.Kind {
+	case yamlv3.SequenceNode:
+		seq := *n
+		seq.Content = []*yamlv3.Node{}
+		for _, item := range n.Content {
+			if paths := includeValue(item); paths != nil && len(item.Content) == 2 {
+				included, err := f.includeItems(paths, dir, stack)
+				if err != nil {
+					return nil, err
+				}
+				seq.Content = append(seq.Content, included...)
+				continue
+			}
+			resolved, err := f.resolveIncludes(item, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, resolved)
+		}
+		return &seq, nil
+	case yamlv3.MappingNode:
+		merged := *n
+		merged.Content = []*yamlv3.Node{}
+		if paths := includeValue(n); paths != nil {
+			defaults, err := f.includeMappings(paths, dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range defaults {
+				merge(&merged, d)
+			}
+		}
+		own := *n
+		own.Content = []*yamlv3.Node{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == includeKey {
+				continue
+			}
+			resolved, err := f.resolveIncludes(n.Content[i+1], dir, stack)
+			if err != nil {
+				return nil, err
+			}
+			own.Content = append(own.Content, n.Content[i], resolved)
+		}
+		merge(&merged, &own)
+		return &merged, nil
+	}
+	return n, nil
+}
+
+// checkNoIncludes returns an ErrInvalidInclude error if the config contains an include
+// directive, for configs which are not read from a file the include could be resolved against.
+func checkNoIncludes(data []byte) error {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	if n := findInclude(doc); n != nil {
+		return fmt.Errorf("%w: line %d: includes are only resolved in config files", ErrInvalidInclude, n.Line)
+	}
+	return nil
+}
+
+// findInclude returns the first include directive in n, nil if there is none.
+func findInclude(n *yamlv3.Node) *yamlv3.Node {
+	if n.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == includeKey {
+				return n.Content[i]
+			}
+		}
+	}
+	for _, child := range n.Content {
+		if found := findInclude(child); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// includeValue returns the value of the include directive of a mapping, nil if there is none.
+func includeValue(n *yamlv3.Node) *yamlv3.Node {
+	return mappingValue(n, includeKey)
+}
+
+// includePaths returns the paths of an include directive relative to dir.
+func (f configFiles) includePaths(n *yamlv3.Node, dir string) ([]string, error) {
+	values := []*yamlv3.Node{n}
+	if n.Kind == yamlv3.SequenceNode {
+		values = n.Content
+	}
+	paths := make([]string, len(values))
+	for i, value := range values {
+		if value.Kind != yamlv3.ScalarNode || value.Value == "" {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidInclude, n.Line)
+		}
+		paths[i] = f.join(dir, value.Value)
+	}
+	return paths, nil
+}
+
+func (f configFiles) includeItems(n *yamlv3.Node, dir string, stack []string) ([]*yamlv3.Node, error) {
+	paths, err := f.includePaths(n, dir)
+	if err != nil {
+		return nil, err
+	}
+	items := []*yamlv3.Node{}
+	for _, p := range paths {
+		included, err := f.read(p, stack)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case included == nil:
+		case included.Kind == yamlv3.SequenceNode:
+			items = append(items, included.Content...)
+		case included.Kind == yamlv3.MappingNode:
+			items = append(items, included)
+		default:
+			return nil, fmt.Errorf("%w: %s must contain a list or a mapping", ErrInvalidInclude, p)
+		}
+	}
+	return items, nil
+}
+
+func (f configFiles) includeMappings(n *yamlv3.Node, dir string, stack []string) ([]*yamlv3.Node, error) {
+	paths, err := f.includePaths(n, dir)
+	if err != nil {
+		return nil, err
+	}
+	mappings := []*yamlv3.Node{}
+	for _, p := range paths {
+		included, err := f.read(p, stack)
+		if err != nil {
+			return nil, err
+		}
+		if included == nil {
+			continue
+		}
+		if included.Kind != yamlv3.MappingNode {
+			return nil, fmt.Errorf("%w: %s must contain a mapping", ErrInvalidInclude, p)
+		}
+		mappings = append(mappings, included)
+	}
+	return mappings, nil
+}
+
+// merge sets the values of the src mapping in the dst mapping, lists present in both are concatenated.
+func merge(dst, src *yamlv3.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(dst.Content); j += 2 {
+			if dst.Content[j].Value != key.Value {
+				continue
+			}
+			found = true
+			existing := dst.Content[j+1]
+			if existing.Kind == yamlv3.SequenceNode && value.Kind == yamlv3.SequenceNode {
+				list := *value
+				list.Content = append(append([]*yamlv3.Node{}, existing.Content...), value.Content...)
+				dst.Content[j+1] = &list
+			} else {
+				dst.Content[j+1] = value
+			}
+			break
+		}
+		if !found {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+}
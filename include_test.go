@@ -0,0 +1,363 @@
+package tables
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFileInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+- include: shared.yaml
+- table_name: orders
+  include: [defaults.yaml, indexes.yaml]
+  read_throughput: 5
+  indexes:
+    - index_name: byStatus
+`,
+		"shared.yaml": `
+- table_name: users
+  primary_key: id
+`,
+		"defaults.yaml": `
+primary_key: id
+read_throughput: 1
+write_throughput: 1
+`,
+		"indexes.yaml": `
+indexes:
+  - index_name: byEmail
+`,
+	})
+
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0].TableName != "users" {
+		t.Fatalf("expected included table users first but got %+v", tables)
+	}
+	orders := tables[1]
+	if orders.PrimaryKey != "id" || orders.ReadThroughput != 5 || orders.WriteThroughput != 1 {
+		t.Fatalf("expected defaults with overrides but got %+v", orders)
+	}
+	if len(orders.Indexes) != 2 || orders.Indexes[0].IndexName != "byEmail" || orders.Indexes[1].IndexName != "byStatus" {
+		t.Fatalf("expected included indexes before own indexes but got %+v", orders.Indexes)
+	}
+}
+
+func TestLoadFileIncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": `- include: b.yaml`,
+		"b.yaml": `- include: a.yaml`,
+	})
+	if _, err := loadFile(filepath.Join(dir, "a.yaml"), ""); !errors.Is(err, ErrIncludeCycle) {
+		t.Fatalf("expected ErrIncludeCycle but got %v", err)
+	}
+}
+
+func TestLoadFileIndexTemplates(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+index_templates:
+  by_created:
+    primary_key: created
+    primary_key_type: N
+    read_throughput: 1
+    write_throughput: 1
+tables:
+  - table_name: orders
+    indexes:
+      - template: by_created
+      - template: by_created
+        index_name: orders-by-created
+        read_throughput: 5
+`,
+		"unknown.yaml": `
+tables:
+  - table_name: orders
+    indexes:
+      - template: missing
+`,
+	})
+
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes := tables[0].Indexes
+	if indexes[0].IndexName != "by_created" || indexes[0].PrimaryKeyType != "N" {
+		t.Fatalf("expected index named after template but got %+v", indexes[0])
+	}
+	if indexes[1].IndexName != "orders-by-created" || indexes[1].ReadThroughput != 5 || indexes[1].WriteThroughput != 1 {
+		t.Fatalf("expected template with overrides but got %+v", indexes[1])
+	}
+
+	if _, err := loadFile(filepath.Join(dir, "unknown.yaml"), ""); !errors.Is(err, ErrUnknownIndexTemplate) {
+		t.Fatalf("expected ErrUnknownIndexTemplate but got %v", err)
+	}
+}
+
+func TestLoadFileAttributeTypes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+attribute_types:
+  timestamp: N
+  uuid: S
+tables:
+  - table_name: orders
+    primary_key: id
+    sort_key: created
+    sort_key_type: timestamp
+    indexes:
+      - index_name: byUser
+        primary_key: user_id
+        primary_key_type: uuid
+`,
+		"typo.yaml": `
+- table_name: orders
+  primary_key: id
+  sort_key: created
+  sort_key_type: str
+`,
+	})
+
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tables[0].SortKeyType != "N" || tables[0].Indexes[0].PrimaryKeyType != "S" {
+		t.Fatalf("expected aliases to be resolved but got %+v", tables[0])
+	}
+
+	if _, err := loadFile(filepath.Join(dir, "typo.yaml"), ""); !errors.Is(err, ErrInvalidAttributeType) {
+		t.Fatalf("expected ErrInvalidAttributeType but got %v", err)
+	}
+}
+
+func TestLoadFileInheritIndexThroughput(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+- table_name: orders
+  primary_key: id
+  read_throughput: 5
+  write_throughput: 10
+  indexes:
+    - index_name: byUser
+      primary_key: user_id
+      primary_key_type: S
+      write_throughput: 2
+`,
+	})
+
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index := tables[0].Indexes[0]; index.ReadThroughput != 5 || index.WriteThroughput != 2 {
+		t.Fatalf("expected inherited read throughput only but got %+v", index)
+	}
+}
+
+func TestLoadFileBillingModeConflicts(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+- table_name: events
+  billing_mode: PAY_PER_REQUEST
+  indexes:
+    - index_name: byUser
+      primary_key: user_id
+      primary_key_type: S
+- table_name: orders
+  billing_mode: PROVISIONED
+  read_throughput: 5
+  write_throughput: 5
+`,
+		"conflicts.yaml": `
+- table_name: events
+  billing_mode: PAY_PER_REQUEST
+  read_throughput: 5
+  indexes:
+    - index_name: byUser
+      primary_key: user_id
+      primary_key_type: S
+      write_throughput: 2
+- table_name: orders
+  billing_mode: PROVISIONED
+  read_throughput: 5
+- table_name: users
+  billing_mode: ON_DEMAND
+`,
+	})
+
+	if _, err := loadFile(filepath.Join(dir, "tables.yaml"), ""); err != nil {
+		t.Fatal(err)
+	}
+	_, err := loadFile(filepath.Join(dir, "conflicts.yaml"), "")
+	if !errors.Is(err, ErrBillingModeConflict) {
+		t.Fatalf("expected ErrBillingModeConflict but got %v", err)
+	}
+	for _, want := range []string{
+		"table events: PAY_PER_REQUEST with throughput 5/0",
+		"table events: PAY_PER_REQUEST with index byUser throughput 0/2",
+		"table orders: PROVISIONED without capacity, throughput 5/0",
+		"table users: unknown billing mode ON_DEMAND",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadFileDeclaredAttributes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+attribute_types:
+  timestamp: N
+tables:
+  - table_name: orders
+    primary_key: id
+    sort_key: created
+    attributes:
+      - name: created
+        type: timestamp
+      - name: user_id
+        type: S
+    indexes:
+      - index_name: byUser
+        primary_key: user_id
+        sort_key: created
+`,
+		"undeclared.yaml": `
+- table_name: orders
+  primary_key: id
+  indexes:
+    - index_name: byUser
+      primary_key: user_id
+`,
+	})
+
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := tables[0]
+	if tbl.SortKeyType != "N" || tbl.Indexes[0].PrimaryKeyType != "S" || tbl.Indexes[0].SortKeyType != "N" {
+		t.Fatalf("expected key types from declared attributes but got %+v", tbl)
+	}
+
+	if _, err := loadFile(filepath.Join(dir, "undeclared.yaml"), ""); !errors.Is(err, ErrUndeclaredAttributeType) {
+		t.Fatalf("expected ErrUndeclaredAttributeType but got %v", err)
+	}
+}
+
+func TestLoadFileConflictingAttributeTypes(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"indexes.yaml": `
+- table_name: orders
+  primary_key: id
+  indexes:
+    - index_name: byCreated
+      primary_key: created
+      primary_key_type: N
+    - index_name: byUserCreated
+      primary_key: user_id
+      primary_key_type: S
+      sort_key: created
+      sort_key_type: S
+`,
+		"table.yaml": `
+- table_name: orders
+  primary_key: id
+  indexes:
+    - index_name: byId
+      primary_key: id
+      primary_key_type: N
+`,
+	})
+
+	for _, name := range []string{"indexes.yaml", "table.yaml"} {
+		if _, err := loadFile(filepath.Join(dir, name), ""); !errors.Is(err, ErrConflictingAttributeType) {
+			t.Fatalf("%s: expected ErrConflictingAttributeType but got %v", name, err)
+		}
+	}
+}
+
+func TestLoadFileDefaults(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+defaults:
+  read_throughput: 5
+  write_throughput: 3
+  ttl_attribute: expiry
+  projection_type: KEYS_ONLY
+tables:
+  - table_name: orders
+    primary_key: id
+    indexes:
+      - index_name: by_customer
+        primary_key: customer_id
+        primary_key_type: S
+      - index_name: by_status
+        primary_key: status
+        primary_key_type: S
+        projection_type: INCLUDE
+        projection_fields: [total]
+  - table_name: users
+    primary_key: id
+    read_throughput: 10
+    ttl:
+      attribute_name: deleted_at
+      enabled: false
+  - table_name: events
+    primary_key: id
+    billing_mode: PAY_PER_REQUEST
+`,
+	})
+	tables, err := loadFile(filepath.Join(dir, "tables.yaml"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, users, events := tables[0], tables[1], tables[2]
+	if orders.ReadThroughput != 5 || orders.WriteThroughput != 3 || orders.TTL == nil || orders.TTL.AttributeName != "expiry" || !orders.TTL.Enabled {
+		t.Fatalf("expected defaults on orders but got %+v", orders)
+	}
+	if index := orders.Indexes[0]; index.ProjectionType != "KEYS_ONLY" || index.ReadThroughput != 5 {
+		t.Fatalf("expected default projection and inherited throughput but got %+v", index)
+	}
+	if gsi := NewGlobalSecondaryIndex(orders.Indexes[0]); aws.StringValue(gsi.Projection.ProjectionType) != "KEYS_ONLY" || gsi.Projection.NonKeyAttributes != nil {
+		t.Fatalf("expected KEYS_ONLY projection but got %v", gsi.Projection)
+	}
+	if index := orders.Indexes[1]; index.ProjectionType != "INCLUDE" {
+		t.Fatalf("expected own projection type but got %+v", index)
+	}
+	if users.ReadThroughput != 10 || users.WriteThroughput != 3 || users.TTL.AttributeName != "deleted_at" || users.TTL.Enabled {
+		t.Fatalf("expected own values to take precedence but got %+v", users)
+	}
+	if events.ReadThroughput != 0 || events.WriteThroughput != 0 {
+		t.Fatalf("expected no throughput on PAY_PER_REQUEST table but got %+v", events)
+	}
+
+	dir = writeFiles(t, map[string]string{
+		"tables.yaml": "defaults:\n  projection_type: SOME\ntables:\n  - table_name: orders\n    primary_key: id\n    indexes:\n      - index_name: by_customer\n        primary_key: customer_id\n",
+	})
+	if _, err := loadFile(filepath.Join(dir, "tables.yaml"), ""); !errors.Is(err, ErrInvalidProjectionType) {
+		t.Fatalf("expected invalid projection type but got %v", err)
+	}
+}
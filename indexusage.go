@@ -0,0 +1,134 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// IndexUsage is the read usage of a GSI over a window of CloudWatch metrics.
+type IndexUsage struct {
+	TableName string        `json:"table_name" yaml:"table_name"`
+	IndexName string        `json:"index_name" yaml:"index_name"`
+	Window    time.Duration `json:"window" yaml:"window"`
+	// ConsumedReadCapacity is the sum of the read capacity units consumed by queries and scans of the index.
+	ConsumedReadCapacity float64 `json:"consumed_read_capacity" yaml:"consumed_read_capacity"`
+}
+
+// String returns the usage as evidence for reviews, e.g. "orders/by_email: 0 consumed read units in 14 days".
+func (u IndexUsage) String() string {
+	return fmt.Sprintf("%s/%s: %g consumed read units in %s", u.TableName, u.IndexName, u.ConsumedReadCapacity, formatWindow(u.Window))
+}
+
+// formatWindow returns whole days as "14 days", other windows as durations.
+func formatWindow(window time.Duration) string {
+	if window > 0 && window%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", window/(24*time.Hour))
+	}
+	return window.String()
+}
+
+// UnusedIndexes returns the ACTIVE GSIs of the managed tables which consumed at most maxReads read
+// capacity units within the window, as candidates for removal. Writes are not considered, since every
+// write to the table is also written to its indexes. Tables which are missing or younger than the
+// window are skipped, as their metrics cannot show a lack of usage.
+func (c *Controller) UnusedIndexes(ctx context.Context, cw cloudwatchiface.CloudWatchAPI, window time.Duration, maxReads float64) ([]IndexUsage, error) {
+	end := c.clock.Now()
+	start := end.Add(-window)
+	unused := []IndexUsage{}
+	for _, tbl := range managedTables(c.tenantTables()) {
+		if len(tbl.Indexes) == 0 {
+			continue
+		}
+		desc, err := c.describeTable(tbl)
+		if isNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if created := desc.CreationDateTime; created != nil && created.After(start) {
+			continue
+		}
+		for _, gsi := range desc.GlobalSecondaryIndexes {
+			if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+				continue
+			}
+			reads, err := sumMetric(ctx, cw, "ConsumedReadCapacityUnits", capacityDimensions(physicalName(c.env, tbl), aws.StringValue(gsi.IndexName)), start, end)
+			if err != nil {
+				return nil, err
+			}
+			if reads > maxReads {
+				continue
+			}
+			unused = append(unused, IndexUsage{
+				TableName:            tbl.TableName,
+				IndexName:            aws.StringValue(gsi.IndexName),
+				Window:               window,
+				ConsumedReadCapacity: reads,
+			})
+		}
+	}
+	return unused, nil
+}
+
+// capacityDimensions returns the CloudWatch dimensions of a table, or of one of its GSIs if index is set.
+func capacityDimensions(tableName, index string) []*cloudwatch.Dimension {
+	dimensions := []*cloudwatch.Dimension{{Name: aws.String("TableName"), Value: aws.String(tableName)}}
+	if index != "" {
+		dimensions = append(dimensions, &cloudwatch.Dimension{Name: aws.String("GlobalSecondaryIndexName"), Value: aws.String(index)})
+	}
+	return dimensions
+}
+
+// sumMetric returns the sum of a DynamoDB metric between start and end.
+func sumMetric(ctx context.Context, cw cloudwatchiface.CloudWatchAPI, metric string, dimensions []*cloudwatch.Dimension, start, end time.Time) (float64, error) {
+	sums, err := metricSums(ctx, cw, metric, dimensions, start, end, metricPeriod(end.Sub(start)))
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, sum := range sums {
+		total += sum
+	}
+	return total, nil
+}
+
+// metricSums returns the sums of a DynamoDB metric per period between start and end.
+// Periods without datapoints are omitted.
+func metricSums(ctx context.Context, cw cloudwatchiface.CloudWatchAPI, metric string, dimensions []*cloudwatch.Dimension, start, end time.Time, period int64) ([]float64, error) {
+	output, err := cw.GetMetricStatisticsWithContext(ctx, &cloudwatch.GetMetricStatisticsInput{
+		Namespace:  aws.String("AWS/DynamoDB"),
+		MetricName: aws.String(metric),
+		Dimensions: dimensions,
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(period),
+		Statistics: []*string{aws.String(cloudwatch.StatisticSum)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sums := make([]float64, len(output.Datapoints))
+	for i, dp := range output.Datapoints {
+		sums[i] = aws.Float64Value(dp.Sum)
+	}
+	return sums, nil
+}
+
+// metricPeriod returns a period in seconds for statistics over the window, a day for windows of
+// at least a day so long windows stay within the datapoint limit of a single request.
+func metricPeriod(window time.Duration) int64 {
+	if window >= 24*time.Hour {
+		return 86400
+	}
+	if period := int64(window/time.Minute) * 60; period > 0 {
+		return period
+	}
+	return 60
+}
@@ -0,0 +1,59 @@
+package tables
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestUnusedIndexes(t *testing.T) {
+	now := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		created := now.Add(-90 * 24 * time.Hour)
+		if aws.StringValue(input.TableName) == "users" {
+			created = now.Add(-time.Hour)
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			CreationDateTime: aws.Time(created),
+			GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+				{IndexName: aws.String("by_user"), IndexStatus: aws.String(dynamodb.IndexStatusActive)},
+				{IndexName: aws.String("by_email"), IndexStatus: aws.String(dynamodb.IndexStatusActive)},
+				{IndexName: aws.String("by_status"), IndexStatus: aws.String(dynamodb.IndexStatusCreating)},
+			},
+		}}, nil
+	}
+	cw := &fakeCloudWatch{}
+	cw.statistics = func(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+		if aws.Int64Value(input.Period) != 86400 || !aws.TimeValue(input.StartTime).Equal(now.Add(-14*24*time.Hour)) {
+			t.Fatalf("unexpected statistics request %v", input)
+		}
+		sum := 0.5
+		if aws.StringValue(input.Dimensions[1].Value) == "by_user" {
+			sum = 1200
+		}
+		return &cloudwatch.GetMetricStatisticsOutput{Datapoints: []*cloudwatch.Datapoint{{Sum: aws.Float64(sum)}}}, nil
+	}
+	c, err := NewController(db, "", nil, []TableInfo{
+		{TableName: "orders", Indexes: []IndexInfo{{IndexName: "by_user"}, {IndexName: "by_email"}}},
+		{TableName: "users", Indexes: []IndexInfo{{IndexName: "by_email"}}},
+	}, WithClock(&fakeClock{now: now}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unused, err := c.UnusedIndexes(context.Background(), cw, 14*24*time.Hour, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unused) != 1 || unused[0].IndexName != "by_email" || unused[0].TableName != "orders" {
+		t.Fatalf("expected by_email of orders to be unused but got %v", unused)
+	}
+	if got, want := unused[0].String(), "orders/by_email: 0.5 consumed read units in 14 days"; got != want {
+		t.Fatalf("expected %q but got %q", want, got)
+	}
+}
@@ -0,0 +1,81 @@
+package tables
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit renders validation results as JUnit XML with one test case per table,
+// so CI systems display schema drift natively in their test summaries.
+// Backward incompatible drift is reported as a failure and tables which could not be
+// compared as errors. Backward compatible drift passes and is included as system-out.
+func WriteJUnit(w io.Writer, suiteName string, results []*ValidationResult) error {
+	suite := junitTestSuite{
+		Name:      suiteName,
+		TestCases: []junitTestCase{},
+	}
+	for _, r := range results {
+		tc := junitTestCase{
+			Name:      r.TableInput.TableName,
+			ClassName: suiteName,
+		}
+		switch {
+		case r.Error != nil:
+			suite.Errors++
+			tc.Error = &junitMessage{
+				Message: r.Error.Error(),
+				Type:    "ValidationError",
+				Text:    r.Error.Error(),
+			}
+		case r.incompatible():
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: ErrBackwardIncompatible.Error(),
+				Type:    "BackwardIncompatible",
+				Text:    r.Diff,
+			}
+		case len(r.Diff) > 0:
+			tc.SystemOut = r.String()
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,41 @@
+package tables
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	results := []*ValidationResult{
+		{
+			TableInput: TableInfo{TableName: "in-sync"},
+			CanMigrate: true,
+		},
+		{
+			TableInput: TableInfo{TableName: "incompatible"},
+			Diff:       "Key Schema",
+		},
+		{
+			TableInput: TableInfo{TableName: "broken"},
+			Error:      errors.New("access denied"),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, "sandbox", results); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{
+		`<testsuite name="sandbox" tests="3" failures="1" errors="1">`,
+		`<testcase name="in-sync" classname="sandbox"></testcase>`,
+		`<failure message="table definition contains backward incompatible changes" type="BackwardIncompatible">Key Schema</failure>`,
+		`<error message="access denied" type="ValidationError">access denied</error>`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected output to contain %s but got %s", s, out)
+		}
+	}
+}
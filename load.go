@@ -2,31 +2,90 @@ package tables
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
+	"path/filepath"
 	"runtime"
-	"strings"
-
-	"gopkg.in/yaml.v2"
 )
 
-// Load loads config yaml file and unmarshal config data to a slice of TableInfo
+// Load loads config yaml file and unmarshal config data to a slice of TableInfo.
+// Include directives of the config are resolved relative to the config file.
 func Load() ([]TableInfo, error) {
+	path, err := defaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadFile(path, "")
+}
+
+// defaultConfigPath returns the path of the tables.yaml next to the source of this package.
+func defaultConfigPath() (string, error) {
 	_, file, _, ok := runtime.Caller(0)
 	if !ok {
-		return nil, errors.New("Failed to retrieve file path")
+		return "", errors.New("Failed to retrieve file path")
 	}
-	file = strings.TrimRight(file, "load.go")
+	return filepath.Join(filepath.Dir(file), "tables.yaml"), nil
+}
+
+// LoadFile loads the config file at path, e.g. a tables.yaml kept in the application's own repo.
+// Include directives of the config are resolved relative to the config file.
+func LoadFile(path string, opts ...LoadOption) ([]TableInfo, error) {
+	return LoadFileEnv(path, "", opts...)
+}
 
-	data, err := ioutil.ReadFile(file + "tables.yaml")
+// LoadFileEnv loads the config file at path like LoadFile and applies the settings of the
+// given environment, see LoadEnv.
+func LoadFileEnv(path, env string, opts ...LoadOption) ([]TableInfo, error) {
+	return configFiles{strict: newLoadOptions(opts).strict}.load(path, env)
+}
+
+// LoadFromReader loads a config read from r, e.g. stdin or a generated buffer.
+// Include directives cannot be resolved since there is no file to resolve them relative to,
+// configs containing one return ErrInvalidInclude.
+func LoadFromReader(r io.Reader, opts ...LoadOption) ([]TableInfo, error) {
+	data, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
+	if err := checkNoIncludes(data); err != nil {
+		return nil, err
+	}
+	return decodeConfigOverlay(data, nil, "", newLoadOptions(opts).strict)
+}
 
+// LoadFS loads the config files of fsys matching pattern, e.g. a tables.yaml or a directory of
+// yaml files embedded with go:embed. See fs.Glob for the pattern syntax. Files are loaded on
+// their own like LoadFile, in lexical order, and their tables concatenated. Include directives
+// are resolved relative to the including file within fsys.
+func LoadFS(fsys fs.FS, pattern string, opts ...LoadOption) ([]TableInfo, error) {
+	matches, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: no config file matches %s", fs.ErrNotExist, pattern)
+	}
+	files := configFiles{fsys: fsys, strict: newLoadOptions(opts).strict}
 	tables := []TableInfo{}
+	for _, path := range matches {
+		loaded, err := files.load(path, "")
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, loaded...)
+	}
+	return tables, nil
+}
 
-	if err := yaml.Unmarshal(data, &tables); err != nil {
+// LoadEnv loads the config like Load and applies the settings of the given environment,
+// either its own or those of the longest matching env alias, e.g. pr-* for preview environments.
+// The overlay file of the environment next to the config, e.g. tables.prod.yaml, is applied last.
+func LoadEnv(env string) ([]TableInfo, error) {
+	path, err := defaultConfigPath()
+	if err != nil {
 		return nil, err
 	}
-
-	return tables, nil
+	return loadFile(path, env)
 }
@@ -1,7 +1,13 @@
 package tables
 
 import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestLoad(t *testing.T) {
@@ -14,3 +20,85 @@ func TestLoad(t *testing.T) {
 		t.Fatalf("error loading config: %s", "missing data")
 	}
 }
+
+func TestLoadFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": "- include: shared.yaml\n- table_name: orders\n  primary_key: id\n",
+		"shared.yaml": "- table_name: users\n  primary_key: id\n",
+	})
+	tbl, err := LoadFile(filepath.Join(dir, "tables.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbl) != 2 || tbl[0].TableName != "users" || tbl[1].TableName != "orders" {
+		t.Fatalf("expected users and orders but got %+v", tbl)
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error but got %v", err)
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	config := `
+index_templates:
+  by_created:
+    primary_key: created
+    primary_key_type: N
+tables:
+  - table_name: orders
+    primary_key: id
+    read_throughput: 2
+    write_throughput: 2
+    indexes:
+      - index_name: by_created
+        template: by_created
+`
+	tbl, err := LoadFromReader(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tbl) != 1 || len(tbl[0].Indexes) != 1 {
+		t.Fatalf("expected orders with one index but got %+v", tbl)
+	}
+	if index := tbl[0].Indexes[0]; index.PrimaryKey != "created" || index.ReadThroughput != 2 {
+		t.Fatalf("expected templated index with inherited throughput but got %+v", index)
+	}
+
+	if _, err := LoadFromReader(strings.NewReader("- table_name: [")); err == nil {
+		t.Fatal("expected error for invalid yaml")
+	}
+	if _, err := LoadFromReader(strings.NewReader("- include: users.yaml\n- table_name: orders\n")); !errors.Is(err, ErrInvalidInclude) {
+		t.Fatalf("expected ErrInvalidInclude but got %v", err)
+	}
+	if _, err := LoadFromReader(strings.NewReader("- table_name: orders\n  primary_key: id\n  lifecycle: create-only\n")); !errors.Is(err, ErrInvalidLifecycle) {
+		t.Fatalf("expected ErrInvalidLifecycle but got %v", err)
+	}
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/orders.yaml":        {Data: []byte("- include: shared/users.yaml\n- table_name: orders\n  primary_key: id\n")},
+		"schemas/payments.yaml":      {Data: []byte("tables:\n  - table_name: payments\n    primary_key: id\n")},
+		"schemas/shared/users.yaml":  {Data: []byte("- table_name: users\n  primary_key: id\n")},
+		"schemas/shared/broken.yaml": {Data: []byte("- include: ../../../outside.yaml\n")},
+	}
+	tbl, err := LoadFS(fsys, "schemas/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, tb := range tbl {
+		names = append(names, tb.TableName)
+	}
+	if strings.Join(names, ",") != "users,orders,payments" {
+		t.Fatalf("expected users, orders and payments but got %v", names)
+	}
+
+	if _, err := LoadFS(fsys, "configs/*.yaml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected not exist error but got %v", err)
+	}
+	if _, err := LoadFS(fsys, "schemas/shared/broken.yaml"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected invalid path error but got %v", err)
+	}
+}
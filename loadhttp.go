@@ -0,0 +1,102 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTPLoader loads configs served over HTTP(S), e.g. by an internal config service or schema
+// registry. Responses are cached by their ETag: later loads of the same URL send If-None-Match
+// and decode the cached config if the server responds 304 Not Modified.
+// Include directives are not resolved, the served config must be self-contained.
+type HTTPLoader struct {
+	// Header is sent with every request, e.g. an Authorization header.
+	Header http.Header
+	// Env selects the environment settings to apply, see LoadEnv.
+	Env string
+	// Strict rejects configs with unknown fields, see Strict.
+	Strict bool
+	// Client sends the requests, http.DefaultClient if nil.
+	Client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]httpResponse
+}
+
+type httpResponse struct {
+	etag string
+	data []byte
+}
+
+var defaultHTTPLoader = &HTTPLoader{}
+
+// LoadHTTP loads the config served at url with a shared HTTPLoader without headers.
+// Use an HTTPLoader to authenticate requests or apply environment settings.
+func LoadHTTP(ctx context.Context, url string) ([]TableInfo, error) {
+	return defaultHTTPLoader.Load(ctx, url)
+}
+
+// Load loads the config served at url. Responses other than 200 OK and 304 Not Modified are errors.
+func (l *HTTPLoader) Load(ctx context.Context, url string) ([]TableInfo, error) {
+	data, err := l.fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := decodeConfigOverlay(data, nil, l.Env, l.Strict)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return tables, nil
+}
+
+// fetch returns the config data at url, the cached data if it is not modified.
+func (l *HTTPLoader) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range l.Header {
+		req.Header[k] = v
+	}
+	l.mu.Lock()
+	cached, ok := l.cache[url]
+	l.mu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		return cached.data, nil
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s: %s: %s", url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		l.mu.Lock()
+		if l.cache == nil {
+			l.cache = map[string]httpResponse{}
+		}
+		l.cache[url] = httpResponse{etag: etag, data: data}
+		l.mu.Unlock()
+	}
+	return data, nil
+}
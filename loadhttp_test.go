@@ -0,0 +1,60 @@
+package tables
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPLoader(t *testing.T) {
+	config := "- table_name: orders\n  read_throughput: 5\n"
+	requests, notModified := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(config)))
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(config))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := LoadHTTP(ctx, srv.URL); err == nil || !strings.Contains(err.Error(), "401 Unauthorized: unauthorized") {
+		t.Fatalf("expected unauthorized error but got %v", err)
+	}
+
+	l := &HTTPLoader{Header: http.Header{"Authorization": {"Bearer token"}}}
+	for i := 0; i < 2; i++ {
+		tables, err := l.Load(ctx, srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tables) != 1 || tables[0].TableName != "orders" || tables[0].ReadThroughput != 5 {
+			t.Fatalf("unexpected tables %+v", tables)
+		}
+	}
+	if notModified != 1 {
+		t.Fatalf("expected the second load to be served from cache but got %d of %d requests not modified", notModified, requests)
+	}
+
+	config = "- table_name: users\n"
+	tables, err := l.Load(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].TableName != "users" {
+		t.Fatalf("expected changed config to be loaded but got %+v", tables)
+	}
+}
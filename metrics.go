@@ -0,0 +1,103 @@
+package tables
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+)
+
+// DefaultMetricsNamespace is the CloudWatch namespace of run metrics unless set otherwise.
+const DefaultMetricsNamespace = "DynamoDBTables"
+
+// Metric names published by CloudWatchMetrics.
+const (
+	MetricDriftedTables       = "DriftedTables"
+	MetricIncompatibleChanges = "IncompatibleChanges"
+	MetricMigrationErrors     = "MigrationErrors"
+	MetricMigrationDuration   = "MigrationDuration"
+)
+
+// CloudWatchMetrics is a ValidationNotifier publishing custom metrics per Migrate run and
+// validation of all tables, dimensioned by Environment, so alarms can page when schemas diverge from the config:
+//   - DriftedTables: tables whose schema differs from the config
+//   - IncompatibleChanges: tables with changes Migrate cannot apply
+//   - MigrationErrors: errors of the run, Migrate only
+//   - MigrationDuration: duration of the run in seconds, Migrate only
+type CloudWatchMetrics struct {
+	CloudWatch cloudwatchiface.CloudWatchAPI
+	// Namespace of the metrics, DefaultMetricsNamespace if empty.
+	Namespace string
+}
+
+// Notify publishes the metrics of the run.
+func (m *CloudWatchMetrics) Notify(ctx context.Context, report *RunReport) error {
+	drifted, incompatible := driftCounts(report.Plan)
+	errs := 0
+	for _, r := range report.Results {
+		if r != nil {
+			errs += len(r.Errors)
+		}
+	}
+	dimensions := environmentDimensions(report.Env)
+	return m.put(ctx,
+		metricDatum(MetricDriftedTables, float64(drifted), cloudwatch.StandardUnitCount, dimensions),
+		metricDatum(MetricIncompatibleChanges, float64(incompatible), cloudwatch.StandardUnitCount, dimensions),
+		metricDatum(MetricMigrationErrors, float64(errs), cloudwatch.StandardUnitCount, dimensions),
+		metricDatum(MetricMigrationDuration, report.Duration.Seconds(), cloudwatch.StandardUnitSeconds, dimensions),
+	)
+}
+
+// NotifyValidation publishes the drift metrics of the validation. Partial validations are not
+// published since the metrics count the drift of all tables.
+func (m *CloudWatchMetrics) NotifyValidation(ctx context.Context, report *ValidationRunReport) error {
+	if report.Partial {
+		return nil
+	}
+	drifted, incompatible := driftCounts(report.Results)
+	dimensions := environmentDimensions(report.Env)
+	return m.put(ctx,
+		metricDatum(MetricDriftedTables, float64(drifted), cloudwatch.StandardUnitCount, dimensions),
+		metricDatum(MetricIncompatibleChanges, float64(incompatible), cloudwatch.StandardUnitCount, dimensions),
+	)
+}
+
+func (m *CloudWatchMetrics) put(ctx context.Context, data ...*cloudwatch.MetricDatum) error {
+	namespace := m.Namespace
+	if namespace == "" {
+		namespace = DefaultMetricsNamespace
+	}
+	_, err := m.CloudWatch.PutMetricDataWithContext(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(namespace),
+		MetricData: data,
+	})
+	return err
+}
+
+func environmentDimensions(env string) []*cloudwatch.Dimension {
+	return []*cloudwatch.Dimension{{Name: aws.String("Environment"), Value: aws.String(env)}}
+}
+
+func metricDatum(name string, value float64, unit string, dimensions []*cloudwatch.Dimension) *cloudwatch.MetricDatum {
+	return &cloudwatch.MetricDatum{
+		MetricName: aws.String(name),
+		Dimensions: dimensions,
+		Value:      aws.Float64(value),
+		Unit:       aws.String(unit),
+	}
+}
+
+// driftCounts returns the number of tables differing from the config and of those which cannot be migrated.
+func driftCounts(results []*ValidationResult) (drifted, incompatible int) {
+	for _, r := range results {
+		if r == nil || r.Error != nil || len(r.Diff) == 0 {
+			continue
+		}
+		drifted++
+		if r.incompatible() {
+			incompatible++
+		}
+	}
+	return drifted, incompatible
+}
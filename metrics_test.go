@@ -0,0 +1,147 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type fakeCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	inputs     []*cloudwatch.PutMetricDataInput
+	statistics func(*cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error)
+}
+
+func (f *fakeCloudWatch) GetMetricStatisticsWithContext(ctx aws.Context, input *cloudwatch.GetMetricStatisticsInput, opts ...request.Option) (*cloudwatch.GetMetricStatisticsOutput, error) {
+	return f.statistics(input)
+}
+
+func (f *fakeCloudWatch) PutMetricDataWithContext(ctx aws.Context, input *cloudwatch.PutMetricDataInput, opts ...request.Option) (*cloudwatch.PutMetricDataOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func TestCloudWatchMetrics(t *testing.T) {
+	cw := &fakeCloudWatch{}
+	c, err := NewController(&fakeDynamoDB{}, "prod", nil, nil, WithClock(&fakeClock{}), WithCloudWatchMetrics(cw, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.notify(&RunReport{
+		Env:      "prod",
+		Duration: 90 * time.Second,
+		Plan: []*ValidationResult{
+			{TableInput: TableInfo{TableName: "orders"}, Diff: "Throughput", CanMigrate: true},
+			{TableInput: TableInfo{TableName: "users"}, Diff: "Key Schema", CanMigrate: false},
+			{TableInput: TableInfo{TableName: "events"}, CanMigrate: true},
+		},
+		Results: []*MigrationResult{
+			{Status: StatusFailed, Errors: []error{errors.New("throttled"), errors.New("limit exceeded")}},
+		},
+	})
+
+	if len(cw.inputs) != 1 || aws.StringValue(cw.inputs[0].Namespace) != DefaultMetricsNamespace {
+		t.Fatalf("expected metrics in the default namespace but got %v", cw.inputs)
+	}
+	values := map[string]float64{}
+	for _, d := range cw.inputs[0].MetricData {
+		if v := aws.StringValue(d.Dimensions[0].Value); v != "prod" {
+			t.Fatalf("expected Environment prod but got %s", v)
+		}
+		values[aws.StringValue(d.MetricName)] = aws.Float64Value(d.Value)
+	}
+	want := map[string]float64{
+		MetricDriftedTables:       2,
+		MetricIncompatibleChanges: 1,
+		MetricMigrationErrors:     2,
+		MetricMigrationDuration:   90,
+	}
+	for name, v := range want {
+		if values[name] != v {
+			t.Fatalf("expected %s %v but got %v", name, v, values)
+		}
+	}
+}
+
+func TestCloudWatchMetricsValidation(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	cw := &fakeCloudWatch{}
+	c, err := NewController(db, "prod", nil, []TableInfo{{TableName: "orders", PrimaryKey: "id"}},
+		WithClock(&fakeClock{}), WithCloudWatchMetrics(cw, "Schemas"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Validate(); err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+
+	if len(cw.inputs) != 1 || aws.StringValue(cw.inputs[0].Namespace) != "Schemas" {
+		t.Fatalf("expected validation metrics in namespace Schemas but got %v", cw.inputs)
+	}
+	values := map[string]float64{}
+	for _, d := range cw.inputs[0].MetricData {
+		values[aws.StringValue(d.MetricName)] = aws.Float64Value(d.Value)
+	}
+	if len(values) != 2 || values[MetricDriftedTables] != 1 || values[MetricIncompatibleChanges] != 0 {
+		t.Fatalf("expected drift metrics only but got %v", values)
+	}
+}
+
+// validationRecorder records the validation reports it is told about.
+type validationRecorder struct {
+	reports []*ValidationRunReport
+}
+
+func (r *validationRecorder) Notify(ctx context.Context, report *RunReport) error {
+	return nil
+}
+
+func (r *validationRecorder) NotifyValidation(ctx context.Context, report *ValidationRunReport) error {
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestValidationNotifications(t *testing.T) {
+	db := fleetDynamoDB(nil)
+	rec := &validationRecorder{}
+	cw := &fakeCloudWatch{}
+	c, err := NewController(db, "prod", nil, []TableInfo{
+		{TableName: "orders", PrimaryKey: "id"},
+		{TableName: "users", PrimaryKey: "id"},
+	}, WithClock(&fakeClock{}), WithNotifiers(rec), WithCloudWatchMetrics(cw, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.ValidateTable("orders")
+	c.ValidateFiltered(func(tbl TableInfo) bool { return tbl.TableName == "users" })
+	c.ValidateFleet(context.Background(), func(*ValidationResult) {})
+
+	if len(rec.reports) != 3 {
+		t.Fatalf("expected a report per validation but got %d", len(rec.reports))
+	}
+	for i, want := range []struct {
+		results int
+		partial bool
+	}{{1, true}, {1, true}, {2, false}} {
+		if r := rec.reports[i]; len(r.Results) != want.results || r.Partial != want.partial || r.Env != "prod" {
+			t.Errorf("report %d: expected %d results with partial %v but got %+v", i, want.results, want.partial, r)
+		}
+	}
+
+	// Drift metrics count all tables, partial validations are not published.
+	if len(cw.inputs) != 1 {
+		t.Fatalf("expected metrics of the fleet validation only but got %v", cw.inputs)
+	}
+}
@@ -0,0 +1,428 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultMigrationsTable is the name of the table recording applied migrations
+// unless set via WithMigrationsTable. It is prefixed with the environment, e.g. dev-schema_migrations.
+const DefaultMigrationsTable = "schema_migrations"
+
+// migrationsTableTimeout bounds the wait for a created migrations table to become ACTIVE.
+const migrationsTableTimeout = 5 * time.Minute
+
+// migrationFilePattern matches migration file names, e.g. 0001_add_orders_by_email.yaml.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.ya?ml$`)
+
+// Migration is a versioned set of schema deltas and data steps read from a migrations directory.
+// Up steps are applied in order by ApplyMigrations, down steps by RevertMigration.
+type Migration struct {
+	Version int64  `yaml:"-"`
+	Name    string `yaml:"-"`
+	// Description states the intent of the migration and is recorded when it is applied.
+	Description string          `yaml:"description"`
+	Up          []MigrationStep `yaml:"up"`
+	Down        []MigrationStep `yaml:"down"`
+}
+
+// MigrationStep is a single step of a migration. Exactly one field is set.
+type MigrationStep struct {
+	// AddIndex creates a GSI on a table and waits until it is ACTIVE.
+	AddIndex *AddIndexStep `yaml:"add_index"`
+	// RemoveIndex deletes a GSI of a table.
+	RemoveIndex *RemoveIndexStep `yaml:"remove_index"`
+	// UpdateThroughput sets the provisioned throughput of a table.
+	UpdateThroughput *UpdateThroughputStep `yaml:"update_throughput"`
+	// UpdateTTL sets the TTL of a table.
+	UpdateTTL *UpdateTTLStep `yaml:"update_ttl"`
+	// Data names a data step registered via WithDataStep, e.g. a backfill.
+	Data string `yaml:"data"`
+}
+
+// AddIndexStep creates the index on the table with the given name in the config.
+// The index defaults to the throughput of the table.
+type AddIndexStep struct {
+	Table string    `yaml:"table"`
+	Index IndexInfo `yaml:"index"`
+}
+
+// RemoveIndexStep deletes the named index of the table.
+type RemoveIndexStep struct {
+	Table     string `yaml:"table"`
+	IndexName string `yaml:"index_name"`
+}
+
+// UpdateThroughputStep sets the throughput of the table.
+type UpdateThroughputStep struct {
+	Table           string `yaml:"table"`
+	ReadThroughput  int64  `yaml:"read_throughput"`
+	WriteThroughput int64  `yaml:"write_throughput"`
+}
+
+// UpdateTTLStep sets the TTL of the table.
+type UpdateTTLStep struct {
+	Table string           `yaml:"table"`
+	TTL   TTLAttributeInfo `yaml:"ttl"`
+}
+
+// DataStep is a named data step of migrations, e.g. backfilling an attribute of a new index.
+// db is the client of the controller's default region.
+type DataStep func(ctx context.Context, db DynamoDBAPI) error
+
+// AppliedMigration is a migration recorded in the migrations table.
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time
+	// RunID is the ID of the ApplyMigrations run which applied the migration.
+	RunID string
+	// Description is the stated intent of the run and the migration, see ContextWithChangeDescription.
+	Description string
+}
+
+// LoadMigrations reads the migrations of a directory. Files are named after their version and
+// name, e.g. 0001_add_orders_by_email.yaml, and returned in version order. Other files are ignored.
+func LoadMigrations(dir string) ([]Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	migrations := []Migration{}
+	versions := map[int64]string{}
+	for _, f := range files {
+		m := migrationFilePattern.FindStringSubmatch(f.Name())
+		if f.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", f.Name(), ErrInvalidMigration, err)
+		}
+		if prev, ok := versions[version]; ok {
+			return nil, fmt.Errorf("%w: %s and %s", ErrDuplicateMigration, prev, f.Name())
+		}
+		versions[version] = f.Name()
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration := Migration{}
+		if err := yaml.UnmarshalStrict(data, &migration); err != nil {
+			return nil, fmt.Errorf("%s: %w: %v", f.Name(), ErrInvalidMigration, err)
+		}
+		migration.Version = version
+		migration.Name = m[2]
+		for _, steps := range [][]MigrationStep{migration.Up, migration.Down} {
+			for i, step := range steps {
+				if n := step.count(); n != 1 {
+					return nil, fmt.Errorf("%s: step %d: %w: %d actions", f.Name(), i+1, ErrInvalidMigration, n)
+				}
+			}
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// count returns the number of actions set on the step.
+func (s MigrationStep) count() int {
+	n := 0
+	for _, set := range []bool{s.AddIndex != nil, s.RemoveIndex != nil, s.UpdateThroughput != nil, s.UpdateTTL != nil, s.Data != ""} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// AppliedMigrations returns the migrations recorded in the migrations table in version order.
+// No migrations are returned if the table does not exist yet.
+func (c *Controller) AppliedMigrations() ([]AppliedMigration, error) {
+	applied := []AppliedMigration{}
+	err := c.client(TableInfo{}).ScanPages(&dynamodb.ScanInput{
+		TableName:      aws.String(c.migrationsTableName()),
+		ConsistentRead: aws.Bool(true),
+	}, func(page *dynamodb.ScanOutput, last bool) bool {
+		for _, item := range page.Items {
+			applied = append(applied, appliedMigration(item))
+		}
+		return true
+	})
+	if isNotFound(err) {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].Version < applied[j].Version
+	})
+	return applied, nil
+}
+
+// ApplyMigrations applies the up steps of the migrations which are not recorded in the
+// migrations table yet in version order and records them. The migrations table is created
+// if it does not exist. Applying stops at the first failing migration, whose steps applied
+// so far are not reverted. The migrations applied by this call are returned.
+func (c *Controller) ApplyMigrations(ctx context.Context, migrations []Migration) ([]AppliedMigration, error) {
+	ctx, runID := c.startRun(ctx)
+	if err := c.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+	recorded, err := c.AppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(recorded))
+	for _, m := range recorded {
+		done[m.Version] = true
+	}
+
+	applied := []AppliedMigration{}
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		c.Log.Infof("Applying migration %d_%s in run %s", m.Version, m.Name, runID)
+		if err := c.runSteps(ctx, m.Up); err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		record := AppliedMigration{Version: m.Version, Name: m.Name, AppliedAt: c.clock.Now(), RunID: runID,
+			Description: changeDescription(ctx, m.Description)}
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(c.migrationsTableName()),
+			Item:      record.item(),
+		}
+		if err := c.audit(ctx, TableInfo{}, "PutItem", input); err != nil {
+			return applied, err
+		}
+		if _, err := c.client(TableInfo{}).PutItem(input); err != nil {
+			return applied, fmt.Errorf("record migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, record)
+	}
+	return applied, nil
+}
+
+// RevertMigration applies the down steps of the latest recorded migration and removes its record.
+// ErrNoMigrationToRevert is returned if no migration is recorded and ErrUnknownMigration if
+// the latest recorded migration is not part of migrations.
+func (c *Controller) RevertMigration(ctx context.Context, migrations []Migration) (*AppliedMigration, error) {
+	recorded, err := c.AppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(recorded) == 0 {
+		return nil, ErrNoMigrationToRevert
+	}
+	latest := recorded[len(recorded)-1]
+	for _, m := range migrations {
+		if m.Version != latest.Version {
+			continue
+		}
+		c.Log.Infof("Reverting migration %d_%s", m.Version, m.Name)
+		if err := c.runSteps(ctx, m.Down); err != nil {
+			return nil, fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		input := &dynamodb.DeleteItemInput{
+			TableName: aws.String(c.migrationsTableName()),
+			Key: map[string]*dynamodb.AttributeValue{
+				"version": {N: aws.String(strconv.FormatInt(m.Version, 10))},
+			},
+		}
+		if err := c.audit(ctx, TableInfo{}, "DeleteItem", input); err != nil {
+			return nil, err
+		}
+		if _, err := c.client(TableInfo{}).DeleteItem(input); err != nil {
+			return nil, fmt.Errorf("remove record of migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		return &latest, nil
+	}
+	return nil, fmt.Errorf("%w: %d_%s", ErrUnknownMigration, latest.Version, latest.Name)
+}
+
+// runSteps applies the steps in order and stops at the first error.
+func (c *Controller) runSteps(ctx context.Context, steps []MigrationStep) error {
+	for i, step := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.runStep(ctx, step); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (c *Controller) runStep(ctx context.Context, step MigrationStep) error {
+	if step.Data != "" {
+		fn, ok := c.dataSteps[step.Data]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownDataStep, step.Data)
+		}
+		return fn(ctx, c.client(TableInfo{}))
+	}
+
+	var name string
+	switch {
+	case step.AddIndex != nil:
+		name = step.AddIndex.Table
+	case step.RemoveIndex != nil:
+		name = step.RemoveIndex.Table
+	case step.UpdateThroughput != nil:
+		name = step.UpdateThroughput.Table
+	case step.UpdateTTL != nil:
+		name = step.UpdateTTL.Table
+	}
+	tbl, err := c.lookupTable(name)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case step.AddIndex != nil:
+		index := step.AddIndex.Index
+		if index.ReadThroughput == 0 && index.WriteThroughput == 0 {
+			index.ReadThroughput, index.WriteThroughput = tbl.ReadThroughput, tbl.WriteThroughput
+		}
+		gsi := NewGlobalSecondaryIndex(index)
+		if billingMode(tbl) == dynamodb.BillingModePayPerRequest {
+			gsi.ProvisionedThroughput = nil
+		}
+		input := &dynamodb.UpdateTableInput{
+			TableName: aws.String(physicalName(c.env, tbl)),
+			AttributeDefinitions: []*dynamodb.AttributeDefinition{
+				{AttributeName: aws.String(index.PrimaryKey), AttributeType: aws.String(index.PrimaryKeyType)},
+			},
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+				Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             gsi.IndexName,
+					KeySchema:             gsi.KeySchema,
+					Projection:            gsi.Projection,
+					ProvisionedThroughput: gsi.ProvisionedThroughput,
+				},
+			}},
+		}
+		if index.SortKey != "" {
+			input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+				AttributeName: aws.String(index.SortKey),
+				AttributeType: aws.String(index.SortKeyType),
+			})
+		}
+		if err := c.updateTable(ctx, tbl, input); err != nil {
+			return err
+		}
+		if c.indexWaitTimeout > 0 {
+			return c.waitForIndexes(ctx, tbl, []string{index.IndexName}, c.indexWaitTimeout)
+		}
+		return nil
+	case step.RemoveIndex != nil:
+		return c.updateTable(ctx, tbl, &dynamodb.UpdateTableInput{
+			TableName: aws.String(physicalName(c.env, tbl)),
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+				Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(step.RemoveIndex.IndexName)},
+			}},
+		})
+	case step.UpdateThroughput != nil:
+		return c.updateTable(ctx, tbl, &dynamodb.UpdateTableInput{
+			TableName: aws.String(physicalName(c.env, tbl)),
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(step.UpdateThroughput.ReadThroughput),
+				WriteCapacityUnits: aws.Int64(step.UpdateThroughput.WriteThroughput),
+			},
+		})
+	default:
+		return c.updateTTL(ctx, tbl, NewUpdateTimeToLiveInput(tbl, c.env, &step.UpdateTTL.TTL))
+	}
+}
+
+// migrationsTableName returns the name of the migrations table in DynamoDB.
+func (c *Controller) migrationsTableName() string {
+	if c.env == "" {
+		return c.migrationsTable
+	}
+	return fmt.Sprintf("%s-%s", c.env, c.migrationsTable)
+}
+
+// ensureMigrationsTable creates the on-demand migrations table keyed by version if it is missing.
+func (c *Controller) ensureMigrationsTable(ctx context.Context) error {
+	name := c.migrationsTableName()
+	client := c.client(TableInfo{})
+	_, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if !isNotFound(err) {
+		return err
+	}
+	c.Log.Infof("Creating migrations table %s", name)
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("version"), AttributeType: aws.String(dynamodb.ScalarAttributeTypeN)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("version"), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}
+	if err := c.audit(ctx, TableInfo{}, "CreateTable", input); err != nil {
+		return err
+	}
+	if _, err := client.CreateTable(input); err != nil {
+		return err
+	}
+	// Migrations are recorded right away, which needs the table to be ACTIVE.
+	return c.waitForTable(ctx, TableInfo{TableName: name}, migrationsTableTimeout)
+}
+
+func (m AppliedMigration) item() map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"version":    {N: aws.String(strconv.FormatInt(m.Version, 10))},
+		"name":       {S: aws.String(m.Name)},
+		"applied_at": {S: aws.String(m.AppliedAt.UTC().Format(time.RFC3339))},
+		"run_id":     {S: aws.String(m.RunID)},
+	}
+	// Empty descriptions are omitted, DynamoDB rejected empty strings in older API versions.
+	if m.Description != "" {
+		item["description"] = &dynamodb.AttributeValue{S: aws.String(m.Description)}
+	}
+	return item
+}
+
+func appliedMigration(item map[string]*dynamodb.AttributeValue) AppliedMigration {
+	m := AppliedMigration{}
+	if v := item["version"]; v != nil {
+		m.Version, _ = strconv.ParseInt(aws.StringValue(v.N), 10, 64)
+	}
+	if v := item["name"]; v != nil {
+		m.Name = aws.StringValue(v.S)
+	}
+	if v := item["applied_at"]; v != nil {
+		m.AppliedAt, _ = time.Parse(time.RFC3339, aws.StringValue(v.S))
+	}
+	if v := item["run_id"]; v != nil {
+		m.RunID = aws.StringValue(v.S)
+	}
+	if v := item["description"]; v != nil {
+		m.Description = aws.StringValue(v.S)
+	}
+	return m
+}
+
+// isNotFound reports whether err is a ResourceNotFoundException.
+func isNotFound(err error) bool {
+	return isAWSError(err, dynamodb.ErrCodeResourceNotFoundException)
+}
@@ -0,0 +1,194 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// migrationsDynamoDB stores the items of the migrations table in memory.
+type migrationsDynamoDB struct {
+	fakeDynamoDB
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func (f *migrationsDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.items[aws.StringValue(input.Item["version"].N)] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *migrationsDynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	delete(f.items, aws.StringValue(input.Key["version"].N))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *migrationsDynamoDB) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	if f.items == nil {
+		return awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	page := &dynamodb.ScanOutput{}
+	for _, item := range f.items {
+		page.Items = append(page.Items, item)
+	}
+	fn(page, true)
+	return nil
+}
+
+func writeMigration(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeMigration(t, dir, "0002_backfill.yaml", "up:\n  - data: backfill_email\n")
+	writeMigration(t, dir, "0001_add_by_email.yaml", `
+up:
+  - add_index:
+      table: orders
+      index:
+        index_name: by_email
+        primary_key: email
+        primary_key_type: S
+down:
+  - remove_index:
+      table: orders
+      index_name: by_email
+`)
+	writeMigration(t, dir, "README.md", "not a migration")
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[0].Name != "add_by_email" || migrations[1].Version != 2 {
+		t.Fatalf("expected migrations 1 and 2 in order but got %+v", migrations)
+	}
+	if step := migrations[0].Up[0].AddIndex; step == nil || step.Index.IndexName != "by_email" {
+		t.Fatalf("expected add_index step but got %+v", migrations[0].Up[0])
+	}
+
+	writeMigration(t, dir, "2_duplicate.yaml", "up: []\n")
+	if _, err := LoadMigrations(dir); !errors.Is(err, ErrDuplicateMigration) {
+		t.Fatalf("expected ErrDuplicateMigration but got %v", err)
+	}
+	os.Remove(filepath.Join(dir, "2_duplicate.yaml"))
+
+	writeMigration(t, dir, "0003_invalid.yaml", "up:\n  - data: a\n    remove_index: {table: orders, index_name: by_email}\n")
+	if _, err := LoadMigrations(dir); !errors.Is(err, ErrInvalidMigration) {
+		t.Fatalf("expected ErrInvalidMigration but got %v", err)
+	}
+}
+
+func TestApplyAndRevertMigrations(t *testing.T) {
+	db := &migrationsDynamoDB{}
+	createdTable := ""
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		if db.items == nil {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{TableStatus: aws.String(dynamodb.TableStatusActive)}}, nil
+	}
+	db.createTable = func(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		createdTable = aws.StringValue(input.TableName)
+		db.items = map[string]map[string]*dynamodb.AttributeValue{}
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	updates := []*dynamodb.UpdateTableInput{}
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		updates = append(updates, input)
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	backfills := 0
+	c, err := NewController(db, "test", nil, []TableInfo{{TableName: "orders", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5}},
+		WithClock(&fakeClock{}), WithDataStep("backfill_email", func(context.Context, DynamoDBAPI) error {
+			backfills++
+			return nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations := []Migration{
+		{
+			Version: 1,
+			Name:    "add_by_email",
+			Up: []MigrationStep{{AddIndex: &AddIndexStep{Table: "orders", Index: IndexInfo{
+				IndexName: "by_email", PrimaryKey: "email", PrimaryKeyType: "S",
+			}}}},
+			Down: []MigrationStep{{RemoveIndex: &RemoveIndexStep{Table: "orders", IndexName: "by_email"}}},
+		},
+		{Version: 2, Name: "backfill", Up: []MigrationStep{{Data: "backfill_email"}}},
+	}
+	ctx := ContextWithChangeDescription(context.Background(), "release 42")
+	migrations[0].Description = "login by email"
+	applied, err := c.ApplyMigrations(ctx, migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if createdTable != "test-schema_migrations" {
+		t.Fatalf("expected migrations table test-schema_migrations but got %q", createdTable)
+	}
+	if len(applied) != 2 || len(updates) != 1 || backfills != 1 || len(db.items) != 2 {
+		t.Fatalf("expected 2 applied migrations but got applied=%v updates=%d backfills=%d records=%d", applied, len(updates), backfills, len(db.items))
+	}
+	if recorded, err := c.AppliedMigrations(); err != nil || recorded[0].RunID == "" || recorded[0].RunID != applied[1].RunID {
+		t.Fatalf("expected both migrations to be recorded with the run ID but got %v, %v", recorded, err)
+	}
+	if recorded, _ := c.AppliedMigrations(); recorded[0].Description != "release 42; login by email" || recorded[1].Description != "release 42" {
+		t.Fatalf("expected descriptions to be recorded but got %+v", recorded)
+	}
+	create := updates[0].GlobalSecondaryIndexUpdates[0].Create
+	if create == nil || aws.StringValue(create.IndexName) != "by_email" {
+		t.Fatalf("expected by_email to be created but got %v", updates[0])
+	}
+
+	// Recorded migrations are not applied again.
+	if applied, err := c.ApplyMigrations(context.Background(), migrations); err != nil || len(applied) != 0 {
+		t.Fatalf("expected no migrations to be applied but got %v, %v", applied, err)
+	}
+
+	reverted, err := c.RevertMigration(context.Background(), migrations)
+	if err != nil || reverted.Version != 2 {
+		t.Fatalf("expected migration 2 to be reverted but got %v, %v", reverted, err)
+	}
+	reverted, err = c.RevertMigration(context.Background(), migrations)
+	if err != nil || reverted.Version != 1 {
+		t.Fatalf("expected migration 1 to be reverted but got %v, %v", reverted, err)
+	}
+	if len(updates) != 2 || updates[1].GlobalSecondaryIndexUpdates[0].Delete == nil {
+		t.Fatalf("expected by_email to be deleted but got %v", updates)
+	}
+	if _, err := c.RevertMigration(context.Background(), migrations); err != ErrNoMigrationToRevert {
+		t.Fatalf("expected ErrNoMigrationToRevert but got %v", err)
+	}
+}
+
+func TestApplyMigrationsUnknownDataStep(t *testing.T) {
+	db := &migrationsDynamoDB{items: map[string]map[string]*dynamodb.AttributeValue{}}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{}, nil
+	}
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.ApplyMigrations(context.Background(), []Migration{{Version: 1, Name: "backfill", Up: []MigrationStep{{Data: "missing"}}}})
+	if !errors.Is(err, ErrUnknownDataStep) || len(db.items) != 0 {
+		t.Fatalf("expected ErrUnknownDataStep without record but got %v, %v", err, db.items)
+	}
+}
@@ -0,0 +1,53 @@
+package tables
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestCheckNaming(t *testing.T) {
+	data := []TableInfo{
+		{TableName: "orders", PrimaryKey: "order_id", SortKey: "status", Indexes: []IndexInfo{
+			{IndexName: "byUser", PrimaryKey: "userId"},
+		}},
+		{TableName: "app_users", PrimaryKey: "user_id", TTL: &TTLAttributeInfo{AttributeName: "ttl", Enabled: true}},
+	}
+	warnings, err := CheckNaming(data, NamingConvention{Case: SnakeCase, TablePrefix: "app_", IndexPrefix: "by_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Warning{
+		{TableName: "orders", Message: "table name does not start with app_"},
+		{TableName: "orders", Message: "index byUser does not start with by_"},
+		{TableName: "orders", Message: "index byUser is not snake_case"},
+		{TableName: "orders", Message: "attribute status is a DynamoDB reserved word and requires ExpressionAttributeNames"},
+		{TableName: "orders", Message: "attribute userId is not snake_case"},
+		{TableName: "app_users", Message: "attribute ttl is a DynamoDB reserved word and requires ExpressionAttributeNames"},
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("expected %v but got %v", want, warnings)
+	}
+
+	if _, err := CheckNaming(data, NamingConvention{Case: "SCREAMING"}); !errors.Is(err, ErrInvalidNamingConvention) {
+		t.Fatalf("expected ErrInvalidNamingConvention but got %v", err)
+	}
+}
+
+func TestNamingWarningsInResults(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	c, err := NewController(db, "", nil, []TableInfo{{TableName: "orders", PrimaryKey: "name"}}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := c.ValidateTable("orders")
+	if len(r.Warnings) != 1 || r.Warnings[0] != "attribute name is a DynamoDB reserved word and requires ExpressionAttributeNames" {
+		t.Fatalf("expected reserved word warning but got %v", r.Warnings)
+	}
+}
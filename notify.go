@@ -0,0 +1,121 @@
+package tables
+
+import (
+	"context"
+	"time"
+)
+
+// notifyTimeout bounds the notifications of a run. They are sent with a context of their own,
+// so runs which were cancelled or timed out are still reported.
+const notifyTimeout = 30 * time.Second
+
+// Notifier is told about every Migrate run once it finished, e.g. to post a summary to a chat channel.
+// Notification errors are logged and do not fail the run.
+type Notifier interface {
+	Notify(ctx context.Context, report *RunReport) error
+}
+
+// ValidationNotifier is a Notifier which is also told about every Validate, ValidateFleet,
+// ValidateFiltered and ValidateTable, e.g. to publish drift detected by scheduled validations
+// which never migrate.
+type ValidationNotifier interface {
+	Notifier
+	NotifyValidation(ctx context.Context, report *ValidationRunReport) error
+}
+
+// ValidationRunReport describes a finished Validate.
+type ValidationRunReport struct {
+	// Env is the environment of the controller.
+	Env     string
+	Results []*ValidationResult
+	// Partial is true if only some of the managed tables were validated, by ValidateFiltered
+	// and ValidateTable or by a ValidateFleet whose context was done early.
+	Partial bool
+}
+
+// RunReport describes a finished Migrate run.
+type RunReport struct {
+	RunID string
+	// Env is the environment of the controller.
+	Env string
+	// Description is the stated intent of the run, see ContextWithChangeDescription.
+	Description string
+	Duration    time.Duration
+	// Plan holds the validation results passed to Migrate.
+	Plan    []*ValidationResult
+	Results []*MigrationResult
+}
+
+// Changed returns the results of tables which had at least one change applied.
+func (r *RunReport) Changed() []*MigrationResult {
+	changed := []*MigrationResult{}
+	for _, m := range r.Results {
+		if m != nil && len(m.Applied) > 0 {
+			changed = append(changed, m)
+		}
+	}
+	return changed
+}
+
+// Destructive returns the applied changes which can affect existing data.
+func (r *RunReport) Destructive() []Change {
+	destructive := []Change{}
+	for _, m := range r.Results {
+		if m == nil {
+			continue
+		}
+		for _, ch := range m.Applied {
+			if ch.Destructive {
+				destructive = append(destructive, ch)
+			}
+		}
+	}
+	return destructive
+}
+
+// Failed returns the results with errors.
+func (r *RunReport) Failed() []*MigrationResult {
+	failed := []*MigrationResult{}
+	for _, m := range r.Results {
+		if m != nil && len(m.Errors) > 0 {
+			failed = append(failed, m)
+		}
+	}
+	return failed
+}
+
+// notify reports a finished run to all notifiers.
+func (c *Controller) notify(report *RunReport) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	for _, n := range c.notifiers {
+		if err := n.Notify(ctx, report); err != nil {
+			c.Log.Errorf("Notify run %s with error: %v", report.RunID, err)
+		}
+	}
+}
+
+// hasValidationNotifiers reports whether any notifier is a ValidationNotifier.
+func (c *Controller) hasValidationNotifiers() bool {
+	for _, n := range c.notifiers {
+		if _, ok := n.(ValidationNotifier); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyValidation reports a finished validation to all validation notifiers.
+func (c *Controller) notifyValidation(report *ValidationRunReport) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	for _, n := range c.notifiers {
+		vn, ok := n.(ValidationNotifier)
+		if !ok {
+			continue
+		}
+		if err := vn.NotifyValidation(ctx, report); err != nil {
+			c.Log.Errorf("Notify validation with error: %v", err)
+		}
+	}
+}
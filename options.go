@@ -0,0 +1,394 @@
+package tables
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/google/go-cmp/cmp"
+)
+
+// Option configures optional Controller behaviour.
+type Option func(*Controller)
+
+// WithClientFactory makes the Controller build its DynamoDB clients through f
+// instead of using the client passed to NewController.
+func WithClientFactory(f ClientFactory) Option {
+	return func(c *Controller) {
+		c.clientFactory = f
+	}
+}
+
+// WithRegion sets the default region passed to the ClientFactory.
+func WithRegion(region string) Option {
+	return func(c *Controller) {
+		c.region = region
+	}
+}
+
+// WithAccount sets the default account passed to the ClientFactory.
+func WithAccount(account string) Option {
+	return func(c *Controller) {
+		c.account = account
+	}
+}
+
+// WithMaxChanges limits the number of changes a single Migrate or Rollback run may apply.
+// A run exceeding the limit applies nothing and reports a *ChangeBudgetError.
+func WithMaxChanges(n int) Option {
+	return func(c *Controller) {
+		c.maxChanges = n
+	}
+}
+
+// WithMaxDestructiveChanges limits the number of destructive changes a single Migrate or Rollback run may apply.
+// Pass 0 to reject any destructive change.
+func WithMaxDestructiveChanges(n int) Option {
+	return func(c *Controller) {
+		c.maxDestructiveChanges = n
+	}
+}
+
+// WithAllowCapacityDecrease lets Migrate and Rollback apply throughput reductions without approval.
+// By default capacity decreases are skipped because they can throttle production traffic.
+func WithAllowCapacityDecrease() Option {
+	return func(c *Controller) {
+		c.allowCapacityDecrease = true
+	}
+}
+
+// WithCapacityDecreaseApprover sets the approver consulted before throughput reductions are applied.
+func WithCapacityDecreaseApprover(approver CapacityDecreaseApprover) Option {
+	return func(c *Controller) {
+		c.capacityDecreaseApprover = approver
+	}
+}
+
+// WithWaitForIndexes makes Migrate return only once newly created GSIs are ACTIVE.
+// ErrWaitTimeout is reported in the Migration Result if the indexes are not ACTIVE within timeout.
+func WithWaitForIndexes(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.indexWaitTimeout = timeout
+	}
+}
+
+// WithWaitForBusyTables makes Migrate wait up to timeout for tables which are not ACTIVE
+// (e.g. CREATING or UPDATING) instead of skipping them with StatusBusy.
+func WithWaitForBusyTables(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.busyWaitTimeout = timeout
+	}
+}
+
+// WithClock replaces the clock used for retry and wait delays, e.g. with a fake clock in tests.
+func WithClock(clock Clock) Option {
+	return func(c *Controller) {
+		c.clock = clock
+	}
+}
+
+// WithRetryPolicy sets the retry policy for requests failing with the given AWS error code.
+// A policy with zero MaxAttempts disables retries for the code.
+func WithRetryPolicy(code string, policy RetryPolicy) Option {
+	return func(c *Controller) {
+		if policy.MaxAttempts <= 0 {
+			delete(c.retryPolicies, code)
+			return
+		}
+		c.retryPolicies[code] = policy
+	}
+}
+
+// WithStopOnError makes Migrate apply tables one by one in order and abort the run when
+// the first table fails. The remaining tables are reported with StatusAborted.
+// By default independent tables are migrated concurrently and failures do not affect other tables.
+func WithStopOnError() Option {
+	return func(c *Controller) {
+		c.stopOnError = true
+	}
+}
+
+// WithTableTimeout limits the duration of each table migration, including waits for
+// busy tables and index backfills. A table exceeding it is reported with StatusTimedOut
+// and its last observed table status.
+func WithTableTimeout(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.tableTimeout = timeout
+	}
+}
+
+// WithFilter scopes Validate runs to the tables selected by f.
+func WithFilter(f Filter) Option {
+	return func(c *Controller) {
+		c.filter = f
+	}
+}
+
+// WithSelector scopes Validate runs to the tables matching the label selector, see LabelFilter.
+// NewController returns ErrInvalidSelector if the selector is invalid.
+func WithSelector(selector string) Option {
+	return func(c *Controller) {
+		f, err := LabelFilter(selector)
+		if err != nil {
+			if c.optionErr == nil {
+				c.optionErr = err
+			}
+			return
+		}
+		c.filter = f
+	}
+}
+
+// WithPrune enables prune mode: Validate schedules the deletion of deprecated tables
+// which still exist, and Migrate deletes them once approver consents.
+func WithPrune(approver DeletionApprover) Option {
+	return func(c *Controller) {
+		c.prune = true
+		c.pruneApprover = approver
+	}
+}
+
+// WithCmpOptions registers additional cmp.Options used by every schema comparison of the
+// Controller, e.g. cmpopts.IgnoreSliceElements to ignore specific projection attributes.
+func WithCmpOptions(opts ...cmp.Option) Option {
+	return func(c *Controller) {
+		c.cmpOptions = append(c.cmpOptions, opts...)
+	}
+}
+
+// WithFastDiff checks schemas with hand-written comparers before diffing them with cmp, which
+// is reflective and allocation heavy. Only values which differ are diffed, so validating large
+// schemas which are mostly in sync, e.g. thousands of GSIs, is much cheaper. Results are the
+// same as without the option. It has no effect together with WithCmpOptions.
+func WithFastDiff() Option {
+	return func(c *Controller) {
+		c.fastDiff = true
+	}
+}
+
+// WithTTLSampling makes Validate scan up to n items of tables with TTL enabled and warn
+// when the TTL attribute is missing or not a numeric epoch value in seconds.
+func WithTTLSampling(n int64) Option {
+	return func(c *Controller) {
+		c.ttlSampleSize = n
+	}
+}
+
+// WithWaitForTTL makes Migrate poll the TTL after UpdateTimeToLive until it is ENABLED or
+// DISABLED and record the final state in the Migration Result. ErrWaitTimeout is reported
+// if the transition does not complete within timeout.
+func WithWaitForTTL(timeout time.Duration) Option {
+	return func(c *Controller) {
+		c.ttlWaitTimeout = timeout
+	}
+}
+
+// WithTTLChangeApprover sets the approver consulted before Migrate or Rollback disables TTL or switches
+// the TTL attribute of an existing table. Without approval such changes are skipped with
+// ErrTTLChangeNotApproved.
+func WithTTLChangeApprover(approver TTLChangeApprover) Option {
+	return func(c *Controller) {
+		c.ttlChangeApprover = approver
+	}
+}
+
+// WithFingerprints makes Migrate tag tables in sync with their config with a
+// fingerprint of the config, see FingerprintTagKey. Pass the account ID via WithAccount to avoid describing
+// tables for their ARN.
+func WithFingerprints() Option {
+	return func(c *Controller) {
+		c.fingerprints = true
+	}
+}
+
+// WithFingerprintRefresh enables fingerprints and makes Validate tag tables a full comparison
+// found in sync, so tables changed outside of Migrate or with an outdated fingerprint are fast
+// to validate again. Validate makes no changes without it.
+func WithFingerprintRefresh() Option {
+	return func(c *Controller) {
+		c.fingerprints = true
+		c.refreshFingerprints = true
+	}
+}
+
+// WithFastValidate enables fingerprints and makes Validate report tables whose fingerprint
+// matches their config as in sync without comparing them. Changes made outside of Migrate are
+// not detected for such tables, so a full comparison is forced once the fingerprint is older
+// than fullEvery. Fingerprints are only refreshed by Migrate unless WithFingerprintRefresh is set.
+// Zero fullEvery never forces a full comparison.
+func WithFastValidate(fullEvery time.Duration) Option {
+	return func(c *Controller) {
+		c.fingerprints = true
+		c.fastValidate = true
+		c.fullValidationInterval = fullEvery
+	}
+}
+
+// WithTenants makes the Controller manage a copy of each configured table per tenant,
+// named title-env-tenant-name. Validate, Migrate and Reset operate on all copies, the
+// tenant of a result is set in its TableInput. Tables with a tenant set in the config
+// are not copied.
+func WithTenants(tenants ...string) Option {
+	return func(c *Controller) {
+		c.tenants = tenants
+	}
+}
+
+// WithCreateConcurrency sets the maximum number of tables CreateAll creates at the same time,
+// DefaultCreateConcurrency by default. Values below 1 are ignored.
+func WithCreateConcurrency(n int) Option {
+	return func(c *Controller) {
+		if n > 0 {
+			c.createConcurrency = n
+		}
+	}
+}
+
+// WithDescribeCacheTTL sets how long Describe caches table states, zero disables caching.
+func WithDescribeCacheTTL(ttl time.Duration) Option {
+	return func(c *Controller) {
+		c.describeCacheTTL = ttl
+	}
+}
+
+// WithCreateTableInputHook registers a hook adjusting every CreateTableInput right before
+// it is sent, e.g. to inject an SSESpecification or company specific settings.
+func WithCreateTableInputHook(hook CreateTableInputHook) Option {
+	return func(c *Controller) {
+		c.inputHooks.createTable = append(c.inputHooks.createTable, hook)
+	}
+}
+
+// WithUpdateTableInputHook registers a hook adjusting every UpdateTableInput right before it is sent.
+func WithUpdateTableInputHook(hook UpdateTableInputHook) Option {
+	return func(c *Controller) {
+		c.inputHooks.updateTable = append(c.inputHooks.updateTable, hook)
+	}
+}
+
+// WithUpdateTTLInputHook registers a hook adjusting every UpdateTimeToLiveInput right before it is sent.
+func WithUpdateTTLInputHook(hook UpdateTTLInputHook) Option {
+	return func(c *Controller) {
+		c.inputHooks.updateTTL = append(c.inputHooks.updateTTL, hook)
+	}
+}
+
+// WithHooks sets lifecycle hooks called around the changes applied by Migrate and CreateAll.
+func WithHooks(hooks Hooks) Option {
+	return func(c *Controller) {
+		c.hooks = hooks
+	}
+}
+
+// WithResourceManagers registers managers of resources associated with each table, e.g. alarms
+// or IAM grants, which are validated and migrated together with the table schemas.
+func WithResourceManagers(managers ...ResourceManager) Option {
+	return func(c *Controller) {
+		c.resourceManagers = append(c.resourceManagers, managers...)
+	}
+}
+
+// WithFleetConcurrency sets the maximum number of tables ValidateFleet compares at the same time,
+// DefaultFleetConcurrency by default. Values below 1 are ignored.
+func WithFleetConcurrency(n int) Option {
+	return func(c *Controller) {
+		if n > 0 {
+			c.fleetConcurrency = n
+		}
+	}
+}
+
+// WithConfigRevision sets the revision of the config, e.g. the commit hash it was loaded from.
+// It is tagged on created tables and stored fingerprints next to the run ID.
+func WithConfigRevision(rev string) Option {
+	return func(c *Controller) {
+		c.configRevision = rev
+	}
+}
+
+// WithRunApprover sets an approver consulted before a Migrate run applies any change,
+// e.g. the Approve method of an SNSApprovalGate.
+func WithRunApprover(approver RunApprover) Option {
+	return func(c *Controller) {
+		c.runApprover = approver
+	}
+}
+
+// WithNotifiers registers notifiers told about every finished Migrate run, e.g. a SlackNotifier.
+func WithNotifiers(notifiers ...Notifier) Option {
+	return func(c *Controller) {
+		c.notifiers = append(c.notifiers, notifiers...)
+	}
+}
+
+// WithCloudWatchMetrics publishes drift and migration metrics of every Migrate run, and drift
+// metrics of every Validate and ValidateFleet, to CloudWatch, see CloudWatchMetrics. An empty namespace means DefaultMetricsNamespace.
+func WithCloudWatchMetrics(cw cloudwatchiface.CloudWatchAPI, namespace string) Option {
+	return WithNotifiers(&CloudWatchMetrics{CloudWatch: cw, Namespace: namespace})
+}
+
+// WithKMSClient sets the KMS client SecurityPosture uses to tell customer managed keys
+// from AWS managed keys. Without it no table is reported as encrypted with a CMK.
+func WithKMSClient(client kmsiface.KMSAPI) Option {
+	return func(c *Controller) {
+		c.kms = client
+	}
+}
+
+// WithUtilizationAdvisories adds advisories to the validation results of provisioned tables whose
+// consumed capacity, read from CloudWatch over the window, is consistently far below or near the
+// configured throughput. window defaults to DefaultUtilizationWindow if zero.
+func WithUtilizationAdvisories(cw cloudwatchiface.CloudWatchAPI, window time.Duration) Option {
+	return func(c *Controller) {
+		if window <= 0 {
+			window = DefaultUtilizationWindow
+		}
+		c.utilizationCloudWatch = cw
+		c.utilizationWindow = window
+	}
+}
+
+// WithNamingConvention sets the naming convention NewController and Refresh check the tables against, in addition
+// to DynamoDB reserved words. Violations are logged and added to the warnings of the validation results.
+func WithNamingConvention(conv NamingConvention) Option {
+	return func(c *Controller) {
+		c.naming = conv
+	}
+}
+
+// WithCompliance enables compliance enforcement. The requirements are added to the desired state
+// of every managed table and violations are reported as drift which Migrate fixes, e.g. to require
+// point in time recovery everywhere and deletion protection in prod only.
+func WithCompliance(req Compliance) Option {
+	return func(c *Controller) {
+		c.compliance = &req
+	}
+}
+
+// WithMigrationsTable sets the name of the table recording applied migrations,
+// DefaultMigrationsTable by default.
+func WithMigrationsTable(name string) Option {
+	return func(c *Controller) {
+		c.migrationsTable = name
+	}
+}
+
+// WithDataStep registers a data step which migrations refer to by name.
+func WithDataStep(name string, step DataStep) Option {
+	return func(c *Controller) {
+		if c.dataSteps == nil {
+			c.dataSteps = make(map[string]DataStep)
+		}
+		c.dataSteps[name] = step
+	}
+}
+
+// WithAuditLog records the JSON request payload of every mutating DynamoDB call, with its run ID
+// and time, before it is sent, e.g. via NewAuditWriter or an S3AuditLog. Retried calls are recorded
+// per attempt, calls which cannot be recorded are not sent. The CloudWatch and DAX calls of
+// AlarmManager and DAXManager are recorded as well, other resource managers are not.
+func WithAuditLog(log AuditLog) Option {
+	return func(c *Controller) {
+		c.auditLog = log
+	}
+}
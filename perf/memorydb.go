@@ -0,0 +1,286 @@
+package perf
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jacygao/tables"
+)
+
+// MemoryDB is an in-memory DynamoDB serving the table management calls of a tables.Controller.
+// Tables and indexes become ACTIVE immediately, items are not stored. Other calls panic.
+// It is safe for concurrent use.
+type MemoryDB struct {
+	tables.DynamoDBAPI
+
+	mu     sync.Mutex
+	tables map[string]*dynamodb.TableDescription
+	ttl    map[string]*dynamodb.TimeToLiveDescription
+	tags   map[string]map[string]string
+}
+
+// NewMemoryDB returns an empty in-memory DynamoDB.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		tables: map[string]*dynamodb.TableDescription{},
+		ttl:    map[string]*dynamodb.TimeToLiveDescription{},
+		tags:   map[string]map[string]string{},
+	}
+}
+
+func notFound(name *string) error {
+	return awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found: "+aws.StringValue(name), nil)
+}
+
+func (db *MemoryDB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	name := aws.StringValue(input.TableName)
+	if _, ok := db.tables[name]; ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceInUseException, "table exists: "+name, nil)
+	}
+	billingMode := aws.StringValue(input.BillingMode)
+	if billingMode == "" {
+		billingMode = dynamodb.BillingModeProvisioned
+	}
+	desc := &dynamodb.TableDescription{
+		TableName:             input.TableName,
+		TableArn:              aws.String("arn:aws:dynamodb:local:000000000000:table/" + name),
+		TableStatus:           aws.String(dynamodb.TableStatusActive),
+		CreationDateTime:      aws.Time(time.Now()),
+		KeySchema:             input.KeySchema,
+		AttributeDefinitions:  input.AttributeDefinitions,
+		BillingModeSummary:    &dynamodb.BillingModeSummary{BillingMode: aws.String(billingMode)},
+		ProvisionedThroughput: throughputDescription(input.ProvisionedThroughput),
+		StreamSpecification:   input.StreamSpecification,
+	}
+	for _, gsi := range input.GlobalSecondaryIndexes {
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:             gsi.IndexName,
+			IndexStatus:           aws.String(dynamodb.IndexStatusActive),
+			KeySchema:             gsi.KeySchema,
+			Projection:            gsi.Projection,
+			ProvisionedThroughput: throughputDescription(gsi.ProvisionedThroughput),
+		})
+	}
+	for _, lsi := range input.LocalSecondaryIndexes {
+		desc.LocalSecondaryIndexes = append(desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+	desc = awsutil.CopyOf(desc).(*dynamodb.TableDescription)
+	db.tables[name] = desc
+	tags := map[string]string{}
+	for _, t := range input.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	db.tags[aws.StringValue(desc.TableArn)] = tags
+	return &dynamodb.CreateTableOutput{TableDescription: awsutil.CopyOf(desc).(*dynamodb.TableDescription)}, nil
+}
+
+// throughputDescription returns the description of provisioned throughput, zero for on-demand.
+func throughputDescription(pt *dynamodb.ProvisionedThroughput) *dynamodb.ProvisionedThroughputDescription {
+	if pt == nil {
+		return &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(0), WriteCapacityUnits: aws.Int64(0)}
+	}
+	return &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: pt.ReadCapacityUnits, WriteCapacityUnits: pt.WriteCapacityUnits}
+}
+
+func (db *MemoryDB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	desc, ok := db.tables[aws.StringValue(input.TableName)]
+	if !ok {
+		return nil, notFound(input.TableName)
+	}
+	return &dynamodb.DescribeTableOutput{Table: awsutil.CopyOf(desc).(*dynamodb.TableDescription)}, nil
+}
+
+func (db *MemoryDB) DescribeTableWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.Option) (*dynamodb.DescribeTableOutput, error) {
+	return db.DescribeTable(input)
+}
+
+// WaitUntilTableExistsWithContext returns immediately, tables are ACTIVE once created.
+func (db *MemoryDB) WaitUntilTableExistsWithContext(ctx aws.Context, input *dynamodb.DescribeTableInput, opts ...request.WaiterOption) error {
+	_, err := db.DescribeTable(input)
+	return err
+}
+
+func (db *MemoryDB) UpdateTable(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	desc, ok := db.tables[aws.StringValue(input.TableName)]
+	if !ok {
+		return nil, notFound(input.TableName)
+	}
+	if input.BillingMode != nil {
+		desc.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: input.BillingMode}
+		if aws.StringValue(input.BillingMode) == dynamodb.BillingModePayPerRequest {
+			desc.ProvisionedThroughput = throughputDescription(nil)
+		}
+	}
+	if input.ProvisionedThroughput != nil {
+		desc.ProvisionedThroughput = throughputDescription(input.ProvisionedThroughput)
+	}
+	for _, attr := range input.AttributeDefinitions {
+		if !hasAttribute(desc.AttributeDefinitions, aws.StringValue(attr.AttributeName)) {
+			desc.AttributeDefinitions = append(desc.AttributeDefinitions, awsutil.CopyOf(attr).(*dynamodb.AttributeDefinition))
+		}
+	}
+	for _, update := range input.GlobalSecondaryIndexUpdates {
+		switch {
+		case update.Create != nil:
+			desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, awsutil.CopyOf(&dynamodb.GlobalSecondaryIndexDescription{
+				IndexName:             update.Create.IndexName,
+				IndexStatus:           aws.String(dynamodb.IndexStatusActive),
+				KeySchema:             update.Create.KeySchema,
+				Projection:            update.Create.Projection,
+				ProvisionedThroughput: throughputDescription(update.Create.ProvisionedThroughput),
+			}).(*dynamodb.GlobalSecondaryIndexDescription))
+		case update.Update != nil:
+			for _, gsi := range desc.GlobalSecondaryIndexes {
+				if aws.StringValue(gsi.IndexName) == aws.StringValue(update.Update.IndexName) {
+					gsi.ProvisionedThroughput = throughputDescription(update.Update.ProvisionedThroughput)
+				}
+			}
+		case update.Delete != nil:
+			kept := desc.GlobalSecondaryIndexes[:0]
+			for _, gsi := range desc.GlobalSecondaryIndexes {
+				if aws.StringValue(gsi.IndexName) != aws.StringValue(update.Delete.IndexName) {
+					kept = append(kept, gsi)
+				}
+			}
+			desc.GlobalSecondaryIndexes = kept
+		}
+	}
+	return &dynamodb.UpdateTableOutput{TableDescription: awsutil.CopyOf(desc).(*dynamodb.TableDescription)}, nil
+}
+
+func hasAttribute(attrs []*dynamodb.AttributeDefinition, name string) bool {
+	for _, attr := range attrs {
+		if aws.StringValue(attr.AttributeName) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *MemoryDB) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	name := aws.StringValue(input.TableName)
+	desc, ok := db.tables[name]
+	if !ok {
+		return nil, notFound(input.TableName)
+	}
+	delete(db.tables, name)
+	delete(db.ttl, name)
+	delete(db.tags, aws.StringValue(desc.TableArn))
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+func (db *MemoryDB) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tables[aws.StringValue(input.TableName)]; !ok {
+		return nil, notFound(input.TableName)
+	}
+	ttl, ok := db.ttl[aws.StringValue(input.TableName)]
+	if !ok {
+		ttl = &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: awsutil.CopyOf(ttl).(*dynamodb.TimeToLiveDescription)}, nil
+}
+
+func (db *MemoryDB) UpdateTimeToLive(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tables[aws.StringValue(input.TableName)]; !ok {
+		return nil, notFound(input.TableName)
+	}
+	status := dynamodb.TimeToLiveStatusDisabled
+	if aws.BoolValue(input.TimeToLiveSpecification.Enabled) {
+		status = dynamodb.TimeToLiveStatusEnabled
+	}
+	db.ttl[aws.StringValue(input.TableName)] = &dynamodb.TimeToLiveDescription{
+		AttributeName:    input.TimeToLiveSpecification.AttributeName,
+		TimeToLiveStatus: aws.String(status),
+	}
+	return &dynamodb.UpdateTimeToLiveOutput{TimeToLiveSpecification: input.TimeToLiveSpecification}, nil
+}
+
+// DescribeContinuousBackups reports point in time recovery as disabled for all tables.
+func (db *MemoryDB) DescribeContinuousBackups(input *dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if _, ok := db.tables[aws.StringValue(input.TableName)]; !ok {
+		return nil, notFound(input.TableName)
+	}
+	return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: &dynamodb.ContinuousBackupsDescription{
+		ContinuousBackupsStatus: aws.String(dynamodb.ContinuousBackupsStatusEnabled),
+		PointInTimeRecoveryDescription: &dynamodb.PointInTimeRecoveryDescription{
+			PointInTimeRecoveryStatus: aws.String(dynamodb.PointInTimeRecoveryStatusDisabled),
+		},
+	}}, nil
+}
+
+func (db *MemoryDB) ListTablesPages(input *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool) error {
+	db.mu.Lock()
+	names := make([]string, 0, len(db.tables))
+	for name := range db.tables {
+		names = append(names, name)
+	}
+	db.mu.Unlock()
+	sort.Strings(names)
+	fn(&dynamodb.ListTablesOutput{TableNames: aws.StringSlice(names)}, true)
+	return nil
+}
+
+func (db *MemoryDB) ListTagsOfResource(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	output := &dynamodb.ListTagsOfResourceOutput{}
+	keys := []string{}
+	for k := range db.tags[aws.StringValue(input.ResourceArn)] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		output.Tags = append(output.Tags, &dynamodb.Tag{Key: aws.String(k), Value: aws.String(db.tags[aws.StringValue(input.ResourceArn)][k])})
+	}
+	return output, nil
+}
+
+func (db *MemoryDB) TagResource(input *dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tags, ok := db.tags[aws.StringValue(input.ResourceArn)]
+	if !ok {
+		return nil, notFound(input.ResourceArn)
+	}
+	for _, t := range input.Tags {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return &dynamodb.TagResourceOutput{}, nil
+}
+
+func (db *MemoryDB) UntagResource(input *dynamodb.UntagResourceInput) (*dynamodb.UntagResourceOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tags, ok := db.tags[aws.StringValue(input.ResourceArn)]
+	if !ok {
+		return nil, notFound(input.ResourceArn)
+	}
+	for _, k := range input.TagKeys {
+		delete(tags, aws.StringValue(k))
+	}
+	return &dynamodb.UntagResourceOutput{}, nil
+}
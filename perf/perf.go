@@ -0,0 +1,246 @@
+// Package perf measures the comparison and migration engines of package tables on synthetic
+// configs, against a MemoryDB or DynamoDB Local, so performance regressions are measurable.
+package perf
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/jacygao/tables"
+)
+
+// GenerateConfig returns a config of n provisioned tables with m GSIs and TTL each.
+func GenerateConfig(n, m int) []tables.TableInfo {
+	config := make([]tables.TableInfo, n)
+	for i := range config {
+		tbl := tables.TableInfo{
+			TableName:       fmt.Sprintf("table-%05d", i),
+			PrimaryKey:      "id",
+			SortKey:         "created",
+			SortKeyType:     "N",
+			ReadThroughput:  5,
+			WriteThroughput: 5,
+			TTL:             &tables.TTLAttributeInfo{AttributeName: "expiry", Enabled: true},
+		}
+		for j := 0; j < m; j++ {
+			tbl.Indexes = append(tbl.Indexes, tables.IndexInfo{
+				IndexName:       fmt.Sprintf("by_attr%02d", j),
+				PrimaryKey:      fmt.Sprintf("attr%02d", j),
+				PrimaryKeyType:  "S",
+				SortKey:         "created",
+				SortKeyType:     "N",
+				ReadThroughput:  1,
+				WriteThroughput: 1,
+				ProjectedFields: []string{"status"},
+			})
+		}
+		config[i] = tbl
+	}
+	return config
+}
+
+// Options configures a harness run.
+type Options struct {
+	// Tables and GSIs set the size of the generated config, see GenerateConfig.
+	Tables int
+	GSIs   int
+	// Iterations per measured operation, 1 if zero.
+	Iterations int
+	// DB is the DynamoDB to run against, e.g. a client of DynamoDB Local. A MemoryDB if nil.
+	// All tables of Env are deleted between iterations.
+	DB tables.DynamoDBAPI
+	// Env prefixes the table names, perf if empty.
+	Env string
+}
+
+// Result is the measurement of a single operation, averaged over the iterations.
+type Result struct {
+	Operation   string
+	Tables      int
+	GSIs        int
+	Iterations  int
+	PerOp       time.Duration
+	AllocsPerOp uint64
+	BytesPerOp  uint64
+}
+
+// TablesPerSecond is the throughput of the operation.
+func (r Result) TablesPerSecond() float64 {
+	if r.PerOp <= 0 {
+		return 0
+	}
+	return float64(r.Tables) / r.PerOp.Seconds()
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%-16s %6d tables x %3d GSIs %12s/op %10.0f tables/s %10d allocs/op %12d B/op",
+		r.Operation, r.Tables, r.GSIs, r.PerOp, r.TablesPerSecond(), r.AllocsPerOp, r.BytesPerOp)
+}
+
+// Report is the outcome of a harness run.
+type Report struct {
+	Results []Result
+}
+
+func (r *Report) String() string {
+	lines := make([]string, len(r.Results))
+	for i, res := range r.Results {
+		lines[i] = res.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Operations measured by Run, in order.
+const (
+	// ValidateMissing compares the config while no table exists.
+	ValidateMissing = "ValidateMissing"
+	// MigrateCreate creates all tables with their GSIs and TTL.
+	MigrateCreate = "MigrateCreate"
+	// ValidateInSync compares the config to tables matching it, the full comparison path.
+	ValidateInSync = "ValidateInSync"
+	// MigrateUpdate applies a throughput change to every table and GSI.
+	MigrateUpdate = "MigrateUpdate"
+)
+
+// Run generates a config, measures Validate and Migrate in all states of its tables and deletes
+// the tables afterwards. Migrations which fail are returned as errors.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Iterations <= 0 {
+		opts.Iterations = 1
+	}
+	if opts.Env == "" {
+		opts.Env = "perf"
+	}
+	if opts.DB == nil {
+		opts.DB = NewMemoryDB()
+	}
+	config := GenerateConfig(opts.Tables, opts.GSIs)
+	changed := GenerateConfig(opts.Tables, opts.GSIs)
+	for i := range changed {
+		changed[i].ReadThroughput++
+		for j := range changed[i].Indexes {
+			changed[i].Indexes[j].ReadThroughput++
+		}
+	}
+	c, err := tables.NewController(opts.DB, opts.Env, nopLogger{}, config)
+	if err != nil {
+		return nil, err
+	}
+	updater, err := tables.NewController(opts.DB, opts.Env, nopLogger{}, changed)
+	if err != nil {
+		return nil, err
+	}
+	defer reset(c)
+
+	report := &Report{}
+	measure := func(operation string, setup func() error, op func() error) error {
+		res := Result{Operation: operation, Tables: opts.Tables, GSIs: opts.GSIs, Iterations: opts.Iterations}
+		var elapsed time.Duration
+		var allocs, bytes uint64
+		for i := 0; i < opts.Iterations; i++ {
+			if err := setup(); err != nil {
+				return err
+			}
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			start := time.Now()
+			err := op()
+			elapsed += time.Since(start)
+			runtime.ReadMemStats(&after)
+			if err != nil {
+				return fmt.Errorf("%s: %w", operation, err)
+			}
+			allocs += after.Mallocs - before.Mallocs
+			bytes += after.TotalAlloc - before.TotalAlloc
+		}
+		n := uint64(opts.Iterations)
+		res.PerOp, res.AllocsPerOp, res.BytesPerOp = elapsed/time.Duration(n), allocs/n, bytes/n
+		report.Results = append(report.Results, res)
+		return nil
+	}
+
+	var results []*tables.ValidationResult
+	validate := func(c *tables.Controller) func() error {
+		return func() error {
+			results, _ = c.Validate()
+			return firstError(results)
+		}
+	}
+	migrate := func(c *tables.Controller) func() error {
+		return func() error {
+			for _, m := range c.MigrateWithContext(ctx, results) {
+				if len(m.Errors) > 0 {
+					return fmt.Errorf("table %s: %v", m.TableInput.TableName, m.Errors[0])
+				}
+			}
+			return nil
+		}
+	}
+	none := func() error { return nil }
+	steps := []struct {
+		operation string
+		setup     func() error
+		op        func() error
+	}{
+		{ValidateMissing, func() error { return reset(c) }, validate(c)},
+		{MigrateCreate, func() error {
+			if err := reset(c); err != nil {
+				return err
+			}
+			return validate(c)()
+		}, migrate(c)},
+		{ValidateInSync, none, validate(c)},
+		{MigrateUpdate, func() error {
+			if err := reset(c); err != nil {
+				return err
+			}
+			if err := validate(c)(); err != nil {
+				return err
+			}
+			if err := migrate(c)(); err != nil {
+				return err
+			}
+			return validate(updater)()
+		}, migrate(updater)},
+	}
+	for _, s := range steps {
+		if err := measure(s.operation, s.setup, s.op); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// reset deletes the tables of the controller, missing tables are fine.
+func reset(c *tables.Controller) error {
+	for _, r := range c.Reset() {
+		if aerr, ok := r.Error.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+			continue
+		}
+		if r.Error != nil {
+			return fmt.Errorf("reset table %s: %w", r.TableName, r.Error)
+		}
+	}
+	return nil
+}
+
+func firstError(results []*tables.ValidationResult) error {
+	for _, r := range results {
+		if r.Error != nil {
+			return fmt.Errorf("table %s: %w", r.TableInput.TableName, r.Error)
+		}
+	}
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(args ...interface{})                    {}
+func (nopLogger) Infof(template string, args ...interface{})  {}
+func (nopLogger) Error(args ...interface{})                   {}
+func (nopLogger) Errorf(template string, args ...interface{}) {}
@@ -0,0 +1,64 @@
+package perf
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jacygao/tables"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), Options{Tables: 20, GSIs: 3, Iterations: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{ValidateMissing, MigrateCreate, ValidateInSync, MigrateUpdate}
+	if len(report.Results) != len(want) {
+		t.Fatalf("unexpected report\n%s", report)
+	}
+	for i, r := range report.Results {
+		if r.Operation != want[i] || r.PerOp <= 0 || r.AllocsPerOp == 0 || r.Tables != 20 {
+			t.Fatalf("unexpected result %+v", r)
+		}
+	}
+	t.Log("\n" + report.String())
+}
+
+func TestMemoryDBInSync(t *testing.T) {
+	db := NewMemoryDB()
+	c, err := tables.NewController(db, "test", nopLogger{}, GenerateConfig(3, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, _ := c.Validate()
+	c.Migrate(results)
+
+	results, err = c.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Diff != "" || r.Error != nil {
+			t.Fatalf("expected migrated table %s in sync but got %s %v", r.TableInput.TableName, r.Diff, r.Error)
+		}
+	}
+}
+
+func BenchmarkValidateInSync(b *testing.B) {
+	for _, size := range []struct{ tables, gsis int }{{100, 5}, {1000, 5}, {100, 20}} {
+		db := NewMemoryDB()
+		c, err := tables.NewController(db, "bench", nopLogger{}, GenerateConfig(size.tables, size.gsis))
+		if err != nil {
+			b.Fatal(err)
+		}
+		results, _ := c.Validate()
+		c.Migrate(results)
+		b.Run(fmt.Sprintf("tables=%d/gsis=%d", size.tables, size.gsis), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c.Validate()
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package tables
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ChangeType is the kind of a single schema change applied by Migrate.
+type ChangeType string
+
+const (
+	ChangeCreateTable ChangeType = "CreateTable"
+	ChangeUpdateTable ChangeType = "UpdateTable"
+	ChangeUpdateTTL   ChangeType = "UpdateTimeToLive"
+	ChangeDeleteTable ChangeType = "DeleteTable"
+	// Point in time recovery is only enabled for tables required to by WithCompliance.
+	ChangeUpdateContinuousBackups ChangeType = "UpdateContinuousBackups"
+	// Tag changes are only applied by Rollback.
+	ChangeTagResource   ChangeType = "TagResource"
+	ChangeUntagResource ChangeType = "UntagResource"
+)
+
+// Change is a single schema change derived from a ValidationResult.
+type Change struct {
+	TableName string
+	Type      ChangeType
+	// Destructive is true if the change can affect existing data,
+	// such as changing the TTL of an existing table which starts or stops expiring items
+	// or deleting a GSI when rolling back.
+	Destructive bool
+	// CapacityDecrease is true if the change reduces table or GSI throughput.
+	CapacityDecrease bool
+	// TTLRetentionChange is true if the change disables TTL or switches the TTL attribute
+	// of a table, which changes how long existing items are retained.
+	TTLRetentionChange bool
+	// Input is the AWS request input of the change, e.g. *dynamodb.UpdateTableInput.
+	Input interface{}
+}
+
+func (ch Change) String() string {
+	if ch.Destructive {
+		return fmt.Sprintf("%s %s (destructive)", ch.Type, ch.TableName)
+	}
+	if ch.CapacityDecrease {
+		return fmt.Sprintf("%s %s (capacity decrease)", ch.Type, ch.TableName)
+	}
+	if ch.TTLRetentionChange {
+		return fmt.Sprintf("%s %s (ttl retention change)", ch.Type, ch.TableName)
+	}
+	return fmt.Sprintf("%s %s", ch.Type, ch.TableName)
+}
+
+// Changes lists the schema changes Migrate would apply for the given validation results.
+// Results which cannot be migrated and results of unmanaged tables are skipped.
+func Changes(results []*ValidationResult) []Change {
+	changes := []Change{}
+	for _, r := range results {
+		if r == nil || r.Error != nil || !r.CanMigrate || r.TableInput.Unmanaged {
+			continue
+		}
+		changes = append(changes, tableChanges(r)...)
+	}
+	return changes
+}
+
+// tableChanges lists the changes of a single validation result in the order they are applied.
+func tableChanges(r *ValidationResult) []Change {
+	changes := []Change{}
+	name := r.TableInput.TableName
+	if r.CreateTableInput != nil {
+		changes = append(changes, Change{
+			TableName: name,
+			Type:      ChangeCreateTable,
+			Input:     r.CreateTableInput,
+		})
+	}
+	if r.UpdateTTLInput != nil {
+		changes = append(changes, Change{
+			TableName:          name,
+			Type:               ChangeUpdateTTL,
+			Destructive:        r.CreateTableInput == nil,
+			TTLRetentionChange: IsTTLRetentionChange(r.TimeToLiveDescription, r.UpdateTTLInput),
+			Input:              r.UpdateTTLInput,
+		})
+	}
+	if r.UpdateContinuousBackupsInput != nil {
+		changes = append(changes, Change{
+			TableName: name,
+			Type:      ChangeUpdateContinuousBackups,
+			Input:     r.UpdateContinuousBackupsInput,
+		})
+	}
+	for _, input := range r.UpdateTableInput {
+		changes = append(changes, Change{
+			TableName:        name,
+			Type:             ChangeUpdateTable,
+			CapacityDecrease: IsCapacityDecrease(r.TableDescription, input),
+			Input:            input,
+		})
+	}
+	if r.DeleteTableInput != nil {
+		changes = append(changes, Change{
+			TableName:   name,
+			Type:        ChangeDeleteTable,
+			Destructive: true,
+			Input:       r.DeleteTableInput,
+		})
+	}
+	return changes
+}
+
+// ChangeBudgetError is returned when a migration plan exceeds the configured change budget.
+// Changes contains the changes beyond the budget.
+type ChangeBudgetError struct {
+	Limit       int
+	Destructive bool
+	Changes     []Change
+}
+
+func (e *ChangeBudgetError) Error() string {
+	kind := "changes"
+	if e.Destructive {
+		kind = "destructive changes"
+	}
+	excess := make([]string, len(e.Changes))
+	for i, ch := range e.Changes {
+		excess[i] = ch.String()
+	}
+	return fmt.Sprintf("%v: limit of %d %s exceeded by: %s", ErrChangeBudgetExceeded, e.Limit, kind, strings.Join(excess, ", "))
+}
+
+func (e *ChangeBudgetError) Unwrap() error {
+	return ErrChangeBudgetExceeded
+}
+
+// CheckBudget returns a *ChangeBudgetError if the changes required by the given
+// validation results exceed the limits set via WithMaxChanges or WithMaxDestructiveChanges.
+func (c *Controller) CheckBudget(results []*ValidationResult) error {
+	return c.checkBudget(Changes(results))
+}
+
+// checkBudget returns a *ChangeBudgetError if the given changes exceed the change budget.
+func (c *Controller) checkBudget(changes []Change) error {
+	if c.maxChanges >= 0 && len(changes) > c.maxChanges {
+		return &ChangeBudgetError{
+			Limit:   c.maxChanges,
+			Changes: changes[c.maxChanges:],
+		}
+	}
+
+	destructive := []Change{}
+	for _, ch := range changes {
+		if ch.Destructive {
+			destructive = append(destructive, ch)
+		}
+	}
+	if c.maxDestructiveChanges >= 0 && len(destructive) > c.maxDestructiveChanges {
+		return &ChangeBudgetError{
+			Limit:       c.maxDestructiveChanges,
+			Destructive: true,
+			Changes:     destructive[c.maxDestructiveChanges:],
+		}
+	}
+	return nil
+}
+
+// CapacityDecreaseApprover is consulted before a Migrate or Rollback run applies throughput reductions.
+// It receives every capacity decrease of the run and returns true to approve them.
+type CapacityDecreaseApprover func(changes []Change) bool
+
+// IsCapacityDecrease reports whether the given update lowers the read or write capacity
+// of the table or any of its GSIs compared to the current table description.
+func IsCapacityDecrease(desc *dynamodb.TableDescription, input *dynamodb.UpdateTableInput) bool {
+	if desc == nil || input == nil {
+		return false
+	}
+	if pt := input.ProvisionedThroughput; pt != nil && desc.ProvisionedThroughput != nil {
+		if isLower(pt.ReadCapacityUnits, desc.ProvisionedThroughput.ReadCapacityUnits) ||
+			isLower(pt.WriteCapacityUnits, desc.ProvisionedThroughput.WriteCapacityUnits) {
+			return true
+		}
+	}
+	for _, u := range input.GlobalSecondaryIndexUpdates {
+		if u.Update == nil || u.Update.ProvisionedThroughput == nil {
+			continue
+		}
+		for _, gsi := range desc.GlobalSecondaryIndexes {
+			if aws.StringValue(gsi.IndexName) != aws.StringValue(u.Update.IndexName) || gsi.ProvisionedThroughput == nil {
+				continue
+			}
+			if isLower(u.Update.ProvisionedThroughput.ReadCapacityUnits, gsi.ProvisionedThroughput.ReadCapacityUnits) ||
+				isLower(u.Update.ProvisionedThroughput.WriteCapacityUnits, gsi.ProvisionedThroughput.WriteCapacityUnits) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLower(v, current *int64) bool {
+	return v != nil && current != nil && *v < *current
+}
+
+// approveCapacityDecreases returns true if the capacity decreases among the given changes may be applied.
+func (c *Controller) approveCapacityDecreases(changes []Change) bool {
+	if c.allowCapacityDecrease {
+		return true
+	}
+	decreases := []Change{}
+	for _, ch := range changes {
+		if ch.CapacityDecrease {
+			decreases = append(decreases, ch)
+		}
+	}
+	if len(decreases) == 0 || c.capacityDecreaseApprover == nil {
+		return false
+	}
+	return c.capacityDecreaseApprover(decreases)
+}
+
+// DeletionApprover is consulted before a Migrate run deletes deprecated tables in prune mode.
+// It receives every deletion of the run and returns true to approve them.
+type DeletionApprover func(changes []Change) bool
+
+// approvals holds the consent given for changes requiring approval in a single Migrate or Rollback run.
+type approvals struct {
+	capacityDecrease bool
+	deletion         bool
+	ttlChange        bool
+}
+
+// approve consults the approvers of the changes of a run which require approval.
+func (c *Controller) approve(changes []Change) approvals {
+	return approvals{
+		capacityDecrease: c.approveCapacityDecreases(changes),
+		deletion:         c.approveDeletions(changes),
+		ttlChange:        c.approveTTLChanges(changes),
+	}
+}
+
+// approveDeletions returns true if the table deletions among the given changes may be applied.
+func (c *Controller) approveDeletions(changes []Change) bool {
+	deletions := []Change{}
+	for _, ch := range changes {
+		if ch.Type == ChangeDeleteTable {
+			deletions = append(deletions, ch)
+		}
+	}
+	if len(deletions) == 0 || c.pruneApprover == nil {
+		return false
+	}
+	return c.pruneApprover(deletions)
+}
+
+// TTLChangeApprover is consulted before a Migrate or Rollback run disables TTL or switches the TTL attribute.
+// It receives every such change of the run and returns true to approve them.
+type TTLChangeApprover func(changes []Change) bool
+
+// IsTTLRetentionChange reports whether the given update disables the current TTL
+// or moves it to a different attribute.
+func IsTTLRetentionChange(desc *dynamodb.TimeToLiveDescription, input *dynamodb.UpdateTimeToLiveInput) bool {
+	if !isTTLEnabled(desc) || input == nil || input.TimeToLiveSpecification == nil {
+		return false
+	}
+	spec := input.TimeToLiveSpecification
+	return !aws.BoolValue(spec.Enabled) || aws.StringValue(spec.AttributeName) != aws.StringValue(desc.AttributeName)
+}
+
+func isTTLEnabled(desc *dynamodb.TimeToLiveDescription) bool {
+	if desc == nil {
+		return false
+	}
+	status := aws.StringValue(desc.TimeToLiveStatus)
+	return status == dynamodb.TimeToLiveStatusEnabled || status == dynamodb.TimeToLiveStatusEnabling
+}
+
+// approveTTLChanges returns true if the TTL retention changes among the given changes may be applied.
+func (c *Controller) approveTTLChanges(changes []Change) bool {
+	retention := []Change{}
+	for _, ch := range changes {
+		if ch.TTLRetentionChange {
+			retention = append(retention, ch)
+		}
+	}
+	if len(retention) == 0 || c.ttlChangeApprover == nil {
+		return false
+	}
+	return c.ttlChangeApprover(retention)
+}
+
+// Action is a single AWS API call Migrate would issue, used to review a plan.
+type Action struct {
+	// Step is the 1-based position of the action in the plan.
+	Step      int
+	TableName string
+	// API is the name of the DynamoDB API operation, e.g. UpdateTable.
+	API ChangeType
+	// Input is the JSON marshaled request input.
+	Input json.RawMessage
+	// DependsOn lists the steps which must complete before this action runs.
+	// Actions of different tables do not depend on each other and may run concurrently.
+	DependsOn []int
+}
+
+func (a Action) String() string {
+	if len(a.DependsOn) == 0 {
+		return fmt.Sprintf("%d. %s %s: %s", a.Step, a.API, a.TableName, a.Input)
+	}
+	after := make([]string, len(a.DependsOn))
+	for i, d := range a.DependsOn {
+		after[i] = strconv.Itoa(d)
+	}
+	return fmt.Sprintf("%d. %s %s (after %s): %s", a.Step, a.API, a.TableName, strings.Join(after, ", "), a.Input)
+}
+
+// Actions lists the AWS API calls Migrate would issue for the given validation results, in order.
+// Like Changes, results which cannot be migrated and results of unmanaged tables are skipped.
+// The actions of a single table run sequentially, so each depends on the previous action of its table.
+func Actions(results []*ValidationResult) ([]Action, error) {
+	actions := []Action{}
+	for _, r := range results {
+		if r == nil || r.Error != nil || !r.CanMigrate || r.TableInput.Unmanaged {
+			continue
+		}
+		prev := 0
+		for _, ch := range tableChanges(r) {
+			input, err := json.Marshal(ch.Input)
+			if err != nil {
+				return nil, err
+			}
+			a := Action{
+				Step:      len(actions) + 1,
+				TableName: ch.TableName,
+				API:       ch.Type,
+				Input:     input,
+			}
+			if prev > 0 {
+				a.DependsOn = []int{prev}
+			}
+			prev = a.Step
+			actions = append(actions, a)
+		}
+	}
+	return actions, nil
+}
@@ -0,0 +1,197 @@
+package tables
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestCheckBudget(t *testing.T) {
+	results := []*ValidationResult{
+		{
+			TableInput:       TableInfo{TableName: "new"},
+			CreateTableInput: &dynamodb.CreateTableInput{},
+			UpdateTTLInput:   &dynamodb.UpdateTimeToLiveInput{},
+			CanMigrate:       true,
+		},
+		{
+			TableInput:       TableInfo{TableName: "existing"},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{}, {}},
+			UpdateTTLInput:   &dynamodb.UpdateTimeToLiveInput{},
+			CanMigrate:       true,
+		},
+	}
+
+	if l := len(Changes(results)); l != 5 {
+		t.Fatalf("expected 5 changes but got %d", l)
+	}
+
+	c := &Controller{maxChanges: -1, maxDestructiveChanges: -1}
+	if err := c.CheckBudget(results); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	c.maxChanges = 3
+	err := c.CheckBudget(results)
+	if !errors.Is(err, ErrChangeBudgetExceeded) {
+		t.Fatalf("expected ErrChangeBudgetExceeded but got %v", err)
+	}
+	if l := len(err.(*ChangeBudgetError).Changes); l != 2 {
+		t.Fatalf("expected 2 excess changes but got %d", l)
+	}
+
+	c.maxChanges = -1
+	c.maxDestructiveChanges = 0
+	err = c.CheckBudget(results)
+	if !errors.Is(err, ErrChangeBudgetExceeded) {
+		t.Fatalf("expected ErrChangeBudgetExceeded but got %v", err)
+	}
+	if !err.(*ChangeBudgetError).Destructive {
+		t.Fatal("expected destructive budget error")
+	}
+}
+
+func TestIsCapacityDecrease(t *testing.T) {
+	desc := &dynamodb.TableDescription{
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(10),
+			WriteCapacityUnits: aws.Int64(10),
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			{
+				IndexName: aws.String("test"),
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+					ReadCapacityUnits:  aws.Int64(5),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+		},
+	}
+
+	increase := &dynamodb.UpdateTableInput{
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(20),
+			WriteCapacityUnits: aws.Int64(10),
+		},
+	}
+	if IsCapacityDecrease(desc, increase) {
+		t.Fatal("expected no capacity decrease")
+	}
+
+	decrease := &dynamodb.UpdateTableInput{
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(20),
+			WriteCapacityUnits: aws.Int64(1),
+		},
+	}
+	if !IsCapacityDecrease(desc, decrease) {
+		t.Fatal("expected capacity decrease")
+	}
+
+	gsiDecrease := &dynamodb.UpdateTableInput{
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+			{
+				Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+					IndexName: aws.String("test"),
+					ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+						ReadCapacityUnits:  aws.Int64(1),
+						WriteCapacityUnits: aws.Int64(5),
+					},
+				},
+			},
+		},
+	}
+	if !IsCapacityDecrease(desc, gsiDecrease) {
+		t.Fatal("expected GSI capacity decrease")
+	}
+}
+
+func TestActions(t *testing.T) {
+	tbl := TableInfo{
+		TableName:       "test",
+		PrimaryKey:      "id",
+		ReadThroughput:  1,
+		WriteThroughput: 1,
+		TTL: &TTLAttributeInfo{
+			AttributeName: "expiry",
+			Enabled:       true,
+		},
+	}
+	results := []*ValidationResult{
+		{
+			TableInput:       tbl,
+			CreateTableInput: CreateTableInput(tbl, ""),
+			UpdateTTLInput:   NewUpdateTimeToLiveInput(tbl, "", tbl.TTL),
+			CanMigrate:       true,
+		},
+		{
+			TableInput:       TableInfo{TableName: "other"},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String("other")}},
+			CanMigrate:       true,
+		},
+		{
+			TableInput:       TableInfo{TableName: "legacy", Unmanaged: true},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{TableName: aws.String("legacy")}},
+			CanMigrate:       true,
+		},
+	}
+
+	actions, err := Actions(results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions without the unmanaged table but got %v", actions)
+	}
+	if actions[0].API != ChangeCreateTable || len(actions[0].DependsOn) != 0 {
+		t.Fatalf("expected independent CreateTable action but got %v", actions[0])
+	}
+	if actions[1].API != ChangeUpdateTTL || len(actions[1].DependsOn) != 1 || actions[1].DependsOn[0] != 1 {
+		t.Fatalf("expected UpdateTimeToLive action after step 1 but got %v", actions[1])
+	}
+	if len(actions[2].DependsOn) != 0 {
+		t.Fatalf("expected independent action for other table but got %v", actions[2])
+	}
+	if !strings.Contains(string(actions[0].Input), `"TableName":"test"`) {
+		t.Fatalf("expected marshaled input but got %s", actions[0].Input)
+	}
+}
+
+func TestIsTTLRetentionChange(t *testing.T) {
+	enabled := &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String("expires_at"),
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+	}
+	disabled := &dynamodb.TimeToLiveDescription{
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled),
+	}
+	update := func(name string, enabled bool) *dynamodb.UpdateTimeToLiveInput {
+		return &dynamodb.UpdateTimeToLiveInput{
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: aws.String(name),
+				Enabled:       aws.Bool(enabled),
+			},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		desc  *dynamodb.TimeToLiveDescription
+		input *dynamodb.UpdateTimeToLiveInput
+		want  bool
+	}{
+		{"enable", disabled, update("expires_at", true), false},
+		{"missing", nil, update("expires_at", true), false},
+		{"disable", enabled, update("expires_at", false), true},
+		{"switch attribute", enabled, update("ttl", true), true},
+		{"unchanged", enabled, update("expires_at", true), false},
+	}
+	for _, tc := range cases {
+		if got := IsTTLRetentionChange(tc.desc, tc.input); got != tc.want {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package tables
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// Plan is the serializable migration plan of a Validate run, e.g. stored by CI to diff plans between
+// runs. Identical validation results always serialize to identical bytes: tables are sorted by their
+// name in DynamoDB and region, actions follow in the order Migrate applies them, and map keys are sorted.
+type Plan struct {
+	Tables  []ValidationReport `json:"tables"`
+	Actions []Action           `json:"actions"`
+	Summary ChangeSummary      `json:"summary"`
+}
+
+// NewPlan builds the plan of the given validation results. The results are not modified.
+func NewPlan(results []*ValidationResult) (*Plan, error) {
+	sorted := make([]*ValidationResult, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return tableLess(sorted[i].TableInput, sorted[j].TableInput)
+	})
+
+	actions, err := Actions(sorted)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{
+		Tables:  validationReports(sorted),
+		Actions: actions,
+		Summary: Summarize(sorted),
+	}, nil
+}
+
+// tableLess orders tables by the parts of their name in DynamoDB, see physicalName, and their
+// region, so copies of a table per title, tenant or region are ordered too. The environment is
+// the same for all results of a run and not compared.
+func tableLess(a, b TableInfo) bool {
+	switch {
+	case a.Title != b.Title:
+		return a.Title < b.Title
+	case a.Tenant != b.Tenant:
+		return a.Tenant < b.Tenant
+	case a.TableName != b.TableName:
+		return a.TableName < b.TableName
+	}
+	return a.Region < b.Region
+}
+
+// HasChanges reports whether applying the plan would change anything, e.g. to cache "no change" results.
+func (p *Plan) HasChanges() bool {
+	return len(p.Actions) > 0
+}
+
+// WritePlan writes the plan as indented JSON.
+func WritePlan(w io.Writer, p *Plan) error {
+	return writeJSON(w, p)
+}
+
+// ReadPlan reads a plan written by WritePlan.
+func ReadPlan(r io.Reader) (*Plan, error) {
+	p := &Plan{}
+	if err := json.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// stableDiff normalizes a diff of go-cmp, which randomly uses non-breaking spaces in its output
+// to discourage comparing diffs, so reports of identical results are identical.
+func stableDiff(diff string) string {
+	return strings.Replace(diff, "\u00a0", " ", -1)
+}
+
+// encryptedPlan is the envelope of a plan file encrypted with a KMS data key: the compressed plan
+// sealed with AES-256-GCM under the data key, stored next to the data key encrypted by KMS.
+type encryptedPlan struct {
+	KeyID        string `json:"key_id"`
+	EncryptedKey []byte `json:"encrypted_key"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// WritePlanFile writes the plan gzip-compressed, e.g. as a build artifact. If keys is not nil, the
+// compressed plan is encrypted with a data key generated under the given KMS key, as plans reveal
+// table names and capacity figures. Only principals allowed to decrypt with the key can read the plan.
+func WritePlanFile(w io.Writer, p *Plan, keys kmsiface.KMSAPI, keyID string) error {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if err := writeJSON(zw, p); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if keys == nil {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	key, err := keys.GenerateDataKey(&kms.GenerateDataKeyInput{
+		KeyId:   aws.String(keyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return err
+	}
+	gcm, err := planCipher(key.Plaintext)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(encryptedPlan{
+		KeyID:        aws.StringValue(key.KeyId),
+		EncryptedKey: key.CiphertextBlob,
+		Nonce:        nonce,
+		Ciphertext:   gcm.Seal(nil, nonce, buf.Bytes(), nil),
+	})
+}
+
+// ReadPlanFile reads a plan written by WritePlanFile or WritePlan. keys is only needed for
+// encrypted plans, reading one without it returns ErrEncryptedPlan.
+func ReadPlanFile(r io.Reader, keys kmsiface.KMSAPI) (*Plan, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, gzipMagic) {
+		envelope := &encryptedPlan{}
+		if err := json.Unmarshal(data, envelope); err != nil {
+			return nil, err
+		}
+		if envelope.EncryptedKey == nil {
+			return ReadPlan(bytes.NewReader(data))
+		}
+		if keys == nil {
+			return nil, ErrEncryptedPlan
+		}
+		key, err := keys.Decrypt(&kms.DecryptInput{
+			KeyId:          aws.String(envelope.KeyID),
+			CiphertextBlob: envelope.EncryptedKey,
+		})
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := planCipher(key.Plaintext)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil); err != nil {
+			return nil, err
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ReadPlan(zr)
+}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// planCipher returns the AES-GCM cipher of a plaintext data key.
+func planCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,138 @@
+package tables
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+func TestPlanDeterministic(t *testing.T) {
+	orders := &ValidationResult{
+		TableInput: TableInfo{TableName: "orders"},
+		Diff:       "Throughput: {\n- ReadCapacityUnits: 5\n+ ReadCapacityUnits: 10}",
+		CanMigrate: true,
+		UpdateTableInput: []*dynamodb.UpdateTableInput{{
+			TableName:             aws.String("orders"),
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(10), WriteCapacityUnits: aws.Int64(5)},
+		}},
+		ResourceDiffs: map[string]string{"dax": "missing cluster", "alarms": "missing alarm"},
+	}
+	// go-cmp randomly renders diffs with non-breaking spaces.
+	ordersEU := &ValidationResult{
+		TableInput: TableInfo{TableName: "orders", Region: "eu-west-1"},
+		Diff:       "Throughput: {\n-\u00a0ReadCapacityUnits: 5\n+\u00a0ReadCapacityUnits: 10}",
+		CanMigrate: true,
+		UpdateTableInput: []*dynamodb.UpdateTableInput{{
+			TableName:             aws.String("orders"),
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(10), WriteCapacityUnits: aws.Int64(5)},
+		}},
+	}
+	users := &ValidationResult{
+		TableInput:       TableInfo{TableName: "users"},
+		Diff:             "missing table: users",
+		CanMigrate:       true,
+		CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("users")},
+	}
+
+	write := func(results ...*ValidationResult) []byte {
+		p, err := NewPlan(results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := &bytes.Buffer{}
+		if err := WritePlan(buf, p); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	first := write(users, ordersEU, orders)
+	if second := write(ordersEU, orders, nil, users); !bytes.Equal(first, second) {
+		t.Fatalf("expected identical plans but got\n%s\n%s", first, second)
+	}
+	if bytes.Contains(first, []byte(" ")) {
+		t.Fatalf("expected normalized diff in %s", first)
+	}
+
+	p, err := ReadPlan(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.HasChanges() || p.Tables[0].Table != "orders" || p.Tables[1].Diff != "Throughput: {\n- ReadCapacityUnits: 5\n+ ReadCapacityUnits: 10}" ||
+		p.Actions[2].API != ChangeCreateTable {
+		t.Fatalf("unexpected plan %+v", p)
+	}
+	if want := (ChangeSummary{Creates: 1, Updates: 2}); !reflect.DeepEqual(p.Summary, want) {
+		t.Fatalf("expected summary %+v but got %+v", want, p.Summary)
+	}
+}
+
+type planKMS struct {
+	kmsiface.KMSAPI
+	key []byte
+}
+
+func (f *planKMS) GenerateDataKey(input *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+	return &kms.GenerateDataKeyOutput{
+		KeyId:          input.KeyId,
+		Plaintext:      append([]byte(nil), f.key...),
+		CiphertextBlob: []byte("sealed"),
+	}, nil
+}
+
+func (f *planKMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	if string(input.CiphertextBlob) != "sealed" || aws.StringValue(input.KeyId) != "alias/plans" {
+		return nil, errors.New("access denied")
+	}
+	return &kms.DecryptOutput{Plaintext: append([]byte(nil), f.key...)}, nil
+}
+
+func TestPlanFile(t *testing.T) {
+	p, err := NewPlan([]*ValidationResult{{
+		TableInput:       TableInfo{TableName: "users"},
+		Diff:             "missing table: users",
+		CanMigrate:       true,
+		CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("users")},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := &planKMS{key: bytes.Repeat([]byte{7}, 32)}
+
+	compressed, encrypted := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := WritePlanFile(compressed, p, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePlanFile(encrypted, p, keys, "alias/plans"); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("users")) {
+		t.Fatalf("expected table names to be encrypted in %s", encrypted)
+	}
+	if _, err := ReadPlanFile(bytes.NewReader(encrypted.Bytes()), nil); !errors.Is(err, ErrEncryptedPlan) {
+		t.Fatalf("expected ErrEncryptedPlan but got %v", err)
+	}
+
+	plain := &bytes.Buffer{}
+	if err := WritePlan(plain, p); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{"compressed": compressed.Bytes(), "encrypted": encrypted.Bytes(), "plain": plain.Bytes()} {
+		read, err := ReadPlanFile(bytes.NewReader(data), keys)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		written := &bytes.Buffer{}
+		if err := WritePlan(written, read); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written.Bytes(), plain.Bytes()) {
+			t.Fatalf("%s: expected\n%s\nbut got\n%s", name, plain, written)
+		}
+	}
+}
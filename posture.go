@@ -0,0 +1,169 @@
+package tables
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// PostureReport summarizes the security settings of all managed tables.
+type PostureReport struct {
+	GeneratedAt time.Time       `json:"generated_at" yaml:"generated_at"`
+	Tables      []TablePosture  `json:"tables" yaml:"tables"`
+	Coverage    PostureCoverage `json:"coverage" yaml:"coverage"`
+}
+
+// TablePosture holds the security settings of a single table.
+type TablePosture struct {
+	TableName string `json:"table_name" yaml:"table_name"`
+	// Exists is false if the table is missing, missing tables are not part of the coverage.
+	Exists bool `json:"exists" yaml:"exists"`
+	// CMKEncryption is true if the table is encrypted with a customer managed KMS key.
+	// It is only known if a KMS client is set via WithKMSClient.
+	CMKEncryption       bool   `json:"cmk_encryption" yaml:"cmk_encryption"`
+	KMSKeyARN           string `json:"kms_key_arn,omitempty" yaml:"kms_key_arn,omitempty"`
+	PointInTimeRecovery bool   `json:"point_in_time_recovery" yaml:"point_in_time_recovery"`
+	DeletionProtection  bool   `json:"deletion_protection" yaml:"deletion_protection"`
+	ResourcePolicy      bool   `json:"resource_policy" yaml:"resource_policy"`
+}
+
+// PostureCoverage holds the percentage of existing tables with each setting enabled.
+type PostureCoverage struct {
+	CMKEncryption       float64 `json:"cmk_encryption" yaml:"cmk_encryption"`
+	PointInTimeRecovery float64 `json:"point_in_time_recovery" yaml:"point_in_time_recovery"`
+	DeletionProtection  float64 `json:"deletion_protection" yaml:"deletion_protection"`
+	ResourcePolicy      float64 `json:"resource_policy" yaml:"resource_policy"`
+}
+
+// SecurityPosture reports whether CMK encryption, point in time recovery, deletion protection
+// and resource policies are enabled per managed table, plus the coverage of each setting.
+// Tables are listed in config order. The first error encountered is returned.
+func (c *Controller) SecurityPosture() (*PostureReport, error) {
+	tables := managedTables(c.tenantTables())
+	postures := make([]TablePosture, len(tables))
+	errs := make([]error, len(tables))
+	keys := &keyManagers{managers: map[string]string{}}
+
+	var wg sync.WaitGroup
+	for i, tbl := range tables {
+		wg.Add(1)
+		go func(i int, tbl TableInfo) {
+			defer wg.Done()
+			postures[i], errs[i] = c.tablePosture(tbl, keys)
+			if errs[i] != nil {
+				c.Log.Errorf("Security posture of table [%s] with error: %v", tbl.TableName, errs[i])
+			}
+		}(i, tbl)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &PostureReport{
+		GeneratedAt: c.clock.Now(),
+		Tables:      postures,
+		Coverage:    postureCoverage(postures),
+	}, nil
+}
+
+func (c *Controller) tablePosture(tbl TableInfo, keys *keyManagers) (TablePosture, error) {
+	p := TablePosture{TableName: physicalName(c.env, tbl)}
+	desc, err := c.describeTable(tbl)
+	if isNotFound(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	p.Exists = true
+	p.DeletionProtection = aws.BoolValue(desc.DeletionProtectionEnabled)
+
+	if sse := desc.SSEDescription; sse != nil && aws.StringValue(sse.SSEType) == dynamodb.SSETypeKms {
+		p.KMSKeyARN = aws.StringValue(sse.KMSMasterKeyArn)
+		if c.kms != nil && p.KMSKeyARN != "" {
+			manager, err := keys.get(c, p.KMSKeyARN)
+			if err != nil {
+				return p, err
+			}
+			p.CMKEncryption = manager == kms.KeyManagerTypeCustomer
+		}
+	}
+
+	backups, err := c.client(tbl).DescribeContinuousBackups(&dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(p.TableName),
+	})
+	if err != nil {
+		return p, err
+	}
+	if d := backups.ContinuousBackupsDescription; d != nil && d.PointInTimeRecoveryDescription != nil {
+		p.PointInTimeRecovery = aws.StringValue(d.PointInTimeRecoveryDescription.PointInTimeRecoveryStatus) == dynamodb.PointInTimeRecoveryStatusEnabled
+	}
+
+	_, err = c.client(tbl).GetResourcePolicy(&dynamodb.GetResourcePolicyInput{ResourceArn: desc.TableArn})
+	switch {
+	case err == nil:
+		p.ResourcePolicy = true
+	case !isAWSError(err, dynamodb.ErrCodePolicyNotFoundException):
+		return p, err
+	}
+	return p, nil
+}
+
+// keyManagers caches whether KMS keys are AWS or customer managed, tables often share a key.
+type keyManagers struct {
+	mu       sync.Mutex
+	managers map[string]string
+}
+
+func (k *keyManagers) get(c *Controller, arn string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if manager, ok := k.managers[arn]; ok {
+		return manager, nil
+	}
+	output, err := c.kms.DescribeKey(&kms.DescribeKeyInput{KeyId: aws.String(arn)})
+	if err != nil {
+		return "", err
+	}
+	manager := aws.StringValue(output.KeyMetadata.KeyManager)
+	k.managers[arn] = manager
+	return manager, nil
+}
+
+// postureCoverage returns the percentage of existing tables with each setting enabled.
+func postureCoverage(postures []TablePosture) PostureCoverage {
+	var cov PostureCoverage
+	existing := 0
+	for _, p := range postures {
+		if !p.Exists {
+			continue
+		}
+		existing++
+		for _, s := range []struct {
+			enabled bool
+			count   *float64
+		}{
+			{p.CMKEncryption, &cov.CMKEncryption},
+			{p.PointInTimeRecovery, &cov.PointInTimeRecovery},
+			{p.DeletionProtection, &cov.DeletionProtection},
+			{p.ResourcePolicy, &cov.ResourcePolicy},
+		} {
+			if s.enabled {
+				*s.count++
+			}
+		}
+	}
+	if existing == 0 {
+		return cov
+	}
+	for _, v := range []*float64{&cov.CMKEncryption, &cov.PointInTimeRecovery, &cov.DeletionProtection, &cov.ResourcePolicy} {
+		*v = *v * 100 / float64(existing)
+	}
+	return cov
+}
@@ -0,0 +1,92 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+// postureDynamoDB reports a resource policy for the tables in policies.
+type postureDynamoDB struct {
+	fakeDynamoDB
+	policies map[string]bool
+}
+
+func (f *postureDynamoDB) GetResourcePolicy(input *dynamodb.GetResourcePolicyInput) (*dynamodb.GetResourcePolicyOutput, error) {
+	if !f.policies[aws.StringValue(input.ResourceArn)] {
+		return nil, awserr.New(dynamodb.ErrCodePolicyNotFoundException, "no policy", nil)
+	}
+	return &dynamodb.GetResourcePolicyOutput{Policy: aws.String("{}")}, nil
+}
+
+type fakeKMS struct {
+	kmsiface.KMSAPI
+	calls int
+}
+
+func (f *fakeKMS) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	f.calls++
+	manager := kms.KeyManagerTypeAws
+	if aws.StringValue(input.KeyId) == "arn:cmk" {
+		manager = kms.KeyManagerTypeCustomer
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: &kms.KeyMetadata{KeyManager: aws.String(manager)}}, nil
+}
+
+func TestSecurityPosture(t *testing.T) {
+	db := &postureDynamoDB{policies: map[string]bool{"arn:orders": true}}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		switch aws.StringValue(input.TableName) {
+		case "orders":
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableArn:                  aws.String("arn:orders"),
+				DeletionProtectionEnabled: aws.Bool(true),
+				SSEDescription:            &dynamodb.SSEDescription{SSEType: aws.String(dynamodb.SSETypeKms), KMSMasterKeyArn: aws.String("arn:cmk")},
+			}}, nil
+		case "users":
+			return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+				TableArn:       aws.String("arn:users"),
+				SSEDescription: &dynamodb.SSEDescription{SSEType: aws.String(dynamodb.SSETypeKms), KMSMasterKeyArn: aws.String("arn:aws-managed")},
+			}}, nil
+		}
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	db.describeBackups = func(input *dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+		status := dynamodb.PointInTimeRecoveryStatusDisabled
+		if aws.StringValue(input.TableName) == "users" {
+			status = dynamodb.PointInTimeRecoveryStatusEnabled
+		}
+		return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: &dynamodb.ContinuousBackupsDescription{
+			PointInTimeRecoveryDescription: &dynamodb.PointInTimeRecoveryDescription{PointInTimeRecoveryStatus: aws.String(status)},
+		}}, nil
+	}
+	keys := &fakeKMS{}
+	c, err := NewController(db, "", nil, []TableInfo{{TableName: "orders"}, {TableName: "users"}, {TableName: "missing"}},
+		WithClock(&fakeClock{}), WithKMSClient(keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := c.SecurityPosture()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders, users, missing := report.Tables[0], report.Tables[1], report.Tables[2]
+	if !orders.CMKEncryption || !orders.DeletionProtection || !orders.ResourcePolicy || orders.PointInTimeRecovery {
+		t.Fatalf("unexpected posture of orders %+v", orders)
+	}
+	if users.CMKEncryption || !users.PointInTimeRecovery || users.ResourcePolicy {
+		t.Fatalf("unexpected posture of users %+v", users)
+	}
+	if missing.Exists {
+		t.Fatalf("expected missing table not to exist but got %+v", missing)
+	}
+	want := PostureCoverage{CMKEncryption: 50, PointInTimeRecovery: 50, DeletionProtection: 50, ResourcePolicy: 50}
+	if report.Coverage != want {
+		t.Fatalf("expected coverage %+v but got %+v", want, report.Coverage)
+	}
+}
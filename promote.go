@@ -0,0 +1,154 @@
+package tables
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// PromotionResult lists the schema changes the table of the target environment needs
+// to catch up with the table of the source environment.
+type PromotionResult struct {
+	TableInput TableInfo
+	// From and To are the names of the table in DynamoDB in the source and target environment.
+	From string
+	To   string
+	// Changes describe the differences, e.g. "creates GSI byEmail", empty if the tables match.
+	Changes []string
+	// Error contains errors of describing the tables.
+	Error error
+}
+
+// PromoteDiff compares the live schemas of the managed tables in two environments, e.g. staging
+// and prod, and reports what the tables of toEnv need to match those of fromEnv. This detects
+// config applied to one environment while the deploy of the other was skipped.
+// Structural differences are reported: missing tables and GSIs, key schemas, projections and TTL.
+// Throughput and billing mode commonly differ between environments and are not compared.
+// Tables missing in fromEnv and tables without a title, which are shared by all environments,
+// have no changes. Results are returned in config order.
+func (c *Controller) PromoteDiff(fromEnv, toEnv string) []*PromotionResult {
+	tables := managedTables(filterTables(c.tenantTables(), c.filter))
+	rs := make([]*PromotionResult, len(tables))
+
+	var wg sync.WaitGroup
+	for i, tbl := range tables {
+		wg.Add(1)
+		go func(i int, tbl TableInfo) {
+			defer wg.Done()
+			rs[i] = c.promoteTable(fromEnv, toEnv, tbl)
+		}(i, tbl)
+	}
+	wg.Wait()
+	return rs
+}
+
+func (c *Controller) promoteTable(fromEnv, toEnv string, tbl TableInfo) *PromotionResult {
+	r := &PromotionResult{
+		TableInput: tbl.clone(),
+		From:       physicalName(fromEnv, tbl),
+		To:         physicalName(toEnv, tbl),
+	}
+	if r.From == r.To {
+		return r
+	}
+
+	from, fromTTL, err := c.describeNamed(tbl, r.From)
+	if err != nil || from == nil {
+		r.Error = err
+		return r
+	}
+	to, toTTL, err := c.describeNamed(tbl, r.To)
+	if err != nil {
+		r.Error = err
+		return r
+	}
+	if to == nil {
+		r.Changes = []string{fmt.Sprintf("creates table %s", r.To)}
+		return r
+	}
+	r.Changes = promotionChanges(from, to)
+	if f, t := ttlStatusString(fromTTL), ttlStatusString(toTTL); f != t {
+		r.Changes = append(r.Changes, fmt.Sprintf("changes TTL from %s to %s", t, f))
+	}
+	if len(r.Changes) > 0 {
+		c.Log.Infof("Promote table [%s] from %s to %s with changes: %s", tbl.TableName, fromEnv, toEnv, strings.Join(r.Changes, ", "))
+	}
+	return r
+}
+
+// describeNamed describes the table and its TTL by name in DynamoDB.
+// A nil description is returned if the table does not exist.
+func (c *Controller) describeNamed(tbl TableInfo, name string) (*dynamodb.TableDescription, *dynamodb.TimeToLiveDescription, error) {
+	client := c.client(tbl)
+	output, err := client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	ttl, err := client.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(name)})
+	if err != nil {
+		return nil, nil, err
+	}
+	return output.Table, ttl.TimeToLiveDescription, nil
+}
+
+// promotionChanges describes the structural changes turning the table to into the table from.
+func promotionChanges(from, to *dynamodb.TableDescription) []string {
+	changes := []string{}
+	if f, t := keySchemaElementsString(from.KeySchema), keySchemaElementsString(to.KeySchema); f != t {
+		changes = append(changes, fmt.Sprintf("changes key schema from %s to %s", t, f))
+	}
+
+	current := make(map[string]*dynamodb.GlobalSecondaryIndexDescription, len(to.GlobalSecondaryIndexes))
+	for _, gsi := range to.GlobalSecondaryIndexes {
+		current[aws.StringValue(gsi.IndexName)] = gsi
+	}
+	expected := make(map[string]bool, len(from.GlobalSecondaryIndexes))
+	for _, gsi := range from.GlobalSecondaryIndexes {
+		name := aws.StringValue(gsi.IndexName)
+		expected[name] = true
+		existing, ok := current[name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("creates GSI %s", name))
+			continue
+		}
+		if f, t := keySchemaElementsString(gsi.KeySchema), keySchemaElementsString(existing.KeySchema); f != t {
+			changes = append(changes, fmt.Sprintf("changes key schema of GSI %s from %s to %s", name, t, f))
+		}
+		if DiffProjection(existing.Projection, gsi.Projection) != "" {
+			changes = append(changes, fmt.Sprintf("changes projection of GSI %s", name))
+		}
+	}
+	for _, gsi := range to.GlobalSecondaryIndexes {
+		if name := aws.StringValue(gsi.IndexName); !expected[name] {
+			changes = append(changes, fmt.Sprintf("removes GSI %s", name))
+		}
+	}
+	return changes
+}
+
+// keySchemaElementsString formats a key schema like keySchemaString, e.g. id/created.
+func keySchemaElementsString(keys []*dynamodb.KeySchemaElement) string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = aws.StringValue(k.AttributeName)
+	}
+	return strings.Join(names, "/")
+}
+
+// ttlStatusString formats a TTL description like ttlString.
+func ttlStatusString(ttl *dynamodb.TimeToLiveDescription) string {
+	if ttl == nil || ttl.AttributeName == nil {
+		return "unset"
+	}
+	if !isTTLEnabled(ttl) {
+		return fmt.Sprintf("%s (disabled)", aws.StringValue(ttl.AttributeName))
+	}
+	return aws.StringValue(ttl.AttributeName)
+}
@@ -0,0 +1,72 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPromoteDiff(t *testing.T) {
+	keys := []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}}
+	gsi := func(name, key string) *dynamodb.GlobalSecondaryIndexDescription {
+		return &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:  aws.String(name),
+			KeySchema:  []*dynamodb.KeySchemaElement{{AttributeName: aws.String(key), KeyType: aws.String("HASH")}},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		}
+	}
+	descs := map[string]*dynamodb.TableDescription{
+		"shop-staging-orders": {KeySchema: keys, GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			gsi("byEmail", "email"), gsi("byUser", "user_id"),
+		}},
+		"shop-prod-orders": {KeySchema: keys, GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndexDescription{
+			gsi("byUser", "user"), gsi("legacy", "old"),
+		}},
+		"shop-staging-users": {KeySchema: keys},
+		"shop-prod-users":    {KeySchema: keys},
+		"shop-staging-carts": {KeySchema: keys},
+	}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		desc, ok := descs[aws.StringValue(input.TableName)]
+		if !ok {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: desc}, nil
+	}
+	db.describeTTL = func(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		ttl := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+		if aws.StringValue(input.TableName) == "shop-staging-users" {
+			ttl = &dynamodb.TimeToLiveDescription{AttributeName: aws.String("expires_at"), TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled)}
+		}
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: ttl}, nil
+	}
+	c, err := NewController(db, "staging", nil, []TableInfo{
+		{Title: "shop", TableName: "orders", PrimaryKey: "id"},
+		{Title: "shop", TableName: "users", PrimaryKey: "id"},
+		{Title: "shop", TableName: "carts", PrimaryKey: "id"},
+		{TableName: "shared", PrimaryKey: "id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := c.PromoteDiff("staging", "prod")
+	expected := [][]string{
+		{"creates GSI byEmail", "changes key schema of GSI byUser from user to user_id", "removes GSI legacy"},
+		{"changes TTL from unset to expires_at"},
+		{"creates table shop-prod-carts"},
+		nil,
+	}
+	for i, r := range rs {
+		if r.Error != nil {
+			t.Fatalf("%s: unexpected error %v", r.TableInput.TableName, r.Error)
+		}
+		if diff := cmp.Diff(expected[i], r.Changes); diff != "" {
+			t.Fatalf("%s: unexpected changes: %s", r.TableInput.TableName, diff)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package tables
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Renderer formats validation and migration results, so non-CLI integrations
+// such as bots and web UIs present results consistently.
+type Renderer interface {
+	RenderValidation(w io.Writer, results []*ValidationResult) error
+	RenderMigration(w io.Writer, results []*MigrationResult) error
+}
+
+// Built-in renderers.
+var (
+	JSONRenderer     Renderer = jsonRenderer{}
+	YAMLRenderer     Renderer = yamlRenderer{}
+	TableRenderer    Renderer = tableRenderer{}
+	MarkdownRenderer Renderer = markdownRenderer{}
+)
+
+// Validation states reported by renderers.
+const (
+	ValidationInSync       = "in_sync"
+	ValidationCompatible   = "compatible"
+	ValidationIncompatible = "incompatible"
+	ValidationError        = "error"
+)
+
+// ValidationReport is the serializable form of a ValidationResult.
+type ValidationReport struct {
+	Table   string `json:"table" yaml:"table"`
+	Status  string `json:"status" yaml:"status"`
+	Summary string `json:"summary" yaml:"summary"`
+	// Description is the stated intent of the table's changes, empty if the table is in sync.
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Diff        string   `json:"diff,omitempty" yaml:"diff,omitempty"`
+	Changes     []string `json:"changes,omitempty" yaml:"changes,omitempty"`
+	Warnings    []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+	Advisories  []string `json:"advisories,omitempty" yaml:"advisories,omitempty"`
+	Error       string   `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// MigrationReport is the serializable form of a MigrationResult.
+type MigrationReport struct {
+	Table       string   `json:"table" yaml:"table"`
+	Status      string   `json:"status" yaml:"status"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Applied     []string `json:"applied,omitempty" yaml:"applied,omitempty"`
+	Skipped     []string `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+	Errors      []string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// NewValidationReport converts a ValidationResult to its serializable form.
+func NewValidationReport(r *ValidationResult) ValidationReport {
+	report := ValidationReport{
+		Table:    r.TableInput.TableName,
+		Summary:  stableDiff(r.String()),
+		Diff:     stableDiff(r.Diff),
+		Warnings: r.Warnings,
+	}
+	switch {
+	case r.Error != nil:
+		report.Status = ValidationError
+		report.Error = r.Error.Error()
+	case r.incompatible():
+		report.Status = ValidationIncompatible
+	case len(r.Diff) > 0:
+		report.Status = ValidationCompatible
+	default:
+		report.Status = ValidationInSync
+	}
+	if len(r.Diff) > 0 {
+		report.Description = r.TableInput.Description
+	}
+	for _, a := range r.Advisories {
+		report.Advisories = append(report.Advisories, a.String())
+	}
+	if r.Error == nil && r.CanMigrate {
+		for _, ch := range tableChanges(r) {
+			report.Changes = append(report.Changes, ch.String())
+		}
+	}
+	return report
+}
+
+// NewMigrationReport converts a MigrationResult to its serializable form.
+func NewMigrationReport(m *MigrationResult) MigrationReport {
+	report := MigrationReport{
+		Table:       m.TableInput.TableName,
+		Status:      string(m.Status),
+		Description: m.Description,
+	}
+	for _, ch := range m.Applied {
+		report.Applied = append(report.Applied, ch.String())
+	}
+	for _, ch := range m.Skipped {
+		report.Skipped = append(report.Skipped, ch.String())
+	}
+	for _, err := range m.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	return report
+}
+
+// details lists the description, changes, warnings, advisories and error of the report.
+func (r ValidationReport) details() []string {
+	details := []string{}
+	if r.Description != "" {
+		details = append(details, "intent: "+r.Description)
+	}
+	details = append(details, r.Changes...)
+	details = append(details, r.Warnings...)
+	for _, a := range r.Advisories {
+		details = append(details, "advisory: "+a)
+	}
+	if r.Error != "" {
+		details = append(details, r.Error)
+	}
+	return details
+}
+
+func validationReports(results []*ValidationResult) []ValidationReport {
+	reports := []ValidationReport{}
+	for _, r := range results {
+		if r != nil {
+			reports = append(reports, NewValidationReport(r))
+		}
+	}
+	return reports
+}
+
+// migrationReports skips nil entries, e.g. of results assembled by callers.
+func migrationReports(results []*MigrationResult) []MigrationReport {
+	reports := []MigrationReport{}
+	for _, m := range results {
+		if m != nil {
+			reports = append(reports, NewMigrationReport(m))
+		}
+	}
+	return reports
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) RenderValidation(w io.Writer, results []*ValidationResult) error {
+	return writeJSON(w, validationReports(results))
+}
+
+func (jsonRenderer) RenderMigration(w io.Writer, results []*MigrationResult) error {
+	return writeJSON(w, migrationReports(results))
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+type yamlRenderer struct{}
+
+func (yamlRenderer) RenderValidation(w io.Writer, results []*ValidationResult) error {
+	return writeYAML(w, validationReports(results))
+}
+
+func (yamlRenderer) RenderMigration(w io.Writer, results []*MigrationResult) error {
+	return writeYAML(w, migrationReports(results))
+}
+
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type tableRenderer struct{}
+
+func (tableRenderer) RenderValidation(w io.Writer, results []*ValidationResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tSTATUS\tCHANGES")
+	for _, r := range validationReports(results) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Table, r.Status, strings.Join(r.details(), ", "))
+	}
+	return tw.Flush()
+}
+
+func (tableRenderer) RenderMigration(w io.Writer, results []*MigrationResult) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tSTATUS\tAPPLIED\tSKIPPED\tERRORS")
+	for _, m := range migrationReports(results) {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", m.Table, m.Status, len(m.Applied), len(m.Skipped), strings.Join(m.Errors, "; "))
+	}
+	return tw.Flush()
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) RenderValidation(w io.Writer, results []*ValidationResult) error {
+	lines := []string{
+		"| Table | Status | Changes |",
+		"| --- | --- | --- |",
+	}
+	for _, r := range validationReports(results) {
+		lines = append(lines, fmt.Sprintf("| %s | %s | %s |",
+			escapeMarkdown(r.Table), r.Status, escapeMarkdown(strings.Join(r.details(), "<br>"))))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func (markdownRenderer) RenderMigration(w io.Writer, results []*MigrationResult) error {
+	lines := []string{
+		"| Table | Status | Applied | Skipped | Errors |",
+		"| --- | --- | --- | --- | --- |",
+	}
+	for _, m := range migrationReports(results) {
+		lines = append(lines, fmt.Sprintf("| %s | %s | %d | %d | %s |",
+			escapeMarkdown(m.Table), m.Status, len(m.Applied), len(m.Skipped), escapeMarkdown(strings.Join(m.Errors, "<br>"))))
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,76 @@
+package tables
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderers(t *testing.T) {
+	results := []*ValidationResult{
+		{
+			TableInput: TableInfo{TableName: "in-sync"},
+			CanMigrate: true,
+		},
+		{
+			TableInput: TableInfo{TableName: "broken"},
+			Error:      errors.New("access denied"),
+		},
+		{
+			TableInput: TableInfo{TableName: "users", Description: "add by_email for login lookups"},
+			Diff:       "GSI: by_email missing",
+			CanMigrate: true,
+		},
+	}
+	migrations := []*MigrationResult{
+		nil,
+		{
+			TableInput: TableInfo{TableName: "orders"},
+			Status:     StatusFailed,
+			Errors:     []error{ErrCapacityDecreaseNotApproved},
+		},
+	}
+
+	for name, r := range map[string]Renderer{
+		"json":     JSONRenderer,
+		"yaml":     YAMLRenderer,
+		"table":    TableRenderer,
+		"markdown": MarkdownRenderer,
+	} {
+		var buf bytes.Buffer
+		if err := r.RenderValidation(&buf, results); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(buf.String(), "access denied") {
+			t.Fatalf("%s: expected error in output but got %s", name, buf.String())
+		}
+		if !strings.Contains(buf.String(), "add by_email for login lookups") {
+			t.Fatalf("%s: expected description in output but got %s", name, buf.String())
+		}
+
+		buf.Reset()
+		if err := r.RenderMigration(&buf, migrations); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(buf.String(), "orders") {
+			t.Fatalf("%s: expected table in output but got %s", name, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := JSONRenderer.RenderValidation(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	reports := []ValidationReport{}
+	if err := json.Unmarshal(buf.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if reports[0].Status != ValidationInSync || reports[1].Status != ValidationError {
+		t.Fatalf("unexpected statuses %+v", reports)
+	}
+	if reports[0].Description != "" || reports[2].Description != "add by_email for login lookups" {
+		t.Fatalf("expected description of the changed table only but got %+v", reports)
+	}
+}
@@ -0,0 +1,64 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+)
+
+// ResourceManager manages resources associated with a table, e.g. alarms, dashboards or IAM grants.
+// Registered managers run alongside the schema comparison of every managed table via
+// WithResourceManagers.
+type ResourceManager interface {
+	// Name identifies the manager in diffs and errors.
+	Name() string
+	// Validate compares the resources of the table to the config and returns a diff,
+	// empty if they are in sync.
+	Validate(tbl TableInfo) (string, error)
+	// Migrate brings the resources of the table in line with the config. It is called by
+	// Migrate after the schema changes of the table were applied without errors.
+	Migrate(tbl TableInfo) error
+}
+
+// validateResources runs the resource managers for the table and adds their diffs to the result.
+// A failing manager makes the result unmigratable.
+func (c *Controller) validateResources(tbl TableInfo, result *ValidationResult) {
+	for _, rm := range c.resourceManagers {
+		diff, err := rm.Validate(tbl)
+		if err != nil {
+			result.CanMigrate = false
+			result.Error = fmt.Errorf("resource %s: %w", rm.Name(), err)
+			continue
+		}
+		if diff == "" {
+			continue
+		}
+		if result.ResourceDiffs == nil {
+			result.ResourceDiffs = map[string]string{}
+		}
+		result.ResourceDiffs[rm.Name()] = diff
+		result.Diff = fmt.Sprintf("%s, Resource %s: %s", result.Diff, rm.Name(), diff)
+	}
+}
+
+// migrateResources calls the resource managers which reported a diff for the table.
+// The calls of managers of this package are recorded in the audit log.
+func (c *Controller) migrateResources(ctx context.Context, r *ValidationResult, m *MigrationResult) {
+	for _, rm := range c.resourceManagers {
+		if _, ok := r.ResourceDiffs[rm.Name()]; !ok {
+			continue
+		}
+		c.Log.Infof("Migrating resource %s of table %s", rm.Name(), r.TableInput.TableName)
+		var err error
+		if am, ok := rm.(auditedResourceManager); ok {
+			// The region of the manager's client is not known, records leave it out.
+			err = am.migrate(r.TableInput, func(operation string, input interface{}) error {
+				return c.record(ctx, "", operation, input)
+			})
+		} else {
+			err = rm.Migrate(r.TableInput)
+		}
+		if err != nil {
+			m.Errors = append(m.Errors, fmt.Errorf("resource %s: %w", rm.Name(), err))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type fakeResourceManager struct {
+	name     string
+	diff     string
+	err      error
+	migrated []string
+}
+
+func (f *fakeResourceManager) Name() string { return f.name }
+
+func (f *fakeResourceManager) Validate(tbl TableInfo) (string, error) {
+	return f.diff, f.err
+}
+
+func (f *fakeResourceManager) Migrate(tbl TableInfo) error {
+	f.migrated = append(f.migrated, tbl.TableName)
+	return nil
+}
+
+func TestResourceManagers(t *testing.T) {
+	created := false
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		created = true
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	alarms := &fakeResourceManager{name: "alarms", diff: "missing alarm: throttles"}
+	grants := &fakeResourceManager{name: "grants"}
+	c, err := NewController(db, "test", nil, []TableInfo{{TableName: "orders", PrimaryKey: "id"}},
+		WithClock(&fakeClock{}), WithResourceManagers(alarms, grants))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Validate()
+	if err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	if d := results[0].ResourceDiffs; len(d) != 1 || d["alarms"] != "missing alarm: throttles" {
+		t.Fatalf("expected alarms diff only but got %v", d)
+	}
+
+	ms := c.Migrate(results)
+	if ms[0].Status != StatusApplied {
+		t.Fatalf("expected migration to be applied but got %+v", ms[0])
+	}
+	if !created || len(alarms.migrated) != 1 || len(grants.migrated) != 0 {
+		t.Fatalf("expected table and alarms to be migrated but got created=%v alarms=%v grants=%v", created, alarms.migrated, grants.migrated)
+	}
+
+	grants.err = errors.New("access denied")
+	results, err = c.Validate()
+	if err != ErrBackwardIncompatible || results[0].CanMigrate || !errors.Is(results[0].Error, grants.err) {
+		t.Fatalf("expected failing resource manager to block migration but got %v, %+v", err, results[0])
+	}
+}
@@ -0,0 +1,94 @@
+package tables
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrCodeThrottlingException is the error code returned when control plane requests are throttled.
+const ErrCodeThrottlingException = "ThrottlingException"
+
+// RetryPolicy describes how requests failing with a specific AWS error code are retried.
+// MaxAttempts is the maximum number of attempts, Backoff returns the delay before
+// the given retry attempt (starting at 1).
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration
+}
+
+// ConstantBackoff waits the same delay before every retry.
+func ConstantBackoff(d time.Duration) func(int) time.Duration {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff doubles the delay before every retry, starting at base and capped at max.
+func ExponentialBackoff(base, max time.Duration) func(int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt && d < max; i++ {
+			d *= 2
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// DefaultRetryPolicies returns the retry policies used unless overridden via WithRetryPolicy.
+// Only errors with application-level semantics are retried here: a table or index being
+// busy or not yet visible, continuous backups not yet available on a new table, and the limit of concurrent control plane operations.
+// ResourceNotFound is not retried for UpdateTable, where it means the table is missing.
+// Throttling and transient 5xx errors are left to the retryer of the AWS SDK client,
+// see SessionClientFactory, so requests are not retried by two layered loops.
+func DefaultRetryPolicies() map[string]RetryPolicy {
+	constant := RetryPolicy{
+		MaxAttempts: MultiIndexUpdateRetryAttempts,
+		Backoff:     ConstantBackoff(MultiIndexUpdateRetryInterval * time.Second),
+	}
+	return map[string]RetryPolicy{
+		dynamodb.ErrCodeResourceInUseException:                constant,
+		dynamodb.ErrCodeLimitExceededException:                constant,
+		dynamodb.ErrCodeResourceNotFoundException:             constant,
+		dynamodb.ErrCodeContinuousBackupsUnavailableException: constant,
+	}
+}
+
+// withRetry calls op until it succeeds or fails with an error code without retry policy.
+// Error codes listed in noRetry are never retried, e.g. ResourceNotFound where it means a table is missing.
+// Attempts are counted per call across error codes, ErrRequestWithMaxRetry is returned once the call
+// has used up the attempts of the policy of its latest error code. No retry is started once ctx is done.
+func (c *Controller) withRetry(ctx context.Context, op func() error, noRetry ...string) error {
+	attempts := 0
+	for {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			return err
+		}
+		for _, code := range noRetry {
+			if aerr.Code() == code {
+				return err
+			}
+		}
+		policy, ok := c.retryPolicies[aerr.Code()]
+		if !ok {
+			return err
+		}
+		attempts++
+		if attempts >= policy.MaxAttempts {
+			return ErrRequestWithMaxRetry
+		}
+		if err := c.sleepWithContext(ctx, policy.Backoff(attempts)); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package tables
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// PriorState is the state of a table captured by Migrate before its changes are applied.
+type PriorState struct {
+	Table      *dynamodb.TableDescription
+	TimeToLive *dynamodb.TimeToLiveDescription
+	// Tags is nil unless fingerprints are stored, the only tags Migrate changes.
+	Tags []*dynamodb.Tag
+}
+
+// capturePriorState records the state of an existing table in the migration result before
+// Migrate changes it. The table and TTL descriptions of the validation are reused.
+func (c *Controller) capturePriorState(r *ValidationResult, m *MigrationResult) error {
+	if r.TableDescription == nil {
+		return nil
+	}
+	prior := &PriorState{
+		Table:      r.TableDescription,
+		TimeToLive: r.TimeToLiveDescription,
+	}
+	if prior.TimeToLive == nil && r.UpdateTTLInput != nil {
+		ttl, err := c.describeTTL(r.TableInput)
+		if err != nil {
+			return err
+		}
+		prior.TimeToLive = ttl
+	}
+	if c.fingerprints {
+		tags, err := c.listTags(r.TableInput, r.TableDescription.TableArn)
+		if err != nil {
+			return err
+		}
+		prior.Tags = tags
+	}
+	m.PriorState = prior
+	return nil
+}
+
+// Rollback reverts the reversible changes of the given migration results, e.g. when a deployment
+// is rolled back. Throughput, TTL and fingerprint tags are restored from the prior state captured
+// by Migrate and GSIs created by the migration are deleted. Created tables and other changes are not
+// reverted. Results without prior state or changes to revert are skipped.
+// Reverting changes is subject to the same change budget and approvers as Migrate: if the rollback
+// exceeds the budget nothing is reverted, unapproved changes are skipped.
+func (c *Controller) Rollback(ctx context.Context, results []*MigrationResult) []*MigrationResult {
+	ctx, runID := c.startRun(ctx)
+	c.Log.Infof("Rollback run %s started", runID)
+	rs := make([]*MigrationResult, len(results))
+	plans := make([][]Change, len(results))
+	var wg sync.WaitGroup
+	for i, m := range results {
+		if m == nil {
+			continue
+		}
+		rs[i] = &MigrationResult{
+			TableInput: m.TableInput,
+			Status:     StatusSkipped,
+			RunID:      runID,
+		}
+		if m.PriorState == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, m *MigrationResult) {
+			defer wg.Done()
+			changes, err := c.rollbackChanges(m)
+			if err != nil {
+				rs[i].Status = StatusFailed
+				rs[i].Errors = append(rs[i].Errors, err)
+				c.Log.Errorf("Rollback table [%s] with error: %v", m.TableInput.TableName, err)
+				return
+			}
+			plans[i] = changes
+		}(i, m)
+	}
+	wg.Wait()
+
+	changes := []Change{}
+	for _, plan := range plans {
+		changes = append(changes, plan...)
+	}
+	if err := c.checkBudget(changes); err != nil {
+		c.Log.Errorf("Rollback aborted: %v", err)
+		for i, plan := range plans {
+			if len(plan) > 0 {
+				rs[i].Status = StatusFailed
+				rs[i].Skipped = plan
+				rs[i].Errors = []error{err}
+			}
+		}
+		return rs
+	}
+	approved := c.approve(changes)
+
+	for i, m := range results {
+		if len(plans[i]) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(m, r *MigrationResult, changes []Change) {
+			defer wg.Done()
+			c.rollback(ctx, m, r, changes, approved)
+			c.describeCache.invalidate(physicalName(c.env, m.TableInput))
+		}(m, rs[i], plans[i])
+	}
+	wg.Wait()
+	return rs
+}
+
+// rollback applies the changes reverting a single table and records them in r.
+// Capacity decreases and TTL retention changes are skipped unless approved.
+func (c *Controller) rollback(ctx context.Context, m *MigrationResult, r *MigrationResult, changes []Change, approved approvals) {
+	r.Status = StatusApplied
+	for i, ch := range changes {
+		if ctx.Err() != nil {
+			r.Status = StatusCancelled
+			r.Skipped = append(r.Skipped, changes[i:]...)
+			r.Errors = append(r.Errors, ctx.Err())
+			return
+		}
+		var err error
+		switch input := ch.Input.(type) {
+		case *dynamodb.UpdateTableInput:
+			if ch.CapacityDecrease && !approved.capacityDecrease {
+				c.Log.Errorf("Skipping capacity decrease for table %s", aws.StringValue(input.TableName))
+				r.Skipped = append(r.Skipped, ch)
+				r.Errors = append(r.Errors, ErrCapacityDecreaseNotApproved)
+				continue
+			}
+			c.Log.Infof("Reverting update of table %s", aws.StringValue(input.TableName))
+			err = c.updateTable(ctx, m.TableInput, input)
+		case *dynamodb.UpdateTimeToLiveInput:
+			if ch.TTLRetentionChange && !approved.ttlChange {
+				c.Log.Errorf("Skipping TTL change for table %s", aws.StringValue(input.TableName))
+				r.Skipped = append(r.Skipped, ch)
+				r.Errors = append(r.Errors, ErrTTLChangeNotApproved)
+				continue
+			}
+			c.Log.Infof("Reverting TTL of table %s", aws.StringValue(input.TableName))
+			err = c.updateTTL(ctx, m.TableInput, input)
+		case *dynamodb.TagResourceInput:
+			err = c.withRetry(ctx, func() error {
+				if err := c.audit(ctx, m.TableInput, "TagResource", input); err != nil {
+					return err
+				}
+				_, err := c.client(m.TableInput).TagResource(input)
+				return err
+			})
+		case *dynamodb.UntagResourceInput:
+			err = c.withRetry(ctx, func() error {
+				if err := c.audit(ctx, m.TableInput, "UntagResource", input); err != nil {
+					return err
+				}
+				_, err := c.client(m.TableInput).UntagResource(input)
+				return err
+			})
+		}
+		if err != nil {
+			r.Errors = append(r.Errors, err)
+			continue
+		}
+		r.Applied = append(r.Applied, ch)
+	}
+	if len(r.Errors) > 0 {
+		r.Status = StatusFailed
+	}
+	c.Log.Infof("Rollback table [%s] with errors: %+v", m.TableInput.TableName, r.Errors)
+}
+
+// rollbackChanges compares the current state of the table with the prior state captured
+// by the migration and returns the changes restoring it.
+func (c *Controller) rollbackChanges(m *MigrationResult) ([]Change, error) {
+	tbl, prior := m.TableInput, m.PriorState
+	name := tbl.TableName
+	desc, err := c.describeTable(tbl)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	for _, input := range RollbackInputs(prior.Table, desc, appliedIndexes(m.Applied)) {
+		changes = append(changes, Change{
+			TableName:        name,
+			Type:             ChangeUpdateTable,
+			Destructive:      deletesIndex(input),
+			CapacityDecrease: IsCapacityDecrease(desc, input),
+			Input:            input,
+		})
+	}
+
+	if prior.TimeToLive != nil {
+		ttl, err := c.describeTTL(tbl)
+		if err != nil {
+			return nil, err
+		}
+		if input := rollbackTTLInput(prior.TimeToLive, ttl, desc.TableName); input != nil {
+			changes = append(changes, Change{
+				TableName:          name,
+				Type:               ChangeUpdateTTL,
+				Destructive:        true,
+				TTLRetentionChange: IsTTLRetentionChange(ttl, input),
+				Input:              input,
+			})
+		}
+	}
+
+	if prior.Tags != nil {
+		tags, err := c.listTags(tbl, desc.TableArn)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, rollbackTagChanges(name, desc.TableArn, prior.Tags, tags)...)
+	}
+	return changes, nil
+}
+
+// appliedIndexes returns the names of the GSIs created by the given applied changes.
+func appliedIndexes(applied []Change) []string {
+	names := []string{}
+	for _, ch := range applied {
+		input, ok := ch.Input.(*dynamodb.UpdateTableInput)
+		if !ok {
+			continue
+		}
+		for _, u := range input.GlobalSecondaryIndexUpdates {
+			if u.Create != nil {
+				names = append(names, aws.StringValue(u.Create.IndexName))
+			}
+		}
+	}
+	return names
+}
+
+func deletesIndex(input *dynamodb.UpdateTableInput) bool {
+	for _, u := range input.GlobalSecondaryIndexUpdates {
+		if u.Delete != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RollbackInputs returns the inputs restoring the throughput of a table and its GSIs and deleting
+// the created GSIs which did not exist in the prior description. GSIs created by other means are
+// kept. GSIs are deleted one per input as required by DynamoDB.
+// Throughput is only restored if the table was and still is provisioned.
+func RollbackInputs(prior, current *dynamodb.TableDescription, created []string) []*dynamodb.UpdateTableInput {
+	inputs := []*dynamodb.UpdateTableInput{}
+	createdIndexes := make(map[string]bool, len(created))
+	for _, name := range created {
+		createdIndexes[name] = true
+	}
+	priorIndexes := make(map[string]*dynamodb.GlobalSecondaryIndexDescription, len(prior.GlobalSecondaryIndexes))
+	for _, gsi := range prior.GlobalSecondaryIndexes {
+		priorIndexes[aws.StringValue(gsi.IndexName)] = gsi
+	}
+	provisioned := currentBillingMode(prior) == dynamodb.BillingModeProvisioned &&
+		currentBillingMode(current) == dynamodb.BillingModeProvisioned
+
+	throughput := &dynamodb.UpdateTableInput{TableName: current.TableName}
+	if provisioned && throughputChanged(prior.ProvisionedThroughput, current.ProvisionedThroughput) {
+		throughput.ProvisionedThroughput = provisionedThroughput(prior.ProvisionedThroughput)
+	}
+	for _, gsi := range current.GlobalSecondaryIndexes {
+		p, ok := priorIndexes[aws.StringValue(gsi.IndexName)]
+		if !ok {
+			if !createdIndexes[aws.StringValue(gsi.IndexName)] {
+				continue
+			}
+			inputs = append(inputs, &dynamodb.UpdateTableInput{
+				TableName: current.TableName,
+				GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+					Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: gsi.IndexName},
+				}},
+			})
+			continue
+		}
+		if provisioned && throughputChanged(p.ProvisionedThroughput, gsi.ProvisionedThroughput) {
+			throughput.GlobalSecondaryIndexUpdates = append(throughput.GlobalSecondaryIndexUpdates, &dynamodb.GlobalSecondaryIndexUpdate{
+				Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+					IndexName:             gsi.IndexName,
+					ProvisionedThroughput: provisionedThroughput(p.ProvisionedThroughput),
+				},
+			})
+		}
+	}
+	if throughput.ProvisionedThroughput != nil || len(throughput.GlobalSecondaryIndexUpdates) > 0 {
+		inputs = append([]*dynamodb.UpdateTableInput{throughput}, inputs...)
+	}
+	return inputs
+}
+
+func throughputChanged(prior, current *dynamodb.ProvisionedThroughputDescription) bool {
+	if prior == nil || current == nil {
+		return false
+	}
+	return aws.Int64Value(prior.ReadCapacityUnits) != aws.Int64Value(current.ReadCapacityUnits) ||
+		aws.Int64Value(prior.WriteCapacityUnits) != aws.Int64Value(current.WriteCapacityUnits)
+}
+
+func provisionedThroughput(desc *dynamodb.ProvisionedThroughputDescription) *dynamodb.ProvisionedThroughput {
+	return &dynamodb.ProvisionedThroughput{
+		ReadCapacityUnits:  desc.ReadCapacityUnits,
+		WriteCapacityUnits: desc.WriteCapacityUnits,
+	}
+}
+
+// rollbackTTLInput returns the input restoring the prior TTL, nil if it is unchanged.
+func rollbackTTLInput(prior, current *dynamodb.TimeToLiveDescription, tableName *string) *dynamodb.UpdateTimeToLiveInput {
+	priorEnabled, enabled := isTTLEnabled(prior), isTTLEnabled(current)
+	switch {
+	case priorEnabled && (!enabled || aws.StringValue(prior.AttributeName) != aws.StringValue(current.AttributeName)):
+		return &dynamodb.UpdateTimeToLiveInput{
+			TableName: tableName,
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: prior.AttributeName,
+				Enabled:       aws.Bool(true),
+			},
+		}
+	case !priorEnabled && enabled:
+		return &dynamodb.UpdateTimeToLiveInput{
+			TableName: tableName,
+			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+				AttributeName: current.AttributeName,
+				Enabled:       aws.Bool(false),
+			},
+		}
+	}
+	return nil
+}
+
+// rollbackTagChanges returns the changes restoring the prior fingerprint tags.
+func rollbackTagChanges(name string, arn *string, prior, current []*dynamodb.Tag) []Change {
+	priorValues := make(map[string]*string, len(prior))
+	for _, tag := range prior {
+		priorValues[aws.StringValue(tag.Key)] = tag.Value
+	}
+	currentValues := make(map[string]string, len(current))
+	for _, tag := range current {
+		currentValues[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	tag := &dynamodb.TagResourceInput{ResourceArn: arn}
+	untag := &dynamodb.UntagResourceInput{ResourceArn: arn}
+	for _, key := range []string{FingerprintTagKey, FingerprintTimeTagKey} {
+		value, existed := priorValues[key]
+		currentValue, exists := currentValues[key]
+		switch {
+		case existed && (!exists || aws.StringValue(value) != currentValue):
+			tag.Tags = append(tag.Tags, &dynamodb.Tag{Key: aws.String(key), Value: value})
+		case !existed && exists:
+			untag.TagKeys = append(untag.TagKeys, aws.String(key))
+		}
+	}
+
+	changes := []Change{}
+	if len(tag.Tags) > 0 {
+		changes = append(changes, Change{TableName: name, Type: ChangeTagResource, Input: tag})
+	}
+	if len(untag.TagKeys) > 0 {
+		changes = append(changes, Change{TableName: name, Type: ChangeUntagResource, Input: untag})
+	}
+	return changes
+}
@@ -0,0 +1,243 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func provisionedDescription(read, write int64, indexes ...string) *dynamodb.TableDescription {
+	desc := &dynamodb.TableDescription{
+		TableName:   aws.String("test-orders"),
+		TableStatus: aws.String(dynamodb.TableStatusActive),
+		ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  aws.Int64(read),
+			WriteCapacityUnits: aws.Int64(write),
+		},
+	}
+	for _, name := range indexes {
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName: aws.String(name),
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(read),
+				WriteCapacityUnits: aws.Int64(write),
+			},
+		})
+	}
+	return desc
+}
+
+func TestRollbackInputs(t *testing.T) {
+	prior := provisionedDescription(5, 5, "by_customer")
+	current := provisionedDescription(10, 5, "by_customer", "by_email", "by_status")
+
+	inputs := RollbackInputs(prior, current, []string{"by_email"})
+	if len(inputs) != 2 {
+		t.Fatalf("expected throughput and index deletion inputs but got %v", inputs)
+	}
+	if r := aws.Int64Value(inputs[0].ProvisionedThroughput.ReadCapacityUnits); r != 5 {
+		t.Fatalf("expected read capacity to be restored to 5 but got %d", r)
+	}
+	if u := inputs[0].GlobalSecondaryIndexUpdates; len(u) != 1 || aws.StringValue(u[0].Update.IndexName) != "by_customer" {
+		t.Fatalf("expected by_customer throughput to be restored but got %v", u)
+	}
+	if d := inputs[1].GlobalSecondaryIndexUpdates[0].Delete; d == nil || aws.StringValue(d.IndexName) != "by_email" {
+		t.Fatalf("expected by_email to be deleted but got %v", inputs[1])
+	}
+
+	if inputs := RollbackInputs(prior, current, nil); len(inputs) != 1 || inputs[0].ProvisionedThroughput == nil {
+		t.Fatalf("expected indexes not created by the migration to be kept but got %v", inputs)
+	}
+	if inputs := RollbackInputs(prior, prior, nil); len(inputs) != 0 {
+		t.Fatalf("expected no inputs for unchanged table but got %v", inputs)
+	}
+}
+
+func TestRollbackTagChanges(t *testing.T) {
+	arn := aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/test-orders")
+	prior := []*dynamodb.Tag{{Key: aws.String(FingerprintTagKey), Value: aws.String("old")}, {Key: aws.String("team"), Value: aws.String("a")}}
+	current := []*dynamodb.Tag{
+		{Key: aws.String(FingerprintTagKey), Value: aws.String("new")},
+		{Key: aws.String(FingerprintTimeTagKey), Value: aws.String("2020-01-01T00:00:00Z")},
+	}
+
+	changes := rollbackTagChanges("orders", arn, prior, current)
+	if len(changes) != 2 || changes[0].Type != ChangeTagResource || changes[1].Type != ChangeUntagResource {
+		t.Fatalf("expected tag and untag changes but got %v", changes)
+	}
+	tag := changes[0].Input.(*dynamodb.TagResourceInput)
+	if len(tag.Tags) != 1 || aws.StringValue(tag.Tags[0].Value) != "old" {
+		t.Fatalf("expected fingerprint to be restored but got %v", tag.Tags)
+	}
+	untag := changes[1].Input.(*dynamodb.UntagResourceInput)
+	if len(untag.TagKeys) != 1 || aws.StringValue(untag.TagKeys[0]) != FingerprintTimeTagKey {
+		t.Fatalf("expected fingerprint time to be removed but got %v", untag.TagKeys)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	prior := provisionedDescription(5, 5)
+	current := prior
+	ttl := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: current}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: ttl}, nil
+	}
+	updates := []*dynamodb.UpdateTableInput{}
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		updates = append(updates, input)
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	ttlUpdates := []*dynamodb.UpdateTimeToLiveInput{}
+	db.updateTTL = func(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		ttlUpdates = append(ttlUpdates, input)
+		return &dynamodb.UpdateTimeToLiveOutput{}, nil
+	}
+	c, err := NewController(db, "test", nil, nil,
+		WithClock(&fakeClock{}),
+		WithAllowCapacityDecrease(),
+		WithTTLChangeApprover(func([]Change) bool { return true }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 10, WriteThroughput: 5}
+	plan := []*ValidationResult{
+		{
+			TableInput:            tbl,
+			TableDescription:      prior,
+			TimeToLiveDescription: ttl,
+			UpdateTableInput: []*dynamodb.UpdateTableInput{
+				{
+					TableName:             aws.String("test-orders"),
+					ProvisionedThroughput: &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(10), WriteCapacityUnits: aws.Int64(5)},
+				},
+				{
+					TableName: aws.String("test-orders"),
+					GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+						Create: &dynamodb.CreateGlobalSecondaryIndexAction{IndexName: aws.String("by_email")},
+					}},
+				},
+			},
+			UpdateTTLInput: NewUpdateTimeToLiveInput(tbl, "test", &TTLAttributeInfo{AttributeName: "expires", Enabled: true}),
+			Diff:           "Throughput: ...",
+			CanMigrate:     true,
+		},
+		{TableInput: TableInfo{TableName: "created"}, CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("created")}, Diff: "Table missing", CanMigrate: true},
+	}
+	ms := c.Migrate(plan)
+	if ms[0].Status != StatusApplied || ms[0].PriorState == nil || ms[1].PriorState != nil {
+		t.Fatalf("expected prior state of the existing table only but got %+v, %+v", ms[0], ms[1])
+	}
+
+	current = provisionedDescription(10, 5, "by_email", "by_status")
+	ttl = &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String("expires"),
+		TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+	}
+	updates, ttlUpdates = nil, nil
+	rs := c.Rollback(context.Background(), ms)
+	if rs[0].Status != StatusApplied || len(rs[0].Applied) != 3 {
+		t.Fatalf("expected throughput, index and TTL to be reverted but got %+v", rs[0])
+	}
+	if rs[1].Status != StatusSkipped {
+		t.Fatalf("expected created table to be skipped but got %+v", rs[1])
+	}
+	if len(updates) != 2 || aws.Int64Value(updates[0].ProvisionedThroughput.ReadCapacityUnits) != 5 {
+		t.Fatalf("expected throughput to be restored but got %v", updates)
+	}
+	if len(ttlUpdates) != 1 || aws.BoolValue(ttlUpdates[0].TimeToLiveSpecification.Enabled) {
+		t.Fatalf("expected TTL to be disabled but got %v", ttlUpdates)
+	}
+}
+
+func TestRollbackApprovalsAndBudget(t *testing.T) {
+	prior := provisionedDescription(5, 5)
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: provisionedDescription(10, 5, "by_email")}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			AttributeName:    aws.String("expires"),
+			TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusEnabled),
+		}}, nil
+	}
+	updates := []*dynamodb.UpdateTableInput{}
+	db.updateTable = func(input *dynamodb.UpdateTableInput) (*dynamodb.UpdateTableOutput, error) {
+		updates = append(updates, input)
+		return &dynamodb.UpdateTableOutput{}, nil
+	}
+	db.updateTTL = func(*dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+		t.Fatal("unexpected TTL update")
+		return nil, nil
+	}
+	ms := []*MigrationResult{{
+		TableInput: TableInfo{TableName: "orders"},
+		Status:     StatusApplied,
+		PriorState: &PriorState{
+			Table:      prior,
+			TimeToLive: &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)},
+		},
+		Applied: []Change{{
+			TableName: "orders",
+			Type:      ChangeUpdateTable,
+			Input: &dynamodb.UpdateTableInput{
+				TableName: aws.String("test-orders"),
+				GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+					Create: &dynamodb.CreateGlobalSecondaryIndexAction{IndexName: aws.String("by_email")},
+				}},
+			},
+		}},
+	}}
+
+	// Unapproved capacity decreases and TTL retention changes are skipped.
+	var decreases []Change
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}),
+		WithCapacityDecreaseApprover(func(changes []Change) bool {
+			decreases = changes
+			return false
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := c.Rollback(context.Background(), ms)
+	if rs[0].Status != StatusFailed || len(rs[0].Applied) != 1 || len(rs[0].Skipped) != 2 {
+		t.Fatalf("expected only the index deletion to be applied but got %+v", rs[0])
+	}
+	if !errors.Is(rs[0].Errors[0], ErrCapacityDecreaseNotApproved) || !errors.Is(rs[0].Errors[1], ErrTTLChangeNotApproved) {
+		t.Fatalf("expected approval errors but got %v", rs[0].Errors)
+	}
+	if len(decreases) != 1 || !decreases[0].CapacityDecrease {
+		t.Fatalf("expected the approver to receive the capacity decrease but got %v", decreases)
+	}
+	if len(updates) != 1 || updates[0].GlobalSecondaryIndexUpdates[0].Delete == nil {
+		t.Fatalf("expected by_email to be deleted but got %v", updates)
+	}
+
+	// Rollbacks exceeding the destructive change budget revert nothing.
+	updates = nil
+	c, err = NewController(db, "test", nil, nil, WithClock(&fakeClock{}), WithMaxDestructiveChanges(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs = c.Rollback(context.Background(), ms)
+	var budgetErr *ChangeBudgetError
+	if rs[0].Status != StatusFailed || len(rs[0].Errors) != 1 || !errors.As(rs[0].Errors[0], &budgetErr) || !budgetErr.Destructive {
+		t.Fatalf("expected a destructive change budget error but got %+v", rs[0])
+	}
+	if len(rs[0].Skipped) != 3 || len(updates) != 0 {
+		t.Fatalf("expected every change to be skipped but got %+v, %v", rs[0], updates)
+	}
+}
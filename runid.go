@@ -0,0 +1,100 @@
+package tables
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// RunIDTagKey is the tag holding the ID of the run which created or last migrated a table.
+	RunIDTagKey = "tables:run_id"
+	// ConfigRevisionTagKey is the tag holding the config revision set via WithConfigRevision.
+	ConfigRevisionTagKey = "tables:config_revision"
+)
+
+type runIDKey struct{}
+
+type descriptionKey struct{}
+
+// ContextWithRunID returns a context whose Migrate, CreateAll and ApplyMigrations runs use the
+// given run ID, e.g. a CI build ID. Otherwise a new run ID is generated for every run.
+func ContextWithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFromContext returns the run ID of the context, empty if none is set.
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// ContextWithChangeDescription returns a context whose runs state the given intent, e.g. the
+// release notes of a deployment. It is combined with the descriptions of the changed tables.
+func ContextWithChangeDescription(ctx context.Context, description string) context.Context {
+	return context.WithValue(ctx, descriptionKey{}, description)
+}
+
+// ChangeDescriptionFromContext returns the change description of the context, empty if none is set.
+func ChangeDescriptionFromContext(ctx context.Context) string {
+	description, _ := ctx.Value(descriptionKey{}).(string)
+	return description
+}
+
+// changeDescription joins the run's change description and the given description, e.g. of a table.
+func changeDescription(ctx context.Context, description string) string {
+	parts := []string{}
+	for _, d := range []string{ChangeDescriptionFromContext(ctx), description} {
+		if d != "" {
+			parts = append(parts, d)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// startRun returns a context carrying the run ID, a new one unless ctx already has one.
+func (c *Controller) startRun(ctx context.Context) (context.Context, string) {
+	if id := RunIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := newRunID(c.clock.Now())
+	return ContextWithRunID(ctx, id), id
+}
+
+// newRunID returns a unique ID which sorts by start time, e.g. 20240102T150405Z-9f86d081.
+func newRunID(now time.Time) string {
+	b := make([]byte, 4)
+	// crypto/rand only fails if the OS has no randomness source, the timestamp still tells runs apart.
+	rand.Read(b)
+	return now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
+
+// runTags returns the run ID and config revision tags of the run.
+func (c *Controller) runTags(ctx context.Context) []*dynamodb.Tag {
+	tags := []*dynamodb.Tag{}
+	if id := RunIDFromContext(ctx); id != "" {
+		tags = append(tags, &dynamodb.Tag{Key: aws.String(RunIDTagKey), Value: aws.String(id)})
+	}
+	if c.configRevision != "" {
+		tags = append(tags, &dynamodb.Tag{Key: aws.String(ConfigRevisionTagKey), Value: aws.String(c.configRevision)})
+	}
+	return tags
+}
+
+// addRunTags adds the run tags to a create table input unless the keys are already set.
+func (c *Controller) addRunTags(ctx context.Context, input *dynamodb.CreateTableInput) {
+	set := make(map[string]bool, len(input.Tags))
+	for _, tag := range input.Tags {
+		set[aws.StringValue(tag.Key)] = true
+	}
+	for _, tag := range c.runTags(ctx) {
+		if !set[aws.StringValue(tag.Key)] {
+			input.Tags = append(input.Tags, tag)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package tables
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// SARIF rule IDs reported by DriftFindings.
+const (
+	SARIFRuleValidationError   = "tables/validation-error"
+	SARIFRuleIncompatibleDrift = "tables/incompatible-drift"
+	SARIFRuleCompatibleDrift   = "tables/compatible-drift"
+	SARIFRuleValidationWarning = "tables/warning"
+)
+
+// SARIF rule IDs reported by ConfigFindings and NamingFindings.
+const (
+	SARIFRuleConfigError = "tables/config-error"
+	SARIFRuleNaming      = "tables/naming"
+)
+
+const (
+	sarifSchema       = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion      = "2.1.0"
+	sarifToolName     = "tables"
+	sarifToolURI      = "https://github.com/jacygao/tables"
+	sarifLevelError   = "error"
+	sarifLevelWarning = "warning"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// SARIFFinding is a single violation reported in SARIF output.
+type SARIFFinding struct {
+	RuleID    string
+	Level     string
+	TableName string
+	Message   string
+}
+
+// DriftFindings converts validation results to SARIF findings.
+// Errors and backward incompatible drift are errors, compatible drift and table warnings are warnings.
+func DriftFindings(results []*ValidationResult) []SARIFFinding {
+	findings := []SARIFFinding{}
+	for _, r := range results {
+		name := r.TableInput.TableName
+		switch {
+		case r.Error != nil:
+			findings = append(findings, SARIFFinding{SARIFRuleValidationError, sarifLevelError, name, r.Error.Error()})
+		case r.incompatible():
+			findings = append(findings, SARIFFinding{SARIFRuleIncompatibleDrift, sarifLevelError, name, r.String()})
+		case len(r.Diff) > 0:
+			findings = append(findings, SARIFFinding{SARIFRuleCompatibleDrift, sarifLevelWarning, name, r.String()})
+		}
+		for _, w := range r.Warnings {
+			findings = append(findings, SARIFFinding{SARIFRuleValidationWarning, sarifLevelWarning, name, w})
+		}
+	}
+	return findings
+}
+
+// ConfigFindings converts the findings of ValidateConfig to SARIF errors, so broken configs are
+// annotated in pull requests without calling AWS.
+func ConfigFindings(errs []ConfigError) []SARIFFinding {
+	findings := []SARIFFinding{}
+	for _, err := range errs {
+		findings = append(findings, SARIFFinding{SARIFRuleConfigError, sarifLevelError, err.TableName, err.Error()})
+	}
+	return findings
+}
+
+// NamingFindings converts the warnings of CheckNaming to SARIF warnings.
+func NamingFindings(warnings []Warning) []SARIFFinding {
+	findings := []SARIFFinding{}
+	for _, w := range warnings {
+		findings = append(findings, SARIFFinding{SARIFRuleNaming, sarifLevelWarning, w.TableName, w.Message})
+	}
+	return findings
+}
+
+// WriteSARIF renders findings as a SARIF 2.1.0 log, e.g. drift and lint findings appended to one another, so they show up as code scanning
+// annotations in pull requests. Each finding is located at the table_name line of
+// its table in the config file at configPath, whose content is passed as config.
+func WriteSARIF(w io.Writer, configPath string, config []byte, findings []SARIFFinding) error {
+	rules := []sarifRule{}
+	seen := map[string]bool{}
+	results := []sarifResult{}
+	for _, f := range findings {
+		if !seen[f.RuleID] {
+			seen[f.RuleID] = true
+			rules = append(rules, sarifRule{ID: f.RuleID, ShortDescription: sarifMessage{Text: f.RuleID}})
+		}
+		results = append(results, sarifResult{
+			RuleID:  f.RuleID,
+			Level:   f.Level,
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: configPath},
+						Region:           sarifRegion{StartLine: tableLine(config, f.TableName)},
+					},
+				},
+			},
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           sarifToolName,
+						InformationURI: sarifToolURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// tableLine returns the 1-based line of the table_name entry of the given table in config.
+func tableLine(config []byte, tableName string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "-"))
+		if !strings.HasPrefix(text, "table_name:") {
+			continue
+		}
+		value := strings.TrimSpace(strings.TrimPrefix(text, "table_name:"))
+		if strings.Trim(value, `"'`) == tableName {
+			return line
+		}
+	}
+	return 1
+}
@@ -0,0 +1,80 @@
+package tables
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteSARIF(t *testing.T) {
+	config := []byte(`- title: "example"
+  table_name: "orders"
+- table_name: users
+`)
+	results := []*ValidationResult{
+		{
+			TableInput: TableInfo{TableName: "orders"},
+			Diff:       "Key Schema",
+		},
+		{
+			TableInput: TableInfo{TableName: "users"},
+			Diff:       "missing table: users",
+			CanMigrate: true,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, "tables.yaml", config, DriftFindings(results)); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	res := log.Runs[0].Results
+	if len(res) != 2 {
+		t.Fatalf("expected 2 results but got %d", len(res))
+	}
+	if res[0].Level != sarifLevelError || res[0].Locations[0].PhysicalLocation.Region.StartLine != 2 {
+		t.Fatalf("expected error on line 2 but got %+v", res[0])
+	}
+	if res[1].Level != sarifLevelWarning || res[1].Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Fatalf("expected warning on line 3 but got %+v", res[1])
+	}
+}
+
+func TestLintFindings(t *testing.T) {
+	config := []byte("- table_name: orders\n  primary_key: id\n- table_name: users\n  primary_key: name\n")
+	tables := []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", BillingMode: "PAY_PER_REQUEST", Lifecycle: "create-only"},
+		{TableName: "users", PrimaryKey: "name", BillingMode: "PAY_PER_REQUEST"},
+	}
+	warnings, err := CheckNaming(tables, NamingConvention{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := append(ConfigFindings(ValidateConfig(tables)), NamingFindings(warnings)...)
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, "tables.yaml", config, findings); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	res := log.Runs[0].Results
+	if len(res) != 2 {
+		t.Fatalf("expected 2 results but got %+v", res)
+	}
+	if res[0].RuleID != SARIFRuleConfigError || res[0].Level != sarifLevelError || res[0].Locations[0].PhysicalLocation.Region.StartLine != 1 {
+		t.Fatalf("expected config error on line 1 but got %+v", res[0])
+	}
+	if res[1].RuleID != SARIFRuleNaming || res[1].Level != sarifLevelWarning || res[1].Locations[0].PhysicalLocation.Region.StartLine != 3 {
+		t.Fatalf("expected naming warning on line 3 but got %+v", res[1])
+	}
+	if rules := log.Runs[0].Tool.Driver.Rules; len(rules) != 2 {
+		t.Fatalf("expected a rule per finding type but got %+v", rules)
+	}
+}
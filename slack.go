@@ -0,0 +1,119 @@
+package tables
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SlackNotifier posts a summary of Migrate runs to a Slack incoming webhook: the tables changed,
+// destructive changes, errors and the run duration. Runs without changes or errors are not posted.
+type SlackNotifier struct {
+	WebhookURL string
+	// Channel overrides the default channel of the webhook if set.
+	Channel string
+	// Client sends the requests, a client with a 10 second timeout if nil.
+	Client *http.Client
+}
+
+var defaultSlackClient = &http.Client{Timeout: 10 * time.Second}
+
+type slackMessage struct {
+	Channel string       `json:"channel,omitempty"`
+	Text    string       `json:"text"`
+	Blocks  []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type   string      `json:"type"`
+	Text   *slackText  `json:"text,omitempty"`
+	Fields []slackText `json:"fields,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Notify posts the report.
+func (s *SlackNotifier) Notify(ctx context.Context, report *RunReport) error {
+	changed, failed := report.Changed(), report.Failed()
+	if len(changed) == 0 && len(failed) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(newSlackMessage(report, s.Channel))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = defaultSlackClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("slack webhook: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// newSlackMessage returns the Slack message of a report, posted to channel unless it is empty.
+func newSlackMessage(report *RunReport, channel string) slackMessage {
+	changed, failed, destructive := report.Changed(), report.Failed(), report.Destructive()
+	status := ":white_check_mark: succeeded"
+	if len(failed) > 0 {
+		status = ":x: failed"
+	}
+	title := fmt.Sprintf("Table migration %s in %s %s", report.RunID, report.Env, status)
+
+	blocks := []slackBlock{
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*" + title + "*"}},
+		{Type: "section", Fields: []slackText{
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Tables changed*\n%d", len(changed))},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Destructive changes*\n%d", len(destructive))},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Errors*\n%d", len(failed))},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Duration*\n%s", report.Duration)},
+		}},
+	}
+	if report.Description != "" {
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: "*Intent*\n" + report.Description}})
+	}
+	if len(changed) > 0 {
+		lines := []string{"*Changes*"}
+		for _, m := range changed {
+			changes := make([]string, len(m.Applied))
+			for i, ch := range m.Applied {
+				changes[i] = ch.String()
+			}
+			lines = append(lines, fmt.Sprintf("• `%s`: %s", m.TableInput.TableName, strings.Join(changes, ", ")))
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}})
+	}
+	if len(failed) > 0 {
+		lines := []string{"*Errors*"}
+		for _, m := range failed {
+			errs := make([]string, len(m.Errors))
+			for i, err := range m.Errors {
+				errs[i] = err.Error()
+			}
+			lines = append(lines, fmt.Sprintf("• `%s`: %s", m.TableInput.TableName, strings.Join(errs, "; ")))
+		}
+		blocks = append(blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: strings.Join(lines, "\n")}})
+	}
+	return slackMessage{Channel: channel, Text: title, Blocks: blocks}
+}
@@ -0,0 +1,135 @@
+package tables
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+type recordingNotifier struct {
+	reports []*RunReport
+	// ctxErrs holds the errors of the notification contexts.
+	ctxErrs []error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, report *RunReport) error {
+	n.reports = append(n.reports, report)
+	n.ctxErrs = append(n.ctxErrs, ctx.Err())
+	return nil
+}
+
+func TestMigrateNotifies(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.createTable = func(*dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+		return &dynamodb.CreateTableOutput{}, nil
+	}
+	n := &recordingNotifier{}
+	c, err := NewController(db, "test", nil, nil, WithClock(&fakeClock{}), WithNotifiers(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := ContextWithChangeDescription(ContextWithRunID(context.Background(), "run-1"), "release 42")
+	c.MigrateWithContext(ctx, []*ValidationResult{
+		{TableInput: TableInfo{TableName: "orders"}, CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("orders")}, Diff: "Table missing", CanMigrate: true},
+		{TableInput: TableInfo{TableName: "users"}, CanMigrate: true},
+	})
+	if len(n.reports) != 1 {
+		t.Fatalf("expected a single report but got %d", len(n.reports))
+	}
+	r := n.reports[0]
+	if r.RunID != "run-1" || r.Env != "test" || r.Description != "release 42" || len(r.Results) != 2 || len(r.Changed()) != 1 {
+		t.Fatalf("unexpected report %+v", r)
+	}
+}
+
+func TestCancelledMigrateNotifies(t *testing.T) {
+	n := &recordingNotifier{}
+	c, err := NewController(&fakeDynamoDB{}, "test", nil, nil, WithClock(&fakeClock{}), WithNotifiers(n))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.MigrateWithContext(ctx, []*ValidationResult{
+		{TableInput: TableInfo{TableName: "orders"}, CreateTableInput: &dynamodb.CreateTableInput{TableName: aws.String("orders")}, Diff: "Table missing", CanMigrate: true},
+	})
+	if len(n.reports) != 1 || len(n.reports[0].Failed()) != 1 {
+		t.Fatalf("expected the failed run to be reported but got %+v", n.reports)
+	}
+	if n.ctxErrs[0] != nil {
+		t.Fatalf("expected notification context to outlive the run but got %v", n.ctxErrs[0])
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var posted slackMessage
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts++
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &posted); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+	s := &SlackNotifier{WebhookURL: srv.URL, Channel: "#deploys"}
+
+	if err := s.Notify(context.Background(), &RunReport{RunID: "run-0", Results: []*MigrationResult{{Status: StatusSkipped}}}); err != nil || posts != 0 {
+		t.Fatalf("expected runs without changes not to be posted but got %d posts, %v", posts, err)
+	}
+
+	report := &RunReport{
+		RunID:    "run-1",
+		Env:      "prod",
+		Duration: 3 * time.Second,
+		Results: []*MigrationResult{
+			{
+				TableInput: TableInfo{TableName: "orders"},
+				Status:     StatusApplied,
+				Applied:    []Change{{TableName: "orders", Type: ChangeUpdateTTL, Destructive: true}},
+			},
+			{
+				TableInput: TableInfo{TableName: "users"},
+				Status:     StatusFailed,
+				Errors:     []error{errors.New("access denied")},
+			},
+		},
+	}
+	if err := s.Notify(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	text := []string{posted.Text}
+	for _, b := range posted.Blocks {
+		if b.Text != nil {
+			text = append(text, b.Text.Text)
+		}
+		for _, f := range b.Fields {
+			text = append(text, f.Text)
+		}
+	}
+	all := strings.Join(text, "\n")
+	for _, want := range []string{"run-1 in prod :x: failed", "*Destructive changes*\n1", "*Duration*\n3s", "`orders`: UpdateTimeToLive orders (destructive)", "`users`: access denied"} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("expected %q in message but got %s", want, all)
+		}
+	}
+	if posted.Channel != "#deploys" {
+		t.Fatalf("expected channel #deploys but got %q", posted.Channel)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	})
+	if err := s.Notify(context.Background(), report); err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Fatalf("expected webhook error but got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package tables
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Snapshot is the current state of all managed tables in DynamoDB.
+// It is serializable and can be stored for audits or compared offline.
+type Snapshot struct {
+	TakenAt time.Time       `json:"taken_at" yaml:"taken_at"`
+	Tables  []TableSnapshot `json:"tables" yaml:"tables"`
+}
+
+// TableSnapshot is the current state of a single table.
+// Table is nil if the table does not exist. Streams are part of the table description.
+type TableSnapshot struct {
+	TableName         string                                 `json:"table_name" yaml:"table_name"`
+	Region            string                                 `json:"region,omitempty" yaml:"region,omitempty"`
+	Table             *dynamodb.TableDescription             `json:"table,omitempty" yaml:"table,omitempty"`
+	TimeToLive        *dynamodb.TimeToLiveDescription        `json:"time_to_live,omitempty" yaml:"time_to_live,omitempty"`
+	Tags              []*dynamodb.Tag                        `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ContinuousBackups *dynamodb.ContinuousBackupsDescription `json:"continuous_backups,omitempty" yaml:"continuous_backups,omitempty"`
+}
+
+// Snapshot describes all managed tables, including their TTL, tags and point in time recovery
+// settings. Tables are listed in config order. The first error encountered is returned.
+func (c *Controller) Snapshot() (*Snapshot, error) {
+	tables := managedTables(c.tenantTables())
+	snapshots := make([]TableSnapshot, len(tables))
+	errs := make([]error, len(tables))
+
+	var wg sync.WaitGroup
+	for i, tbl := range tables {
+		wg.Add(1)
+		go func(i int, tbl TableInfo) {
+			defer wg.Done()
+			snapshots[i], errs[i] = c.snapshotTable(tbl)
+			if errs[i] != nil {
+				c.Log.Errorf("Snapshot table [%s] with error: %v", tbl.TableName, errs[i])
+			}
+		}(i, tbl)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Snapshot{
+		TakenAt: c.clock.Now(),
+		Tables:  snapshots,
+	}, nil
+}
+
+func (c *Controller) snapshotTable(tbl TableInfo) (TableSnapshot, error) {
+	s := TableSnapshot{
+		TableName: physicalName(c.env, tbl),
+		Region:    c.tableRegion(tbl),
+	}
+	desc, err := c.describeTable(tbl)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+			return s, nil
+		}
+		return s, err
+	}
+	s.Table = desc
+
+	if s.TimeToLive, err = c.describeTTL(tbl); err != nil {
+		return s, err
+	}
+	if s.Tags, err = c.listTags(tbl, desc.TableArn); err != nil {
+		return s, err
+	}
+	backups, err := c.client(tbl).DescribeContinuousBackups(&dynamodb.DescribeContinuousBackupsInput{
+		TableName: aws.String(s.TableName),
+	})
+	if err != nil {
+		return s, err
+	}
+	s.ContinuousBackups = backups.ContinuousBackupsDescription
+	return s, nil
+}
+
+// listTags returns all tags of the table with the given ARN.
+func (c *Controller) listTags(tbl TableInfo, arn *string) ([]*dynamodb.Tag, error) {
+	tags := []*dynamodb.Tag{}
+	input := &dynamodb.ListTagsOfResourceInput{ResourceArn: arn}
+	for {
+		output, err := c.client(tbl).ListTagsOfResource(input)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, output.Tags...)
+		if output.NextToken == nil {
+			return tags, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
@@ -0,0 +1,73 @@
+package tables
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestSnapshot(t *testing.T) {
+	db := &fakeDynamoDB{}
+	db.describeTable = func(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		if aws.StringValue(input.TableName) == "missing" {
+			return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+		}
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableName: input.TableName,
+			TableArn:  aws.String("arn:" + aws.StringValue(input.TableName)),
+		}}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled),
+		}}, nil
+	}
+	db.listTags = func(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+		if input.NextToken == nil {
+			return &dynamodb.ListTagsOfResourceOutput{
+				Tags:      []*dynamodb.Tag{{Key: aws.String("team"), Value: aws.String("payments")}},
+				NextToken: aws.String("next"),
+			}, nil
+		}
+		return &dynamodb.ListTagsOfResourceOutput{
+			Tags: []*dynamodb.Tag{{Key: aws.String("env"), Value: aws.String("test")}},
+		}, nil
+	}
+	db.describeBackups = func(*dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+		return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: &dynamodb.ContinuousBackupsDescription{
+			ContinuousBackupsStatus: aws.String(dynamodb.ContinuousBackupsStatusEnabled),
+		}}, nil
+	}
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newTestController(t, db, &fakeClock{now: now})
+	c.Tables = []TableInfo{
+		{TableName: "orders"},
+		{TableName: "missing"},
+		{TableName: "legacy", Unmanaged: true},
+	}
+
+	s, err := c.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.TakenAt.Equal(now) {
+		t.Fatalf("expected snapshot time %v but got %v", now, s.TakenAt)
+	}
+	if len(s.Tables) != 2 {
+		t.Fatalf("expected 2 managed tables but got %d", len(s.Tables))
+	}
+	orders := s.Tables[0]
+	if orders.Table == nil || len(orders.Tags) != 2 || orders.TimeToLive == nil || orders.ContinuousBackups == nil {
+		t.Fatalf("expected complete snapshot of orders but got %+v", orders)
+	}
+	if s.Tables[1].TableName != "missing" || s.Tables[1].Table != nil {
+		t.Fatalf("expected missing table without description but got %+v", s.Tables[1])
+	}
+	if _, err := json.Marshal(s); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,264 @@
+package tables
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// WriteSnapshot writes the snapshot as indented JSON to w.
+func WriteSnapshot(w io.Writer, s *Snapshot) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SaveSnapshot writes the snapshot to a local state file.
+func SaveSnapshot(path string, s *Snapshot) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSnapshot(f, s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSnapshot reads a local state file written by SaveSnapshot.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSnapshot(f)
+}
+
+// SaveSnapshotS3 stores the snapshot as a state file in the given S3 bucket.
+func SaveSnapshotS3(api s3iface.S3API, bucket, key string, s *Snapshot) error {
+	buf := &bytes.Buffer{}
+	if err := WriteSnapshot(buf, s); err != nil {
+		return err
+	}
+	_, err := api.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(buf.Bytes()),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+// LoadSnapshotS3 reads a state file stored by SaveSnapshotS3.
+func LoadSnapshotS3(api s3iface.S3API, bucket, key string) (*Snapshot, error) {
+	output, err := api.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ReadSnapshot(output.Body)
+}
+
+// ValidateAgainstState compares the table schemas in the config file to the table
+// descriptions of a saved snapshot instead of the current database. No AWS calls are made,
+// tables missing from the snapshot are reported as missing and TTL sampling is skipped.
+// The results and errors are the same as Validate's.
+func (c *Controller) ValidateAgainstState(state *Snapshot) ([]*ValidationResult, error) {
+	offline := c.offline(state)
+	return offline.validate(filterTables(offline.Tables, offline.filter))
+}
+
+// ReadTableDescription reads a saved DescribeTable output, e.g. produced by
+// `aws dynamodb describe-table --table-name orders > orders.json`.
+func ReadTableDescription(r io.Reader) (*dynamodb.TableDescription, error) {
+	var raw interface{}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(epochTimestamps(raw, ""))
+	if err != nil {
+		return nil, err
+	}
+	output := &dynamodb.DescribeTableOutput{}
+	if err := json.Unmarshal(b, output); err != nil {
+		return nil, err
+	}
+	if output.Table == nil {
+		return nil, ErrMissingTableDescription
+	}
+	return output.Table, nil
+}
+
+// epochTimestamps replaces the epoch seconds the AWS CLI prints for timestamps such as
+// CreationDateTime with RFC 3339 strings, the format time.Time is decoded from.
+func epochTimestamps(v interface{}, key string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = epochTimestamps(e, k)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = epochTimestamps(e, key)
+		}
+	case json.Number:
+		if !strings.HasSuffix(key, "DateTime") {
+			return v
+		}
+		// Seconds and fraction are parsed separately, float64 would round the nanoseconds.
+		parts := append(strings.SplitN(v.String(), ".", 2), "")
+		secs, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return v
+		}
+		nanos, err := strconv.ParseInt((parts[1] + "000000000")[:9], 10, 64)
+		if err != nil {
+			return v
+		}
+		return time.Unix(secs, nanos).UTC().Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// LoadTableDescriptions reads the saved DescribeTable outputs at the given paths.
+func LoadTableDescriptions(paths ...string) ([]*dynamodb.TableDescription, error) {
+	descs := make([]*dynamodb.TableDescription, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		desc, err := ReadTableDescription(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		descs = append(descs, desc)
+	}
+	return descs, nil
+}
+
+// ValidateAgainstDescriptions compares the table schemas in the config file to exported
+// table descriptions, see ReadTableDescription. No AWS calls are made and tables without
+// a description are reported as missing. TTL is not part of DescribeTable outputs and is
+// therefore not compared.
+func (c *Controller) ValidateAgainstDescriptions(descs []*dynamodb.TableDescription) ([]*ValidationResult, error) {
+	tables := c.tenantTables()
+	regions := make(map[string]string, len(tables))
+	for _, tbl := range tables {
+		regions[physicalName(c.env, tbl)] = c.tableRegion(tbl)
+	}
+	state := &Snapshot{TakenAt: c.clock.Now()}
+	for _, desc := range descs {
+		name := aws.StringValue(desc.TableName)
+		state.Tables = append(state.Tables, TableSnapshot{
+			TableName: name,
+			Region:    regions[name],
+			Table:     desc,
+		})
+	}
+
+	offline := c.offline(state)
+	offline.Tables = make([]TableInfo, len(tables))
+	for i, tbl := range tables {
+		tbl.Ignore = append(append([]string{}, tbl.Ignore...), IgnoreTTL)
+		offline.Tables[i] = tbl
+	}
+	return offline.validate(filterTables(offline.Tables, offline.filter))
+}
+
+// offline returns a copy of the controller which reads table descriptions from the given
+// snapshot instead of DynamoDB. Comparisons which need other AWS calls are disabled.
+func (c *Controller) offline(state *Snapshot) *Controller {
+	cc := *c
+	cc.Tables = c.tenantTables()
+	cc.mu = &sync.RWMutex{}
+	cc.tenants = nil
+	cc.clientFactory = func(region, account string) DynamoDBAPI {
+		return newStateClient(state, region)
+	}
+	cc.clients = &clientCache{}
+	cc.refreshFingerprints = false
+	cc.fastValidate = false
+	cc.ttlSampleSize = 0
+	cc.utilizationCloudWatch = nil
+	cc.resourceManagers = nil
+	return &cc
+}
+
+// stateClient serves table descriptions of a single region from a snapshot.
+// Only the describe calls used by Validate are supported.
+type stateClient struct {
+	DynamoDBAPI
+	tables map[string]TableSnapshot
+}
+
+func newStateClient(state *Snapshot, region string) *stateClient {
+	sc := &stateClient{tables: map[string]TableSnapshot{}}
+	for _, t := range state.Tables {
+		if t.Region == region && t.Table != nil {
+			sc.tables[t.TableName] = t
+		}
+	}
+	return sc
+}
+
+func (sc *stateClient) table(name *string) (TableSnapshot, error) {
+	t, ok := sc.tables[aws.StringValue(name)]
+	if !ok {
+		return t, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "table not found in state: "+aws.StringValue(name), nil)
+	}
+	return t, nil
+}
+
+func (sc *stateClient) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	t, err := sc.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTableOutput{Table: t.Table}, nil
+}
+
+func (sc *stateClient) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	t, err := sc.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: t.TimeToLive}, nil
+}
+
+func (sc *stateClient) DescribeContinuousBackups(input *dynamodb.DescribeContinuousBackupsInput) (*dynamodb.DescribeContinuousBackupsOutput, error) {
+	t, err := sc.table(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.DescribeContinuousBackupsOutput{ContinuousBackupsDescription: t.ContinuousBackups}, nil
+}
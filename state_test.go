@@ -0,0 +1,111 @@
+package tables
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestValidateAgainstState(t *testing.T) {
+	orders := TableInfo{TableName: "orders", PrimaryKey: "id", ReadThroughput: 5, WriteThroughput: 5}
+	input := CreateTableInput(orders, "")
+	state := &Snapshot{Tables: []TableSnapshot{
+		{
+			TableName: "orders",
+			Table: &dynamodb.TableDescription{
+				TableName:            aws.String("orders"),
+				TableStatus:          aws.String(dynamodb.TableStatusActive),
+				AttributeDefinitions: input.AttributeDefinitions,
+				KeySchema:            input.KeySchema,
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+					ReadCapacityUnits:  aws.Int64(1),
+					WriteCapacityUnits: aws.Int64(5),
+				},
+			},
+		},
+		{TableName: "missing"},
+	}}
+
+	buf := &bytes.Buffer{}
+	if err := WriteSnapshot(buf, state); err != nil {
+		t.Fatal(err)
+	}
+	state, err := ReadSnapshot(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The controller has no client, any AWS call would panic.
+	c := newTestController(t, &fakeDynamoDB{}, &fakeClock{})
+	c.env = ""
+	c.Tables = []TableInfo{orders, {TableName: "missing", PrimaryKey: "id"}}
+	results, err := c.ValidateAgainstState(state)
+	if err != ErrBackwardCompatible {
+		t.Fatalf("expected ErrBackwardCompatible but got %v", err)
+	}
+	for _, r := range results {
+		switch r.TableInput.TableName {
+		case "orders":
+			if !strings.Contains(r.Diff, "Throughput") || len(r.UpdateTableInput) != 1 {
+				t.Fatalf("expected throughput update of orders but got %q", r.Diff)
+			}
+		case "missing":
+			if r.CreateTableInput == nil {
+				t.Fatalf("expected missing table to be created but got %q", r.Diff)
+			}
+		}
+	}
+}
+
+func TestReadTableDescription(t *testing.T) {
+	exported := `{
+    "Table": {
+        "AttributeDefinitions": [{"AttributeName": "id", "AttributeType": "S"}],
+        "TableName": "orders",
+        "KeySchema": [{"AttributeName": "id", "KeyType": "HASH"}],
+        "TableStatus": "ACTIVE",
+        "CreationDateTime": 1579087651.123,
+        "ProvisionedThroughput": {
+            "NumberOfDecreasesToday": 0,
+            "ReadCapacityUnits": 5,
+            "WriteCapacityUnits": 5
+        },
+        "TableArn": "arn:aws:dynamodb:us-east-1:123456789012:table/orders"
+    }
+}`
+	desc, err := ReadTableDescription(strings.NewReader(exported))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc.CreationDateTime == nil || desc.CreationDateTime.Unix() != 1579087651 {
+		t.Fatalf("expected creation time to be parsed but got %v", desc.CreationDateTime)
+	}
+	iso, err := ReadTableDescription(strings.NewReader(strings.Replace(exported, "1579087651.123", `"2020-01-15T11:27:31.123000+00:00"`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !iso.CreationDateTime.Equal(*desc.CreationDateTime) {
+		t.Fatalf("expected ISO 8601 creation time %v but got %v", desc.CreationDateTime, iso.CreationDateTime)
+	}
+
+	c := newTestController(t, &fakeDynamoDB{}, &fakeClock{})
+	c.env = ""
+	c.Tables = []TableInfo{{
+		TableName:       "orders",
+		PrimaryKey:      "id",
+		ReadThroughput:  5,
+		WriteThroughput: 5,
+		TTL:             &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true},
+	}}
+	results, err := c.ValidateAgainstDescriptions([]*dynamodb.TableDescription{desc})
+	if err != nil {
+		t.Fatalf("expected no diff but got %v: %q", err, results[0].Diff)
+	}
+
+	if _, err := ReadTableDescription(strings.NewReader(`{}`)); err != ErrMissingTableDescription {
+		t.Fatalf("expected ErrMissingTableDescription but got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package tables
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Object locates a stored plan or snapshot, written as s3://bucket/key?versionId=version.
+type S3Object struct {
+	Bucket string
+	Key    string
+	// VersionID pins a version of the object in a versioned bucket, the latest version if empty.
+	VersionID string
+}
+
+// ParseS3URI parses an S3 URI such as s3://artifacts/plans/prod.json?versionId=abc.
+func ParseS3URI(uri string) (S3Object, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return S3Object{}, fmt.Errorf("%w: %v", ErrInvalidS3URI, err)
+	}
+	obj := S3Object{Bucket: u.Host, Key: strings.TrimPrefix(u.Path, "/"), VersionID: u.Query().Get("versionId")}
+	if u.Scheme != "s3" || obj.Bucket == "" || obj.Key == "" {
+		return S3Object{}, fmt.Errorf("%w: %s", ErrInvalidS3URI, uri)
+	}
+	return obj, nil
+}
+
+func (o S3Object) String() string {
+	uri := "s3://" + o.Bucket + "/" + o.Key
+	if o.VersionID != "" {
+		uri += "?versionId=" + url.QueryEscape(o.VersionID)
+	}
+	return uri
+}
+
+// SavePlanS3 stores the plan at the given S3 URI and returns the URI of the stored version, which
+// pins exactly this plan for a later LoadPlanS3 if the bucket is versioned. The object is encrypted
+// at rest with the given KMS key, or with S3 managed keys if kmsKeyID is empty. A deploy job in
+// another account needs read access to the bucket and, for a KMS key, decrypt access to the key.
+func SavePlanS3(api s3iface.S3API, uri string, p *Plan, kmsKeyID string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := WritePlan(buf, p); err != nil {
+		return "", err
+	}
+	return putS3(api, uri, buf.Bytes(), kmsKeyID)
+}
+
+// LoadPlanS3 reads a plan stored by SavePlanS3 or a plan file written by WritePlanFile without
+// encryption, see ReadPlanFile.
+func LoadPlanS3(api s3iface.S3API, uri string) (*Plan, error) {
+	body, err := getS3(api, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ReadPlanFile(body, nil)
+}
+
+// SaveSnapshotS3URI stores the snapshot at the given S3 URI like SavePlanS3.
+func SaveSnapshotS3URI(api s3iface.S3API, uri string, s *Snapshot, kmsKeyID string) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := WriteSnapshot(buf, s); err != nil {
+		return "", err
+	}
+	return putS3(api, uri, buf.Bytes(), kmsKeyID)
+}
+
+// LoadSnapshotS3URI reads a snapshot stored by SaveSnapshotS3URI or SaveSnapshotS3.
+func LoadSnapshotS3URI(api s3iface.S3API, uri string) (*Snapshot, error) {
+	body, err := getS3(api, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ReadSnapshot(body)
+}
+
+func putS3(api s3iface.S3API, uri string, data []byte, kmsKeyID string) (string, error) {
+	obj, err := ParseS3URI(uri)
+	if err != nil {
+		return "", err
+	}
+	input := &s3.PutObjectInput{
+		Bucket:               aws.String(obj.Bucket),
+		Key:                  aws.String(obj.Key),
+		Body:                 bytes.NewReader(data),
+		ContentType:          aws.String("application/json"),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAes256),
+	}
+	if kmsKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(kmsKeyID)
+	}
+	output, err := api.PutObject(input)
+	if err != nil {
+		return "", err
+	}
+	// Unversioned buckets return no version, the URI then refers to the latest object.
+	obj.VersionID = aws.StringValue(output.VersionId)
+	return obj.String(), nil
+}
+
+func getS3(api s3iface.S3API, uri string) (io.ReadCloser, error) {
+	obj, err := ParseS3URI(uri)
+	if err != nil {
+		return nil, err
+	}
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(obj.Bucket),
+		Key:    aws.String(obj.Key),
+	}
+	if obj.VersionID != "" {
+		input.VersionId = aws.String(obj.VersionID)
+	}
+	output, err := api.GetObject(input)
+	if err != nil {
+		return nil, err
+	}
+	return output.Body, nil
+}
@@ -0,0 +1,99 @@
+package tables
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// fakeS3 is a versioned bucket.
+type fakeS3 struct {
+	s3iface.S3API
+	versions map[string][][]byte
+	puts     []*s3.PutObjectInput
+}
+
+func (f *fakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	data, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.puts = append(f.puts, input)
+	key := aws.StringValue(input.Bucket) + "/" + aws.StringValue(input.Key)
+	f.versions[key] = append(f.versions[key], data)
+	return &s3.PutObjectOutput{VersionId: aws.String(strconv.Itoa(len(f.versions[key])))}, nil
+}
+
+func (f *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	versions := f.versions[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	if len(versions) == 0 {
+		return nil, errors.New("NoSuchKey")
+	}
+	data := versions[len(versions)-1]
+	if input.VersionId != nil {
+		v, _ := strconv.Atoi(aws.StringValue(input.VersionId))
+		data = versions[v-1]
+	}
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func TestPlanS3(t *testing.T) {
+	api := &fakeS3{versions: map[string][][]byte{}}
+	first := &Plan{Tables: []ValidationReport{{Table: "users"}}, Actions: []Action{}}
+	second := &Plan{Tables: []ValidationReport{{Table: "orders"}}, Actions: []Action{}}
+
+	uri, err := SavePlanS3(api, "s3://artifacts/plans/prod.json", first, "alias/plans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri != "s3://artifacts/plans/prod.json?versionId=1" {
+		t.Fatalf("unexpected URI %s", uri)
+	}
+	if _, err := SavePlanS3(api, "s3://artifacts/plans/prod.json", second, ""); err != nil {
+		t.Fatal(err)
+	}
+	if sse := api.puts[0]; aws.StringValue(sse.ServerSideEncryption) != s3.ServerSideEncryptionAwsKms || aws.StringValue(sse.SSEKMSKeyId) != "alias/plans" {
+		t.Fatalf("expected KMS encryption but got %+v", sse)
+	}
+	if sse := api.puts[1]; aws.StringValue(sse.ServerSideEncryption) != s3.ServerSideEncryptionAes256 {
+		t.Fatalf("expected S3 managed encryption but got %+v", sse)
+	}
+
+	for uri, want := range map[string]string{uri: "users", "s3://artifacts/plans/prod.json": "orders"} {
+		p, err := LoadPlanS3(api, uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Tables[0].Table != want {
+			t.Fatalf("%s: expected plan of %s but got %+v", uri, want, p)
+		}
+	}
+
+	if _, err := SaveSnapshotS3URI(api, "s3://artifacts/state.json", &Snapshot{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshotS3URI(api, "s3://artifacts/state.json?versionId=1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseS3URI(t *testing.T) {
+	for _, uri := range []string{"artifacts/plan.json", "https://artifacts/plan.json", "s3://artifacts", "s3:///plan.json"} {
+		if _, err := ParseS3URI(uri); !errors.Is(err, ErrInvalidS3URI) {
+			t.Fatalf("%s: expected ErrInvalidS3URI but got %v", uri, err)
+		}
+	}
+	obj, err := ParseS3URI("s3://artifacts/plans/prod.json?versionId=a%2Bb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj != (S3Object{Bucket: "artifacts", Key: "plans/prod.json", VersionID: "a+b"}) || obj.String() != "s3://artifacts/plans/prod.json?versionId=a%2Bb" {
+		t.Fatalf("unexpected object %+v %s", obj, obj)
+	}
+}
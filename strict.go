@@ -0,0 +1,150 @@
+package tables
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// LoadOption configures how a config is loaded.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	strict bool
+}
+
+func newLoadOptions(opts []LoadOption) loadOptions {
+	o := loadOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Strict rejects configs with unknown fields, e.g. a misspelled read_thoughput which would
+// otherwise be ignored and leave the table without read capacity. The error lists every
+// unknown field with the path to it, e.g. tables[orders].read_thoughput.
+func Strict() LoadOption {
+	return func(o *loadOptions) {
+		o.strict = true
+	}
+}
+
+// envSettings is the shape of the environments section checked by Strict.
+type envSettings map[string]struct {
+	Tables map[string]TableInfo `yaml:"tables"`
+}
+
+// checkKnownFields returns an ErrUnknownField error listing the fields of a config which
+// do not belong to the value they are set on.
+func checkKnownFields(data []byte) error {
+	doc := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	unknown := []string{}
+	if root.Kind == yamlv3.SequenceNode {
+		unknownFields(root, reflect.TypeOf([]TableInfo{}), "tables", &unknown)
+	} else {
+		unknownFields(root, reflect.TypeOf(configFile{}), "", &unknown)
+		if envs := mappingValue(root, "environments"); envs != nil {
+			unknownFields(envs, reflect.TypeOf(envSettings{}), "environments", &unknown)
+		}
+	}
+	return unknownFieldsError(unknown)
+}
+
+func unknownFieldsError(unknown []string) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(unknown, ", "))
+}
+
+// unknownFields appends the paths of the keys of n which are no fields of t.
+// Values of other kinds than t, e.g. a scalar where a mapping is expected, are left to the decoder.
+func unknownFields(n *yamlv3.Node, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n.Kind == yamlv3.AliasNode {
+		n = n.Alias
+	}
+	switch {
+	case t.Kind() == reflect.Struct && n.Kind == yamlv3.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			field, ok := fields[key]
+			if !ok {
+				*unknown = append(*unknown, fieldPath(path, key))
+				continue
+			}
+			unknownFields(n.Content[i+1], field, fieldPath(path, key), unknown)
+		}
+	case t.Kind() == reflect.Slice && n.Kind == yamlv3.SequenceNode:
+		for i, item := range n.Content {
+			unknownFields(item, t.Elem(), path+"["+itemLabel(item, i)+"]", unknown)
+		}
+	case t.Kind() == reflect.Map && n.Kind == yamlv3.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			unknownFields(n.Content[i+1], t.Elem(), path+"["+n.Content[i].Value+"]", unknown)
+		}
+	}
+}
+
+// yamlFields returns the types of the fields of struct type t keyed by their yaml name.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// itemLabel names a list item by its name, e.g. the table name, or its position.
+func itemLabel(n *yamlv3.Node, i int) string {
+	for _, key := range []string{"table_name", "index_name", "name", "region"} {
+		if v := mappingValue(n, key); v != nil && v.Kind == yamlv3.ScalarNode && v.Value != "" {
+			return v.Value
+		}
+	}
+	return strconv.Itoa(i)
+}
+
+func mappingValue(n *yamlv3.Node, key string) *yamlv3.Node {
+	if n.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func fieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,72 @@
+package tables
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFileStrict(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"tables.yaml": `
+index_templates:
+  by_created:
+    primary_key: created
+    primary_key_typ: N
+environments:
+  prod:
+    tables:
+      orders:
+        write_thoughput: 10
+tables:
+  - table_name: orders
+    primary_key: id
+    read_thoughput: 5
+    ttl:
+      attribute_name: expiry
+      enable: true
+    indexes:
+      - index_name: by_customer
+        primary_key: customer_id
+        primary_key_type: S
+        projected_fields: [status]
+`,
+		"valid.yaml":      "tables:\n  - table_name: orders\n    primary_key: id\n    read_throughput: 5\n",
+		"valid.prod.yaml": "tables:\n  orders:\n    read_thoughput: 50\n",
+	})
+
+	// Unknown fields are ignored unless strict.
+	if _, err := LoadFile(filepath.Join(dir, "tables.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadFile(filepath.Join(dir, "tables.yaml"), Strict())
+	if !errors.Is(err, ErrUnknownField) {
+		t.Fatalf("expected unknown field error but got %v", err)
+	}
+	for _, field := range []string{
+		"index_templates[by_created].primary_key_typ",
+		"environments[prod].tables[orders].write_thoughput",
+		"tables[orders].read_thoughput",
+		"tables[orders].ttl.enable",
+		"tables[orders].indexes[by_customer].projected_fields",
+	} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("expected %s to be reported but got %v", field, err)
+		}
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "valid.yaml"), Strict()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadFileEnv(filepath.Join(dir, "valid.yaml"), "prod", Strict())
+	if !errors.Is(err, ErrInvalidOverlay) || !strings.Contains(err.Error(), "tables[orders].read_thoughput") {
+		t.Fatalf("expected unknown field in overlay but got %v", err)
+	}
+
+	_, err = LoadFromReader(strings.NewReader("- table_name: users\n  primary_key: id\n  sort_kye: created\n"), Strict())
+	if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), "tables[users].sort_kye") {
+		t.Fatalf("expected unknown field in list config but got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package tables
+
+import (
+	"fmt"
+)
+
+// TableError is a blocking error of a single table.
+type TableError struct {
+	TableName string
+	Err       error
+}
+
+func (e *TableError) Error() string {
+	return fmt.Sprintf("table %s: %v", e.TableName, e.Err)
+}
+
+func (e *TableError) Unwrap() error {
+	return e.Err
+}
+
+// Warning is a non-blocking finding of a single table, such as backward compatible drift.
+type Warning struct {
+	TableName string
+	Message   string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("table %s: %s", w.TableName, w.Message)
+}
+
+// ValidationSummary separates the findings of a Validate run into non-blocking Warnings,
+// e.g. backward compatible drift which Migrate can fix, and blocking Errors,
+// e.g. backward incompatible drift or tables which could not be compared.
+type ValidationSummary struct {
+	Results  []*ValidationResult
+	Warnings []Warning
+	Errors   []*TableError
+}
+
+// HasErrors returns true if the summary contains blocking errors.
+func (s *ValidationSummary) HasErrors() bool {
+	return len(s.Errors) > 0
+}
+
+// NewValidationSummary builds the summary of the given validation results.
+func NewValidationSummary(results []*ValidationResult) *ValidationSummary {
+	s := &ValidationSummary{
+		Results:  results,
+		Warnings: []Warning{},
+		Errors:   []*TableError{},
+	}
+	for _, r := range results {
+		name := r.TableInput.TableName
+		for _, w := range r.Warnings {
+			s.Warnings = append(s.Warnings, Warning{TableName: name, Message: w})
+		}
+		switch {
+		case r.Error != nil:
+			s.Errors = append(s.Errors, &TableError{TableName: name, Err: r.Error})
+		case r.incompatible():
+			s.Errors = append(s.Errors, &TableError{
+				TableName: name,
+				Err:       fmt.Errorf("%w: %s", ErrBackwardIncompatible, r.Diff),
+			})
+		case len(r.Diff) > 0:
+			s.Warnings = append(s.Warnings, Warning{
+				TableName: name,
+				Message:   fmt.Sprintf("%v: %s", ErrBackwardCompatible, r.Diff),
+			})
+		}
+	}
+	return s
+}
+
+// ValidateSummary is the same as Validate but returns a ValidationSummary, so only
+// genuinely blocking conditions need error handling via HasErrors.
+func (c *Controller) ValidateSummary() *ValidationSummary {
+	results, _ := c.Validate()
+	return NewValidationSummary(results)
+}
+
+// ChangeSummary counts the changes of a set of validation results by action type.
+type ChangeSummary struct {
+	// Tables to create
+	Creates int
+	// Tables to update via UpdateTable
+	Updates int
+	// Tables with TTL changes
+	TTLChanges int
+	// Tables to delete
+	Deletes int
+	// Changes which can affect existing data
+	Destructive int
+	// Tables which could not be compared or contain backward incompatible changes
+	Failures int
+	// Tables without any change
+	Unchanged int
+}
+
+// Summarize counts the changes of the given validation results, so dashboards and CLI footers
+// can show "3 to create, 5 to update, 0 destructive" without re-parsing results.
+func Summarize(results []*ValidationResult) ChangeSummary {
+	s := ChangeSummary{}
+	for _, r := range results {
+		if r == nil {
+			continue
+		}
+		if r.Error != nil || !r.CanMigrate {
+			s.Failures++
+			continue
+		}
+		changes := tableChanges(r)
+		if len(changes) == 0 {
+			s.Unchanged++
+			continue
+		}
+		counted := map[ChangeType]bool{}
+		for _, ch := range changes {
+			if ch.Destructive {
+				s.Destructive++
+			}
+			if counted[ch.Type] {
+				continue
+			}
+			counted[ch.Type] = true
+			switch ch.Type {
+			case ChangeCreateTable:
+				s.Creates++
+			case ChangeUpdateTable, ChangeUpdateContinuousBackups:
+				s.Updates++
+			case ChangeUpdateTTL:
+				s.TTLChanges++
+			case ChangeDeleteTable:
+				s.Deletes++
+			}
+		}
+	}
+	return s
+}
+
+func (s ChangeSummary) String() string {
+	return fmt.Sprintf("%d to create, %d to update, %d TTL changes, %d to delete, %d destructive, %d failed, %d unchanged",
+		s.Creates, s.Updates, s.TTLChanges, s.Deletes, s.Destructive, s.Failures, s.Unchanged)
+}
@@ -0,0 +1,121 @@
+package tables
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestNewValidationSummary(t *testing.T) {
+	results := []*ValidationResult{
+		{
+			TableInput: TableInfo{TableName: "in-sync"},
+			CanMigrate: true,
+		},
+		{
+			TableInput: TableInfo{TableName: "compatible"},
+			Diff:       "missing table: compatible",
+			CanMigrate: true,
+		},
+		{
+			TableInput: TableInfo{TableName: "incompatible"},
+			Diff:       "Key Schema",
+			CanMigrate: false,
+		},
+		{
+			TableInput: TableInfo{TableName: "deprecated"},
+			Warnings:   []string{"deprecated table exists: deprecated"},
+			CanMigrate: true,
+		},
+	}
+
+	s := NewValidationSummary(results)
+	if len(s.Warnings) != 2 {
+		t.Fatalf("expected 2 warnings but got %v", s.Warnings)
+	}
+	if !s.HasErrors() || len(s.Errors) != 1 {
+		t.Fatalf("expected 1 error but got %v", s.Errors)
+	}
+	if !errors.Is(s.Errors[0], ErrBackwardIncompatible) {
+		t.Fatalf("expected ErrBackwardIncompatible but got %v", s.Errors[0])
+	}
+}
+
+func TestValidationResultString(t *testing.T) {
+	r := &ValidationResult{
+		TableInput: TableInfo{TableName: "orders"},
+		TableDescription: &dynamodb.TableDescription{
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  aws.Int64(5),
+				WriteCapacityUnits: aws.Int64(5),
+			},
+		},
+		UpdateTableInput: []*dynamodb.UpdateTableInput{
+			{
+				ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
+					ReadCapacityUnits:  aws.Int64(10),
+					WriteCapacityUnits: aws.Int64(10),
+				},
+			},
+			{
+				GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+					{Create: &dynamodb.CreateGlobalSecondaryIndexAction{IndexName: aws.String("byEmail")}},
+				},
+			},
+		},
+		Diff:       "Throughput",
+		CanMigrate: true,
+	}
+
+	expected := "orders: throughput 5/5→10/10, +1 GSI (compatible)"
+	if s := r.String(); s != expected {
+		t.Fatalf("expected %q but got %q", expected, s)
+	}
+
+	r = &ValidationResult{
+		TableInput: TableInfo{TableName: "orders"},
+		CanMigrate: true,
+	}
+	if s := r.String(); s != "orders: in sync" {
+		t.Fatalf("expected in sync but got %q", s)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []*ValidationResult{
+		{
+			TableInput:       TableInfo{TableName: "new"},
+			CreateTableInput: &dynamodb.CreateTableInput{},
+			UpdateTTLInput:   &dynamodb.UpdateTimeToLiveInput{},
+			CanMigrate:       true,
+		},
+		{
+			TableInput:       TableInfo{TableName: "updated"},
+			UpdateTableInput: []*dynamodb.UpdateTableInput{{}, {}},
+			UpdateTTLInput:   &dynamodb.UpdateTimeToLiveInput{},
+			CanMigrate:       true,
+		},
+		{
+			TableInput: TableInfo{TableName: "unchanged"},
+			CanMigrate: true,
+		},
+		{
+			TableInput: TableInfo{TableName: "incompatible"},
+			CanMigrate: false,
+		},
+	}
+
+	expected := ChangeSummary{
+		Creates:     1,
+		Updates:     1,
+		TTLChanges:  2,
+		Destructive: 1,
+		Failures:    1,
+		Unchanged:   1,
+	}
+	if s := Summarize(results); s != expected {
+		t.Fatalf("expected %+v but got %+v", expected, s)
+	}
+}
@@ -0,0 +1,61 @@
+package tables
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// maxEpochSeconds is used to detect TTL values in milliseconds, which DynamoDB
+// treats as dates far in the future so items never expire.
+const maxEpochSeconds = 1e11
+
+// sampleTTL scans a few items of the table and returns warnings if the configured TTL
+// attribute is missing, not numeric or not an epoch value in seconds, since DynamoDB
+// silently ignores such values and never expires anything.
+func (c *Controller) sampleTTL(tbl TableInfo) ([]string, error) {
+	output, err := c.client(tbl).Scan(&dynamodb.ScanInput{
+		TableName:                aws.String(physicalName(c.env, tbl)),
+		Limit:                    aws.Int64(c.ttlSampleSize),
+		ProjectionExpression:     aws.String("#ttl"),
+		ExpressionAttributeNames: map[string]*string{"#ttl": aws.String(tbl.TTL.AttributeName)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ttlWarnings(tbl.TTL.AttributeName, output.Items), nil
+}
+
+// ttlWarnings checks the TTL attribute of the given sampled items.
+func ttlWarnings(attributeName string, items []map[string]*dynamodb.AttributeValue) []string {
+	if len(items) == 0 {
+		return nil
+	}
+	warnings := []string{}
+	present, numeric, seconds := 0, 0, 0
+	for _, item := range items {
+		v, ok := item[attributeName]
+		if !ok {
+			continue
+		}
+		present++
+		if v.N == nil {
+			continue
+		}
+		numeric++
+		if n, err := strconv.ParseFloat(aws.StringValue(v.N), 64); err == nil && n < maxEpochSeconds {
+			seconds++
+		}
+	}
+	switch {
+	case present == 0:
+		warnings = append(warnings, fmt.Sprintf("TTL attribute %s is missing in %d sampled items", attributeName, len(items)))
+	case numeric < present:
+		warnings = append(warnings, fmt.Sprintf("TTL attribute %s is not numeric in %d of %d sampled items", attributeName, present-numeric, present))
+	case seconds < numeric:
+		warnings = append(warnings, fmt.Sprintf("TTL attribute %s is not an epoch value in seconds in %d of %d sampled items", attributeName, numeric-seconds, numeric))
+	}
+	return warnings
+}
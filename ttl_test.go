@@ -0,0 +1,77 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestTTLWarnings(t *testing.T) {
+	valid := []map[string]*dynamodb.AttributeValue{
+		{"expiry": {N: aws.String("1700000000")}},
+		{},
+	}
+	if w := ttlWarnings("expiry", valid); len(w) != 0 {
+		t.Fatalf("expected no warnings but got %v", w)
+	}
+
+	missing := []map[string]*dynamodb.AttributeValue{
+		{"other": {N: aws.String("1700000000")}},
+	}
+	if w := ttlWarnings("expiry", missing); len(w) != 1 {
+		t.Fatalf("expected 1 warning but got %v", w)
+	}
+
+	notNumeric := []map[string]*dynamodb.AttributeValue{
+		{"expiry": {S: aws.String("2023-11-14")}},
+	}
+	if w := ttlWarnings("expiry", notNumeric); len(w) != 1 {
+		t.Fatalf("expected 1 warning but got %v", w)
+	}
+
+	millis := []map[string]*dynamodb.AttributeValue{
+		{"expiry": {N: aws.String("1700000000000")}},
+	}
+	if w := ttlWarnings("expiry", millis); len(w) != 1 {
+		t.Fatalf("expected 1 warning but got %v", w)
+	}
+}
+
+func TestFrozenTTL(t *testing.T) {
+	tbl := TableInfo{
+		TableName:       "orders",
+		PrimaryKey:      "id",
+		ReadThroughput:  1,
+		WriteThroughput: 1,
+		TTL:             &TTLAttributeInfo{AttributeName: "expires_at", Enabled: true, Frozen: true},
+	}
+	status := dynamodb.TimeToLiveStatusEnabled
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: describeInput(CreateTableInput(tbl, ""))}, nil
+	}
+	db.describeTTL = func(*dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+		return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: &dynamodb.TimeToLiveDescription{
+			AttributeName:    aws.String("expiry"),
+			TimeToLiveStatus: aws.String(status),
+		}}, nil
+	}
+	c, err := NewController(db, "", nil, []TableInfo{tbl}, WithClock(&fakeClock{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes to an enabled frozen TTL are only warned about.
+	r, _ := c.ValidateTable("orders")
+	if r.Diff != "" || r.UpdateTTLInput != nil || len(r.Warnings) != 1 {
+		t.Fatalf("expected frozen TTL to be warned about only but got %+v", r)
+	}
+
+	// A frozen TTL which is not enabled yet is still enabled.
+	status = dynamodb.TimeToLiveStatusDisabled
+	r, _ = c.ValidateTable("orders")
+	if r.Diff == "" || r.UpdateTTLInput == nil || len(r.Warnings) != 0 {
+		t.Fatalf("expected disabled frozen TTL to be enabled but got %+v", r)
+	}
+}
@@ -8,37 +8,157 @@ import (
 )
 
 type TableInfo struct {
-	Title           string            `yaml:"title"`
-	TableName       string            `yaml:"table_name"`
-	PrimaryKey      string            `yaml:"primary_key"`
-	SortKey         string            `yaml:"sort_key"`
-	SortKeyType     string            `yaml:"sort_key_type"`
-	ReadThroughput  int64             `yaml:"read_throughput"`
-	WriteThroughput int64             `yaml:"write_throughput"`
-	Indexes         []IndexInfo       `yaml:"indexes"`
-	TTL             *TTLAttributeInfo `yaml:"ttl"`
+	Title           string            `yaml:"title" hcl:"title,optional"`
+	TableName       string            `yaml:"table_name" hcl:"name,label"`
+	PrimaryKey      string            `yaml:"primary_key" hcl:"primary_key,optional"`
+	SortKey         string            `yaml:"sort_key" hcl:"sort_key,optional"`
+	SortKeyType     string            `yaml:"sort_key_type" hcl:"sort_key_type,optional"`
+	ReadThroughput  int64             `yaml:"read_throughput" hcl:"read_throughput,optional"`
+	WriteThroughput int64             `yaml:"write_throughput" hcl:"write_throughput,optional"`
+	Indexes         []IndexInfo       `yaml:"indexes" hcl:"index,block"`
+	TTL             *TTLAttributeInfo `yaml:"ttl" hcl:"ttl,block"`
+	// BillingMode is either PROVISIONED or PAY_PER_REQUEST, PROVISIONED if empty.
+	// Throughput settings are not used by PAY_PER_REQUEST tables.
+	BillingMode string `yaml:"billing_mode" hcl:"billing_mode,optional"`
+	// Region overrides the controller's default region for this table.
+	// Requires a ClientFactory.
+	Region string `yaml:"region" hcl:"region,optional"`
+	// Replicas lists the expected replica regions of a global table.
+	Replicas []ReplicaInfo `yaml:"replicas" hcl:"replica,block"`
+	// Labels are arbitrary key/value pairs used to select tables, e.g. team: payments.
+	Labels map[string]string `yaml:"labels" hcl:"labels,optional"`
+	// Unmanaged tables are documented in the config but never validated or mutated,
+	// e.g. tables owned by another deployment pipeline.
+	Unmanaged bool `yaml:"unmanaged" hcl:"unmanaged,optional"`
+	// Lifecycle controls which changes are applied to the table, see LifecycleCreateOnly.
+	Lifecycle string `yaml:"lifecycle" hcl:"lifecycle,optional"`
+	// Deprecated tables are never created. Validate warns while they exist
+	// and prune mode schedules their deletion.
+	Deprecated bool `yaml:"deprecated" hcl:"deprecated,optional"`
+	// Ignore lists aspects of the table which are intentionally unmanaged and
+	// excluded from validation, e.g. throughput or gsi.read_throughput.
+	Ignore []string `yaml:"ignore" hcl:"ignore,optional"`
+	// Tenant is part of the table name of per-tenant tables, see WithTenants.
+	Tenant string `yaml:"tenant" hcl:"tenant,optional"`
+	// Attributes declares the types of attributes beyond the base key. Index keys without
+	// a type take the declared one. Declarations are not sent to DynamoDB, which only
+	// accepts definitions of key attributes.
+	Attributes []AttributeInfo `yaml:"attributes" hcl:"attribute,block"`
+	// Description states the intent of the latest change to the table for reviewers and auditors,
+	// e.g. "add by_email for login lookups". It is shown in plans and audit records and excluded
+	// from the fingerprint, as it does not affect the schema.
+	Description string `yaml:"description" json:"-" hcl:"description,optional"`
+	// Alarms configures CloudWatch alarms managed by an AlarmManager.
+	Alarms *AlarmsInfo `yaml:"alarms" json:",omitempty"`
+	// DAX configures the DAX cluster managed by a DAXManager.
+	DAX *DAXInfo `yaml:"dax" json:",omitempty"`
 }
 
+// AttributeInfo declares the type of a single attribute.
+type AttributeInfo struct {
+	Name string `yaml:"name" hcl:"name,label"`
+	// Type is S, N, B or an attribute type alias.
+	Type string `yaml:"type" hcl:"type"`
+}
+
+const (
+	// LifecycleManaged tables are created and kept in sync with the config. This is the default.
+	LifecycleManaged = "managed"
+	// LifecycleCreateOnly tables are created if missing but never updated afterwards,
+	// e.g. tables whose throughput is managed by autoscaling or another tool.
+	LifecycleCreateOnly = "create_only"
+)
+
 type IndexInfo struct {
-	IndexName       string   `yaml:"index_name"`
-	PrimaryKey      string   `yaml:"primary_key"`
-	PrimaryKeyType  string   `yaml:"primary_key_type"`
-	SortKey         string   `yaml:"sort_key"`
-	SortKeyType     string   `yaml:"sort_key_type"`
-	ReadThroughput  int64    `yaml:"read_throughput"`
-	WriteThroughput int64    `yaml:"write_throughput"`
-	ProjectedFields []string `yaml:"projection_fields"`
+	IndexName      string `yaml:"index_name" hcl:"name,label"`
+	PrimaryKey     string `yaml:"primary_key" hcl:"primary_key,optional"`
+	PrimaryKeyType string `yaml:"primary_key_type" hcl:"primary_key_type,optional"`
+	SortKey        string `yaml:"sort_key" hcl:"sort_key,optional"`
+	SortKeyType    string `yaml:"sort_key_type" hcl:"sort_key_type,optional"`
+	// ReadThroughput and WriteThroughput default to the throughput of the table when loaded.
+	ReadThroughput  int64    `yaml:"read_throughput" hcl:"read_throughput,optional"`
+	WriteThroughput int64    `yaml:"write_throughput" hcl:"write_throughput,optional"`
+	ProjectedFields []string `yaml:"projection_fields" hcl:"projection_fields,optional"`
+	// ProjectionType is ALL, KEYS_ONLY or INCLUDE, INCLUDE if empty.
+	// ProjectedFields are only projected by INCLUDE indexes.
+	ProjectionType string `yaml:"projection_type" hcl:"projection_type,optional" json:",omitempty"`
+	// Template names an entry of the config's index_templates the index is based on.
+	// Values set on the index override the template.
+	Template string `yaml:"template" hcl:"template,optional"`
+}
+
+// ReplicaInfo describes a single replica of a global table.
+// Empty TableClass and KMSMasterKeyID are not validated.
+type ReplicaInfo struct {
+	Region         string `yaml:"region" hcl:"region,label"`
+	TableClass     string `yaml:"table_class" hcl:"table_class,optional"`
+	KMSMasterKeyID string `yaml:"kms_master_key_id" hcl:"kms_master_key_id,optional"`
 }
 
 type TTLAttributeInfo struct {
-	AttributeName string `yaml:"attribute_name"`
-	Enabled       bool   `yaml:"enabled"`
+	AttributeName string `yaml:"attribute_name" hcl:"attribute_name"`
+	Enabled       bool   `yaml:"enabled" hcl:"enabled,optional"`
+	// Frozen stops TTL management once TTL is enabled on the table,
+	// later changes to the TTL configuration are reported as warnings only.
+	Frozen bool `yaml:"frozen" hcl:"frozen,optional"`
+}
+
+// clone returns a deep copy of the table, so changes to the copy do not affect the config.
+// Nil and empty slices are preserved, since both are part of the table's fingerprint.
+func (table TableInfo) clone() TableInfo {
+	c := table
+	if table.Indexes != nil {
+		c.Indexes = make([]IndexInfo, len(table.Indexes))
+		for i, index := range table.Indexes {
+			if index.ProjectedFields != nil {
+				index.ProjectedFields = append(make([]string, 0, len(index.ProjectedFields)), index.ProjectedFields...)
+			}
+			c.Indexes[i] = index
+		}
+	}
+	if table.TTL != nil {
+		ttl := *table.TTL
+		c.TTL = &ttl
+	}
+	if table.Replicas != nil {
+		c.Replicas = append(make([]ReplicaInfo, 0, len(table.Replicas)), table.Replicas...)
+	}
+	if table.Ignore != nil {
+		c.Ignore = append(make([]string, 0, len(table.Ignore)), table.Ignore...)
+	}
+	if table.Attributes != nil {
+		c.Attributes = append(make([]AttributeInfo, 0, len(table.Attributes)), table.Attributes...)
+	}
+	if table.Alarms != nil {
+		alarms := *table.Alarms
+		if alarms.Actions != nil {
+			alarms.Actions = append(make([]string, 0, len(alarms.Actions)), alarms.Actions...)
+		}
+		c.Alarms = &alarms
+	}
+	if table.DAX != nil {
+		d := *table.DAX
+		if d.SecurityGroupIDs != nil {
+			d.SecurityGroupIDs = append(make([]string, 0, len(d.SecurityGroupIDs)), d.SecurityGroupIDs...)
+		}
+		if d.SubnetIDs != nil {
+			d.SubnetIDs = append(make([]string, 0, len(d.SubnetIDs)), d.SubnetIDs...)
+		}
+		c.DAX = &d
+	}
+	if table.Labels != nil {
+		c.Labels = make(map[string]string, len(table.Labels))
+		for k, v := range table.Labels {
+			c.Labels[k] = v
+		}
+	}
+	return c
 }
 
 // CreateTableInput is a helper function to create a base CreateTableInput type
 func CreateTableInput(table TableInfo, envPrefix string) *dynamodb.CreateTableInput {
 	input := &dynamodb.CreateTableInput{
-		TableName: aws.String(withPrefix(envPrefix, table.Title, table.TableName)),
+		TableName: aws.String(physicalName(envPrefix, table)),
 		AttributeDefinitions: []*dynamodb.AttributeDefinition{
 			{
 				AttributeName: aws.String(table.PrimaryKey),
@@ -96,17 +216,29 @@ func CreateTableInput(table TableInfo, envPrefix string) *dynamodb.CreateTableIn
 		}
 		input.GlobalSecondaryIndexes = gsi
 	}
+	if billingMode(table) == dynamodb.BillingModePayPerRequest {
+		input.BillingMode = aws.String(dynamodb.BillingModePayPerRequest)
+		input.ProvisionedThroughput = nil
+		for _, gsi := range input.GlobalSecondaryIndexes {
+			gsi.ProvisionedThroughput = nil
+		}
+	}
 	return input
 }
 
 // NewGlobalSecondaryIndex is a helper function to create a base GlobalSecondaryIndex type
 func NewGlobalSecondaryIndex(index IndexInfo) *dynamodb.GlobalSecondaryIndex {
-	projectedAttributes := []*string{
-		aws.String("id"),
+	projection := &dynamodb.Projection{
+		ProjectionType: aws.String(dynamodb.ProjectionTypeInclude),
 	}
-	if len(index.ProjectedFields) > 0 {
+	if t := index.ProjectionType; t == dynamodb.ProjectionTypeAll || t == dynamodb.ProjectionTypeKeysOnly {
+		projection.ProjectionType = aws.String(t)
+	} else {
+		projection.NonKeyAttributes = []*string{
+			aws.String("id"),
+		}
 		for _, pf := range index.ProjectedFields {
-			projectedAttributes = append(projectedAttributes, aws.String(pf))
+			projection.NonKeyAttributes = append(projection.NonKeyAttributes, aws.String(pf))
 		}
 	}
 
@@ -118,10 +250,7 @@ func NewGlobalSecondaryIndex(index IndexInfo) *dynamodb.GlobalSecondaryIndex {
 				KeyType:       aws.String("HASH"),
 			},
 		},
-		Projection: &dynamodb.Projection{
-			NonKeyAttributes: projectedAttributes,
-			ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
-		},
+		Projection: projection,
 		ProvisionedThroughput: &dynamodb.ProvisionedThroughput{
 			ReadCapacityUnits:  aws.Int64(index.ReadThroughput),
 			WriteCapacityUnits: aws.Int64(index.WriteThroughput),
@@ -141,7 +270,7 @@ func NewGlobalSecondaryIndex(index IndexInfo) *dynamodb.GlobalSecondaryIndex {
 // UpdateTableInputBase is a helper function to create a base UpdateTableInput type
 func UpdateTableInputBase(table TableInfo, envPrefix string) *dynamodb.UpdateTableInput {
 	base := &dynamodb.UpdateTableInput{
-		TableName: aws.String(withPrefix(envPrefix, table.Title, table.TableName)),
+		TableName: aws.String(physicalName(envPrefix, table)),
 		AttributeDefinitions: []*dynamodb.AttributeDefinition{
 			{
 				AttributeName: aws.String(table.PrimaryKey),
@@ -190,7 +319,7 @@ func UpdateTableInputBase(table TableInfo, envPrefix string) *dynamodb.UpdateTab
 func NewUpdateTimeToLiveInput(table TableInfo, envPrefix string, ttl *TTLAttributeInfo) *dynamodb.UpdateTimeToLiveInput {
 	if ttl != nil {
 		return &dynamodb.UpdateTimeToLiveInput{
-			TableName: aws.String(withPrefix(envPrefix, table.Title, table.TableName)),
+			TableName: aws.String(physicalName(envPrefix, table)),
 			TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
 				AttributeName: aws.String(ttl.AttributeName),
 				Enabled:       aws.Bool(ttl.Enabled),
@@ -200,6 +329,16 @@ func NewUpdateTimeToLiveInput(table TableInfo, envPrefix string, ttl *TTLAttribu
 	return nil
 }
 
+// physicalName returns the name of the table in DynamoDB, title-env-tenant-name
+// if the table has a title and tenant and env is set.
+func physicalName(env string, table TableInfo) string {
+	name := table.TableName
+	if table.Tenant != "" {
+		name = fmt.Sprintf("%s-%s", table.Tenant, name)
+	}
+	return withPrefix(env, table.Title, name)
+}
+
 func withPrefix(env, title, tableName string) string {
 	if len(env) > 0 && len(title) > 0 {
 		return fmt.Sprintf("%s-%s-%s", title, env, tableName)
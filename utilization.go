@@ -0,0 +1,147 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// DefaultUtilizationWindow is the window of consumed capacity metrics advisories are based on unless set otherwise.
+const DefaultUtilizationWindow = 7 * 24 * time.Hour
+
+// Advisory kinds.
+const (
+	AdvisoryReduceCapacity   = "ReduceCapacity"
+	AdvisoryIncreaseCapacity = "IncreaseCapacity"
+	AdvisorySwitchToOnDemand = "SwitchToOnDemand"
+)
+
+// Utilization ratios of consumed to provisioned capacity the advisories are based on.
+const (
+	// Capacity peaking below lowUtilization is reduced to reach targetUtilization at the peak.
+	lowUtilization = 0.3
+	// Capacity averaging above highUtilization is increased to reach targetUtilization at the peak.
+	highUtilization   = 0.8
+	targetUtilization = 0.7
+	// Capacity averaging below spikyAverage while peaking above targetUtilization suits on-demand billing.
+	spikyAverage = 0.1
+)
+
+// Advisory is a suggestion to change the throughput of a table based on its consumed capacity.
+// Advisories do not affect the validation status and are never applied by Migrate.
+type Advisory struct {
+	Kind string `json:"kind" yaml:"kind"`
+	// Capacity is read or write, empty for AdvisorySwitchToOnDemand.
+	Capacity  string `json:"capacity,omitempty" yaml:"capacity,omitempty"`
+	Current   int64  `json:"current,omitempty" yaml:"current,omitempty"`
+	Suggested int64  `json:"suggested,omitempty" yaml:"suggested,omitempty"`
+	Message   string `json:"message" yaml:"message"`
+}
+
+func (a Advisory) String() string {
+	return a.Message
+}
+
+// capacityUsage is the consumed capacity per second of a table relative to its provisioned capacity.
+type capacityUsage struct {
+	capacity    string
+	provisioned int64
+	average     float64
+	peak        float64
+}
+
+// utilizationAdvisories compares the consumed capacity of a provisioned table over the utilization
+// window to its configured throughput. Tables younger than the window are skipped.
+func (c *Controller) utilizationAdvisories(ctx context.Context, tbl TableInfo, desc *dynamodb.TableDescription) ([]Advisory, error) {
+	if billingMode(tbl) == dynamodb.BillingModePayPerRequest {
+		return nil, nil
+	}
+	end := c.clock.Now()
+	start := end.Add(-c.utilizationWindow)
+	if created := desc.CreationDateTime; created != nil && created.After(start) {
+		return nil, nil
+	}
+
+	usages := []capacityUsage{}
+	for _, u := range []struct {
+		capacity    string
+		metric      string
+		provisioned int64
+	}{
+		{"read", "ConsumedReadCapacityUnits", tbl.ReadThroughput},
+		{"write", "ConsumedWriteCapacityUnits", tbl.WriteThroughput},
+	} {
+		if u.provisioned <= 0 {
+			continue
+		}
+		usage, err := c.capacityUsage(ctx, physicalName(c.env, tbl), u.metric, start, end)
+		if err != nil {
+			return nil, err
+		}
+		usage.capacity, usage.provisioned = u.capacity, u.provisioned
+		usages = append(usages, usage)
+	}
+	return advise(usages, c.utilizationWindow), nil
+}
+
+// capacityUsage returns the average and hourly peak of the consumed capacity per second.
+// Hours without datapoints count as unused.
+func (c *Controller) capacityUsage(ctx context.Context, tableName, metric string, start, end time.Time) (capacityUsage, error) {
+	period := int64(3600)
+	if window := int64(end.Sub(start) / time.Second); window < period {
+		period = metricPeriod(end.Sub(start))
+	}
+	sums, err := metricSums(ctx, c.utilizationCloudWatch, metric, capacityDimensions(tableName, ""), start, end, period)
+	if err != nil {
+		return capacityUsage{}, err
+	}
+	usage := capacityUsage{}
+	total := 0.0
+	for _, sum := range sums {
+		total += sum
+		usage.peak = math.Max(usage.peak, sum/float64(period))
+	}
+	usage.average = total / end.Sub(start).Seconds()
+	return usage, nil
+}
+
+// advise returns the advisories for the given usages. A single on-demand advisory replaces the
+// throughput advisories if any capacity is mostly idle with short peaks.
+func advise(usages []capacityUsage, window time.Duration) []Advisory {
+	advisories := []Advisory{}
+	for _, u := range usages {
+		average, peak := u.average/float64(u.provisioned), u.peak/float64(u.provisioned)
+		if average < spikyAverage && peak > targetUtilization {
+			return []Advisory{{
+				Kind: AdvisorySwitchToOnDemand,
+				Message: fmt.Sprintf("%s capacity averaged %.0f%% of %d units with peaks of %.0f%% over %s, consider billing_mode: %s",
+					u.capacity, average*100, u.provisioned, peak*100, formatWindow(window), dynamodb.BillingModePayPerRequest),
+			}}
+		}
+		suggested := int64(math.Max(1, math.Ceil(u.peak/targetUtilization)))
+		switch {
+		case peak < lowUtilization && suggested < u.provisioned:
+			advisories = append(advisories, Advisory{
+				Kind:      AdvisoryReduceCapacity,
+				Capacity:  u.capacity,
+				Current:   u.provisioned,
+				Suggested: suggested,
+				Message: fmt.Sprintf("%s capacity peaked at %.0f%% of %d units over %s, consider %s_throughput: %d",
+					u.capacity, peak*100, u.provisioned, formatWindow(window), u.capacity, suggested),
+			})
+		case average > highUtilization && suggested > u.provisioned:
+			advisories = append(advisories, Advisory{
+				Kind:      AdvisoryIncreaseCapacity,
+				Capacity:  u.capacity,
+				Current:   u.provisioned,
+				Suggested: suggested,
+				Message: fmt.Sprintf("%s capacity averaged %.0f%% of %d units over %s, consider %s_throughput: %d",
+					u.capacity, average*100, u.provisioned, formatWindow(window), u.capacity, suggested),
+			})
+		}
+	}
+	return advisories
+}
@@ -0,0 +1,83 @@
+package tables
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func TestUtilizationAdvisories(t *testing.T) {
+	now := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	db := &fakeDynamoDB{}
+	db.describeTable = func(*dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: &dynamodb.TableDescription{
+			TableStatus:           aws.String(dynamodb.TableStatusActive),
+			CreationDateTime:      aws.Time(now.Add(-30 * 24 * time.Hour)),
+			AttributeDefinitions:  []*dynamodb.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: aws.String("S")}},
+			KeySchema:             []*dynamodb.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: aws.String("HASH")}},
+			ProvisionedThroughput: &dynamodb.ProvisionedThroughputDescription{ReadCapacityUnits: aws.Int64(100), WriteCapacityUnits: aws.Int64(10)},
+		}}, nil
+	}
+	cw := &fakeCloudWatch{}
+	cw.statistics = func(input *cloudwatch.GetMetricStatisticsInput) (*cloudwatch.GetMetricStatisticsOutput, error) {
+		if aws.Int64Value(input.Period) != 3600 {
+			t.Fatalf("expected hourly statistics but got %v", input)
+		}
+		// Reads peak at 10 units per second in a single hour, writes stay at 9 units per second.
+		if aws.StringValue(input.MetricName) == "ConsumedReadCapacityUnits" {
+			return &cloudwatch.GetMetricStatisticsOutput{Datapoints: []*cloudwatch.Datapoint{{Sum: aws.Float64(36000)}}}, nil
+		}
+		output := &cloudwatch.GetMetricStatisticsOutput{}
+		for i := 0; i < 7*24; i++ {
+			output.Datapoints = append(output.Datapoints, &cloudwatch.Datapoint{Sum: aws.Float64(9 * 3600)})
+		}
+		return output, nil
+	}
+	c, err := NewController(db, "", nil, []TableInfo{
+		{TableName: "orders", PrimaryKey: "id", ReadThroughput: 100, WriteThroughput: 10},
+	}, WithClock(&fakeClock{now: now}), WithUtilizationAdvisories(cw, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := c.ValidateTable("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Advisory{
+		{
+			Kind:      AdvisoryReduceCapacity,
+			Capacity:  "read",
+			Current:   100,
+			Suggested: 15,
+			Message:   "read capacity peaked at 10% of 100 units over 7 days, consider read_throughput: 15",
+		},
+		{
+			Kind:      AdvisoryIncreaseCapacity,
+			Capacity:  "write",
+			Current:   10,
+			Suggested: 13,
+			Message:   "write capacity averaged 90% of 10 units over 7 days, consider write_throughput: 13",
+		},
+	}
+	if !reflect.DeepEqual(r.Advisories, want) {
+		t.Fatalf("expected %+v but got %+v", want, r.Advisories)
+	}
+	if report := NewValidationReport(r); report.Status != ValidationInSync || len(report.Advisories) != 2 {
+		t.Fatalf("expected in sync report with advisories but got %+v", report)
+	}
+}
+
+func TestAdviseOnDemand(t *testing.T) {
+	advisories := advise([]capacityUsage{
+		{capacity: "read", provisioned: 100, average: 2, peak: 95},
+		{capacity: "write", provisioned: 10, average: 1, peak: 2},
+	}, 24*time.Hour)
+	if len(advisories) != 1 || advisories[0].Kind != AdvisorySwitchToOnDemand {
+		t.Fatalf("expected a single on-demand advisory but got %+v", advisories)
+	}
+}
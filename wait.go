@@ -0,0 +1,199 @@
+package tables
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// tableVisibleAttempts is the number of times a newly created table is described
+// until it is visible, MultiIndexUpdateRetryInterval seconds apart.
+const tableVisibleAttempts = 15
+
+// waitUntilTableExists blocks until a newly created table is visible, i.e. can be described.
+// The table may still be CREATING. ErrWaitTimeout is returned if the table is not visible
+// after tableVisibleAttempts.
+func (c *Controller) waitUntilTableExists(ctx context.Context, ti TableInfo) error {
+	for attempt := 1; ; attempt++ {
+		_, err := c.describeTable(ti)
+		if !isNotFound(err) {
+			return err
+		}
+		if attempt >= tableVisibleAttempts {
+			return ErrWaitTimeout
+		}
+		if err := c.sleepWithContext(ctx, MultiIndexUpdateRetryInterval*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// waitUntilNotBusy returns nil if the table of the validation result is missing or ACTIVE.
+// Busy tables are waited for if WithWaitForBusyTables is set, otherwise ErrTableBusy is returned.
+func (c *Controller) waitUntilNotBusy(ctx context.Context, r *ValidationResult) error {
+	if r.TableStatus == "" || r.TableStatus == dynamodb.TableStatusActive {
+		return nil
+	}
+	if c.busyWaitTimeout <= 0 {
+		return ErrTableBusy
+	}
+	c.Log.Infof("Waiting for table %s with status %s", r.TableInput.TableName, r.TableStatus)
+	if err := c.waitForTable(ctx, r.TableInput, c.busyWaitTimeout); err != nil {
+		if err == ErrWaitTimeout {
+			return ErrTableBusy
+		}
+		return err
+	}
+	return nil
+}
+
+// waitForTable blocks until the table is ACTIVE.
+// ErrWaitTimeout is returned if the table is not ACTIVE within timeout.
+func (c *Controller) waitForTable(ctx context.Context, ti TableInfo, timeout time.Duration) error {
+	deadline := c.clock.Now().Add(timeout)
+	for {
+		desc, err := c.describeTable(ti)
+		if err != nil {
+			aerr, ok := err.(awserr.Error)
+			if !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+				return err
+			}
+		} else if aws.StringValue(desc.TableStatus) == dynamodb.TableStatusActive {
+			return nil
+		}
+
+		if c.clock.Now().After(deadline) {
+			return ErrWaitTimeout
+		}
+		if err := c.sleepWithContext(ctx, MultiIndexUpdateRetryInterval*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// WaitForActive blocks until the table and all of its GSIs are ACTIVE, e.g. before serving
+// traffic from tables just created by Migrate. The table is identified by its name in the
+// config or in DynamoDB. ErrWaitTimeout is returned if it is not ACTIVE within timeout.
+func (c *Controller) WaitForActive(ctx context.Context, tableName string, timeout time.Duration) error {
+	ti, err := c.lookupTable(tableName)
+	if err != nil {
+		return err
+	}
+	deadline := c.clock.Now().Add(timeout)
+	for {
+		desc, err := c.describeTable(ti)
+		if err != nil {
+			aerr, ok := err.(awserr.Error)
+			if !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+				return err
+			}
+		} else if aws.StringValue(desc.TableStatus) == dynamodb.TableStatusActive && indexesActive(desc, indexNames(desc)) {
+			return nil
+		}
+
+		if c.clock.Now().After(deadline) {
+			return ErrWaitTimeout
+		}
+		if err := c.sleepWithContext(ctx, MultiIndexUpdateRetryInterval*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// indexNames returns the names of all GSIs of the table.
+func indexNames(desc *dynamodb.TableDescription) []string {
+	names := make([]string, len(desc.GlobalSecondaryIndexes))
+	for i, gsi := range desc.GlobalSecondaryIndexes {
+		names[i] = aws.StringValue(gsi.IndexName)
+	}
+	return names
+}
+
+// waitForIndexes blocks until the given GSIs of the table are ACTIVE.
+// ErrWaitTimeout is returned if the indexes are not ACTIVE within timeout.
+func (c *Controller) waitForIndexes(ctx context.Context, ti TableInfo, indexNames []string, timeout time.Duration) error {
+	if len(indexNames) == 0 {
+		return nil
+	}
+	deadline := c.clock.Now().Add(timeout)
+	for {
+		desc, err := c.describeTable(ti)
+		if err != nil {
+			aerr, ok := err.(awserr.Error)
+			if !ok || aerr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+				return err
+			}
+		} else if indexesActive(desc, indexNames) {
+			return nil
+		}
+
+		if c.clock.Now().After(deadline) {
+			return ErrWaitTimeout
+		}
+		if err := c.sleepWithContext(ctx, MultiIndexUpdateRetryInterval*time.Second); err != nil {
+			return err
+		}
+	}
+}
+
+// indexesActive returns true if all given GSIs exist in the description and are ACTIVE.
+func indexesActive(desc *dynamodb.TableDescription, indexNames []string) bool {
+	status := make(map[string]string, len(desc.GlobalSecondaryIndexes))
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		status[aws.StringValue(gsi.IndexName)] = aws.StringValue(gsi.IndexStatus)
+	}
+	for _, name := range indexNames {
+		if status[name] != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+	return true
+}
+
+// createdIndexes returns the names of all GSIs created by the given validation result.
+func createdIndexes(r *ValidationResult) []string {
+	names := []string{}
+	if r.CreateTableInput != nil {
+		for _, gsi := range r.CreateTableInput.GlobalSecondaryIndexes {
+			names = append(names, aws.StringValue(gsi.IndexName))
+		}
+	}
+	for _, input := range r.UpdateTableInput {
+		for _, u := range input.GlobalSecondaryIndexUpdates {
+			if u.Create != nil {
+				names = append(names, aws.StringValue(u.Create.IndexName))
+			}
+		}
+	}
+	return names
+}
+
+// waitForTTL polls the TTL of the table until it reaches a terminal ENABLED or DISABLED state
+// and returns that state. ErrWaitTimeout is returned with the last observed state if the TTL
+// is still ENABLING or DISABLING after timeout.
+func (c *Controller) waitForTTL(ctx context.Context, ti TableInfo, timeout time.Duration) (string, error) {
+	deadline := c.clock.Now().Add(timeout)
+	status := ""
+	for {
+		ttl, err := c.describeTTL(ti)
+		if err != nil {
+			return status, err
+		}
+		if ttl != nil {
+			status = aws.StringValue(ttl.TimeToLiveStatus)
+		}
+		if status == dynamodb.TimeToLiveStatusEnabled || status == dynamodb.TimeToLiveStatusDisabled {
+			return status, nil
+		}
+
+		if c.clock.Now().After(deadline) {
+			return status, ErrWaitTimeout
+		}
+		if err := c.sleepWithContext(ctx, MultiIndexUpdateRetryInterval*time.Second); err != nil {
+			return status, err
+		}
+	}
+}
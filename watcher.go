@@ -0,0 +1,210 @@
+package tables
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+// DefaultWatchInterval is the interval a Watcher polls its source at unless set otherwise.
+const DefaultWatchInterval = time.Minute
+
+// ConfigSource is a config monitored by a Watcher.
+type ConfigSource interface {
+	// Version returns an identifier which changes whenever the config changes.
+	Version(ctx context.Context) (string, error)
+	// Load loads the config.
+	Load(ctx context.Context) ([]TableInfo, error)
+}
+
+// FileSource is a local config file, versioned by its modification time and size.
+// Only the file itself is monitored, not the files it includes nor its environment overlay.
+type FileSource struct {
+	Path string
+	// Env selects the environment settings to apply, see LoadEnv.
+	Env string
+	// Strict rejects configs with unknown fields, see Strict.
+	Strict bool
+}
+
+func (s FileSource) Version(ctx context.Context) (string, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+func (s FileSource) Load(ctx context.Context) ([]TableInfo, error) {
+	return configFiles{strict: s.Strict}.load(s.Path, s.Env)
+}
+
+// S3Source is a config file in S3, versioned by its object version, or its ETag in unversioned buckets.
+type S3Source struct {
+	API    s3iface.S3API
+	Bucket string
+	Key    string
+	Env    string
+	Strict bool
+}
+
+func (s S3Source) Version(ctx context.Context) (string, error) {
+	output, err := s.API.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		return "", err
+	}
+	if output.VersionId != nil {
+		return aws.StringValue(output.VersionId), nil
+	}
+	return aws.StringValue(output.ETag), nil
+}
+
+func (s S3Source) Load(ctx context.Context) ([]TableInfo, error) {
+	output, err := s.API.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfigOverlay(data, nil, s.Env, s.Strict)
+}
+
+// SSMSource is a config stored in an SSM parameter, versioned by the parameter version.
+type SSMSource struct {
+	API    ssmiface.SSMAPI
+	Name   string
+	Env    string
+	Strict bool
+}
+
+func (s SSMSource) Version(ctx context.Context) (string, error) {
+	p, err := s.parameter(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(aws.Int64Value(p.Version)), nil
+}
+
+func (s SSMSource) Load(ctx context.Context) ([]TableInfo, error) {
+	p, err := s.parameter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeConfigOverlay([]byte(aws.StringValue(p.Value)), nil, s.Env, s.Strict)
+}
+
+func (s SSMSource) parameter(ctx context.Context) (*ssm.Parameter, error) {
+	output, err := s.API.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(s.Name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output.Parameter, nil
+}
+
+// Watcher monitors a config source and notifies its subscribers with the reloaded tables whenever
+// the config changes, e.g. so a long-running drift daemon picks up new tables without a restart.
+type Watcher struct {
+	source   ConfigSource
+	interval time.Duration
+	log      Logger
+
+	mu          sync.Mutex
+	version     string
+	subscribers []func([]TableInfo)
+}
+
+// NewWatcher returns a watcher polling source at the given interval, DefaultWatchInterval if zero.
+// If logger is nil the default logger is used.
+func NewWatcher(source ConfigSource, interval time.Duration, logger Logger) *Watcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	if logger == nil {
+		logger = &defaultLogger{}
+	}
+	return &Watcher{source: source, interval: interval, log: logger}
+}
+
+// Subscribe registers fn to be called with the tables of every loaded config version.
+// Subscribers are called in order of registration, from the goroutine running the watcher.
+func (w *Watcher) Subscribe(fn func(tables []TableInfo)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Check polls the source once, reloads the config and notifies the subscribers if its version
+// changed since the last successful load. The first check always loads the config.
+// A config which fails to load is retried by the next check.
+func (w *Watcher) Check(ctx context.Context) (bool, error) {
+	version, err := w.source.Version(ctx)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	unchanged := w.version != "" && version == w.version
+	w.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	tables, err := w.source.Load(ctx)
+	if err != nil {
+		return false, err
+	}
+	w.mu.Lock()
+	w.version = version
+	subscribers := append([]func([]TableInfo){}, w.subscribers...)
+	w.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(tables)
+	}
+	return true, nil
+}
+
+// Version returns the version of the last loaded config, empty if none was loaded yet.
+func (w *Watcher) Version() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.version
+}
+
+// Run checks the source immediately and then at every interval until ctx is done, which is
+// the only error returned. Failed checks are logged and retried at the next interval.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if changed, err := w.Check(ctx); err != nil {
+			w.log.Errorf("Watch config: %v", err)
+		} else if changed {
+			w.log.Infof("Watch config: loaded version %s", w.Version())
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tables
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+)
+
+type fakeSSM struct {
+	ssmiface.SSMAPI
+	value   string
+	version int64
+	err     error
+}
+
+func (f *fakeSSM) GetParameterWithContext(ctx aws.Context, input *ssm.GetParameterInput, opts ...request.Option) (*ssm.GetParameterOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ssm.GetParameterOutput{Parameter: &ssm.Parameter{
+		Name:    input.Name,
+		Value:   aws.String(f.value),
+		Version: aws.Int64(f.version),
+	}}, nil
+}
+
+func TestWatcher(t *testing.T) {
+	api := &fakeSSM{value: "- table_name: orders\n", version: 1}
+	w := NewWatcher(SSMSource{API: api, Name: "/tables/config"}, 0, nil)
+	loaded := [][]TableInfo{}
+	w.Subscribe(func(tables []TableInfo) {
+		loaded = append(loaded, tables)
+	})
+	ctx := context.Background()
+
+	check := func(want bool) {
+		t.Helper()
+		changed, err := w.Check(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != want {
+			t.Fatalf("expected changed %v at version %s", want, w.Version())
+		}
+	}
+	check(true)
+	check(false)
+
+	api.value, api.version = "- table_name: orders\n- table_name: users\n", 2
+	check(true)
+	if len(loaded) != 2 || len(loaded[1]) != 2 || loaded[1][1].TableName != "users" || w.Version() != "2" {
+		t.Fatalf("unexpected loaded configs %+v", loaded)
+	}
+
+	api.value, api.version = "- table_name: [", 3
+	if _, err := w.Check(ctx); err == nil {
+		t.Fatal("expected invalid config to fail")
+	}
+	api.value = "- table_name: carts\n"
+	check(true)
+	api.err = errors.New("throttled")
+	if _, err := w.Check(ctx); err == nil || len(loaded) != 3 {
+		t.Fatalf("expected failed check without notification but got %v", err)
+	}
+}
+
+func TestWatcherFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tables.yaml")
+	if err := ioutil.WriteFile(path, []byte("- table_name: orders\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatcher(FileSource{Path: path}, time.Millisecond, nil)
+	loaded := make(chan []TableInfo, 4)
+	w.Subscribe(func(tables []TableInfo) {
+		loaded <- tables
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	next := func() []TableInfo {
+		t.Helper()
+		select {
+		case tables := <-loaded:
+			return tables
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the config to load")
+		}
+		return nil
+	}
+	if tables := next(); tables[0].TableName != "orders" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	if err := ioutil.WriteFile(path, []byte("- table_name: users\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if tables := next(); tables[0].TableName != "users" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+}